@@ -49,10 +49,20 @@ func main() {
 	log.Println("Database health check passed")
 
 	// Initialize services
-	services := &services.Services{
-		DB:     db,
-		Config: cfg,
+	services, err := services.NewServices(db, cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize services:", err)
 	}
+	defer services.Cleanup()
+
+	// Start background video transcoding, upload cleanup, blob collection, download expiry and playback rollup workers
+	services.TranscodeService.Start()
+	services.UploadService.Start()
+	services.BlobService.Start()
+	services.DownloadService.Start()
+	services.PlaybackService.Start()
+	services.LiveService.Start()
+	services.CDNService.Start()
 
 	// Set Gin mode based on environment
 	if cfg.IsProduction() {
@@ -17,12 +17,17 @@ type Config struct {
 	Storage StorageConfig
 	Redis   RedisConfig
 	AWS     AWSConfig
+	Video   VideoConfig
+	DRM     DRMConfig
+	CDN     CDNConfig
+	MFA     MFAConfig
 }
 
 type ServerConfig struct {
-	Port   string
-	Env    string
-	AppURL string
+	Port        string
+	Env         string
+	AppURL      string
+	CORSOrigins []string // Browser origins allowed to call the API and open watch party sockets
 }
 
 type MongoConfig struct {
@@ -55,9 +60,13 @@ type EmailConfig struct {
 }
 
 type StorageConfig struct {
+	Driver       string // "local" or "s3"
 	BasePath     string
 	MaxFileSize  int64
 	AllowedTypes []string
+	// Extra image variant formats encoded with ffmpeg (.webp, .avif)
+	ImageFormats   []string
+	BlobGraceHours int // Unreferenced blobs are kept this long before garbage collection
 }
 
 type RedisConfig struct {
@@ -73,14 +82,91 @@ type AWSConfig struct {
 	Region          string
 	S3Bucket        string
 	CloudFrontURL   string
+	// Custom endpoint for S3-compatible services such as MinIO
+	S3Endpoint       string
+	S3ForcePathStyle bool
+	S3PartSize       int64
+	S3URLExpiryHours int
+}
+
+type VideoConfig struct {
+	FFmpegPath            string
+	FFprobePath           string
+	HLSSegmentDuration    int    // In seconds
+	DASHSegmentMode       string // "template" (segmented) or "base" (single indexed file)
+	StreamTimeout         int    // Seconds without a heartbeat before a stream stops counting
+	TranscodeWorkers      int
+	TranscodeAttempts     int
+	UploadMaxSize         int64
+	UploadExpiryHours     int    // Abandoned resumable uploads are removed after this long
+	HLSEncryption         string // "aes-128" or "none"
+	DASHEncryption        string // "cenc" or "none"
+	ForensicWatermark     string // "ab" packages A/B segment variants per session, "none" disables it
+	WatermarkFilter       string // ffmpeg video filter that marks the B variant
+	DownloadLicenseDays   int    // Offline licences must be renewed online within this many days
+	DownloadPlaybackHours int    // Downloads stop playing this long after they are first played
+	DownloadMaxDevices    int    // Devices per account that may hold downloads at once
+	TrickPlayInterval     int    // Seconds between seek-preview thumbnails, 0 disables them
+	TrickPlayWidth        int    // Width of each seek-preview thumbnail in pixels
+	LiveDVRWindow         int    // Default seconds viewers can seek back in a live event
+	LiveIngestLead        int    // Minutes before a live event's start the encoder may begin pushing
+	LiveSegmentMaxSize    int64  // Largest segment an encoder may push
+	PartyMaxMembers       int    // Accounts per watch party, host included
+	PartyDriftTolerance   int    // Milliseconds a participant may drift before its playback rate is nudged
+	PartySeekThreshold    int    // Milliseconds of drift after which a participant is told to seek instead
+	PartyIdleHours        int    // Watch parties nobody joins for this long are closed
+	AutoplayCountdown     int    // Seconds the up-next card counts down before autoplaying
+	AutoplayLimit         int    // Autoplays in a row before asking whether the viewer is still watching, 0 never asks
+}
+
+type DRMConfig struct {
+	// Base64 encoded 32-byte key that encrypts content keys at rest
+	MasterKey string
+}
+
+type MFAConfig struct {
+	Issuer       string // Account issuer shown in authenticator apps
+	RequireAdmin bool   // Admin routes refuse admins without a confirmed second factor
+	// Base64 encoded 32-byte key that encrypts TOTP secrets at rest
+	EncryptionKey string
+}
+
+type CDNConfig struct {
+	// MaxMind-format .mmdb file (GeoLite2 or GeoIP2 Country/City) that
+	// resolves client IPs to a country and continent
+	GeoIPDatabase  string
+	Origins        []CDNOrigin
+	HealthInterval int // Seconds between origin health probes
+	HealthTimeout  int // Seconds a probe may take before the origin counts as down
+	// Seconds a signed CDN link stays valid. The edge cannot re-check the
+	// subscription, so this bounds how long a link outlives it.
+	URLExpiry int
+}
+
+// CDNOrigin is a CDN hostname fronting the storage root. Each is configured
+// with CDN_<NAME>_* variables for a name listed in CDN_ORIGINS.
+type CDNOrigin struct {
+	Name    string
+	BaseURL string   // Storage paths are appended to this
+	Weight  int      // Share of traffic among origins serving the same client
+	Regions []string // ISO country or continent codes served, empty serves everyone
+	Signing string   // "cloudfront", "token" or "none"
+	// CloudFront key pair ID and path to its PEM private key
+	KeyPairID  string
+	PrivateKey string
+	// Hex HMAC key and query parameter for token auth
+	TokenKey   string
+	TokenName  string
+	HealthPath string // Probed relative to BaseURL, empty skips probing
 }
 
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:   getEnv("PORT", "8080"),
-			Env:    getEnv("ENV", "development"),
-			AppURL: getEnv("APP_URL", "http://localhost:3000"),
+			Port:        getEnv("PORT", "8080"),
+			Env:         getEnv("ENV", "development"),
+			AppURL:      getEnv("APP_URL", "http://localhost:3000"),
+			CORSOrigins: parseList(getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:3001")),
 		},
 		MongoDB: MongoConfig{
 			URI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
@@ -107,9 +193,12 @@ func Load() *Config {
 			FromEmail:    getEnv("FROM_EMAIL", "noreply@netflix-clone.com"),
 		},
 		Storage: StorageConfig{
-			BasePath:     getEnv("STORAGE_BASE_PATH", "./uploads"),
-			MaxFileSize:  parseFileSize(getEnv("STORAGE_MAX_FILE_SIZE", "500MB")),
-			AllowedTypes: parseAllowedTypes(getEnv("STORAGE_ALLOWED_TYPES", ".jpg,.jpeg,.png,.gif,.webp,.mp4,.avi,.mov,.mkv,.webm,.srt,.vtt")),
+			Driver:         getEnv("STORAGE_DRIVER", "local"),
+			BasePath:       getEnv("STORAGE_BASE_PATH", "./uploads"),
+			MaxFileSize:    parseFileSize(getEnv("STORAGE_MAX_FILE_SIZE", "500MB")),
+			AllowedTypes:   parseAllowedTypes(getEnv("STORAGE_ALLOWED_TYPES", ".jpg,.jpeg,.png,.gif,.webp,.mp4,.avi,.mov,.mkv,.webm,.srt,.vtt")),
+			ImageFormats:   parseAllowedTypes(getEnv("IMAGE_VARIANT_FORMATS", ".webp,.avif")),
+			BlobGraceHours: parseInt(getEnv("BLOB_GC_GRACE_HOURS", "24")),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
@@ -118,11 +207,59 @@ func Load() *Config {
 			DB:       parseInt(getEnv("REDIS_DB", "0")),
 		},
 		AWS: AWSConfig{
-			AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
-			SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
-			Region:          getEnv("AWS_REGION", "us-east-1"),
-			S3Bucket:        getEnv("AWS_S3_BUCKET", ""),
-			CloudFrontURL:   getEnv("AWS_CLOUDFRONT_URL", ""),
+			AccessKeyID:      getEnv("AWS_ACCESS_KEY_ID", ""),
+			SecretAccessKey:  getEnv("AWS_SECRET_ACCESS_KEY", ""),
+			Region:           getEnv("AWS_REGION", "us-east-1"),
+			S3Bucket:         getEnv("AWS_S3_BUCKET", ""),
+			CloudFrontURL:    getEnv("AWS_CLOUDFRONT_URL", ""),
+			S3Endpoint:       getEnv("AWS_S3_ENDPOINT", ""),
+			S3ForcePathStyle: getEnv("AWS_S3_FORCE_PATH_STYLE", "false") == "true",
+			S3PartSize:       parseFileSize(getEnv("AWS_S3_PART_SIZE", "64MB")),
+			S3URLExpiryHours: parseInt(getEnv("AWS_S3_URL_EXPIRY_HOURS", "24")),
+		},
+		Video: VideoConfig{
+			FFmpegPath:            getEnv("FFMPEG_PATH", "ffmpeg"),
+			FFprobePath:           getEnv("FFPROBE_PATH", "ffprobe"),
+			HLSSegmentDuration:    parseInt(getEnv("HLS_SEGMENT_DURATION", "6")),
+			DASHSegmentMode:       getEnv("DASH_SEGMENT_MODE", "template"),
+			StreamTimeout:         parseInt(getEnv("STREAM_TIMEOUT", "90")),
+			TranscodeWorkers:      parseInt(getEnv("TRANSCODE_WORKERS", "2")),
+			TranscodeAttempts:     parseInt(getEnv("TRANSCODE_MAX_ATTEMPTS", "3")),
+			UploadMaxSize:         parseFileSize(getEnv("UPLOAD_MAX_SIZE", "50GB")),
+			UploadExpiryHours:     parseInt(getEnv("UPLOAD_EXPIRY_HOURS", "24")),
+			HLSEncryption:         getEnv("HLS_ENCRYPTION", "aes-128"),
+			DASHEncryption:        getEnv("DASH_ENCRYPTION", "cenc"),
+			ForensicWatermark:     getEnv("FORENSIC_WATERMARK", "ab"),
+			WatermarkFilter:       getEnv("FORENSIC_WATERMARK_FILTER", "drawbox=x=iw*31/32:y=ih*31/32:w=iw/64:h=ih/64:color=white@0.03:t=fill"),
+			DownloadLicenseDays:   parseInt(getEnv("DOWNLOAD_LICENSE_DAYS", "30")),
+			DownloadPlaybackHours: parseInt(getEnv("DOWNLOAD_PLAYBACK_HOURS", "48")),
+			DownloadMaxDevices:    parseInt(getEnv("DOWNLOAD_MAX_DEVICES", "4")),
+			TrickPlayInterval:     parseInt(getEnv("TRICKPLAY_INTERVAL", "10")),
+			TrickPlayWidth:        parseInt(getEnv("TRICKPLAY_WIDTH", "240")),
+			LiveDVRWindow:         parseInt(getEnv("LIVE_DVR_WINDOW", "7200")),
+			LiveIngestLead:        parseInt(getEnv("LIVE_INGEST_LEAD", "30")),
+			LiveSegmentMaxSize:    parseFileSize(getEnv("LIVE_SEGMENT_MAX_SIZE", "50MB")),
+			PartyMaxMembers:       parseInt(getEnv("WATCH_PARTY_MAX_MEMBERS", "8")),
+			PartyDriftTolerance:   parseInt(getEnv("WATCH_PARTY_DRIFT_TOLERANCE", "500")),
+			PartySeekThreshold:    parseInt(getEnv("WATCH_PARTY_SEEK_THRESHOLD", "3000")),
+			PartyIdleHours:        parseInt(getEnv("WATCH_PARTY_IDLE_HOURS", "6")),
+			AutoplayCountdown:     parseInt(getEnv("AUTOPLAY_COUNTDOWN", "10")),
+			AutoplayLimit:         parseInt(getEnv("AUTOPLAY_STILL_WATCHING_LIMIT", "3")),
+		},
+		DRM: DRMConfig{
+			MasterKey: getEnv("DRM_MASTER_KEY", ""),
+		},
+		MFA: MFAConfig{
+			Issuer:        getEnv("MFA_ISSUER", "Onflix"),
+			RequireAdmin:  getEnv("MFA_REQUIRE_ADMIN", "false") == "true",
+			EncryptionKey: getEnv("MFA_ENCRYPTION_KEY", ""),
+		},
+		CDN: CDNConfig{
+			GeoIPDatabase:  getEnv("CDN_GEOIP_DATABASE", ""),
+			Origins:        parseCDNOrigins(getEnv("CDN_ORIGINS", "")),
+			HealthInterval: parseInt(getEnv("CDN_HEALTH_INTERVAL", "30")),
+			HealthTimeout:  parseInt(getEnv("CDN_HEALTH_TIMEOUT", "5")),
+			URLExpiry:      parseInt(getEnv("CDN_URL_EXPIRY", "900")),
 		},
 	}
 }
@@ -170,6 +307,24 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("MongoDB URI is required")
 	}
 
+	if c.Storage.Driver == "s3" && c.AWS.S3Bucket == "" {
+		return fmt.Errorf("AWS_S3_BUCKET is required when STORAGE_DRIVER is s3")
+	}
+
+	if c.DRM.MasterKey == "" {
+		if c.IsProduction() {
+			return fmt.Errorf("DRM_MASTER_KEY is required in production")
+		}
+		fmt.Println("Warning: DRM_MASTER_KEY is not set - content keys are encrypted with a key derived from the JWT secret")
+	}
+
+	if c.MFA.EncryptionKey == "" {
+		if c.IsProduction() {
+			return fmt.Errorf("MFA_ENCRYPTION_KEY is required in production")
+		}
+		fmt.Println("Warning: MFA_ENCRYPTION_KEY is not set - TOTP secrets are encrypted with a key derived from the JWT secret")
+	}
+
 	if c.Email.SMTPHost == "" || c.Email.SMTPUsername == "" || c.Email.SMTPPassword == "" {
 		fmt.Println("Warning: Email configuration is incomplete - email functionality may not work")
 	}
@@ -204,6 +359,51 @@ func parseAllowedTypes(typesStr string) []string {
 	return result
 }
 
+// parseList splits a comma-separated value, dropping empty entries
+func parseList(valueStr string) []string {
+	var result []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// parseCDNOrigins reads the CDN_<NAME>_* variables of each comma-separated origin name
+func parseCDNOrigins(namesStr string) []CDNOrigin {
+	var origins []CDNOrigin
+	for _, name := range strings.Split(namesStr, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "CDN_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		var regions []string
+		for _, region := range strings.Split(getEnv(prefix+"REGIONS", ""), ",") {
+			if region = strings.ToUpper(strings.TrimSpace(region)); region != "" {
+				regions = append(regions, region)
+			}
+		}
+
+		origins = append(origins, CDNOrigin{
+			Name:       name,
+			BaseURL:    strings.TrimSuffix(getEnv(prefix+"URL", ""), "/"),
+			Weight:     parseInt(getEnv(prefix+"WEIGHT", "1")),
+			Regions:    regions,
+			Signing:    strings.ToLower(getEnv(prefix+"SIGNING", "none")),
+			KeyPairID:  getEnv(prefix+"KEY_PAIR_ID", ""),
+			PrivateKey: getEnv(prefix+"PRIVATE_KEY", ""),
+			TokenKey:   getEnv(prefix+"TOKEN_KEY", ""),
+			TokenName:  getEnv(prefix+"TOKEN_NAME", "__token__"),
+			HealthPath: getEnv(prefix+"HEALTH_PATH", ""),
+		})
+	}
+	return origins
+}
+
 // IsProduction returns true if running in production environment
 func (c *Config) IsProduction() bool {
 	return strings.ToLower(c.Server.Env) == "production"
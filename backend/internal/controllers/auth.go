@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -227,6 +228,17 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
+	// Accounts with a second factor finish signing in at /auth/2fa/login
+	if services.TwoFactorEnabled(&user) {
+		challenge, expiresAt := ac.services.AuthService.IssueMFAChallenge(&user, false)
+		utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication required", gin.H{
+			"requires_mfa":   true,
+			"mfa_token":      challenge,
+			"mfa_expires_at": expiresAt,
+		})
+		return
+	}
+
 	// Update last login
 	now := time.Now()
 	_, err = ac.services.DB.Collection("users").UpdateOne(
@@ -625,20 +637,207 @@ func (ac *AuthController) FacebookLogin(c *gin.Context) {
 	utils.BadRequestResponse(c, "Facebook login not yet implemented")
 }
 
-// Two-factor authentication methods (basic structure for future implementation)
+// Two-factor authentication
+func (ac *AuthController) Get2FAStatus(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	utils.SuccessResponse(c, http.StatusOK, "Two-factor status retrieved successfully", ac.services.AuthService.TwoFactorStatus(u))
+}
+
+// Enable2FA starts TOTP enrolment and returns the otpauth URI and QR code
+// to set up an authenticator app with
 func (ac *AuthController) Enable2FA(c *gin.Context) {
-	// TODO: Implement 2FA setup
-	utils.BadRequestResponse(c, "2FA not yet implemented")
+	var req struct {
+		Password string `json:"password" validate:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if errors := utils.ValidateStruct(req); errors != nil {
+		utils.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	enrollment, err := ac.services.AuthService.Enable2FA(u.ID, req.Password)
+	if err != nil {
+		twoFactorErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scan the QR code and confirm with a code from your authenticator app", enrollment)
 }
 
+// Verify2FA confirms enrolment with the first code from the authenticator
+// app and returns the recovery codes, which are not shown again
 func (ac *AuthController) Verify2FA(c *gin.Context) {
-	// TODO: Implement 2FA verification
-	utils.BadRequestResponse(c, "2FA not yet implemented")
+	var req struct {
+		Code string `json:"code" validate:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if errors := utils.ValidateStruct(req); errors != nil {
+		utils.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	codes, err := ac.services.AuthService.Verify2FA(u.ID, req.Code)
+	if err != nil {
+		twoFactorErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication enabled", gin.H{
+		"recovery_codes": codes,
+	})
 }
 
 func (ac *AuthController) Disable2FA(c *gin.Context) {
-	// TODO: Implement 2FA disable
-	utils.BadRequestResponse(c, "2FA not yet implemented")
+	var req struct {
+		Password string `json:"password" validate:"required"`
+		Code     string `json:"code" validate:"required"` // Authenticator or recovery code
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if errors := utils.ValidateStruct(req); errors != nil {
+		utils.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	if err := ac.services.AuthService.Disable2FA(u.ID, req.Password, req.Code); err != nil {
+		twoFactorErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+func (ac *AuthController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" validate:"required"` // Authenticator or recovery code
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if errors := utils.ValidateStruct(req); errors != nil {
+		utils.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	codes, err := ac.services.AuthService.RegenerateRecoveryCodes(u.ID, req.Code)
+	if err != nil {
+		twoFactorErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Recovery codes regenerated", gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// CompleteMFALogin exchanges the mfa_token from Login and an authenticator
+// or recovery code for session tokens
+func (ac *AuthController) CompleteMFALogin(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" validate:"required"`
+		Code     string `json:"code" validate:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if errors := utils.ValidateStruct(req); errors != nil {
+		utils.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	result, err := ac.services.AuthService.CompleteMFALogin(req.MFAToken, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		utils.InternalServerErrorResponse(c)
+		return
+	}
+
+	if !result.Success {
+		utils.ErrorResponse(c, http.StatusUnauthorized, result.Error)
+		return
+	}
+
+	response := AuthResponse{
+		User:         result.User,
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		ExpiresAt:    result.ExpiresAt,
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Login successful", response)
+}
+
+func twoFactorErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorPassword), errors.Is(err, services.ErrTwoFactorCode):
+		utils.BadRequestResponse(c, err.Error())
+	case errors.Is(err, services.ErrTwoFactorEnabled):
+		utils.ConflictResponse(c, err.Error())
+	case errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrTwoFactorNotPending):
+		utils.BadRequestResponse(c, err.Error())
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c)
+	}
 }
 
 // Helper function to generate random tokens
@@ -0,0 +1,162 @@
+// backend/internal/controllers/drm.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"onflix/internal/models"
+	"onflix/internal/services"
+	"onflix/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DRMController struct {
+	services *services.Services
+}
+
+func NewDRMController(services *services.Services) *DRMController {
+	return &DRMController{
+		services: services,
+	}
+}
+
+// GetHLSKey delivers the AES-128 key named by an EXT-X-KEY URI. Players
+// cannot send the API's bearer token here, so the request carries the
+// streaming token added to the URI when the media playlist was served.
+func (dc *DRMController) GetHLSKey(c *gin.Context) {
+	token, _, ok := dc.authorize(c)
+	if !ok {
+		return
+	}
+
+	key, rawKey, err := dc.services.DRMService.GetKey(c.Request.Context(), c.Param("keyID"))
+	if err != nil {
+		utils.NotFoundResponse(c, "Key")
+		return
+	}
+
+	if key.ContentID.Hex() != token.ContentID {
+		utils.ForbiddenResponse(c)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/octet-stream", rawKey)
+}
+
+// ClearKeyLicense answers W3C ClearKey license requests from EME players.
+// The license URL in the MPD carries the streaming token that scopes the
+// license to the requesting user and title, and its expiry bounds the license.
+func (dc *DRMController) ClearKeyLicense(c *gin.Context) {
+	token, user, ok := dc.authorize(c)
+	if !ok {
+		return
+	}
+
+	var req services.ClearKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid license request")
+		return
+	}
+
+	contentObjID, _ := primitive.ObjectIDFromHex(token.ContentID)
+
+	license, err := dc.services.DRMService.IssueClearKeyLicense(c.Request.Context(), req, services.LicenseGrant{
+		UserID:    user.ID,
+		ContentID: contentObjID,
+		ExpiresAt: token.ExpiresAt,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLicenseType), errors.Is(err, services.ErrInvalidKeyIDs):
+			utils.BadRequestResponse(c, err.Error())
+		case errors.Is(err, services.ErrNoLicensedKeys):
+			utils.ForbiddenResponse(c)
+		default:
+			utils.InternalServerErrorResponse(c)
+		}
+		return
+	}
+
+	// EME expects the bare JWK set rather than the API response envelope
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, license)
+}
+
+// authorize checks the streaming token on a key or license request, that its
+// user may still stream the title it was issued for, and that the stream
+// session it was issued to is still live
+func (dc *DRMController) authorize(c *gin.Context) (*services.StreamingToken, *models.User, bool) {
+	token, err := dc.services.VideoService.ValidateStreamingToken(c.Query("token"))
+	if err != nil {
+		utils.UnauthorizedResponse(c)
+		return nil, nil, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(token.UserID)
+	if err != nil {
+		utils.UnauthorizedResponse(c)
+		return nil, nil, false
+	}
+
+	var user models.User
+	err = dc.services.DB.Collection("users").FindOne(context.Background(), bson.M{
+		"_id":       userID,
+		"is_active": true,
+	}).Decode(&user)
+	if err != nil {
+		utils.UnauthorizedResponse(c)
+		return nil, nil, false
+	}
+
+	if !services.SubscriptionActive(&user) {
+		utils.ForbiddenResponse(c)
+		return nil, nil, false
+	}
+
+	// Keys and licenses stop with the stream slot the token was issued for
+	sessionID, err := primitive.ObjectIDFromHex(token.SessionID)
+	if err != nil {
+		utils.UnauthorizedResponse(c)
+		return nil, nil, false
+	}
+
+	session, err := dc.services.StreamService.ActiveSession(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		if errors.Is(err, services.ErrStreamSessionInactive) {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		} else {
+			utils.InternalServerErrorResponse(c)
+		}
+		return nil, nil, false
+	}
+
+	if session.ContentID.Hex() != token.ContentID {
+		utils.ForbiddenResponse(c)
+		return nil, nil, false
+	}
+
+	contentID, err := primitive.ObjectIDFromHex(token.ContentID)
+	if err != nil {
+		utils.UnauthorizedResponse(c)
+		return nil, nil, false
+	}
+
+	count, err := dc.services.DB.Collection("content").CountDocuments(context.Background(), bson.M{
+		"_id":    contentID,
+		"status": models.ContentStatusPublished,
+	})
+	if err != nil || count == 0 {
+		utils.NotFoundResponse(c, "Content")
+		return nil, nil, false
+	}
+
+	return token, &user, true
+}
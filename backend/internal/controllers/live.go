@@ -0,0 +1,78 @@
+// backend/internal/controllers/live.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"onflix/internal/services"
+	"onflix/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LiveController struct {
+	services *services.Services
+}
+
+func NewLiveController(services *services.Services) *LiveController {
+	return &LiveController{
+		services: services,
+	}
+}
+
+// Ingest takes a playlist or segment pushed by an encoder over HTTP, such
+// as ffmpeg's HLS muxer with -method PUT. The stream key in the URL is the
+// only credential, since encoders cannot hold an API session.
+func (lc *LiveController) Ingest(c *gin.Context) {
+	content, err := lc.services.LiveService.AuthorizeIngest(c.Request.Context(), c.Param("streamKey"))
+	if err != nil {
+		liveErrorResponse(c, err)
+		return
+	}
+
+	if err := lc.services.LiveService.Ingest(c.Request.Context(), content, c.Param("filepath"), c.Request.Body, c.Request.ContentLength); err != nil {
+		liveErrorResponse(c, err)
+		return
+	}
+
+	utils.NoContentResponse(c)
+}
+
+// IngestDelete accepts the deletes encoders send for segments that left
+// their own window. Segments are kept until they leave the DVR window instead.
+func (lc *LiveController) IngestDelete(c *gin.Context) {
+	if _, err := lc.services.LiveService.AuthorizeIngest(c.Request.Context(), c.Param("streamKey")); err != nil {
+		liveErrorResponse(c, err)
+		return
+	}
+
+	utils.NoContentResponse(c)
+}
+
+func liveErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrLiveContentNotFound):
+		utils.NotFoundResponse(c, "Content")
+	case errors.Is(err, services.ErrLiveNoPlaylist):
+		utils.NotFoundResponse(c, "Live stream")
+	case errors.Is(err, services.ErrLiveStreamKey):
+		utils.UnauthorizedResponse(c)
+	case errors.Is(err, services.ErrLiveIngestClosed):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrLiveSegmentTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrLiveNotStarted):
+		utils.ErrorResponse(c, http.StatusTooEarly, err.Error())
+	case errors.Is(err, services.ErrLiveBlockingTimeout):
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, services.ErrLiveNotLiveContent), errors.Is(err, services.ErrLiveSchedule),
+		errors.Is(err, services.ErrLiveStarted), errors.Is(err, services.ErrLivePremiereSource),
+		errors.Is(err, services.ErrLiveNotScheduled), errors.Is(err, services.ErrLiveNotEncoded),
+		errors.Is(err, services.ErrLiveIngestPath), errors.Is(err, services.ErrLivePlaylistInvalid),
+		errors.Is(err, services.ErrLiveBlockingRequest):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c)
+	}
+}
@@ -0,0 +1,343 @@
+// backend/internal/controllers/party.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"onflix/internal/models"
+	"onflix/internal/services"
+	"onflix/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WatchPartyController serves the party API and its WebSocket rooms. Rooms
+// live in the API process, so when several instances run, the load balancer
+// must route every participant of a party to the same one (for example by
+// hashing the party ID in the socket path).
+type WatchPartyController struct {
+	services *services.Services
+	content  *ContentController
+	upgrader websocket.Upgrader
+}
+
+func NewWatchPartyController(services *services.Services) *WatchPartyController {
+	return &WatchPartyController{
+		services: services,
+		content:  NewContentController(services),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     partyOriginChecker(services.Config.Server.CORSOrigins),
+		},
+	}
+}
+
+// partyOriginChecker accepts sockets opened by the API's own pages or by the
+// configured CORS origins. Clients that send no Origin are not browsers and
+// rely on the ticket alone.
+func partyOriginChecker(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || slices.Contains(origins, origin) {
+			return true
+		}
+
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}
+}
+
+func (pc *WatchPartyController) CreateParty(c *gin.Context) {
+	var req struct {
+		ContentID    string   `json:"content_id" validate:"required"`
+		EpisodeID    string   `json:"episode_id"`
+		Invite       []string `json:"invite" validate:"dive,email"`
+		GuestControl bool     `json:"guest_control"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if errors := utils.ValidateStruct(req); errors != nil {
+		utils.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	contentID, err := primitive.ObjectIDFromHex(req.ContentID)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid content ID")
+		return
+	}
+
+	var episodeID *primitive.ObjectID
+	if req.EpisodeID != "" {
+		episodeObjID, err := primitive.ObjectIDFromHex(req.EpisodeID)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid episode ID")
+			return
+		}
+		episodeID = &episodeObjID
+	}
+
+	content, ok := pc.partyContent(c, u, contentID, episodeID)
+	if !ok {
+		return
+	}
+
+	if services.LiveOnly(content) {
+		utils.BadRequestResponse(c, "Live events are already watched in sync")
+		return
+	}
+
+	party, err := pc.services.PartyService.CreateParty(c.Request.Context(), u, contentID, episodeID, partyTitle(content, episodeID), req.Invite, req.GuestControl)
+	if err != nil {
+		partyErrorResponse(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, "Watch party created successfully", party)
+}
+
+func (pc *WatchPartyController) GetParties(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	parties, err := pc.services.PartyService.Parties(c.Request.Context(), u.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Watch parties retrieved successfully", parties)
+}
+
+func (pc *WatchPartyController) GetParty(c *gin.Context) {
+	u, partyID, ok := partyRequest(c)
+	if !ok {
+		return
+	}
+
+	party, err := pc.services.PartyService.Party(c.Request.Context(), partyID, u)
+	if err != nil {
+		partyErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Watch party retrieved successfully", party)
+}
+
+func (pc *WatchPartyController) InviteToParty(c *gin.Context) {
+	u, partyID, ok := partyRequest(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Emails []string `json:"emails" validate:"required,min=1,dive,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if errors := utils.ValidateStruct(req); errors != nil {
+		utils.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	party, err := pc.services.PartyService.Invite(c.Request.Context(), partyID, u, req.Emails)
+	if err != nil {
+		partyErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Invitations sent successfully", party)
+}
+
+// JoinParty checks that the participant may stream the party's title,
+// takes one of their account's stream slots, and returns the ticket their
+// player opens the party socket with
+func (pc *WatchPartyController) JoinParty(c *gin.Context) {
+	u, partyID, ok := partyRequest(c)
+	if !ok {
+		return
+	}
+
+	party, err := pc.services.PartyService.Party(c.Request.Context(), partyID, u)
+	if err != nil {
+		partyErrorResponse(c, err)
+		return
+	}
+
+	if _, ok := pc.partyContent(c, u, party.ContentID, party.EpisodeID); !ok {
+		return
+	}
+
+	session, ok := pc.content.startStream(c, u, party.ContentID, party.EpisodeID)
+	if !ok {
+		return
+	}
+
+	ticket := pc.services.PartyService.IssueTicket(party.ID, u.ID, session.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Joined watch party", gin.H{
+		"party":          party,
+		"stream_session": session,
+		"socket_url":     fmt.Sprintf("/api/v1/watch-parties/%s/socket?ticket=%s", party.ID.Hex(), url.QueryEscape(ticket)),
+	})
+}
+
+func (pc *WatchPartyController) EndParty(c *gin.Context) {
+	u, partyID, ok := partyRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := pc.services.PartyService.End(c.Request.Context(), partyID, u); err != nil {
+		partyErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Watch party ended", nil)
+}
+
+// PartySocket upgrades to the WebSocket that keeps a participant in sync.
+// It is authorized by the ticket from JoinParty rather than the API session.
+func (pc *WatchPartyController) PartySocket(c *gin.Context) {
+	partyID, err := primitive.ObjectIDFromHex(c.Param("partyID"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid watch party ID")
+		return
+	}
+
+	userID, sessionID, err := pc.services.PartyService.ParseTicket(c.Query("ticket"), partyID)
+	if err != nil {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	var user models.User
+	err = pc.services.DB.Collection("users").FindOne(context.Background(), bson.M{
+		"_id":       userID,
+		"is_active": true,
+	}).Decode(&user)
+	if err != nil {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	conn, err := pc.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		return
+	}
+
+	pc.services.PartyService.Serve(conn, partyID, &user, sessionID)
+}
+
+// partyContent loads the published title, checking the episode belongs to
+// it, and that the user may stream it. It writes the error response itself.
+func (pc *WatchPartyController) partyContent(c *gin.Context, u *models.User, contentID primitive.ObjectID, episodeID *primitive.ObjectID) (*models.Content, bool) {
+	filter := bson.M{
+		"_id":    contentID,
+		"status": models.ContentStatusPublished,
+	}
+	if episodeID != nil {
+		filter["type"] = models.ContentTypeTVShow
+		filter["seasons.episodes._id"] = *episodeID
+	}
+
+	var content models.Content
+	if err := pc.services.DB.Collection("content").FindOne(context.Background(), filter).Decode(&content); err != nil {
+		utils.NotFoundResponse(c, "Content")
+		return nil, false
+	}
+
+	if !pc.content.hasStreamingAccess(u, &content) {
+		utils.ForbiddenResponse(c)
+		return nil, false
+	}
+
+	return &content, true
+}
+
+// partyTitle names a party after its title, and episode when it has one
+func partyTitle(content *models.Content, episodeID *primitive.ObjectID) string {
+	if episodeID == nil {
+		return content.Title
+	}
+
+	for _, season := range content.Seasons {
+		for _, episode := range season.Episodes {
+			if episode.ID == *episodeID {
+				return fmt.Sprintf("%s S%dE%d: %s", content.Title, season.SeasonNumber, episode.EpisodeNumber, episode.Name)
+			}
+		}
+	}
+	return content.Title
+}
+
+func partyRequest(c *gin.Context) (*models.User, primitive.ObjectID, bool) {
+	partyID, err := primitive.ObjectIDFromHex(c.Param("partyID"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid watch party ID")
+		return nil, partyID, false
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return nil, partyID, false
+	}
+
+	return user.(*models.User), partyID, true
+}
+
+func partyErrorResponse(c *gin.Context, err error) {
+	if inviteErr, ok := err.(*services.PartyInviteError); ok {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Some invited emails do not belong to an active account",
+			Error:   inviteErr.Error(),
+			Data:    inviteErr,
+		})
+		return
+	}
+
+	switch {
+	case errors.Is(err, services.ErrPartyNotFound):
+		utils.NotFoundResponse(c, "Watch party")
+	case errors.Is(err, services.ErrPartyNotHost):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrPartyFull):
+		utils.ConflictResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c)
+	}
+}
@@ -0,0 +1,230 @@
+// backend/internal/controllers/playback.go
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"onflix/internal/models"
+	"onflix/internal/services"
+	"onflix/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Events or throughput samples a player may send in one request
+const maxPlaybackEventBatch = 100
+
+type PlaybackController struct {
+	services *services.Services
+}
+
+func NewPlaybackController(services *services.Services) *PlaybackController {
+	return &PlaybackController{
+		services: services,
+	}
+}
+
+// StartSession opens a playback session once the player has started rendering
+// frames, and tells it how often to send heartbeats
+func (pc *PlaybackController) StartSession(c *gin.Context) {
+	var req struct {
+		ContentID     string              `json:"content_id" validate:"required"`
+		EpisodeID     string              `json:"episode_id"`
+		ProfileID     string              `json:"profile_id"`
+		Autoplayed    bool                `json:"autoplayed"` // Started by the up-next countdown
+		DeviceType    string              `json:"device_type" validate:"required"`
+		Quality       models.VideoQuality `json:"quality" validate:"required"`
+		BitrateKbps   int                 `json:"bitrate_kbps"`
+		StartupTimeMs int64               `json:"startup_time_ms"`
+		Position      float64             `json:"position"`
+		Duration      float64             `json:"duration"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if errors := utils.ValidateStruct(req); errors != nil {
+		utils.ValidationErrorResponse(c, errors)
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	contentID, err := primitive.ObjectIDFromHex(req.ContentID)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid content ID")
+		return
+	}
+
+	var content models.Content
+	err = pc.services.DB.Collection("content").FindOne(
+		context.Background(),
+		bson.M{
+			"_id":    contentID,
+			"status": models.ContentStatusPublished,
+		},
+	).Decode(&content)
+
+	if err != nil {
+		utils.NotFoundResponse(c, "Content")
+		return
+	}
+
+	if !services.SubscriptionActive(u) {
+		utils.ForbiddenResponse(c)
+		return
+	}
+
+	var episodeID *primitive.ObjectID
+	if req.EpisodeID != "" {
+		episodeObjID, err := primitive.ObjectIDFromHex(req.EpisodeID)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid episode ID")
+			return
+		}
+		episodeID = &episodeObjID
+	}
+
+	var profileID *primitive.ObjectID
+	if req.ProfileID != "" {
+		profileObjID, err := primitive.ObjectIDFromHex(req.ProfileID)
+		if err != nil || services.FindProfile(u, profileObjID) == nil {
+			utils.BadRequestResponse(c, "Invalid profile ID")
+			return
+		}
+		profileID = &profileObjID
+	}
+
+	session, err := pc.services.PlaybackService.StartSession(c.Request.Context(), u.ID, services.PlaybackStart{
+		ProfileID:     profileID,
+		ContentID:     contentID,
+		EpisodeID:     episodeID,
+		Autoplayed:    req.Autoplayed,
+		DeviceID:      c.GetHeader("X-Device-ID"),
+		DeviceType:    req.DeviceType,
+		Quality:       req.Quality,
+		BitrateKbps:   req.BitrateKbps,
+		StartupTimeMs: req.StartupTimeMs,
+		Position:      req.Position,
+		Duration:      req.Duration,
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c)
+		return
+	}
+
+	utils.CreatedResponse(c, "Playback session started", gin.H{
+		"session":            session,
+		"heartbeat_interval": int(pc.services.PlaybackService.HeartbeatInterval().Seconds()),
+	})
+}
+
+// RecordEvents accepts a batch of heartbeat, bitrate_switch, rebuffer, error
+// and stop events for a session
+func (pc *PlaybackController) RecordEvents(c *gin.Context) {
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("sessionID"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid session ID")
+		return
+	}
+
+	var req struct {
+		Events []services.PlaybackEventInput `json:"events"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if len(req.Events) == 0 || len(req.Events) > maxPlaybackEventBatch {
+		utils.BadRequestResponse(c, "A batch must contain between 1 and 100 events")
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	session, err := pc.services.PlaybackService.RecordEvents(c.Request.Context(), u.ID, sessionID, req.Events)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPlaybackSessionNotFound):
+			utils.NotFoundResponse(c, "Playback session")
+		case errors.Is(err, services.ErrPlaybackSessionEnded):
+			utils.ErrorResponse(c, http.StatusGone, err.Error())
+		case errors.Is(err, services.ErrPlaybackEventType):
+			utils.BadRequestResponse(c, err.Error())
+		default:
+			utils.InternalServerErrorResponse(c)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Playback events recorded", session)
+}
+
+// GetAdvice recommends the rendition a player should switch to, from the
+// throughput samples and buffer health it reports for a session
+func (pc *PlaybackController) GetAdvice(c *gin.Context) {
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("sessionID"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid session ID")
+		return
+	}
+
+	var req services.PlaybackConditions
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request format")
+		return
+	}
+
+	if req.BufferHealth < 0 || req.BufferHealth > 1 {
+		utils.BadRequestResponse(c, "buffer_health must be between 0 and 1")
+		return
+	}
+
+	if len(req.Samples) > maxPlaybackEventBatch {
+		utils.BadRequestResponse(c, "Too many throughput samples")
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c)
+		return
+	}
+
+	u := user.(*models.User)
+
+	advice, err := pc.services.PlaybackService.Advise(c.Request.Context(), u, sessionID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPlaybackSessionNotFound):
+			utils.NotFoundResponse(c, "Playback session")
+		case errors.Is(err, services.ErrPlaybackSessionEnded):
+			utils.ErrorResponse(c, http.StatusGone, err.Error())
+		default:
+			utils.InternalServerErrorResponse(c)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Playback advice", advice)
+}
@@ -4,9 +4,7 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"time"
 
@@ -87,55 +85,36 @@ func (uc *UserController) UploadAvatar(c *gin.Context) {
 
 	u := user.(*models.User)
 
-	// Parse multipart form
-	file, header, err := c.Request.FormFile("avatar")
-	if err != nil {
-		utils.BadRequestResponse(c, "No file uploaded")
-		return
-	}
-	defer file.Close()
-
-	// Validate file type
-	allowedTypes := []string{".jpg", ".jpeg", ".png", ".gif"}
-	if !utils.ValidateFileType(header.Filename, allowedTypes) {
-		utils.BadRequestResponse(c, "Invalid file type. Only JPG, PNG, and GIF are allowed")
-		return
-	}
-
-	// Read file content
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		utils.InternalServerErrorResponse(c)
-		return
-	}
-
-	// Upload to storage service
-	avatarURL, err := uc.services.StorageService.UploadFile(
-		fmt.Sprintf("avatars/%s%s", u.ID.Hex(), filepath.Ext(header.Filename)),
-		fileContent,
-	)
-	if err != nil {
-		utils.InternalServerErrorResponse(c)
+	// Store the avatar with its resized variants
+	image := processImageUpload(c, uc.services.ImageService, "avatar", services.ImageKindAvatar, models.BlobOwnerUser, u.ID.Hex())
+	if image == nil {
 		return
 	}
+	avatarURL := image.Original.URL
 
 	// Update user avatar
 	now := time.Now()
-	_, err = uc.services.DB.Collection("users").UpdateOne(
+	_, err := uc.services.DB.Collection("users").UpdateOne(
 		context.Background(),
 		bson.M{"_id": u.ID},
 		bson.M{"$set": bson.M{
-			"avatar":     avatarURL,
-			"updated_at": now,
+			"avatar":          avatarURL,
+			"avatar_variants": image.Variants,
+			"updated_at":      now,
 		}},
 	)
 
 	if err != nil {
+		uc.services.ImageService.Discard(context.Background(), image)
 		utils.InternalServerErrorResponse(c)
 		return
 	}
+	uc.services.ImageService.Commit(context.Background(), image)
 
-	utils.SuccessResponse(c, http.StatusOK, "Avatar uploaded successfully", gin.H{"avatar_url": avatarURL})
+	utils.SuccessResponse(c, http.StatusOK, "Avatar uploaded successfully", gin.H{
+		"avatar_url":      avatarURL,
+		"avatar_variants": image.Variants,
+	})
 }
 
 // Multiple Profiles Management
@@ -325,6 +304,12 @@ func (uc *UserController) DeleteProfile(c *gin.Context) {
 		return
 	}
 
+	// The profile's avatar files are collected once nothing else uses them
+	ownerID := fmt.Sprintf("%s-%s", u.ID.Hex(), profileID)
+	if err := uc.services.BlobService.ReleaseOwner(context.Background(), models.BlobOwnerProfile, ownerID); err != nil {
+		fmt.Printf("Failed to release avatar for profile %s: %v\n", profileID, err)
+	}
+
 	utils.SuccessResponse(c, http.StatusOK, "Profile deleted successfully", nil)
 }
 
@@ -342,51 +327,40 @@ func (uc *UserController) UpdateProfileAvatar(c *gin.Context) {
 	}
 
 	u := user.(*models.User)
+	profileObjID, _ := primitive.ObjectIDFromHex(profileID)
 
-	// Parse multipart form
-	file, header, err := c.Request.FormFile("avatar")
-	if err != nil {
-		utils.BadRequestResponse(c, "No file uploaded")
-		return
-	}
-	defer file.Close()
-
-	// Validate file type
-	allowedTypes := []string{".jpg", ".jpeg", ".png", ".gif"}
-	if !utils.ValidateFileType(header.Filename, allowedTypes) {
-		utils.BadRequestResponse(c, "Invalid file type. Only JPG, PNG, and GIF are allowed")
-		return
+	// Check the profile before storing anything for it
+	found := false
+	for _, p := range u.Profiles {
+		if p.ID == profileObjID {
+			found = true
+			break
+		}
 	}
 
-	// Read file content
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		utils.InternalServerErrorResponse(c)
+	if !found {
+		utils.NotFoundResponse(c, "Profile")
 		return
 	}
 
-	// Upload to storage service
-	avatarURL, err := uc.services.StorageService.UploadFile(
-		fmt.Sprintf("profile-avatars/%s-%s%s", u.ID.Hex(), profileID, filepath.Ext(header.Filename)),
-		fileContent,
-	)
-	if err != nil {
-		utils.InternalServerErrorResponse(c)
+	// Store the avatar with its resized variants
+	image := processImageUpload(c, uc.services.ImageService, "avatar", services.ImageKindAvatar, models.BlobOwnerProfile, fmt.Sprintf("%s-%s", u.ID.Hex(), profileID))
+	if image == nil {
 		return
 	}
-
-	profileObjID, _ := primitive.ObjectIDFromHex(profileID)
+	avatarURL := image.Original.URL
 
 	// Update profile avatar
 	now := time.Now()
-	_, err = uc.services.DB.Collection("users").UpdateOne(
+	result, err := uc.services.DB.Collection("users").UpdateOne(
 		context.Background(),
-		bson.M{"_id": u.ID},
+		bson.M{"_id": u.ID, "profiles._id": profileObjID},
 		bson.M{
 			"$set": bson.M{
-				"profiles.$[elem].avatar":     avatarURL,
-				"profiles.$[elem].updated_at": now,
-				"updated_at":                  now,
+				"profiles.$[elem].avatar":          avatarURL,
+				"profiles.$[elem].avatar_variants": image.Variants,
+				"profiles.$[elem].updated_at":      now,
+				"updated_at":                       now,
 			},
 		},
 		options.Update().SetArrayFilters(options.ArrayFilters{
@@ -396,12 +370,22 @@ func (uc *UserController) UpdateProfileAvatar(c *gin.Context) {
 		}),
 	)
 
-	if err != nil {
-		utils.InternalServerErrorResponse(c)
+	// The profile may have been deleted while the image was processed
+	if err != nil || result.MatchedCount == 0 {
+		uc.services.ImageService.Discard(context.Background(), image)
+		if err != nil {
+			utils.InternalServerErrorResponse(c)
+		} else {
+			utils.NotFoundResponse(c, "Profile")
+		}
 		return
 	}
+	uc.services.ImageService.Commit(context.Background(), image)
 
-	utils.SuccessResponse(c, http.StatusOK, "Profile avatar updated successfully", gin.H{"avatar_url": avatarURL})
+	utils.SuccessResponse(c, http.StatusOK, "Profile avatar updated successfully", gin.H{
+		"avatar_url":      avatarURL,
+		"avatar_variants": image.Variants,
+	})
 }
 
 // Watchlist Management
@@ -168,6 +168,13 @@ func createIndexes(db *mongo.Database) error {
 			},
 			Options: options.Index().SetName("content_text_search"),
 		},
+		{
+			Keys:    bson.D{{Key: "live.stream_key_hash", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "live.state", Value: 1}, {Key: "live.scheduled_start", Value: 1}},
+		},
 	}
 
 	_, err = db.Collection("content").Indexes().CreateMany(ctx, contentIndexes)
@@ -307,15 +314,29 @@ func createIndexes(db *mongo.Database) error {
 	}
 
 	// Video metrics collection indexes
-	metricsIndexes := []mongo.IndexModel{
+	playbackSessionIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "started_at", Value: -1}},
+		},
 		{
 			Keys: bson.D{{Key: "content_id", Value: 1}},
 		},
 		{
-			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "completed", Value: 1}, {Key: "content_id", Value: 1}},
 		},
+	}
+
+	_, err = db.Collection("playback_sessions").Indexes().CreateMany(ctx, playbackSessionIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create playback_sessions indexes: %v", err)
+	}
+
+	playbackEventIndexes := []mongo.IndexModel{
 		{
-			Keys: bson.D{{Key: "timestamp", Value: -1}},
+			Keys: bson.D{{Key: "session_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "content_id", Value: 1}, {Key: "timestamp", Value: 1}},
 		},
 		{
 			Keys:    bson.D{{Key: "timestamp", Value: 1}},
@@ -323,9 +344,23 @@ func createIndexes(db *mongo.Database) error {
 		},
 	}
 
-	_, err = db.Collection("video_metrics").Indexes().CreateMany(ctx, metricsIndexes)
+	_, err = db.Collection("playback_events").Indexes().CreateMany(ctx, playbackEventIndexes)
 	if err != nil {
-		return fmt.Errorf("failed to create video_metrics indexes: %v", err)
+		return fmt.Errorf("failed to create playback_events indexes: %v", err)
+	}
+
+	playbackRollupIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "_id.day", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "_id.content_id", Value: 1}, {Key: "_id.day", Value: 1}},
+		},
+	}
+
+	_, err = db.Collection("playback_rollups").Indexes().CreateMany(ctx, playbackRollupIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create playback_rollups indexes: %v", err)
 	}
 
 	// Subscription usage collection indexes
@@ -349,6 +384,163 @@ func createIndexes(db *mongo.Database) error {
 		return fmt.Errorf("failed to create subscription_usage indexes: %v", err)
 	}
 
+	// Stream sessions collection indexes
+	streamSessionIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "expires_at", Value: 1}},
+		},
+		{
+			// Drop finished sessions after a week
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+		},
+	}
+
+	_, err = db.Collection("stream_sessions").Indexes().CreateMany(ctx, streamSessionIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create stream_sessions indexes: %v", err)
+	}
+
+	// Transcode jobs collection indexes
+	transcodeJobIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "source_video_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	_, err = db.Collection("transcode_jobs").Indexes().CreateMany(ctx, transcodeJobIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create transcode_jobs indexes: %v", err)
+	}
+
+	// Video uploads collection indexes
+	videoUploadIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "content_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}},
+		},
+	}
+
+	_, err = db.Collection("video_uploads").Indexes().CreateMany(ctx, videoUploadIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create video_uploads indexes: %v", err)
+	}
+
+	// Blobs collection indexes
+	blobIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "refs.owner_type", Value: 1}, {Key: "refs.owner_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "ref_count", Value: 1}, {Key: "unreferenced_at", Value: 1}},
+		},
+	}
+
+	_, err = db.Collection("blobs").Indexes().CreateMany(ctx, blobIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create blobs indexes: %v", err)
+	}
+
+	// Content keys collection indexes
+	contentKeyIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err = db.Collection("content_keys").Indexes().CreateMany(ctx, contentKeyIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create content_keys indexes: %v", err)
+	}
+
+	// DRM licenses collection indexes
+	drmLicenseIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "issued_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "content_id", Value: 1}},
+		},
+	}
+
+	_, err = db.Collection("drm_licenses").Indexes().CreateMany(ctx, drmLicenseIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create drm_licenses indexes: %v", err)
+	}
+
+	// Watermark sessions collection indexes
+	watermarkSessionIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "watermark_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "content_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	}
+
+	_, err = db.Collection("watermark_sessions").Indexes().CreateMany(ctx, watermarkSessionIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create watermark_sessions indexes: %v", err)
+	}
+
+	// Downloads collection indexes
+	downloadIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "device_id", Value: 1}, {Key: "content_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "license_expires_at", Value: 1}},
+		},
+	}
+
+	_, err = db.Collection("downloads").Indexes().CreateMany(ctx, downloadIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create downloads indexes: %v", err)
+	}
+
+	livePlaylistIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "content_id", Value: 1}, {Key: "rendition", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err = db.Collection("live_playlists").Indexes().CreateMany(ctx, livePlaylistIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create live playlist indexes: %v", err)
+	}
+
+	watchPartyIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "host_id", Value: 1}, {Key: "status", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "invited", Value: 1}, {Key: "status", Value: 1}},
+		},
+	}
+
+	_, err = db.Collection("watch_parties").Indexes().CreateMany(ctx, watchPartyIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create watch party indexes: %v", err)
+	}
+
 	fmt.Println("Successfully created database indexes")
 	return nil
 }
@@ -500,14 +692,14 @@ func CleanupOldData(db *mongo.Database) error {
 		return fmt.Errorf("failed to cleanup old login attempts: %v", err)
 	}
 
-	// Clean up old video metrics (older than 90 days)
+	// Clean up playback sessions older than their events (90 days)
 	ninetyDaysAgo := time.Now().AddDate(0, 0, -90)
-	_, err = db.Collection("video_metrics").DeleteMany(
+	_, err = db.Collection("playback_sessions").DeleteMany(
 		ctx,
-		bson.M{"timestamp": bson.M{"$lt": ninetyDaysAgo}},
+		bson.M{"started_at": bson.M{"$lt": ninetyDaysAgo}},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to cleanup old video metrics: %v", err)
+		return fmt.Errorf("failed to cleanup old playback sessions: %v", err)
 	}
 
 	// Archive old completed subscriptions (older than 2 years)
@@ -29,6 +29,33 @@ func RequireAdmin() gin.HandlerFunc {
 	}
 }
 
+// RequireTwoFactor blocks admins without two-factor authentication when
+// enforced, so a leaked password alone cannot reach admin routes
+func RequireTwoFactor(enforced bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enforced {
+			c.Next()
+			return
+		}
+
+		user, exists := c.Get("user")
+		if !exists {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication required")
+			c.Abort()
+			return
+		}
+
+		u := user.(*models.User)
+		if u.Role == models.RoleAdmin && (u.TwoFactor == nil || !u.TwoFactor.Enabled) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Two-factor authentication must be enabled to use admin routes")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func RequireAdminOrSelf() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, exists := c.Get("user")
@@ -7,13 +7,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func CORSMiddleware() gin.HandlerFunc {
+func CORSMiddleware(origins []string) gin.HandlerFunc {
 	return cors.New(cors.Config{
-		AllowOrigins: []string{
-			"http://localhost:3000",
-			"http://localhost:3001",
-			"https://your-domain.com",
-		},
+		AllowOrigins: origins,
 		AllowMethods: []string{
 			"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS",
 		},
@@ -24,6 +20,25 @@ func CORSMiddleware() gin.HandlerFunc {
 			"Authorization",
 			"Accept",
 			"X-Requested-With",
+			"X-Device-ID",
+			"X-Device-Name",
+			"X-Stream-Session",
+			"Tus-Resumable",
+			"Upload-Length",
+			"Upload-Metadata",
+			"Upload-Offset",
+			"Upload-Checksum",
+		},
+		ExposeHeaders: []string{
+			"Location",
+			"Tus-Resumable",
+			"Tus-Version",
+			"Tus-Extension",
+			"Tus-Max-Size",
+			"Tus-Checksum-Algorithm",
+			"Upload-Offset",
+			"Upload-Length",
+			"Upload-Expires",
 		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -0,0 +1,40 @@
+// backend/internal/models/blob.go
+package models
+
+import (
+	"time"
+)
+
+// Blob is a stored file addressed by the SHA-256 of its contents. Identical
+// uploads share one blob and each user of it holds a reference.
+type Blob struct {
+	Hash           string     `json:"hash" bson:"_id"`
+	StoragePath    string     `json:"storage_path" bson:"storage_path"`
+	Size           int64      `json:"size" bson:"size"`
+	ContentType    string     `json:"content_type" bson:"content_type"`
+	MD5Hash        string     `json:"md5_hash" bson:"md5_hash"`
+	RefCount       int        `json:"ref_count" bson:"ref_count"`
+	Refs           []BlobRef  `json:"refs" bson:"refs"`
+	Deleting       bool       `json:"-" bson:"deleting"`
+	UnreferencedAt *time.Time `json:"unreferenced_at,omitempty" bson:"unreferenced_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+// BlobRef records one owner using a blob. Field says what the owner uses it
+// for, such as "poster" or "file".
+type BlobRef struct {
+	OwnerType BlobOwnerType `json:"owner_type" bson:"owner_type"`
+	OwnerID   string        `json:"owner_id" bson:"owner_id"`
+	Field     string        `json:"field" bson:"field"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+}
+
+type BlobOwnerType string
+
+const (
+	BlobOwnerContent BlobOwnerType = "content"
+	BlobOwnerUser    BlobOwnerType = "user"
+	BlobOwnerProfile BlobOwnerType = "profile"
+	BlobOwnerVideo   BlobOwnerType = "video"
+)
@@ -27,6 +27,8 @@ type Content struct {
 	Images         ContentImages      `json:"images" bson:"images"`
 	Videos         []ContentVideo     `json:"videos" bson:"videos"`
 	Seasons        []Season           `json:"seasons,omitempty" bson:"seasons,omitempty"` // For TV shows
+	Streaming      StreamingAssets    `json:"streaming" bson:"streaming"`
+	Live           *LiveEvent         `json:"live,omitempty" bson:"live,omitempty"` // For live titles
 	Status         ContentStatus      `json:"status" bson:"status"`
 	IsFeatured     bool               `json:"is_featured" bson:"is_featured"`
 	IsOriginal     bool               `json:"is_original" bson:"is_original"`
@@ -43,6 +45,7 @@ type ContentType string
 const (
 	ContentTypeMovie  ContentType = "movie"
 	ContentTypeTVShow ContentType = "tv_show"
+	ContentTypeLive   ContentType = "live"
 )
 
 type ContentStatus string
@@ -65,6 +68,19 @@ type ContentImages struct {
 	BackdropPath string   `json:"backdrop_path" bson:"backdrop_path"`
 	LogoPath     string   `json:"logo_path" bson:"logo_path"`
 	Screenshots  []string `json:"screenshots" bson:"screenshots"`
+	// Resized renditions of uploaded images, e.g. poster_w342 as JPEG and WebP
+	PosterVariants   []ImageVariant `json:"poster_variants,omitempty" bson:"poster_variants,omitempty"`
+	BackdropVariants []ImageVariant `json:"backdrop_variants,omitempty" bson:"backdrop_variants,omitempty"`
+	LogoVariants     []ImageVariant `json:"logo_variants,omitempty" bson:"logo_variants,omitempty"`
+}
+
+type ImageVariant struct {
+	Name   string `json:"name" bson:"name"`
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+	Format string `json:"format" bson:"format"` // jpeg, png, webp or avif
+	URL    string `json:"url" bson:"url"`
+	Size   int64  `json:"size" bson:"size"`
 }
 
 type ContentVideo struct {
@@ -77,7 +93,41 @@ type ContentVideo struct {
 	FileSize  int64              `json:"file_size" bson:"file_size"` // In bytes
 	Subtitles []Subtitle         `json:"subtitles" bson:"subtitles"`
 	IsDefault bool               `json:"is_default" bson:"is_default"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	// Set on renditions produced by a transcode job from another video entry
+	SourceVideoID *primitive.ObjectID `json:"source_video_id,omitempty" bson:"source_video_id,omitempty"`
+	// Storage path of the packaged HLS media playlist for this rendition
+	HLSPlaylist string `json:"hls_playlist,omitempty" bson:"hls_playlist,omitempty"`
+	// Seek-preview thumbnails, shared by every rendition of the same title
+	TrickPlay *TrickPlayTrack `json:"trick_play,omitempty" bson:"trick_play,omitempty"`
+	// Intro, recap and credits ranges. Renditions transcoded from this video
+	// share its timeline and use its markers.
+	Markers []VideoMarker `json:"markers,omitempty" bson:"markers,omitempty"`
+	// Dubs, audio descriptions and other alternate audio, shared by every
+	// rendition of the same title
+	AudioTracks []AudioTrack `json:"audio_tracks,omitempty" bson:"audio_tracks,omitempty"`
+	CreatedAt   time.Time    `json:"created_at" bson:"created_at"`
+}
+
+// TrickPlayTrack is a set of seek-preview thumbnails packed into JPEG sprite
+// sheets, with a WebVTT track mapping each interval to a tile
+type TrickPlayTrack struct {
+	VTTPath      string `json:"vtt_path" bson:"vtt_path"`           // Storage path of the WebVTT thumbnail track
+	PlaylistPath string `json:"playlist_path" bson:"playlist_path"` // Storage path of the HLS image playlist
+	Interval     int    `json:"interval" bson:"interval"`           // Seconds between thumbnails
+	Width        int    `json:"width" bson:"width"`
+	Height       int    `json:"height" bson:"height"`
+	Columns      int    `json:"columns" bson:"columns"`
+	Rows         int    `json:"rows" bson:"rows"`
+	Thumbnails   int    `json:"thumbnails" bson:"thumbnails"`
+	Sheets       int    `json:"sheets" bson:"sheets"`
+	Bandwidth    int    `json:"bandwidth" bson:"bandwidth"` // Peak bitrate of a sheet over the time it covers
+}
+
+// Packaged adaptive streaming output for a title or episode
+type StreamingAssets struct {
+	HLSMaster    string     `json:"hls_master,omitempty" bson:"hls_master,omitempty"`
+	DASHManifest string     `json:"dash_manifest,omitempty" bson:"dash_manifest,omitempty"`
+	PackagedAt   *time.Time `json:"packaged_at,omitempty" bson:"packaged_at,omitempty"`
 }
 
 type VideoType string
@@ -98,10 +148,66 @@ const (
 	Quality4K    VideoQuality = "4k"
 )
 
+type MarkerType string
+
+const (
+	MarkerTypeIntro       MarkerType = "intro"
+	MarkerTypeRecap       MarkerType = "recap"
+	MarkerTypeCredits     MarkerType = "credits"
+	MarkerTypePostCredits MarkerType = "post_credits"
+)
+
+type MarkerSource string
+
+const (
+	MarkerSourceManual   MarkerSource = "manual"
+	MarkerSourceDetected MarkerSource = "detected"
+)
+
+// VideoMarker is a typed range of a video's timeline, such as the intro a
+// player offers to skip or the credits that start the next-episode countdown
+type VideoMarker struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type      MarkerType         `json:"type" bson:"type"`
+	Start     float64            `json:"start" bson:"start"` // In seconds
+	End       float64            `json:"end" bson:"end"`
+	Source    MarkerSource       `json:"source" bson:"source"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type AudioRole string
+
+const (
+	AudioRoleMain        AudioRole = "main"
+	AudioRoleDub         AudioRole = "dub"
+	AudioRoleDescription AudioRole = "description" // Narrates the picture for blind and low-vision viewers
+	AudioRoleCommentary  AudioRole = "commentary"
+)
+
+// AudioTrack is an audio rendition of a title. A Muxed track describes the
+// audio already carried in the video renditions; every other track is
+// packaged as a separate rendition players can switch to.
+type AudioTrack struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Language string             `json:"language" bson:"language"`
+	Label    string             `json:"label" bson:"label"`
+	Role     AudioRole          `json:"role" bson:"role"`
+	Channels int                `json:"channels" bson:"channels"` // 2 for stereo, 6 for 5.1
+	// File holding the track; empty to take it from the video's own file
+	FileURL     string `json:"file_url,omitempty" bson:"file_url,omitempty"`
+	StreamIndex int    `json:"stream_index" bson:"stream_index"` // Audio stream within the file
+	Muxed       bool   `json:"muxed" bson:"muxed"`
+	// Storage path of the packaged HLS media playlist for this track
+	HLSPlaylist string    `json:"hls_playlist,omitempty" bson:"hls_playlist,omitempty"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
 type Subtitle struct {
-	Language string `json:"language" bson:"language"`
-	Label    string `json:"label" bson:"label"`
-	FileURL  string `json:"file_url" bson:"file_url"`
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Language string             `json:"language" bson:"language"`
+	Label    string             `json:"label" bson:"label"`
+	FileURL  string             `json:"file_url" bson:"file_url"`
 }
 
 // For TV Shows
@@ -125,5 +231,8 @@ type Episode struct {
 	AirDate       time.Time          `json:"air_date" bson:"air_date"`
 	Runtime       int                `json:"runtime" bson:"runtime"`
 	Videos        []ContentVideo     `json:"videos" bson:"videos"`
+	Streaming     StreamingAssets    `json:"streaming" bson:"streaming"`
+	TrickPlay     *TrickPlayTrack    `json:"trick_play,omitempty" bson:"trick_play,omitempty"`
+	Markers       []VideoMarker      `json:"markers,omitempty" bson:"markers,omitempty"`
 	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
 }
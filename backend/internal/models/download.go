@@ -0,0 +1,39 @@
+// backend/internal/models/download.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Download is a title saved to a device for offline viewing. It counts
+// towards PlanLimits.MaxDownloads until it is removed or stops being active.
+type Download struct {
+	ID                primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID            primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	ContentID         primitive.ObjectID  `json:"content_id" bson:"content_id"`
+	EpisodeID         *primitive.ObjectID `json:"episode_id,omitempty" bson:"episode_id,omitempty"`
+	VideoID           primitive.ObjectID  `json:"video_id" bson:"video_id"`
+	Quality           VideoQuality        `json:"quality" bson:"quality"`
+	FileSize          int64               `json:"file_size" bson:"file_size"`
+	DeviceID          string              `json:"device_id" bson:"device_id"`
+	DeviceName        string              `json:"device_name" bson:"device_name"`
+	Status            DownloadStatus      `json:"status" bson:"status"`
+	LicenseExpiresAt  time.Time           `json:"license_expires_at" bson:"license_expires_at"`               // Renewed each time the device checks in
+	FirstPlayedAt     *time.Time          `json:"first_played_at,omitempty" bson:"first_played_at,omitempty"` // Reported by the device on renewal
+	PlaybackExpiresAt *time.Time          `json:"playback_expires_at,omitempty" bson:"playback_expires_at,omitempty"`
+	LastRenewedAt     time.Time           `json:"last_renewed_at" bson:"last_renewed_at"`
+	RevokedAt         *time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedReason     string              `json:"revoked_reason,omitempty" bson:"revoked_reason,omitempty"`
+	CreatedAt         time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type DownloadStatus string
+
+const (
+	DownloadStatusActive  DownloadStatus = "active"
+	DownloadStatusExpired DownloadStatus = "expired"
+	DownloadStatusRevoked DownloadStatus = "revoked"
+)
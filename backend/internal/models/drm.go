@@ -0,0 +1,32 @@
+// backend/internal/models/drm.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ContentKey is the media encryption key for a title or episode. The key
+// itself is stored encrypted with the DRM master key.
+type ContentKey struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	KeyID        string             `json:"key_id" bson:"key_id"`     // 16 random bytes, hex encoded
+	OwnerID      string             `json:"owner_id" bson:"owner_id"` // Content ID for movies, episode ID for episodes
+	ContentID    primitive.ObjectID `json:"content_id" bson:"content_id"`
+	EncryptedKey []byte             `json:"-" bson:"encrypted_key"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// DRMLicense records a license handed to a user's player
+type DRMLicense struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ContentID primitive.ObjectID `json:"content_id" bson:"content_id"`
+	KeyIDs    []string           `json:"key_ids" bson:"key_ids"`
+	Type      string             `json:"type" bson:"type"` // EME session type, e.g. "temporary"
+	IPAddress string             `json:"ip_address" bson:"ip_address"`
+	UserAgent string             `json:"user_agent" bson:"user_agent"`
+	IssuedAt  time.Time          `json:"issued_at" bson:"issued_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+}
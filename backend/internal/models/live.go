@@ -0,0 +1,83 @@
+// backend/internal/models/live.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LiveMode string
+
+const (
+	// Segments are pushed by an encoder as the event happens
+	LiveModeLive LiveMode = "live"
+	// The title's packaged video is played out to everyone on one timeline
+	LiveModePremiere LiveMode = "premiere"
+)
+
+type LiveState string
+
+const (
+	LiveStateScheduled LiveState = "scheduled"
+	LiveStateLive      LiveState = "live"
+	LiveStateEnded     LiveState = "ended"
+)
+
+// LiveEvent schedules a live title. Premieres end when their video does;
+// live events end when the encoder finishes, an admin ends them or their
+// scheduled end has long passed.
+type LiveEvent struct {
+	Mode           LiveMode   `json:"mode" bson:"mode"`
+	State          LiveState  `json:"state" bson:"state"`
+	ScheduledStart time.Time  `json:"scheduled_start" bson:"scheduled_start"`
+	ScheduledEnd   time.Time  `json:"scheduled_end" bson:"scheduled_end"`
+	DVRWindow      int        `json:"dvr_window" bson:"dvr_window"` // Seconds viewers can seek back from the live edge
+	StreamKeyHash  string     `json:"-" bson:"stream_key_hash,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	EndedAt        *time.Time `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+	// Variants announced by the encoder's master playlist
+	Renditions []LiveRendition `json:"renditions,omitempty" bson:"renditions,omitempty"`
+}
+
+type LiveRendition struct {
+	Name             string `json:"name" bson:"name"` // Directory the encoder pushes the rendition to
+	Bandwidth        int    `json:"bandwidth" bson:"bandwidth"`
+	AverageBandwidth int    `json:"average_bandwidth,omitempty" bson:"average_bandwidth,omitempty"`
+	Resolution       string `json:"resolution,omitempty" bson:"resolution,omitempty"`
+	Codecs           string `json:"codecs,omitempty" bson:"codecs,omitempty"`
+}
+
+// LivePlaylist is the DVR window of one rendition of a live event, merged
+// from the media playlists the encoder pushes
+type LivePlaylist struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ContentID      primitive.ObjectID `json:"content_id" bson:"content_id"`
+	Rendition      string             `json:"rendition" bson:"rendition"`
+	TargetDuration int                `json:"target_duration" bson:"target_duration"`
+	PartTarget     float64            `json:"part_target,omitempty" bson:"part_target,omitempty"` // Set when the encoder sends low-latency parts
+	MapURI         string             `json:"map_uri,omitempty" bson:"map_uri,omitempty"`         // fMP4 initialization section
+	Segments       []LiveSegment      `json:"segments" bson:"segments"`
+	// Parts of the segment the encoder is still writing
+	PendingParts []LivePart `json:"pending_parts,omitempty" bson:"pending_parts,omitempty"`
+	PreloadHint  string     `json:"preload_hint,omitempty" bson:"preload_hint,omitempty"`
+	Ended        bool       `json:"ended" bson:"ended"`
+	// Peak bitrate seen, announced when the encoder sends no master playlist
+	Bandwidth int       `json:"bandwidth" bson:"bandwidth"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type LiveSegment struct {
+	Sequence        int        `json:"sequence" bson:"sequence"`
+	Duration        float64    `json:"duration" bson:"duration"`
+	URI             string     `json:"uri" bson:"uri"`
+	Parts           []LivePart `json:"parts,omitempty" bson:"parts,omitempty"`
+	ProgramDateTime *time.Time `json:"program_date_time,omitempty" bson:"program_date_time,omitempty"`
+	Discontinuity   bool       `json:"discontinuity,omitempty" bson:"discontinuity,omitempty"`
+}
+
+type LivePart struct {
+	URI         string  `json:"uri" bson:"uri"`
+	Duration    float64 `json:"duration" bson:"duration"`
+	Independent bool    `json:"independent,omitempty" bson:"independent,omitempty"`
+}
@@ -0,0 +1,61 @@
+// backend/internal/models/party.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WatchPartyStatus string
+
+const (
+	WatchPartyStatusActive WatchPartyStatus = "active"
+	WatchPartyStatusEnded  WatchPartyStatus = "ended"
+)
+
+// WatchParty is a group of accounts watching one title or episode together.
+// Each participant streams on their own account, so each needs a
+// subscription and a free stream slot to join.
+type WatchParty struct {
+	ID        primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	HostID    primitive.ObjectID   `json:"host_id" bson:"host_id"`
+	ContentID primitive.ObjectID   `json:"content_id" bson:"content_id"`
+	EpisodeID *primitive.ObjectID  `json:"episode_id,omitempty" bson:"episode_id,omitempty"`
+	Title     string               `json:"title" bson:"title"`
+	Invited   []primitive.ObjectID `json:"invited" bson:"invited"`
+	// Everyone who has joined at least once, host included
+	Members []WatchPartyMember `json:"members" bson:"members"`
+	// Lets guests play, pause and seek as well as the host
+	GuestControl bool               `json:"guest_control" bson:"guest_control"`
+	Status       WatchPartyStatus   `json:"status" bson:"status"`
+	Playback     WatchPartyPlayback `json:"playback" bson:"playback"`
+	// The most recent chat messages, oldest first
+	Chat      []WatchPartyMessage `json:"chat" bson:"chat"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at" bson:"updated_at"`
+	ExpiresAt time.Time           `json:"expires_at" bson:"expires_at"` // Pushed back whenever someone joins or leaves
+	EndedAt   *time.Time          `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+}
+
+type WatchPartyMember struct {
+	UserID   primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name     string             `json:"name" bson:"name"`
+	JoinedAt time.Time          `json:"joined_at" bson:"joined_at"`
+}
+
+// WatchPartyPlayback is the shared playback clock. While playing, the
+// position advances in real time from UpdatedAt.
+type WatchPartyPlayback struct {
+	Playing   bool      `json:"playing" bson:"playing"`
+	Position  float64   `json:"position" bson:"position"` // Seconds
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type WatchPartyMessage struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name   string             `json:"name" bson:"name"`
+	Text   string             `json:"text" bson:"text"`
+	SentAt time.Time          `json:"sent_at" bson:"sent_at"`
+}
@@ -0,0 +1,92 @@
+// backend/internal/models/playback.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PlaybackSession is one viewing of a title reported by a player, with
+// running totals of the quality-of-experience events it sent
+type PlaybackSession struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID          primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	ProfileID       *primitive.ObjectID `json:"profile_id,omitempty" bson:"profile_id,omitempty"`
+	ContentID       primitive.ObjectID  `json:"content_id" bson:"content_id"`
+	EpisodeID       *primitive.ObjectID `json:"episode_id,omitempty" bson:"episode_id,omitempty"`
+	Autoplayed      bool                `json:"autoplayed" bson:"autoplayed"` // Started by the up-next countdown rather than the viewer
+	DeviceID        string              `json:"device_id" bson:"device_id"`
+	DeviceType      string              `json:"device_type" bson:"device_type"` // e.g. "web", "tv", "mobile"
+	Quality         VideoQuality        `json:"quality" bson:"quality"`         // Rendition currently playing
+	BitrateKbps     int                 `json:"bitrate_kbps" bson:"bitrate_kbps"`
+	StartupTimeMs   int64               `json:"startup_time_ms" bson:"startup_time_ms"`
+	PlayedSeconds   float64             `json:"played_seconds" bson:"played_seconds"`
+	RebufferSeconds float64             `json:"rebuffer_seconds" bson:"rebuffer_seconds"`
+	RebufferCount   int                 `json:"rebuffer_count" bson:"rebuffer_count"`
+	BitrateSwitches int                 `json:"bitrate_switches" bson:"bitrate_switches"`
+	Errors          int                 `json:"errors" bson:"errors"`
+	BandwidthBps    int                 `json:"bandwidth_bps" bson:"bandwidth_bps"` // Smoothed throughput estimate used for ABR advice
+	Position        float64             `json:"position" bson:"position"`           // Seconds into the title
+	Duration        float64             `json:"duration" bson:"duration"`           // Length of the title in seconds
+	Completed       bool                `json:"completed" bson:"completed"`
+	StartedAt       time.Time           `json:"started_at" bson:"started_at"`
+	LastEventAt     time.Time           `json:"last_event_at" bson:"last_event_at"`
+	EndedAt         *time.Time          `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+}
+
+type PlaybackEventType string
+
+const (
+	PlaybackEventStart         PlaybackEventType = "start"
+	PlaybackEventHeartbeat     PlaybackEventType = "heartbeat"
+	PlaybackEventBitrateSwitch PlaybackEventType = "bitrate_switch"
+	PlaybackEventRebuffer      PlaybackEventType = "rebuffer"
+	PlaybackEventError         PlaybackEventType = "error"
+	PlaybackEventStop          PlaybackEventType = "stop"
+)
+
+// PlaybackEvent is a single event from a playback session. Events carry the
+// dimensions they are rolled up by so the aggregation needs no lookups.
+type PlaybackEvent struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	SessionID     primitive.ObjectID `json:"session_id" bson:"session_id"`
+	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ContentID     primitive.ObjectID `json:"content_id" bson:"content_id"`
+	DeviceType    string             `json:"device_type" bson:"device_type"`
+	Type          PlaybackEventType  `json:"type" bson:"type"`
+	Quality       VideoQuality       `json:"quality" bson:"quality"`
+	BitrateKbps   int                `json:"bitrate_kbps" bson:"bitrate_kbps"`
+	PlayedSeconds float64            `json:"played_seconds" bson:"played_seconds"` // Time spent playing since the previous event
+	Position      float64            `json:"position" bson:"position"`
+	DurationMs    int64              `json:"duration_ms,omitempty" bson:"duration_ms,omitempty"` // Startup time or stall length
+	ErrorCode     string             `json:"error_code,omitempty" bson:"error_code,omitempty"`
+	ErrorMessage  string             `json:"error_message,omitempty" bson:"error_message,omitempty"`
+	Completed     bool               `json:"completed,omitempty" bson:"completed,omitempty"`
+	Timestamp     time.Time          `json:"timestamp" bson:"timestamp"`
+}
+
+// PlaybackRollup holds the quality-of-experience aggregates for one title,
+// rendition and device type on one day (UTC)
+type PlaybackRollup struct {
+	Key                PlaybackRollupKey `json:"key" bson:"_id"`
+	Sessions           int               `json:"sessions" bson:"sessions"`
+	UniqueViewers      int               `json:"unique_viewers" bson:"unique_viewers"`
+	PlayedSeconds      float64           `json:"played_seconds" bson:"played_seconds"`
+	RebufferSeconds    float64           `json:"rebuffer_seconds" bson:"rebuffer_seconds"`
+	RebufferRatio      float64           `json:"rebuffer_ratio" bson:"rebuffer_ratio"`
+	AverageStartupMs   float64           `json:"average_startup_ms" bson:"average_startup_ms"`
+	AverageBitrateKbps float64           `json:"average_bitrate_kbps" bson:"average_bitrate_kbps"`
+	CompletedSessions  int               `json:"completed_sessions" bson:"completed_sessions"`
+	CompletionRate     float64           `json:"completion_rate" bson:"completion_rate"`
+	Errors             int               `json:"errors" bson:"errors"`
+	PeakConcurrency    int               `json:"peak_concurrency" bson:"peak_concurrency"`
+	UpdatedAt          time.Time         `json:"updated_at" bson:"updated_at"`
+}
+
+type PlaybackRollupKey struct {
+	Day        time.Time          `json:"day" bson:"day"`
+	ContentID  primitive.ObjectID `json:"content_id" bson:"content_id"`
+	Quality    VideoQuality       `json:"quality" bson:"quality"`
+	DeviceType string             `json:"device_type" bson:"device_type"`
+}
@@ -0,0 +1,25 @@
+// backend/internal/models/stream.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StreamSession is one device playing a title. A session counts towards
+// PlanLimits.MaxConcurrentStreams until it is stopped or its heartbeat lapses.
+type StreamSession struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID          primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	ContentID       primitive.ObjectID  `json:"content_id" bson:"content_id"`
+	EpisodeID       *primitive.ObjectID `json:"episode_id,omitempty" bson:"episode_id,omitempty"`
+	DeviceID        string              `json:"device_id" bson:"device_id"`
+	DeviceName      string              `json:"device_name" bson:"device_name"`
+	IPAddress       string              `json:"ip_address" bson:"ip_address"`
+	UserAgent       string              `json:"user_agent" bson:"user_agent"`
+	StartedAt       time.Time           `json:"started_at" bson:"started_at"`
+	LastHeartbeatAt time.Time           `json:"last_heartbeat_at" bson:"last_heartbeat_at"`
+	ExpiresAt       time.Time           `json:"expires_at" bson:"expires_at"`
+	EndedAt         *time.Time          `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+}
@@ -0,0 +1,72 @@
+// backend/internal/models/transcode.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TranscodeJob converts an uploaded source video into the quality ladder.
+// Intro detection jobs share the queue and fingerprint a season instead.
+type TranscodeJob struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Type            TranscodeJobType    `json:"type" bson:"type,omitempty"` // Empty on jobs queued before intro detection
+	ContentID       primitive.ObjectID  `json:"content_id" bson:"content_id"`
+	SourceVideoID   primitive.ObjectID  `json:"source_video_id" bson:"source_video_id"`
+	SourceURL       string              `json:"source_url" bson:"source_url"`
+	Qualities       []VideoQuality      `json:"qualities" bson:"qualities"`
+	SeasonID        *primitive.ObjectID `json:"season_id,omitempty" bson:"season_id,omitempty"`
+	ApplyIntros     bool                `json:"apply_intros,omitempty" bson:"apply_intros,omitempty"`
+	Intros          []IntroSuggestion   `json:"intros,omitempty" bson:"intros,omitempty"`
+	Status          TranscodeStatus     `json:"status" bson:"status"`
+	Progress        float64             `json:"progress" bson:"progress"` // Percentage (0-100)
+	CurrentQuality  VideoQuality        `json:"current_quality,omitempty" bson:"current_quality,omitempty"`
+	Outputs         []TranscodeOutput   `json:"outputs" bson:"outputs"`
+	Attempts        int                 `json:"attempts" bson:"attempts"`
+	MaxAttempts     int                 `json:"max_attempts" bson:"max_attempts"`
+	Error           string              `json:"error,omitempty" bson:"error,omitempty"`
+	CancelRequested bool                `json:"cancel_requested" bson:"cancel_requested"`
+	WorkerID        string              `json:"worker_id,omitempty" bson:"worker_id,omitempty"`
+	LeaseExpiresAt  *time.Time          `json:"-" bson:"lease_expires_at,omitempty"`
+	NextAttemptAt   time.Time           `json:"next_attempt_at" bson:"next_attempt_at"`
+	StartedAt       *time.Time          `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	CompletedAt     *time.Time          `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	CreatedAt       time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type TranscodeJobType string
+
+const (
+	TranscodeJobVideo  TranscodeJobType = "transcode"
+	TranscodeJobIntros TranscodeJobType = "intro_detection"
+)
+
+type TranscodeStatus string
+
+const (
+	TranscodeStatusQueued     TranscodeStatus = "queued"
+	TranscodeStatusProcessing TranscodeStatus = "processing"
+	TranscodeStatusCompleted  TranscodeStatus = "completed"
+	TranscodeStatusFailed     TranscodeStatus = "failed"
+	TranscodeStatusCancelled  TranscodeStatus = "cancelled"
+)
+
+type TranscodeOutput struct {
+	VideoID  primitive.ObjectID `json:"video_id" bson:"video_id"`
+	Quality  VideoQuality       `json:"quality" bson:"quality"`
+	FileURL  string             `json:"file_url" bson:"file_url"`
+	FileSize int64              `json:"file_size" bson:"file_size"`
+	Duration int                `json:"duration" bson:"duration"`
+}
+
+// IntroSuggestion is an intro range found by matching an episode's opening
+// against the other episodes of its season
+type IntroSuggestion struct {
+	EpisodeID       primitive.ObjectID `json:"episode_id" bson:"episode_id"`
+	EpisodeNumber   int                `json:"episode_number" bson:"episode_number"`
+	Start           float64            `json:"start" bson:"start"`
+	End             float64            `json:"end" bson:"end"`
+	MatchedEpisodes int                `json:"matched_episodes" bson:"matched_episodes"` // Other episodes that share the range
+}
@@ -0,0 +1,34 @@
+// backend/internal/models/upload.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VideoUpload tracks a resumable (tus) upload of a video file for a title
+type VideoUpload struct {
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	ContentID   primitive.ObjectID  `json:"content_id" bson:"content_id"`
+	UploadedBy  primitive.ObjectID  `json:"uploaded_by" bson:"uploaded_by"`
+	Filename    string              `json:"filename" bson:"filename"`
+	Title       string              `json:"title" bson:"title"`
+	Type        VideoType           `json:"type" bson:"type"`
+	Quality     VideoQuality        `json:"quality" bson:"quality"`
+	Length      int64               `json:"length" bson:"length"`
+	Offset      int64               `json:"offset" bson:"offset"`
+	StoragePath string              `json:"-" bson:"storage_path"`
+	Status      UploadStatus        `json:"status" bson:"status"`
+	VideoID     *primitive.ObjectID `json:"video_id,omitempty" bson:"video_id,omitempty"`
+	ExpiresAt   time.Time           `json:"expires_at" bson:"expires_at"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type UploadStatus string
+
+const (
+	UploadStatusUploading UploadStatus = "uploading"
+	UploadStatusCompleted UploadStatus = "completed"
+)
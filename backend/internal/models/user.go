@@ -15,6 +15,7 @@ type User struct {
 	LastName          string             `json:"last_name" bson:"last_name" validate:"required"`
 	Phone             string             `json:"phone" bson:"phone"`
 	Avatar            string             `json:"avatar" bson:"avatar"`
+	AvatarVariants    []ImageVariant     `json:"avatar_variants,omitempty" bson:"avatar_variants,omitempty"`
 	IsActive          bool               `json:"is_active" bson:"is_active"`
 	IsEmailVerified   bool               `json:"is_email_verified" bson:"is_email_verified"`
 	EmailVerifiedAt   *time.Time         `json:"email_verified_at" bson:"email_verified_at"`
@@ -25,10 +26,24 @@ type User struct {
 	LastLoginAt       *time.Time         `json:"last_login_at" bson:"last_login_at"`
 	PasswordResetToken string            `json:"-" bson:"password_reset_token"`
 	PasswordResetExpiry *time.Time       `json:"-" bson:"password_reset_expiry"`
+	TwoFactor         *TwoFactorAuth     `json:"two_factor,omitempty" bson:"two_factor,omitempty"`
 	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt         time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// TwoFactorAuth is a user's TOTP factor. It is pending until the first code
+// from the authenticator app confirms it.
+type TwoFactorAuth struct {
+	Enabled bool `json:"enabled" bson:"enabled"`
+	// TOTP secret sealed under MFA_ENCRYPTION_KEY
+	Secret []byte `json:"-" bson:"secret"`
+	// bcrypt hashes of the unused recovery codes
+	RecoveryCodes []string   `json:"-" bson:"recovery_codes"`
+	LastUsedStep  int64      `json:"-" bson:"last_used_step"` // Time step of the last accepted code, so codes cannot be replayed
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`
+	ConfirmedAt   *time.Time `json:"confirmed_at,omitempty" bson:"confirmed_at,omitempty"`
+}
+
 type UserRole string
 
 const (
@@ -53,6 +68,7 @@ type UserProfile struct {
 	ID           primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
 	Name         string               `json:"name" bson:"name" validate:"required"`
 	Avatar       string               `json:"avatar" bson:"avatar"`
+	AvatarVariants []ImageVariant     `json:"avatar_variants,omitempty" bson:"avatar_variants,omitempty"`
 	IsKidsProfile bool                `json:"is_kids_profile" bson:"is_kids_profile"`
 	Language     string               `json:"language" bson:"language"`
 	Watchlist    []primitive.ObjectID `json:"watchlist" bson:"watchlist"`
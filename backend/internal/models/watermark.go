@@ -0,0 +1,20 @@
+// backend/internal/models/watermark.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WatermarkSession ties the watermark ID embedded in a streaming token to the
+// user it was issued to, so the A/B segment sequence in a leaked copy can be
+// traced back to them
+type WatermarkSession struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WatermarkID string             `json:"watermark_id" bson:"watermark_id"` // 8 random bytes, hex encoded
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ContentID   primitive.ObjectID `json:"content_id" bson:"content_id"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt   time.Time          `json:"expires_at" bson:"expires_at"`
+}
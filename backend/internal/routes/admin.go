@@ -18,6 +18,7 @@ func SetupAdminRoutes(rg *gin.RouterGroup, services *services.Services) {
 	rg.GET("/analytics/users", adminController.GetUserAnalytics)
 	rg.GET("/analytics/content", adminController.GetContentAnalytics)
 	rg.GET("/analytics/revenue", adminController.GetRevenueAnalytics)
+	rg.GET("/analytics/playback", adminController.GetPlaybackAnalytics)
 
 	// User management
 	users := rg.Group("/users")
@@ -45,6 +46,12 @@ func SetupAdminRoutes(rg *gin.RouterGroup, services *services.Services) {
 		content.DELETE("/:contentID", adminController.DeleteContent)
 		content.POST("/:contentID/publish", adminController.PublishContent)
 		content.POST("/:contentID/unpublish", adminController.UnpublishContent)
+		content.POST("/:contentID/package", adminController.PackageContent)
+
+		// Live events and premieres
+		content.PUT("/:contentID/live", adminController.ScheduleLiveEvent)
+		content.POST("/:contentID/live/stream-key", adminController.RotateLiveStreamKey)
+		content.POST("/:contentID/live/end", adminController.EndLiveEvent)
 
 		// TMDB integration
 		content.POST("/import/tmdb/:tmdbID", adminController.ImportFromTMDB)
@@ -59,6 +66,29 @@ func SetupAdminRoutes(rg *gin.RouterGroup, services *services.Services) {
 			videos.PUT("/:videoID", adminController.UpdateVideo)
 			videos.DELETE("/:videoID", adminController.DeleteVideo)
 			videos.POST("/:videoID/process", adminController.ProcessVideo)
+			videos.GET("/:videoID/process", adminController.GetProcessingStatus)
+			videos.DELETE("/:videoID/process", adminController.CancelProcessing)
+			videos.GET("/:videoID/markers", adminController.GetMarkers)
+			videos.POST("/:videoID/markers", adminController.CreateMarker)
+			videos.PUT("/:videoID/markers/:markerID", adminController.UpdateMarker)
+			videos.DELETE("/:videoID/markers/:markerID", adminController.DeleteMarker)
+			videos.GET("/:videoID/audio-tracks", adminController.GetAudioTracks)
+			videos.POST("/:videoID/audio-tracks", adminController.CreateAudioTrack)
+			videos.DELETE("/:videoID/audio-tracks/:trackID", adminController.DeleteAudioTrack)
+		}
+
+		// Poster, backdrop and logo images with resized variants
+		content.POST("/:contentID/images/:kind", adminController.UploadContentImage)
+
+		// Resumable video uploads (tus protocol)
+		uploads := content.Group("/:contentID/uploads")
+		{
+			uploads.OPTIONS("", adminController.TusOptions)
+			uploads.POST("", adminController.CreateUpload)
+			uploads.GET("/:uploadID", adminController.GetUpload)
+			uploads.HEAD("/:uploadID", adminController.GetUploadOffset)
+			uploads.PATCH("/:uploadID", adminController.UploadChunk)
+			uploads.DELETE("/:uploadID", adminController.TerminateUpload)
 		}
 
 		// Season and episode management (for TV shows)
@@ -68,6 +98,8 @@ func SetupAdminRoutes(rg *gin.RouterGroup, services *services.Services) {
 			seasons.GET("", adminController.GetSeasons)
 			seasons.PUT("/:seasonID", adminController.UpdateSeason)
 			seasons.DELETE("/:seasonID", adminController.DeleteSeason)
+			seasons.POST("/:seasonID/intros/detect", adminController.DetectIntros)
+			seasons.GET("/:seasonID/intros/detect/:jobID", adminController.GetIntroDetection)
 
 			episodes := seasons.Group("/:seasonID/episodes")
 			{
@@ -75,6 +107,11 @@ func SetupAdminRoutes(rg *gin.RouterGroup, services *services.Services) {
 				episodes.GET("", adminController.GetEpisodes)
 				episodes.PUT("/:episodeID", adminController.UpdateEpisode)
 				episodes.DELETE("/:episodeID", adminController.DeleteEpisode)
+				episodes.POST("/:episodeID/package", adminController.PackageEpisode)
+				episodes.GET("/:episodeID/markers", adminController.GetMarkers)
+				episodes.POST("/:episodeID/markers", adminController.CreateMarker)
+				episodes.PUT("/:episodeID/markers/:markerID", adminController.UpdateMarker)
+				episodes.DELETE("/:episodeID/markers/:markerID", adminController.DeleteMarker)
 			}
 		}
 
@@ -150,6 +187,7 @@ func SetupAdminRoutes(rg *gin.RouterGroup, services *services.Services) {
 		system.POST("/cache/clear", adminController.ClearCache)
 		system.POST("/database/backup", adminController.BackupDatabase)
 		system.GET("/database/status", adminController.GetDatabaseStatus)
+		system.GET("/cdn", adminController.GetCDNStatus)
 	}
 
 	// Reports
@@ -163,6 +201,12 @@ func SetupAdminRoutes(rg *gin.RouterGroup, services *services.Services) {
 		reports.GET("/export/:reportID", adminController.ExportReport)
 	}
 
+	// Forensic watermark tracing
+	watermarks := rg.Group("/watermarks")
+	{
+		watermarks.POST("/decode", adminController.DecodeWatermark)
+	}
+
 	// Notifications and announcements
 	notifications := rg.Group("/notifications")
 	{
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"onflix/internal/controllers"
+	"onflix/internal/middleware"
 	"onflix/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +10,7 @@ import (
 
 func SetupAuthRoutes(rg *gin.RouterGroup, services *services.Services) {
 	authController := controllers.NewAuthController(services)
+	authMiddleware := middleware.NewAuthMiddleware(services.DB, services.Config.JWT.Secret)
 
 	auth := rg.Group("/auth")
 	{
@@ -34,9 +36,17 @@ func SetupAuthRoutes(rg *gin.RouterGroup, services *services.Services) {
 		auth.POST("/google", authController.GoogleLogin)
 		auth.POST("/facebook", authController.FacebookLogin)
 
-		// Two-factor authentication
-		auth.POST("/2fa/enable", authController.Enable2FA)
-		auth.POST("/2fa/verify", authController.Verify2FA)
-		auth.POST("/2fa/disable", authController.Disable2FA)
+		// Two-factor authentication: the second login step, then enrolment
+		// and management for signed-in users
+		auth.POST("/2fa/login", authController.CompleteMFALogin)
+		twoFactor := auth.Group("/2fa")
+		twoFactor.Use(authMiddleware.RequireAuth())
+		{
+			twoFactor.GET("", authController.Get2FAStatus)
+			twoFactor.POST("/enable", authController.Enable2FA)
+			twoFactor.POST("/verify", authController.Verify2FA)
+			twoFactor.POST("/disable", authController.Disable2FA)
+			twoFactor.POST("/recovery-codes", authController.RegenerateRecoveryCodes)
+		}
 	}
 }
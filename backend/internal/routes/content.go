@@ -22,6 +22,7 @@ func SetupPublicContentRoutes(rg *gin.RouterGroup, services *services.Services)
 		content.GET("/trending", contentController.GetTrendingContent)
 		content.GET("/new-releases", contentController.GetNewReleases)
 		content.GET("/originals", contentController.GetOriginals)
+		content.GET("/live", contentController.GetLiveSchedule)
 
 		// Content details
 		content.GET("/:contentID", contentController.GetContentDetails)
@@ -62,12 +63,24 @@ func SetupContentRoutes(rg *gin.RouterGroup, services *services.Services) {
 		content.GET("/:contentID/stream/:quality", contentController.StreamVideoQuality)
 		content.POST("/:contentID/stream/token", contentController.GetStreamingToken)
 
+		// HLS adaptive streaming (master playlist, media playlists and segments)
+		content.GET("/:contentID/hls/*filepath", contentController.ServeHLS)
+		content.GET("/:contentID/episodes/:episodeID/hls/*filepath", contentController.ServeEpisodeHLS)
+
+		// DASH adaptive streaming (rendered MPD and fragmented MP4 segments)
+		content.GET("/:contentID/dash/*filepath", contentController.ServeDASH)
+		content.GET("/:contentID/episodes/:episodeID/dash/*filepath", contentController.ServeEpisodeDASH)
+
 		// TV Show streaming
 		content.GET("/tv-shows/:showID/seasons/:seasonNumber/episodes/:episodeNumber/stream", contentController.StreamEpisode)
 
+		// What plays when the current title or episode ends
+		content.GET("/:contentID/up-next", contentController.GetUpNext)
+
 		// Download for offline viewing
 		content.POST("/:contentID/download", contentController.DownloadContent)
 		content.GET("/downloads", contentController.GetDownloads)
+		content.POST("/downloads/:downloadID/renew", contentController.RenewDownload)
 		content.DELETE("/downloads/:downloadID", contentController.RemoveDownload)
 
 		// Subtitles
@@ -89,6 +102,14 @@ func SetupContentRoutes(rg *gin.RouterGroup, services *services.Services) {
 		content.POST("/:contentID/watch-progress", contentController.UpdateWatchProgress)
 	}
 
+	// Playback sessions counted against the plan's concurrent stream limit
+	streams := rg.Group("/streams")
+	{
+		streams.GET("", contentController.GetActiveStreams)
+		streams.POST("/:sessionID/heartbeat", contentController.StreamHeartbeat)
+		streams.DELETE("/:sessionID", contentController.StopStream)
+	}
+
 	// Recommendations (require auth but not necessarily subscription)
 	recommendations := rg.Group("/recommendations")
 	{
@@ -98,3 +119,11 @@ func SetupContentRoutes(rg *gin.RouterGroup, services *services.Services) {
 		recommendations.POST("/feedback", contentController.SubmitRecommendationFeedback)
 	}
 }
+
+func SetupDeliveryRoutes(rg *gin.RouterGroup, services *services.Services) {
+	contentController := controllers.NewContentController(services)
+
+	// Storage files at their public URL; access is checked per file by signature
+	rg.GET("/*filepath", contentController.DeliverFile)
+	rg.HEAD("/*filepath", contentController.DeliverFile)
+}
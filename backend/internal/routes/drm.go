@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"onflix/internal/controllers"
+	"onflix/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupDRMRoutes(rg *gin.RouterGroup, services *services.Services) {
+	drmController := controllers.NewDRMController(services)
+
+	// Key delivery authorizes each request with a streaming token rather
+	// than the API session, since players fetch keys on their own
+	drm := rg.Group("/drm")
+	{
+		drm.GET("/keys/:keyID", drmController.GetHLSKey)
+		drm.POST("/clearkey/license", drmController.ClearKeyLicense)
+	}
+}
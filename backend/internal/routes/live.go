@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"onflix/internal/controllers"
+	"onflix/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupLiveRoutes(rg *gin.RouterGroup, services *services.Services) {
+	liveController := controllers.NewLiveController(services)
+
+	// Encoders push to a URL holding the event's stream key, which stands
+	// in for the API session
+	ingest := rg.Group("/live/ingest/:streamKey")
+	{
+		ingest.PUT("/*filepath", liveController.Ingest)
+		ingest.POST("/*filepath", liveController.Ingest)
+		ingest.DELETE("/*filepath", liveController.IngestDelete)
+	}
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"onflix/internal/controllers"
+	"onflix/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupWatchPartyRoutes(rg *gin.RouterGroup, services *services.Services) {
+	partyController := controllers.NewWatchPartyController(services)
+
+	parties := rg.Group("/watch-parties")
+	{
+		parties.POST("", partyController.CreateParty)
+		parties.GET("", partyController.GetParties)
+		parties.GET("/:partyID", partyController.GetParty)
+		parties.POST("/:partyID/invites", partyController.InviteToParty)
+		parties.POST("/:partyID/join", partyController.JoinParty)
+		parties.DELETE("/:partyID", partyController.EndParty)
+	}
+}
+
+// SetupWatchPartySocketRoutes registers the party socket, which browsers open
+// without the bearer token and authorize with the ticket from joining instead
+func SetupWatchPartySocketRoutes(rg *gin.RouterGroup, services *services.Services) {
+	partyController := controllers.NewWatchPartyController(services)
+
+	rg.GET("/watch-parties/:partyID/socket", partyController.PartySocket)
+}
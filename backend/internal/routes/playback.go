@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"onflix/internal/controllers"
+	"onflix/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupPlaybackRoutes(rg *gin.RouterGroup, services *services.Services) {
+	playbackController := controllers.NewPlaybackController(services)
+
+	playback := rg.Group("/playback")
+	{
+		playback.POST("/sessions", playbackController.StartSession)
+		playback.POST("/sessions/:sessionID/events", playbackController.RecordEvents)
+		playback.POST("/sessions/:sessionID/advice", playbackController.GetAdvice)
+	}
+}
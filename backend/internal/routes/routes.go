@@ -13,7 +13,7 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 	authMiddleware := middleware.NewAuthMiddleware(services.DB, services.Config.JWT.Secret)
 
 	// Global middleware
-	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.CORSMiddleware(services.Config.Server.CORSOrigins))
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	// Serve static files
@@ -40,6 +40,9 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 	router.GET("/admin/*path", func(c *gin.Context) {
 		c.File("./web/templates/admin/index.html")
 	})
+	// Uploaded media delivery (signed URLs from VideoService.GenerateStreamingURL)
+	SetupDeliveryRoutes(router.Group("/uploads"), services)
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "service": "onflix"})
@@ -51,6 +54,9 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 		// Public routes
 		SetupAuthRoutes(v1, services)
 		SetupPublicContentRoutes(v1, services)
+		SetupDRMRoutes(v1, services)
+		SetupLiveRoutes(v1, services)
+		SetupWatchPartySocketRoutes(v1, services)
 
 		// Protected routes
 		protected := v1.Group("")
@@ -58,12 +64,15 @@ func SetupRoutes(router *gin.Engine, services *services.Services) {
 		{
 			SetupUserRoutes(protected, services)
 			SetupContentRoutes(protected, services)
+			SetupPlaybackRoutes(protected, services)
+			SetupWatchPartyRoutes(protected, services)
 		}
 
 		// Admin routes
 		admin := v1.Group("/admin")
 		admin.Use(authMiddleware.RequireAuth())
 		admin.Use(middleware.RequireAdmin())
+		admin.Use(middleware.RequireTwoFactor(services.Config.MFA.RequireAdmin))
 		{
 			SetupAdminRoutes(admin, services)
 		}
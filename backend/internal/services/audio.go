@@ -0,0 +1,453 @@
+// backend/internal/services/audio.go
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	hlsAudioGroup = "audio"
+	// Apple's characteristic for tracks that narrate the picture
+	hlsDescribesVideo = "public.accessibility.describes-video"
+)
+
+var (
+	ErrAudioVideoNotFound = errors.New("video not found")
+	ErrAudioTrackNotFound = errors.New("audio track not found")
+	ErrAudioTrackInvalid  = errors.New("audio track needs a language, a role of main, dub, description or commentary and 1, 2, 6 or 8 channels")
+	ErrAudioTrackExists   = errors.New("video already has an audio track with this language, role and channel layout")
+	ErrAudioTrackMuxed    = errors.New("only one main track can describe the muxed audio, and it cannot name a file")
+)
+
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// AAC bitrates by channel count
+var audioChannelBitrate = map[int]int{
+	1: 64000,
+	2: 128000,
+	6: 384000,
+	8: 512000,
+}
+
+// Order tracks are listed in, so players that take the first match of a
+// language get the main mix before commentary or descriptions
+var audioRolePriority = map[models.AudioRole]int{
+	models.AudioRoleMain:        0,
+	models.AudioRoleDub:         1,
+	models.AudioRoleCommentary:  2,
+	models.AudioRoleDescription: 3,
+}
+
+// HLSAudioRendition is an alternate audio track written by PackageHLS
+type HLSAudioRendition struct {
+	TrackID      primitive.ObjectID `json:"track_id"`
+	Language     string             `json:"language"`
+	Role         models.AudioRole   `json:"role"`
+	Channels     int                `json:"channels"`
+	PlaylistPath string             `json:"playlist_path"`
+	Segments     int                `json:"segments"`
+	Bandwidth    int                `json:"bandwidth"`
+}
+
+func (vs *VideoService) AudioTracks(ctx context.Context, contentID, videoID primitive.ObjectID) ([]models.AudioTrack, error) {
+	video, err := vs.audioVideo(ctx, contentID, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if video.AudioTracks == nil {
+		return []models.AudioTrack{}, nil
+	}
+	return video.AudioTracks, nil
+}
+
+// AddAudioTrack attaches an audio track to a full-length video. It is
+// packaged the next time the title is.
+func (vs *VideoService) AddAudioTrack(ctx context.Context, contentID, videoID primitive.ObjectID, track models.AudioTrack) (*models.AudioTrack, error) {
+	video, err := vs.audioVideo(ctx, contentID, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !languageTag.MatchString(track.Language) || audioChannelBitrate[track.Channels] == 0 {
+		return nil, ErrAudioTrackInvalid
+	}
+	if _, ok := audioRolePriority[track.Role]; !ok {
+		return nil, ErrAudioTrackInvalid
+	}
+
+	if track.Muxed && (track.Role != models.AudioRoleMain || track.FileURL != "") {
+		return nil, ErrAudioTrackMuxed
+	}
+
+	for _, existing := range video.AudioTracks {
+		if track.Muxed && existing.Muxed {
+			return nil, ErrAudioTrackMuxed
+		}
+		if strings.EqualFold(existing.Language, track.Language) && existing.Role == track.Role && existing.Channels == track.Channels {
+			return nil, ErrAudioTrackExists
+		}
+	}
+
+	track.ID = primitive.NewObjectID()
+	track.HLSPlaylist = ""
+	track.CreatedAt = time.Now()
+
+	if err := vs.saveAudioTracks(ctx, contentID, videoID, append(video.AudioTracks, track)); err != nil {
+		return nil, err
+	}
+
+	return &track, nil
+}
+
+func (vs *VideoService) DeleteAudioTrack(ctx context.Context, contentID, videoID, trackID primitive.ObjectID) error {
+	video, err := vs.audioVideo(ctx, contentID, videoID)
+	if err != nil {
+		return err
+	}
+
+	for i, track := range video.AudioTracks {
+		if track.ID == trackID {
+			return vs.saveAudioTracks(ctx, contentID, videoID, append(video.AudioTracks[:i], video.AudioTracks[i+1:]...))
+		}
+	}
+
+	return ErrAudioTrackNotFound
+}
+
+func (vs *VideoService) audioVideo(ctx context.Context, contentID, videoID primitive.ObjectID) (*models.ContentVideo, error) {
+	var content models.Content
+	err := vs.db.Collection("content").FindOne(ctx, bson.M{
+		"_id":        contentID,
+		"videos._id": videoID,
+	}).Decode(&content)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAudioVideoNotFound
+		}
+		return nil, fmt.Errorf("failed to load content: %v", err)
+	}
+
+	for i := range content.Videos {
+		if content.Videos[i].ID == videoID && content.Videos[i].Type == models.VideoTypeFull {
+			return &content.Videos[i], nil
+		}
+	}
+
+	return nil, ErrAudioVideoNotFound
+}
+
+func (vs *VideoService) saveAudioTracks(ctx context.Context, contentID, videoID primitive.ObjectID, tracks []models.AudioTrack) error {
+	result, err := vs.db.Collection("content").UpdateOne(
+		ctx,
+		bson.M{"_id": contentID},
+		bson.M{"$set": bson.M{
+			"videos.$[video].audio_tracks": tracks,
+			"updated_at":                   time.Now(),
+		}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"video._id": videoID}},
+		}),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save audio tracks: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrAudioVideoNotFound
+	}
+
+	return nil
+}
+
+// titleAudioTracks gathers the audio tracks of a title's full-length videos,
+// resolving tracks without a file to the video they belong to
+func titleAudioTracks(videos []models.ContentVideo) []models.AudioTrack {
+	var tracks []models.AudioTrack
+	seen := make(map[primitive.ObjectID]bool)
+
+	for _, video := range videos {
+		if video.Type != models.VideoTypeFull {
+			continue
+		}
+		for _, track := range video.AudioTracks {
+			if seen[track.ID] {
+				continue
+			}
+			seen[track.ID] = true
+
+			if track.FileURL == "" {
+				track.FileURL = video.FileURL
+			}
+			tracks = append(tracks, track)
+		}
+	}
+
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return audioRolePriority[tracks[i].Role] < audioRolePriority[tracks[j].Role]
+	})
+
+	return tracks
+}
+
+func audioTrackDir(track models.AudioTrack) string {
+	return "audio-" + track.ID.Hex()
+}
+
+func audioTrackName(track models.AudioTrack) string {
+	if track.Label != "" {
+		return track.Label
+	}
+
+	name := track.Language
+	switch track.Role {
+	case models.AudioRoleDescription:
+		name += " (Audio Description)"
+	case models.AudioRoleCommentary:
+		name += " (Commentary)"
+	}
+	if track.Channels == 6 {
+		name += " 5.1"
+	} else if track.Channels == 8 {
+		name += " 7.1"
+	}
+	return name
+}
+
+// audioChannels returns the channel count a track is encoded with, stereo
+// for layouts we have no bitrate for
+func audioChannels(track models.AudioTrack) int {
+	if audioChannelBitrate[track.Channels] == 0 {
+		return 2
+	}
+	return track.Channels
+}
+
+// audioTrackArgs selects a track from its file and encodes it to AAC
+func audioTrackArgs(track models.AudioTrack) []string {
+	channels := audioChannels(track)
+	return []string{
+		"-map", fmt.Sprintf("0:a:%d", track.StreamIndex),
+		"-vn",
+		"-c:a", "aac",
+		"-b:a", strconv.Itoa(audioChannelBitrate[channels]),
+		"-ac", strconv.Itoa(channels),
+	}
+}
+
+// packageAudioTrack segments an alternate audio track into its own HLS media
+// playlist next to the video renditions, encrypted with the same key
+func (vs *VideoService) packageAudioTrack(ctx context.Context, ownerID string, track models.AudioTrack, key *models.ContentKey, rawKey []byte) (*HLSAudioRendition, error) {
+	source, release, err := vs.storage.Fetch(ctx, vs.storage.PathFromURL(track.FileURL))
+	if err != nil {
+		return nil, fmt.Errorf("source for %s audio track not found: %v", track.Language, err)
+	}
+	defer release()
+
+	dir := path.Join(HLSDirectory(ownerID), audioTrackDir(track))
+	outputDir, err := vs.storage.ResolvePath(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vs.storage.DeleteAll(ctx, dir); err != nil {
+		return nil, fmt.Errorf("failed to clear %s: %v", outputDir, err)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-i", source}
+	args = append(args, audioTrackArgs(track)...)
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(segmentDuration(vs.config)),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(outputDir, hlsSegmentPattern),
+		filepath.Join(outputDir, hlsMediaPlaylistName),
+	)
+
+	cmd := exec.CommandContext(ctx, vs.config.Video.FFmpegPath, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed for %s audio track: %v: %s", track.Language, err, strings.TrimSpace(string(output)))
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, hlsMediaPlaylistName))
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg did not produce a playlist for %s audio track: %v", track.Language, err)
+	}
+
+	playlist, err := ParseHLSMediaPlaylist(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if key != nil {
+		if err := encryptHLSSegments(outputDir, playlist, rawKey); err != nil {
+			return nil, err
+		}
+		playlist.Key = &HLSKey{Method: "AES-128", URI: vs.drm.HLSKeyURI(key.KeyID)}
+	}
+
+	_, bandwidth, _, err := measureHLSSegments(outputDir, playlist)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vs.storage.Publish(ctx, dir); err != nil {
+		return nil, err
+	}
+
+	rendition := &HLSAudioRendition{
+		TrackID:      track.ID,
+		Language:     track.Language,
+		Role:         track.Role,
+		Channels:     track.Channels,
+		PlaylistPath: path.Join(dir, hlsMediaPlaylistName),
+		Segments:     len(playlist.Sequences),
+		Bandwidth:    bandwidth,
+	}
+
+	if err := vs.writeStorageFile(ctx, rendition.PlaylistPath, playlist.Encode()); err != nil {
+		return nil, err
+	}
+
+	return rendition, nil
+}
+
+// hlsAudioMedia returns the EXT-X-MEDIA entry for a track. The muxed track
+// has no URI, which tells players its audio is carried in the variants.
+func hlsAudioMedia(track models.AudioTrack) HLSMedia {
+	media := HLSMedia{
+		Type:       "AUDIO",
+		GroupID:    hlsAudioGroup,
+		Name:       audioTrackName(track),
+		Language:   track.Language,
+		Autoselect: track.Role != models.AudioRoleCommentary,
+		Channels:   strconv.Itoa(audioChannels(track)),
+	}
+	if track.Role == models.AudioRoleDescription {
+		media.Characteristics = hlsDescribesVideo
+	}
+	if !track.Muxed {
+		media.URI = path.Join(audioTrackDir(track), hlsMediaPlaylistName)
+	}
+	return media
+}
+
+// SelectHLSAudio makes the first audio rendition of a master playlist in
+// language that players may pick on their own its DEFAULT. Descriptions are
+// left to the player's accessibility settings. The packaged default stands
+// when no rendition matches.
+func SelectHLSAudio(data []byte, language string) []byte {
+	if language == "" {
+		return data
+	}
+
+	var lines []string
+	var languages []string
+	var candidates []int
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if value, ok := strings.CutPrefix(line, "#EXT-X-MEDIA:"); ok {
+			attrs := parseHLSAttributes(value)
+			if attrs["TYPE"] == "AUDIO" && attrs["AUTOSELECT"] == "YES" && attrs["CHARACTERISTICS"] == "" {
+				candidates = append(candidates, len(lines))
+				languages = append(languages, attrs["LANGUAGE"])
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	match := matchLanguage(languages, language)
+	if match < 0 {
+		return data
+	}
+
+	var b bytes.Buffer
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#EXT-X-MEDIA:") && strings.Contains(line, "TYPE=AUDIO") {
+			if i == candidates[match] {
+				line = strings.Replace(line, "DEFAULT=NO", "DEFAULT=YES", 1)
+			} else {
+				line = strings.Replace(line, "DEFAULT=YES", "DEFAULT=NO", 1)
+			}
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	return b.Bytes()
+}
+
+// selectDASHAudio gives the Role main to the first main or dubbed audio set
+// in language, and demotes the set that had it
+func (m *DASHManifest) selectDASHAudio(language string) {
+	var languages []string
+	var candidates []int
+	for i, set := range m.AdaptationSets {
+		if set.ContentType == "audio" && (set.Role == "main" || set.Role == "dub") {
+			candidates = append(candidates, i)
+			languages = append(languages, set.Lang)
+		}
+	}
+
+	match := matchLanguage(languages, language)
+	if match < 0 {
+		return
+	}
+
+	for _, i := range candidates {
+		if m.AdaptationSets[i].Role == "main" {
+			m.AdaptationSets[i].Role = "alternate"
+		}
+	}
+	m.AdaptationSets[candidates[match]].Role = "main"
+}
+
+// matchLanguage returns the index of the first of languages that is
+// language, or failing that the first sharing its primary subtag, so an
+// en-GB profile still gets an en track
+func matchLanguage(languages []string, language string) int {
+	if language == "" {
+		return -1
+	}
+
+	for i, candidate := range languages {
+		if strings.EqualFold(candidate, language) {
+			return i
+		}
+	}
+
+	primary, _, _ := strings.Cut(language, "-")
+	for i, candidate := range languages {
+		candidatePrimary, _, _ := strings.Cut(candidate, "-")
+		if candidate != "" && strings.EqualFold(candidatePrimary, primary) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -2,25 +2,59 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"image/png"
+	"strconv"
+	"strings"
 	"time"
 
 	"onflix/internal/config"
 	"onflix/internal/models"
 	"onflix/internal/utils"
 
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	totpPeriod = 30
+	// Time steps of clock drift tolerated either side of the current one
+	totpSkew             = 1
+	mfaChallengeLifetime = 5 * time.Minute
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+var (
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotPending = errors.New("start two-factor enrolment before confirming it")
+	ErrTwoFactorCode       = errors.New("invalid two-factor code")
+	ErrTwoFactorPassword   = errors.New("password is incorrect")
+	ErrTwoFactorRequired   = errors.New("two-factor authentication is required for admin accounts")
+	ErrMFAChallenge        = errors.New("invalid or expired MFA challenge")
+)
+
 type AuthService struct {
 	config *config.Config
 	db     *mongo.Database
+	mfaKey []byte
 }
 
 type LoginAttempt struct {
@@ -51,6 +85,9 @@ type AuthResult struct {
 	Message      string       `json:"message"`
 	Error        string       `json:"error,omitempty"`
 	RequiresMFA  bool         `json:"requires_mfa,omitempty"`
+	// Exchanged with a second-factor code at CompleteMFALogin when RequiresMFA is set
+	MFAToken     string    `json:"mfa_token,omitempty"`
+	MFAExpiresAt time.Time `json:"mfa_expires_at,omitempty"`
 }
 
 type RegisterRequest struct {
@@ -70,9 +107,20 @@ type LoginRequest struct {
 }
 
 func NewAuthService(cfg *config.Config, db *mongo.Database) *AuthService {
+	mfaKey, err := base64.StdEncoding.DecodeString(cfg.MFA.EncryptionKey)
+	if err != nil || len(mfaKey) != 32 {
+		if cfg.MFA.EncryptionKey != "" {
+			fmt.Println("Warning: MFA_ENCRYPTION_KEY is not a base64 encoded 32-byte key, deriving one from it")
+		}
+		// Development fallback; Validate requires a real key in production
+		sum := sha256.Sum256([]byte("onflix-mfa:" + cfg.MFA.EncryptionKey + cfg.JWT.Secret))
+		mfaKey = sum[:]
+	}
+
 	return &AuthService{
 		config: cfg,
 		db:     db,
+		mfaKey: mfaKey,
 	}
 }
 
@@ -252,61 +300,28 @@ func (as *AuthService) LoginUser(req LoginRequest) (*AuthResult, error) {
 		}, nil
 	}
 
+	// Accounts with a second factor finish signing in with CompleteMFALogin
+	if TwoFactorEnabled(user) {
+		challenge, expiresAt := as.IssueMFAChallenge(user, req.RememberMe)
+		return &AuthResult{
+			Success:      false,
+			RequiresMFA:  true,
+			MFAToken:     challenge,
+			MFAExpiresAt: expiresAt,
+			Message:      "Two-factor authentication required",
+		}, nil
+	}
+
 	// Log successful attempt
 	as.LogLoginAttempt(req.Email, req.IP, req.UserAgent, true)
 
-	// Update last login
-	now := time.Now()
-	_, err = as.db.Collection("users").UpdateOne(
-		context.Background(),
-		bson.M{"_id": user.ID},
-		bson.M{"$set": bson.M{"last_login_at": now, "updated_at": now}},
-	)
-	if err != nil {
-		// Log but don't fail login
-		fmt.Printf("Failed to update last login: %v\n", err)
-	}
-
 	// Determine token expiry based on remember me
 	expiryDays := as.config.JWT.ExpiryDays
 	if req.RememberMe {
 		expiryDays = 30 // 30 days for remember me
 	}
 
-	// Generate tokens
-	accessToken, err := utils.GenerateJWT(
-		user.ID.Hex(),
-		user.Email,
-		string(user.Role),
-		as.config.JWT.Secret,
-		expiryDays,
-	)
-	if err != nil {
-		return &AuthResult{
-			Success: false,
-			Error:   "Failed to generate access token",
-		}, err
-	}
-
-	refreshToken, err := utils.GenerateRefreshToken(user.ID.Hex(), as.config.JWT.Secret)
-	if err != nil {
-		return &AuthResult{
-			Success: false,
-			Error:   "Failed to generate refresh token",
-		}, err
-	}
-
-	// Remove password from response
-	user.Password = ""
-
-	return &AuthResult{
-		Success:      true,
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(expiryDays) * 24 * time.Hour),
-		Message:      "Login successful",
-	}, nil
+	return as.completeLogin(user, expiryDays)
 }
 
 // Password Management
@@ -748,7 +763,7 @@ func (as *AuthService) GetSecuritySettings() SecuritySettings {
 		RequireNumbers:      true,
 		RequireUppercase:    true,
 		SessionTimeout:      24 * time.Hour,
-		RequireMFA:          false,
+		RequireMFA:          as.config.MFA.RequireAdmin,
 	}
 }
 
@@ -795,20 +810,565 @@ func (as *AuthService) InvalidateAllUserSessions(userID primitive.ObjectID) erro
 	return err
 }
 
-// Two-Factor Authentication (placeholder for future implementation)
-func (as *AuthService) Enable2FA(userID primitive.ObjectID) (string, error) {
-	// TODO: Implement 2FA setup
-	return "", fmt.Errorf("2FA not yet implemented")
+// Two-Factor Authentication
+// TwoFactorEnrollment is what a user sets their authenticator app up from
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCodePNG  []byte `json:"qr_code_png"` // Encodes OTPAuthURI; base64 in JSON
+}
+
+// TwoFactorStatus describes a user's second factor without its secrets
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Pending                bool       `json:"pending"` // Enrolment started but not yet confirmed with a code
+	ConfirmedAt            *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	Required               bool       `json:"required"` // Admin routes need it for this account
+}
+
+// TwoFactorEnabled reports whether the user has a confirmed second factor
+func TwoFactorEnabled(user *models.User) bool {
+	return user.TwoFactor != nil && user.TwoFactor.Enabled
+}
+
+// TwoFactorRequired reports whether the user must have a second factor
+func (as *AuthService) TwoFactorRequired(user *models.User) bool {
+	return as.config.MFA.RequireAdmin && user.Role == models.RoleAdmin
+}
+
+func (as *AuthService) TwoFactorStatus(user *models.User) TwoFactorStatus {
+	status := TwoFactorStatus{Required: as.TwoFactorRequired(user)}
+	if user.TwoFactor != nil {
+		status.Enabled = user.TwoFactor.Enabled
+		status.Pending = !user.TwoFactor.Enabled
+		status.ConfirmedAt = user.TwoFactor.ConfirmedAt
+		status.RecoveryCodesRemaining = len(user.TwoFactor.RecoveryCodes)
+	}
+	return status
+}
+
+// Enable2FA starts TOTP enrolment with a new secret, replacing any earlier
+// unconfirmed one. The factor is not used until Verify2FA confirms it.
+func (as *AuthService) Enable2FA(userID primitive.ObjectID, password string) (*TwoFactorEnrollment, error) {
+	user, err := as.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	if !as.VerifyPassword(password, user.Password) {
+		return nil, ErrTwoFactorPassword
+	}
+
+	if TwoFactorEnabled(user) {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      as.config.MFA.Issuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %v", err)
+	}
+
+	sealed, err := as.sealTOTPSecret(key.Secret(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	qr, err := key.Image(256, 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %v", err)
+	}
+
+	var qrPNG bytes.Buffer
+	if err := png.Encode(&qrPNG, qr); err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %v", err)
+	}
+
+	now := time.Now()
+	_, err = as.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID, "two_factor.enabled": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{
+			"two_factor": models.TwoFactorAuth{
+				Secret:        sealed,
+				RecoveryCodes: []string{},
+				CreatedAt:     now,
+			},
+			"updated_at": now,
+		}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save TOTP secret: %v", err)
+	}
+
+	return &TwoFactorEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+		QRCodePNG:  qrPNG.Bytes(),
+	}, nil
+}
+
+// Verify2FA confirms a pending enrolment with a code from the authenticator
+// app, activates the factor and returns its recovery codes. The codes are
+// only stored hashed, so this is the one time they can be shown.
+func (as *AuthService) Verify2FA(userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := as.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	if user.TwoFactor == nil {
+		return nil, ErrTwoFactorNotPending
+	}
+	if user.TwoFactor.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	step, err := as.checkTOTP(user, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := as.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result, err := as.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID, "two_factor.enabled": false, "two_factor.secret": user.TwoFactor.Secret},
+		bson.M{"$set": bson.M{
+			"two_factor.enabled":        true,
+			"two_factor.recovery_codes": hashes,
+			"two_factor.last_used_step": step,
+			"two_factor.confirmed_at":   now,
+			"updated_at":                now,
+		}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		// Enrolment was restarted or confirmed by another request meanwhile
+		return nil, ErrTwoFactorNotPending
+	}
+
+	return codes, nil
+}
+
+// Disable2FA removes the user's second factor after checking their password
+// and a current code or recovery code
+func (as *AuthService) Disable2FA(userID primitive.ObjectID, password, code string) error {
+	user, err := as.GetUserByID(userID)
+	if err != nil || user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	if !as.VerifyPassword(password, user.Password) {
+		return ErrTwoFactorPassword
+	}
+
+	if !TwoFactorEnabled(user) {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if as.TwoFactorRequired(user) {
+		return ErrTwoFactorRequired
+	}
+
+	if err := as.verifySecondFactor(user, code); err != nil {
+		return err
+	}
+
+	_, err = as.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$unset": bson.M{"two_factor": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %v", err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not, once a
+// current code or recovery code is given
+func (as *AuthService) RegenerateRecoveryCodes(userID primitive.ObjectID, code string) ([]string, error) {
+	user, err := as.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	if !TwoFactorEnabled(user) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	if err := as.verifySecondFactor(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := as.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = as.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{
+			"two_factor.recovery_codes": hashes,
+			"updated_at":                time.Now(),
+		}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %v", err)
+	}
+
+	return codes, nil
+}
+
+// IssueMFAChallenge returns the token that proves a user got their password
+// right, which CompleteMFALogin exchanges for session tokens along with a
+// second-factor code. It stops working when the password changes.
+func (as *AuthService) IssueMFAChallenge(user *models.User, rememberMe bool) (string, time.Time) {
+	expiresAt := time.Now().Add(mfaChallengeLifetime)
+	payload := fmt.Sprintf("%s:%t:%d", user.ID.Hex(), rememberMe, expiresAt.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + as.signMFAChallenge(payload, user), expiresAt
+}
+
+// CompleteMFALogin finishes a login that IssueMFAChallenge started. Wrong
+// codes count as failed login attempts, so they lock the account like wrong
+// passwords do.
+func (as *AuthService) CompleteMFALogin(challenge, code, ip, userAgent string) (*AuthResult, error) {
+	user, rememberMe, err := as.parseMFAChallenge(challenge)
+	if err != nil {
+		return &AuthResult{
+			Success: false,
+			Error:   ErrMFAChallenge.Error(),
+		}, nil
+	}
+
+	if locked, err := as.IsAccountLocked(user.Email, ip); err != nil {
+		return &AuthResult{
+			Success: false,
+			Error:   "Service temporarily unavailable",
+		}, err
+	} else if locked {
+		return &AuthResult{
+			Success: false,
+			Error:   "Account temporarily locked due to multiple failed login attempts",
+		}, nil
+	}
+
+	if !user.IsActive {
+		return &AuthResult{
+			Success: false,
+			Error:   "Account is deactivated",
+		}, nil
+	}
+
+	if err := as.verifySecondFactor(user, code); err != nil {
+		as.LogLoginAttempt(user.Email, ip, userAgent, false)
+		if errors.Is(err, ErrTwoFactorCode) || errors.Is(err, ErrTwoFactorNotEnabled) {
+			return &AuthResult{
+				Success: false,
+				Error:   ErrTwoFactorCode.Error(),
+			}, nil
+		}
+		return &AuthResult{
+			Success: false,
+			Error:   "Service temporarily unavailable",
+		}, err
+	}
+
+	as.LogLoginAttempt(user.Email, ip, userAgent, true)
+
+	expiryDays := as.config.JWT.ExpiryDays
+	if rememberMe {
+		expiryDays = 30
+	}
+
+	return as.completeLogin(user, expiryDays)
+}
+
+// completeLogin records the login and issues the user's session tokens
+func (as *AuthService) completeLogin(user *models.User, expiryDays int) (*AuthResult, error) {
+	now := time.Now()
+	_, err := as.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"last_login_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		fmt.Printf("Failed to update last login: %v\n", err)
+	}
+
+	accessToken, err := utils.GenerateJWT(
+		user.ID.Hex(),
+		user.Email,
+		string(user.Role),
+		as.config.JWT.Secret,
+		expiryDays,
+	)
+	if err != nil {
+		return &AuthResult{
+			Success: false,
+			Error:   "Failed to generate access token",
+		}, err
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken(user.ID.Hex(), as.config.JWT.Secret)
+	if err != nil {
+		return &AuthResult{
+			Success: false,
+			Error:   "Failed to generate refresh token",
+		}, err
+	}
+
+	user.Password = ""
+
+	return &AuthResult{
+		Success:      true,
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(expiryDays) * 24 * time.Hour),
+		Message:      "Login successful",
+	}, nil
+}
+
+func (as *AuthService) parseMFAChallenge(challenge string) (*models.User, bool, error) {
+	encoded, signature, ok := strings.Cut(challenge, ".")
+	if !ok {
+		return nil, false, ErrMFAChallenge
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false, ErrMFAChallenge
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, false, ErrMFAChallenge
+	}
+
+	userID, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		return nil, false, ErrMFAChallenge
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, false, ErrMFAChallenge
+	}
+
+	user, err := as.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, false, ErrMFAChallenge
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(as.signMFAChallenge(string(raw), user))) {
+		return nil, false, ErrMFAChallenge
+	}
+
+	return user, parts[1] == "true", nil
+}
+
+func (as *AuthService) signMFAChallenge(payload string, user *models.User) string {
+	mac := hmac.New(sha256.New, []byte(as.config.JWT.Secret))
+	mac.Write([]byte("mfa-challenge:" + payload + ":" + user.Password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySecondFactor accepts a current TOTP code, or consumes a recovery code
+func (as *AuthService) verifySecondFactor(user *models.User, code string) error {
+	if !TwoFactorEnabled(user) {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != int(otp.DigitsSix) {
+		return as.useRecoveryCode(user, code)
+	}
+
+	step, err := as.checkTOTP(user, code)
+	if err != nil {
+		return err
+	}
+
+	// Only the first use of a code counts, even across concurrent requests
+	result, err := as.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": user.ID, "two_factor.last_used_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"two_factor.last_used_step": step}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record TOTP use: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrTwoFactorCode
+	}
+
+	return nil
+}
+
+// checkTOTP returns the RFC 6238 time step code is valid for, allowing one
+// step of clock drift either way. Steps at or before the last code accepted
+// are refused so a code cannot be replayed.
+func (as *AuthService) checkTOTP(user *models.User, code string) (int64, error) {
+	secret, err := as.openTOTPSecret(user.TwoFactor.Secret, user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+
+	current := time.Now().Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= user.TwoFactor.LastUsedStep {
+			continue
+		}
+
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), opts)
+		if err != nil {
+			return 0, fmt.Errorf("failed to generate TOTP code: %v", err)
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, nil
+		}
+	}
+
+	return 0, ErrTwoFactorCode
 }
 
-func (as *AuthService) Verify2FA(userID primitive.ObjectID, code string) (bool, error) {
-	// TODO: Implement 2FA verification
-	return false, fmt.Errorf("2FA not yet implemented")
+// useRecoveryCode removes a matching recovery code so it cannot be used again
+func (as *AuthService) useRecoveryCode(user *models.User, code string) error {
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return ErrTwoFactorCode
+	}
+
+	for _, hash := range user.TwoFactor.RecoveryCodes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) != nil {
+			continue
+		}
+
+		result, err := as.db.Collection("users").UpdateOne(
+			context.Background(),
+			bson.M{"_id": user.ID, "two_factor.recovery_codes": hash},
+			bson.M{
+				"$pull": bson.M{"two_factor.recovery_codes": hash},
+				"$set":  bson.M{"updated_at": time.Now()},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to use recovery code: %v", err)
+		}
+		if result.ModifiedCount == 0 {
+			return ErrTwoFactorCode
+		}
+		return nil
+	}
+
+	return ErrTwoFactorCode
+}
+
+// generateRecoveryCodes returns new recovery codes formatted as xxxxx-xxxxx
+// and their bcrypt hashes
+func (as *AuthService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		random := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %v", err)
+		}
+
+		code := make([]byte, recoveryCodeLength)
+		for i, b := range random {
+			code[i] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+
+		hash, err := bcrypt.GenerateFromPassword(code, bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash recovery code: %v", err)
+		}
+
+		half := recoveryCodeLength / 2
+		codes = append(codes, string(code[:half])+"-"+string(code[half:]))
+		hashes = append(hashes, string(hash))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
+
+// sealTOTPSecret encrypts a TOTP secret with AES-256-GCM under the MFA key.
+// The user's ID is authenticated with it, so a sealed secret cannot be
+// copied onto another account.
+func (as *AuthService) sealTOTPSecret(secret string, userID primitive.ObjectID) ([]byte, error) {
+	gcm, err := as.mfaCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	return gcm.Seal(nonce, nonce, []byte(secret), totpSealContext(userID)), nil
+}
+
+func (as *AuthService) openTOTPSecret(sealed []byte, userID primitive.ObjectID) (string, error) {
+	gcm, err := as.mfaCipher()
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("sealed TOTP secret is truncated")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	secret, err := gcm.Open(nil, nonce, ciphertext, totpSealContext(userID))
+	if err != nil {
+		return "", fmt.Errorf("failed to open TOTP secret: %v", err)
+	}
+
+	return string(secret), nil
+}
+
+func (as *AuthService) mfaCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(as.mfaKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MFA encryption key: %v", err)
+	}
+	return cipher.NewGCM(block)
 }
 
-func (as *AuthService) Disable2FA(userID primitive.ObjectID) error {
-	// TODO: Implement 2FA disable
-	return fmt.Errorf("2FA not yet implemented")
+func totpSealContext(userID primitive.ObjectID) []byte {
+	return []byte("totp:" + userID.Hex())
 }
 
 // Health Check
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func newTestAuthService(encryptionKey string) *AuthService {
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	cfg.MFA.EncryptionKey = encryptionKey
+	return NewAuthService(cfg, nil)
+}
+
+// totpCodeAt returns the code for the time step offset steps from now
+func totpCodeAt(t *testing.T, offset int64) (string, int64) {
+	t.Helper()
+	step := time.Now().Unix()/totpPeriod + offset
+	code, err := totp.GenerateCodeCustom(testTOTPSecret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, step
+}
+
+func TestNewAuthServiceMFAKey(t *testing.T) {
+	raw := bytes.Repeat([]byte{9}, 32)
+
+	tests := []struct {
+		name    string
+		key     string
+		wantRaw bool
+	}{
+		{"base64 32-byte key", base64.StdEncoding.EncodeToString(raw), true},
+		{"wrong length is derived", base64.StdEncoding.EncodeToString(raw[:8]), false},
+		{"empty key is derived", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as := newTestAuthService(tt.key)
+			if len(as.mfaKey) != 32 {
+				t.Fatalf("MFA key is %d bytes, want 32", len(as.mfaKey))
+			}
+			if bytes.Equal(as.mfaKey, raw) != tt.wantRaw {
+				t.Errorf("MFA key used as given = %v, want %v", !tt.wantRaw, tt.wantRaw)
+			}
+		})
+	}
+
+	// The MFA key must not be the DRM master key derived from the same secrets
+	if bytes.Equal(newTestAuthService("").mfaKey, newTestDRMService("").masterKey) {
+		t.Error("MFA and DRM fallback keys are identical")
+	}
+}
+
+func TestTOTPSecretSealOpen(t *testing.T) {
+	as := newTestAuthService("")
+	other := newTestAuthService("another key")
+	userID := primitive.NewObjectID()
+
+	sealed, err := as.sealTOTPSecret(testTOTPSecret, userID)
+	if err != nil {
+		t.Fatalf("sealTOTPSecret: %v", err)
+	}
+	if bytes.Contains(sealed, []byte(testTOTPSecret)) {
+		t.Fatal("sealed secret contains the plaintext")
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		as      *AuthService
+		sealed  []byte
+		userID  primitive.ObjectID
+		wantErr bool
+	}{
+		{"round trip", as, sealed, userID, false},
+		{"copied to another user", as, sealed, primitive.NewObjectID(), true},
+		{"other MFA key", other, sealed, userID, true},
+		{"tampered", as, tampered, userID, true},
+		{"truncated", as, sealed[:3], userID, true},
+		{"empty", as, nil, userID, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := tt.as.openTOTPSecret(tt.sealed, tt.userID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("openTOTPSecret succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("openTOTPSecret: %v", err)
+			}
+			if secret != testTOTPSecret {
+				t.Errorf("openTOTPSecret = %q, want %q", secret, testTOTPSecret)
+			}
+		})
+	}
+}
+
+func TestCheckTOTP(t *testing.T) {
+	as := newTestAuthService("")
+	user := &models.User{ID: primitive.NewObjectID()}
+	sealed, err := as.sealTOTPSecret(testTOTPSecret, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		offset   int64 // steps from now the code was generated for
+		lastUsed int64 // steps from now of the last accepted code; 0 means none
+		replay   bool  // the code's own step was the last accepted
+		code     string
+		wantErr  error
+	}{
+		{name: "current step", offset: 0},
+		{name: "previous step within skew", offset: -1},
+		{name: "next step within skew", offset: 1},
+		{name: "two steps old", offset: -2, wantErr: ErrTwoFactorCode},
+		{name: "two steps ahead", offset: 2, wantErr: ErrTwoFactorCode},
+		{name: "replayed code", offset: 0, replay: true, wantErr: ErrTwoFactorCode},
+		{name: "later than last used", offset: 1, lastUsed: -1},
+		{name: "wrong code", code: "00000x", wantErr: ErrTwoFactorCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, step := totpCodeAt(t, tt.offset)
+			if tt.code != "" {
+				code = tt.code
+			}
+
+			user.TwoFactor = &models.TwoFactorAuth{Enabled: true, Secret: sealed}
+			switch {
+			case tt.replay:
+				user.TwoFactor.LastUsedStep = step
+			case tt.lastUsed != 0:
+				user.TwoFactor.LastUsedStep = time.Now().Unix()/totpPeriod + tt.lastUsed
+			}
+
+			got, err := as.checkTOTP(user, code)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("checkTOTP error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkTOTP: %v", err)
+			}
+			if got != step {
+				t.Errorf("checkTOTP step = %d, want %d", got, step)
+			}
+		})
+	}
+}
+
+func TestVerifySecondFactorRejectsWithoutLookup(t *testing.T) {
+	as := newTestAuthService("")
+	sealed, _ := as.sealTOTPSecret(testTOTPSecret, primitive.NilObjectID)
+
+	tests := []struct {
+		name      string
+		twoFactor *models.TwoFactorAuth
+		code      string
+		want      error
+	}{
+		{"not enrolled", nil, "123456", ErrTwoFactorNotEnabled},
+		{"enrolment pending", &models.TwoFactorAuth{Secret: sealed}, "123456", ErrTwoFactorNotEnabled},
+		{"recovery code too short", &models.TwoFactorAuth{Enabled: true, Secret: sealed}, "abcde-fgh", ErrTwoFactorCode},
+		{"unknown recovery code", &models.TwoFactorAuth{Enabled: true, Secret: sealed}, "abcde-fghij", ErrTwoFactorCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &models.User{TwoFactor: tt.twoFactor}
+			if err := as.verifySecondFactor(user, tt.code); !errors.Is(err, tt.want) {
+				t.Errorf("verifySecondFactor error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	as := newTestAuthService("")
+
+	codes, hashes, err := as.generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q is not formatted xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q was issued twice", code)
+		}
+		seen[code] = true
+
+		if bcrypt.CompareHashAndPassword([]byte(hashes[i]), []byte(normalizeRecoveryCode(code))) != nil {
+			t.Errorf("hash %d does not match code %q", i, code)
+		}
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"abcde-fghij", "abcdefghij"},
+		{"ABCDE-FGHIJ", "abcdefghij"},
+		{"abcdefghij", "abcdefghij"},
+		{"ab-cde-fg-hij", "abcdefghij"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+// backend/internal/services/blob.go
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	blobsCollection      = "blobs"
+	blobCollectInterval  = time.Hour
+	blobStagingDir       = "incoming"
+	blobDeleteRetryDelay = 200 * time.Millisecond
+	blobDeleteWait       = 30 * time.Second
+	// A collection that crashed midway leaves blobs marked as deleting; they
+	// are claimed again after this long
+	blobDeleteTimeout = time.Hour
+)
+
+var ErrBlobTooLarge = errors.New("file exceeds the maximum allowed size")
+
+// BlobService stores files once per distinct content. Files are kept under
+// <namespace>/sha256/<xx>/<hash><ext> and the blobs collection records who
+// references each one. Blobs nobody references are removed by GarbageCollect
+// once the grace period has passed.
+type BlobService struct {
+	config  *config.Config
+	db      *mongo.Database
+	storage *StorageService
+	cancel  context.CancelFunc
+}
+
+func NewBlobService(cfg *config.Config, db *mongo.Database, storage *StorageService) *BlobService {
+	return &BlobService{
+		config:  cfg,
+		db:      db,
+		storage: storage,
+	}
+}
+
+// Start launches periodic garbage collection of unreferenced blobs
+func (bs *BlobService) Start() {
+	if bs.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bs.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(blobCollectInterval)
+		defer ticker.Stop()
+
+		for {
+			if removed, err := bs.GarbageCollect(ctx, bs.gracePeriod()); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to collect unreferenced blobs: %v\n", err)
+			} else if removed > 0 {
+				fmt.Printf("Removed %d unreferenced blobs\n", removed)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (bs *BlobService) Close() {
+	if bs.cancel != nil {
+		bs.cancel()
+		bs.cancel = nil
+	}
+}
+
+// Store saves r as a blob and adds ref to it. When a blob with the same
+// content already exists only the reference is added. ext is appended to new
+// storage keys so the file is served with the right content type.
+func (bs *BlobService) Store(ctx context.Context, namespace, ext string, r io.Reader, ref models.BlobRef) (*models.Blob, error) {
+	dir, err := bs.storage.ResolvePath(blobStagingDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %v", err)
+	}
+
+	staged, err := os.CreateTemp(dir, "blob-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging file: %v", err)
+	}
+	defer os.Remove(staged.Name())
+
+	sha, md := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(staged, sha, md), &contextReader{ctx: ctx, r: io.LimitReader(r, bs.storage.maxSize+1)})
+	closeErr := staged.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage file: %v", err)
+	}
+	if closeErr != nil {
+		return nil, fmt.Errorf("failed to stage file: %v", closeErr)
+	}
+	if size > bs.storage.maxSize {
+		return nil, ErrBlobTooLarge
+	}
+
+	return bs.storeStaged(ctx, namespace, ext, staged.Name(), size, sha, md, ref)
+}
+
+// StoreFile is Store for a file already on local disk under the storage base
+// path, such as a finished resumable upload. The file is moved into storage or
+// removed if the blob already exists.
+func (bs *BlobService) StoreFile(ctx context.Context, namespace, ext, localPath string, ref models.BlobRef) (*models.Blob, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	sha, md := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(sha, md), &contextReader{ctx: ctx, r: file})
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash file: %v", err)
+	}
+
+	blob, err := bs.storeStaged(ctx, namespace, ext, localPath, size, sha, md, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	os.Remove(localPath)
+	return blob, nil
+}
+
+func (bs *BlobService) storeStaged(ctx context.Context, namespace, ext, localPath string, size int64, sha, md hash.Hash, ref models.BlobRef) (*models.Blob, error) {
+	digest := hex.EncodeToString(sha.Sum(nil))
+	ext = strings.ToLower(ext)
+	ref.CreatedAt = time.Now()
+
+	deadline := time.Now().Add(blobDeleteWait)
+	for {
+		added, err := bs.addRef(ctx, digest, ref)
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			return bs.Get(ctx, digest)
+		}
+
+		// A blob that is being garbage collected must be gone before its
+		// key can be written again
+		existing, err := bs.db.Collection(blobsCollection).CountDocuments(ctx, bson.M{"_id": digest})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up blob: %v", err)
+		}
+		if existing == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("blob %s is still being deleted", digest)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(blobDeleteRetryDelay):
+		}
+	}
+
+	key := path.Join(namespace, "sha256", digest[:2], digest+ext)
+	if err := bs.storage.Import(ctx, localPath, key); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	_, err := bs.db.Collection(blobsCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": digest, "deleting": bson.M{"$ne": true}, "refs": bson.M{"$not": bson.M{"$elemMatch": refFilter(ref)}}},
+		bson.M{
+			"$setOnInsert": bson.M{
+				"storage_path": key,
+				"size":         size,
+				"content_type": contentTypeForExtension(ext),
+				"md5_hash":     hex.EncodeToString(md.Sum(nil)),
+				"deleting":     false,
+				"created_at":   now,
+			},
+			"$push":  bson.M{"refs": ref},
+			"$inc":   bson.M{"ref_count": 1},
+			"$unset": bson.M{"unreferenced_at": ""},
+			"$set":   bson.M{"updated_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	// Another upload of the same content won the race and already holds this reference
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("failed to record blob: %v", err)
+	}
+
+	return bs.Get(ctx, digest)
+}
+
+// addRef adds ref to an existing blob. It reports false when the blob does
+// not exist or is being deleted.
+func (bs *BlobService) addRef(ctx context.Context, digest string, ref models.BlobRef) (bool, error) {
+	collection := bs.db.Collection(blobsCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": digest, "deleting": bson.M{"$ne": true}, "refs": bson.M{"$not": bson.M{"$elemMatch": refFilter(ref)}}},
+		bson.M{
+			"$push":  bson.M{"refs": ref},
+			"$inc":   bson.M{"ref_count": 1},
+			"$unset": bson.M{"unreferenced_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to add blob reference: %v", err)
+	}
+	if result.MatchedCount > 0 {
+		return true, nil
+	}
+
+	// The owner may already reference the blob
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": digest, "deleting": bson.M{"$ne": true}})
+	if err != nil {
+		return false, fmt.Errorf("failed to look up blob: %v", err)
+	}
+
+	return count > 0, nil
+}
+
+func (bs *BlobService) Get(ctx context.Context, digest string) (*models.Blob, error) {
+	var blob models.Blob
+	if err := bs.db.Collection(blobsCollection).FindOne(ctx, bson.M{"_id": digest}).Decode(&blob); err != nil {
+		return nil, fmt.Errorf("failed to find blob: %v", err)
+	}
+
+	return &blob, nil
+}
+
+// URL is the public URL of a blob's file
+func (bs *BlobService) URL(blob *models.Blob) string {
+	return bs.storage.generatePublicURL(blob.StoragePath)
+}
+
+// Release drops ref from every blob it is held on, except the blobs listed in
+// keep. Blobs left without references become eligible for garbage collection.
+func (bs *BlobService) Release(ctx context.Context, ref models.BlobRef, keep ...string) error {
+	var ids bson.M
+	if len(keep) > 0 {
+		ids = bson.M{"$nin": keep}
+	}
+	return bs.release(ctx, refFilter(ref), ids)
+}
+
+// ReleaseBlobs drops ref from the listed blobs only, undoing Store calls
+// whose result was never saved
+func (bs *BlobService) ReleaseBlobs(ctx context.Context, ref models.BlobRef, hashes ...string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	return bs.release(ctx, refFilter(ref), bson.M{"$in": hashes})
+}
+
+// ReleaseOwner drops every reference held by an owner, for example when a
+// video is deleted
+func (bs *BlobService) ReleaseOwner(ctx context.Context, ownerType models.BlobOwnerType, ownerID string) error {
+	return bs.release(ctx, bson.M{"owner_type": ownerType, "owner_id": ownerID}, nil)
+}
+
+// Referenced returns the hashes of the blobs ref is held on
+func (bs *BlobService) Referenced(ctx context.Context, ref models.BlobRef) ([]string, error) {
+	cursor, err := bs.db.Collection(blobsCollection).Find(
+		ctx,
+		bson.M{"refs": bson.M{"$elemMatch": refFilter(ref)}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find blob references: %v", err)
+	}
+
+	var blobs []models.Blob
+	if err := cursor.All(ctx, &blobs); err != nil {
+		return nil, fmt.Errorf("failed to decode blobs: %v", err)
+	}
+
+	hashes := make([]string, 0, len(blobs))
+	for _, blob := range blobs {
+		hashes = append(hashes, blob.Hash)
+	}
+	return hashes, nil
+}
+
+// release drops references matching match from blobs whose _id matches ids,
+// or from every blob when ids is nil
+func (bs *BlobService) release(ctx context.Context, match bson.M, ids bson.M) error {
+	collection := bs.db.Collection(blobsCollection)
+
+	filter := bson.M{"refs": bson.M{"$elemMatch": match}}
+	if ids != nil {
+		filter["_id"] = ids
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to find blob references: %v", err)
+	}
+
+	var blobs []models.Blob
+	if err := cursor.All(ctx, &blobs); err != nil {
+		return fmt.Errorf("failed to decode blobs: %v", err)
+	}
+
+	for _, blob := range blobs {
+		// Pull and recount in one update so concurrent releases cannot
+		// leave ref_count out of step with refs
+		now := time.Now()
+		remaining := bson.M{"$filter": bson.M{
+			"input": "$refs",
+			"cond":  bson.M{"$not": bson.A{refCondition(match)}},
+		}}
+
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": blob.Hash}, mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"refs": remaining}}},
+			{{Key: "$set", Value: bson.M{
+				"ref_count":  bson.M{"$size": "$refs"},
+				"updated_at": now,
+				"unreferenced_at": bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{bson.M{"$size": "$refs"}, 0}},
+					bson.M{"$ifNull": bson.A{"$unreferenced_at", now}},
+					"$$REMOVE",
+				}},
+			}}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to release blob %s: %v", blob.Hash, err)
+		}
+	}
+
+	return nil
+}
+
+// GarbageCollect deletes blobs that have had no references for longer than
+// grace and returns how many were removed. Each blob is claimed by marking it
+// as deleting, so a concurrent Store waits instead of reusing a file that is
+// about to disappear.
+func (bs *BlobService) GarbageCollect(ctx context.Context, grace time.Duration) (int, error) {
+	collection := bs.db.Collection(blobsCollection)
+	removed := 0
+
+	for ctx.Err() == nil {
+		now := time.Now()
+
+		var blob models.Blob
+		err := collection.FindOneAndUpdate(
+			ctx,
+			bson.M{
+				"ref_count":       bson.M{"$lte": 0},
+				"unreferenced_at": bson.M{"$lt": now.Add(-grace)},
+				"$or": bson.A{
+					bson.M{"deleting": bson.M{"$ne": true}},
+					bson.M{"updated_at": bson.M{"$lt": now.Add(-blobDeleteTimeout)}},
+				},
+			},
+			bson.M{"$set": bson.M{"deleting": true, "updated_at": now}},
+		).Decode(&blob)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return removed, fmt.Errorf("failed to claim blob: %v", err)
+		}
+
+		if err := bs.storage.DeleteAll(ctx, blob.StoragePath); err != nil {
+			// Leave the blob for the next run rather than forgetting the file
+			collection.UpdateOne(ctx, bson.M{"_id": blob.Hash}, bson.M{"$set": bson.M{"deleting": false}})
+			return removed, fmt.Errorf("failed to delete blob %s: %v", blob.Hash, err)
+		}
+
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": blob.Hash, "deleting": true}); err != nil {
+			return removed, fmt.Errorf("failed to remove blob %s: %v", blob.Hash, err)
+		}
+		removed++
+	}
+
+	return removed, ctx.Err()
+}
+
+func (bs *BlobService) gracePeriod() time.Duration {
+	if bs.config.Storage.BlobGraceHours > 0 {
+		return time.Duration(bs.config.Storage.BlobGraceHours) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// IsBlobPath reports whether a storage path names a blob rather than a file
+// owned by a single record
+func IsBlobPath(relativePath string) bool {
+	parts := strings.Split(relativePath, "/")
+	return len(parts) == 4 && parts[1] == "sha256"
+}
+
+// refFilter matches a reference by owner and field, ignoring when it was made
+func refFilter(ref models.BlobRef) bson.M {
+	return bson.M{"owner_type": ref.OwnerType, "owner_id": ref.OwnerID, "field": ref.Field}
+}
+
+// refCondition turns a refFilter style match into an aggregation expression
+// over the $$this element of a $filter
+func refCondition(match bson.M) bson.M {
+	var conditions bson.A
+	for field, value := range match {
+		conditions = append(conditions, bson.M{"$eq": bson.A{"$$this." + field, value}})
+	}
+	return bson.M{"$and": conditions}
+}
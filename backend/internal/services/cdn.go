@@ -0,0 +1,503 @@
+// backend/internal/services/cdn.go
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	mrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"onflix/internal/config"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+const (
+	CDNSigningCloudFront = "cloudfront"
+	CDNSigningToken      = "token"
+	CDNSigningNone       = "none"
+)
+
+// CloudFront encodes signatures in base64 with URL-safe substitutes of its own
+var cloudFrontEncoding = strings.NewReplacer("+", "-", "=", "_", "/", "~")
+
+// CDNLocation is where a client IP resolved to in the GeoIP database
+type CDNLocation struct {
+	Country   string `json:"country,omitempty"`   // ISO 3166-1 code, e.g. "DE"
+	Continent string `json:"continent,omitempty"` // e.g. "EU"
+}
+
+// CDNOriginStatus is an origin's configuration and last health probe
+type CDNOriginStatus struct {
+	Name          string     `json:"name"`
+	BaseURL       string     `json:"base_url"`
+	Weight        int        `json:"weight"`
+	Regions       []string   `json:"regions"`
+	Signing       string     `json:"signing"`
+	Healthy       bool       `json:"healthy"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+type cdnOrigin struct {
+	config.CDNOrigin
+	privateKey *rsa.PrivateKey
+	tokenKey   []byte
+
+	mu        sync.Mutex
+	healthy   bool
+	checkedAt *time.Time
+	lastError string
+}
+
+// CDNService sends streaming links to the CDN origin best placed to serve
+// the client, skipping origins whose health probe fails, and signs each link
+// the way that origin's CDN verifies it
+type CDNService struct {
+	config  *config.Config
+	geoip   *maxminddb.Reader
+	origins []*cdnOrigin
+	client  *http.Client
+	cancel  context.CancelFunc
+}
+
+func NewCDNService(cfg *config.Config) *CDNService {
+	cs := &CDNService{
+		config: cfg,
+		client: &http.Client{
+			Timeout: time.Duration(max(cfg.CDN.HealthTimeout, 1)) * time.Second,
+			// A redirect from a health endpoint is an answer, not something to follow
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	if cfg.CDN.GeoIPDatabase != "" {
+		reader, err := maxminddb.Open(cfg.CDN.GeoIPDatabase)
+		if err != nil {
+			fmt.Printf("Failed to open GeoIP database %s, CDN origins will not be chosen by location: %v\n", cfg.CDN.GeoIPDatabase, err)
+		} else {
+			cs.geoip = reader
+		}
+	}
+
+	for _, originConfig := range cfg.CDN.Origins {
+		origin, err := newCDNOrigin(originConfig)
+		if err != nil {
+			fmt.Printf("Skipping CDN origin %s: %v\n", originConfig.Name, err)
+			continue
+		}
+		cs.origins = append(cs.origins, origin)
+	}
+
+	return cs
+}
+
+func newCDNOrigin(originConfig config.CDNOrigin) (*cdnOrigin, error) {
+	if _, err := url.ParseRequestURI(originConfig.BaseURL); err != nil || originConfig.BaseURL == "" {
+		return nil, fmt.Errorf("a valid URL is required")
+	}
+	if originConfig.Weight <= 0 {
+		return nil, fmt.Errorf("weight must be positive")
+	}
+
+	origin := &cdnOrigin{CDNOrigin: originConfig, healthy: true}
+
+	switch originConfig.Signing {
+	case CDNSigningCloudFront:
+		if originConfig.KeyPairID == "" {
+			return nil, fmt.Errorf("cloudfront signing needs a key pair ID")
+		}
+		key, err := loadRSAPrivateKey(originConfig.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		origin.privateKey = key
+	case CDNSigningToken:
+		key, err := hex.DecodeString(originConfig.TokenKey)
+		if err != nil || len(key) == 0 {
+			return nil, fmt.Errorf("token signing needs a hex token key")
+		}
+		origin.tokenKey = key
+	case CDNSigningNone:
+	default:
+		return nil, fmt.Errorf("signing must be cloudfront, token or none")
+	}
+
+	return origin, nil
+}
+
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// Start probes the origins' health endpoints until Close is called
+func (cs *CDNService) Start() {
+	if cs.cancel != nil {
+		return
+	}
+
+	probed := slices.ContainsFunc(cs.origins, func(origin *cdnOrigin) bool { return origin.HealthPath != "" })
+	if !probed {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cs.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(max(cs.config.CDN.HealthInterval, 1)) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			var wg sync.WaitGroup
+			for _, origin := range cs.origins {
+				if origin.HealthPath == "" {
+					continue
+				}
+				wg.Add(1)
+				go func(origin *cdnOrigin) {
+					defer wg.Done()
+					cs.probe(ctx, origin)
+				}(origin)
+			}
+			wg.Wait()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (cs *CDNService) Close() {
+	if cs.cancel != nil {
+		cs.cancel()
+	}
+	if cs.geoip != nil {
+		cs.geoip.Close()
+	}
+}
+
+// probe marks an origin down when its health endpoint fails to answer
+// with a 2xx or 3xx status, and up again once it does
+func (cs *CDNService) probe(ctx context.Context, origin *cdnOrigin) {
+	var probeErr error
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin.BaseURL+origin.HealthPath, nil)
+	if err != nil {
+		probeErr = err
+	} else if resp, err := cs.client.Do(req); err != nil {
+		probeErr = err
+	} else {
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			probeErr = fmt.Errorf("health probe returned %d", resp.StatusCode)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	origin.mu.Lock()
+	wasHealthy := origin.healthy
+	origin.healthy = probeErr == nil
+	origin.checkedAt = &now
+	origin.lastError = ""
+	if probeErr != nil {
+		origin.lastError = probeErr.Error()
+	}
+	origin.mu.Unlock()
+
+	if wasHealthy && probeErr != nil {
+		fmt.Printf("CDN origin %s is down, failing over: %v\n", origin.Name, probeErr)
+	} else if !wasHealthy && probeErr == nil {
+		fmt.Printf("CDN origin %s is back up\n", origin.Name)
+	}
+}
+
+// Locate resolves a client IP to its country and continent, leaving both
+// empty when there is no GeoIP database or the address is not in it
+func (cs *CDNService) Locate(clientIP string) CDNLocation {
+	ip := net.ParseIP(clientIP)
+	if cs.geoip == nil || ip == nil {
+		return CDNLocation{}
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		RegisteredCountry struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"registered_country"`
+		Continent struct {
+			Code string `maxminddb:"code"`
+		} `maxminddb:"continent"`
+	}
+
+	if err := cs.geoip.Lookup(ip, &record); err != nil {
+		return CDNLocation{}
+	}
+
+	location := CDNLocation{
+		Country:   record.Country.ISOCode,
+		Continent: record.Continent.Code,
+	}
+	if location.Country == "" {
+		location.Country = record.RegisteredCountry.ISOCode
+	}
+	return location
+}
+
+// route picks a healthy origin for the client: those listing its country,
+// else its continent, else those serving everyone, else any other region.
+// Within the best group the choice is random in proportion to weight.
+func (cs *CDNService) route(clientIP string) *cdnOrigin {
+	if len(cs.origins) == 0 {
+		return nil
+	}
+
+	location := cs.Locate(clientIP)
+
+	var best []*cdnOrigin
+	bestRank := -1
+	for _, origin := range cs.origins {
+		origin.mu.Lock()
+		healthy := origin.healthy
+		origin.mu.Unlock()
+		if !healthy {
+			continue
+		}
+
+		rank := originRank(origin, location)
+		switch {
+		case rank > bestRank:
+			best = []*cdnOrigin{origin}
+			bestRank = rank
+		case rank == bestRank:
+			best = append(best, origin)
+		}
+	}
+
+	total := 0
+	for _, origin := range best {
+		total += origin.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	pick := mrand.IntN(total)
+	for _, origin := range best {
+		if pick < origin.Weight {
+			return origin
+		}
+		pick -= origin.Weight
+	}
+	return nil
+}
+
+func originRank(origin *cdnOrigin, location CDNLocation) int {
+	switch {
+	case location.Country != "" && slices.Contains(origin.Regions, location.Country):
+		return 3
+	case location.Continent != "" && slices.Contains(origin.Regions, location.Continent):
+		return 2
+	case len(origin.Regions) == 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SignedURL returns a link to a storage file on the CDN origin routed for
+// the client, valid until expiration, or "" when no origin is available.
+// The watermark session is bound into the signature, so a leaked link still
+// names its user.
+func (cs *CDNService) SignedURL(relativePath, clientIP string, expiration time.Time, watermarkID string) (string, error) {
+	origin := cs.route(clientIP)
+	if origin == nil {
+		return "", nil
+	}
+
+	rawURL := origin.BaseURL + (&url.URL{Path: "/" + strings.TrimPrefix(relativePath, "/")}).EscapedPath()
+
+	switch origin.Signing {
+	case CDNSigningCloudFront:
+		// The signed resource includes the query, so wm cannot be dropped
+		return signCloudFrontURL(rawURL+"?wm="+watermarkID, origin.KeyPairID, origin.privateKey, expiration)
+	case CDNSigningToken:
+		return signTokenURL(rawURL, origin.TokenName, origin.tokenKey, expiration, "wm:"+watermarkID)
+	default:
+		return rawURL + "?wm=" + watermarkID, nil
+	}
+}
+
+// SignedDirectory returns the URL of a storage directory on the CDN origin
+// routed for the client, ending in "/", and a query string that signs every
+// file under it until expiration. Both are "" when no origin is available.
+func (cs *CDNService) SignedDirectory(relativeDir, clientIP string, expiration time.Time) (string, string, error) {
+	origin := cs.route(clientIP)
+	if origin == nil {
+		return "", "", nil
+	}
+
+	dirPath := (&url.URL{Path: "/" + strings.Trim(relativeDir, "/") + "/"}).EscapedPath()
+	baseURL := origin.BaseURL + dirPath
+
+	switch origin.Signing {
+	case CDNSigningCloudFront:
+		query, err := cloudFrontCustomPolicy(baseURL+"*", origin.KeyPairID, origin.privateKey, expiration)
+		return baseURL, query, err
+	case CDNSigningToken:
+		return baseURL, origin.TokenName + "=" + edgeToken(dirPath+"*", origin.tokenKey, expiration, ""), nil
+	default:
+		return baseURL, "", nil
+	}
+}
+
+// LinkLifetime is how long a signed CDN link stays valid after it is issued
+func (cs *CDNService) LinkLifetime() time.Duration {
+	if cs.config.CDN.URLExpiry > 0 {
+		return time.Duration(cs.config.CDN.URLExpiry) * time.Second
+	}
+	return 15 * time.Minute
+}
+
+// signCloudFrontURL signs a canned policy the way CloudFront signed URLs expect
+func signCloudFrontURL(rawURL, keyPairID string, key *rsa.PrivateKey, expiration time.Time) (string, error) {
+	policy := fmt.Sprintf(`{"Statement":[{"Resource":"%s","Condition":{"DateLessThan":{"AWS:EpochTime":%d}}}]}`, rawURL, expiration.Unix())
+
+	signature, err := cloudFrontSignature(policy, key)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("Expires", fmt.Sprintf("%d", expiration.Unix()))
+	query.Set("Signature", signature)
+	query.Set("Key-Pair-Id", keyPairID)
+
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + query.Encode(), nil
+}
+
+// cloudFrontCustomPolicy returns the query parameters of a CloudFront custom
+// policy, whose resource may end in a * wildcard
+func cloudFrontCustomPolicy(resource, keyPairID string, key *rsa.PrivateKey, expiration time.Time) (string, error) {
+	policy := fmt.Sprintf(`{"Statement":[{"Resource":"%s","Condition":{"DateLessThan":{"AWS:EpochTime":%d}}}]}`, resource, expiration.Unix())
+
+	signature, err := cloudFrontSignature(policy, key)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("Policy", cloudFrontEncoding.Replace(base64.StdEncoding.EncodeToString([]byte(policy))))
+	query.Set("Signature", signature)
+	query.Set("Key-Pair-Id", keyPairID)
+
+	return query.Encode(), nil
+}
+
+func cloudFrontSignature(policy string, key *rsa.PrivateKey) (string, error) {
+	hash := sha1.Sum([]byte(policy))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign CloudFront policy: %v", err)
+	}
+
+	return cloudFrontEncoding.Replace(base64.StdEncoding.EncodeToString(signature)), nil
+}
+
+// signTokenURL appends an edge token for the URL's own path
+func signTokenURL(rawURL, tokenName string, key []byte, expiration time.Time, data string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid CDN URL: %v", err)
+	}
+
+	// Edge servers read the token verbatim, so it is not query-escaped
+	return rawURL + "?" + tokenName + "=" + edgeToken(parsed.EscapedPath(), key, expiration, data), nil
+}
+
+// edgeToken builds a token of the form exp=…~acl=…~data=…~hmac=…, with an
+// HMAC-SHA256 over the fields before it, as token-auth CDNs verify. An ACL
+// ending in * covers every path with that prefix.
+func edgeToken(acl string, key []byte, expiration time.Time, data string) string {
+	fields := fmt.Sprintf("exp=%d~acl=%s", expiration.Unix(), acl)
+	if data != "" {
+		fields += "~data=" + data
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(fields))
+	return fields + "~hmac=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Origins reports every configured origin with its health
+func (cs *CDNService) Origins() []CDNOriginStatus {
+	statuses := make([]CDNOriginStatus, 0, len(cs.origins))
+	for _, origin := range cs.origins {
+		origin.mu.Lock()
+		statuses = append(statuses, CDNOriginStatus{
+			Name:          origin.Name,
+			BaseURL:       origin.BaseURL,
+			Weight:        origin.Weight,
+			Regions:       origin.Regions,
+			Signing:       origin.Signing,
+			Healthy:       origin.healthy,
+			LastCheckedAt: origin.checkedAt,
+			LastError:     origin.lastError,
+		})
+		origin.mu.Unlock()
+	}
+	return statuses
+}
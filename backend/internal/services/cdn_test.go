@@ -0,0 +1,341 @@
+package services
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"onflix/internal/config"
+)
+
+var cloudFrontDecoding = strings.NewReplacer("-", "+", "_", "=", "~", "/")
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func verifyCloudFrontSignature(t *testing.T, policy, signature string, key *rsa.PrivateKey) {
+	t.Helper()
+	sig, err := base64.StdEncoding.DecodeString(cloudFrontDecoding.Replace(signature))
+	if err != nil {
+		t.Fatalf("signature is not CloudFront base64: %v", err)
+	}
+	hash := sha1.Sum([]byte(policy))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, hash[:], sig); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+}
+
+// verifyEdgeToken checks a token the way a token-auth CDN does and returns its fields
+func verifyEdgeToken(t *testing.T, token string, key []byte) map[string]string {
+	t.Helper()
+	signed, mac, ok := strings.Cut(token, "~hmac=")
+	if !ok {
+		t.Fatalf("token %q has no hmac", token)
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(signed))
+	if mac != hex.EncodeToString(h.Sum(nil)) {
+		t.Fatalf("token %q has the wrong hmac", token)
+	}
+
+	fields := map[string]string{}
+	for _, field := range strings.Split(signed, "~") {
+		name, value, _ := strings.Cut(field, "=")
+		fields[name] = value
+	}
+	return fields
+}
+
+func newTestCDNService(origins ...*cdnOrigin) *CDNService {
+	return &CDNService{config: &config.Config{}, origins: origins}
+}
+
+func TestCDNSignedURL(t *testing.T) {
+	rsaKey := testRSAKey(t)
+	tokenKey := []byte("0123456789abcdef")
+	expiration := time.Unix(1900000000, 0)
+
+	tests := []struct {
+		name   string
+		origin *cdnOrigin
+		check  func(t *testing.T, signed string)
+	}{
+		{
+			name: "cloudfront canned policy covers the watermark",
+			origin: &cdnOrigin{CDNOrigin: config.CDNOrigin{
+				BaseURL: "https://d1.cloudfront.net", Weight: 1, Signing: CDNSigningCloudFront, KeyPairID: "KPID",
+			}, privateKey: rsaKey, healthy: true},
+			check: func(t *testing.T, signed string) {
+				resource, query, _ := strings.Cut(signed, "&Expires=")
+				if resource != "https://d1.cloudfront.net/videos/a%20b.mp4?wm=wm1" {
+					t.Fatalf("resource = %s", resource)
+				}
+				values, err := url.ParseQuery("Expires=" + query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if values.Get("Expires") != "1900000000" || values.Get("Key-Pair-Id") != "KPID" {
+					t.Errorf("query = %v", values)
+				}
+				policy := fmt.Sprintf(`{"Statement":[{"Resource":"%s","Condition":{"DateLessThan":{"AWS:EpochTime":1900000000}}}]}`, resource)
+				verifyCloudFrontSignature(t, policy, values.Get("Signature"), rsaKey)
+			},
+		},
+		{
+			name: "token binds the path and watermark",
+			origin: &cdnOrigin{CDNOrigin: config.CDNOrigin{
+				BaseURL: "https://media.example.com", Weight: 1, Signing: CDNSigningToken, TokenName: "__token__",
+			}, tokenKey: tokenKey, healthy: true},
+			check: func(t *testing.T, signed string) {
+				base, token, ok := strings.Cut(signed, "?__token__=")
+				if !ok || base != "https://media.example.com/videos/a%20b.mp4" {
+					t.Fatalf("signed = %s", signed)
+				}
+				fields := verifyEdgeToken(t, token, tokenKey)
+				if fields["exp"] != "1900000000" || fields["acl"] != "/videos/a%20b.mp4" || fields["data"] != "wm:wm1" {
+					t.Errorf("token fields = %v", fields)
+				}
+			},
+		},
+		{
+			name: "unsigned origin still carries the watermark",
+			origin: &cdnOrigin{CDNOrigin: config.CDNOrigin{
+				BaseURL: "https://plain.example.com", Weight: 1, Signing: CDNSigningNone,
+			}, healthy: true},
+			check: func(t *testing.T, signed string) {
+				if signed != "https://plain.example.com/videos/a%20b.mp4?wm=wm1" {
+					t.Errorf("signed = %s", signed)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := newTestCDNService(tt.origin).SignedURL("videos/a b.mp4", "203.0.113.9", expiration, "wm1")
+			if err != nil {
+				t.Fatalf("SignedURL: %v", err)
+			}
+			tt.check(t, signed)
+		})
+	}
+}
+
+func TestCDNSignedDirectory(t *testing.T) {
+	rsaKey := testRSAKey(t)
+	tokenKey := []byte("0123456789abcdef")
+	expiration := time.Unix(1900000000, 0)
+
+	tests := []struct {
+		name   string
+		origin *cdnOrigin
+		check  func(t *testing.T, baseURL, query string)
+	}{
+		{
+			name: "cloudfront custom policy with a wildcard",
+			origin: &cdnOrigin{CDNOrigin: config.CDNOrigin{
+				BaseURL: "https://d1.cloudfront.net", Weight: 1, Signing: CDNSigningCloudFront, KeyPairID: "KPID",
+			}, privateKey: rsaKey, healthy: true},
+			check: func(t *testing.T, baseURL, query string) {
+				values, err := url.ParseQuery(query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				policy, err := base64.StdEncoding.DecodeString(cloudFrontDecoding.Replace(values.Get("Policy")))
+				if err != nil {
+					t.Fatalf("policy is not CloudFront base64: %v", err)
+				}
+				if !strings.Contains(string(policy), `"Resource":"https://d1.cloudfront.net/hls/title/720p/*"`) ||
+					!strings.Contains(string(policy), `"AWS:EpochTime":1900000000`) {
+					t.Errorf("policy = %s", policy)
+				}
+				if values.Get("Key-Pair-Id") != "KPID" || values.Has("Expires") {
+					t.Errorf("query = %v", values)
+				}
+				verifyCloudFrontSignature(t, string(policy), values.Get("Signature"), rsaKey)
+			},
+		},
+		{
+			name: "token acl covers the directory",
+			origin: &cdnOrigin{CDNOrigin: config.CDNOrigin{
+				BaseURL: "https://media.example.com", Weight: 1, Signing: CDNSigningToken, TokenName: "hdnts",
+			}, tokenKey: tokenKey, healthy: true},
+			check: func(t *testing.T, baseURL, query string) {
+				token, ok := strings.CutPrefix(query, "hdnts=")
+				if !ok {
+					t.Fatalf("query = %s", query)
+				}
+				fields := verifyEdgeToken(t, token, tokenKey)
+				if fields["acl"] != "/hls/title/720p/*" || fields["exp"] != "1900000000" {
+					t.Errorf("token fields = %v", fields)
+				}
+			},
+		},
+		{
+			name: "unsigned origin",
+			origin: &cdnOrigin{CDNOrigin: config.CDNOrigin{
+				BaseURL: "https://media.example.com", Weight: 1, Signing: CDNSigningNone,
+			}, healthy: true},
+			check: func(t *testing.T, baseURL, query string) {
+				if query != "" {
+					t.Errorf("query = %s", query)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL, query, err := newTestCDNService(tt.origin).SignedDirectory("/hls/title/720p", "203.0.113.9", expiration)
+			if err != nil {
+				t.Fatalf("SignedDirectory: %v", err)
+			}
+			if want := tt.origin.BaseURL + "/hls/title/720p/"; baseURL != want {
+				t.Errorf("baseURL = %s, want %s", baseURL, want)
+			}
+			tt.check(t, baseURL, query)
+		})
+	}
+}
+
+func TestCDNNoOrigin(t *testing.T) {
+	down := &cdnOrigin{CDNOrigin: config.CDNOrigin{BaseURL: "https://down.example.com", Weight: 1, Signing: CDNSigningNone}}
+
+	for _, cs := range []*CDNService{newTestCDNService(), newTestCDNService(down)} {
+		signed, err := cs.SignedURL("videos/a.mp4", "203.0.113.9", time.Now().Add(time.Minute), "wm1")
+		if err != nil || signed != "" {
+			t.Errorf("SignedURL with no healthy origin = %q, %v", signed, err)
+		}
+	}
+}
+
+func TestOriginRank(t *testing.T) {
+	tests := []struct {
+		name     string
+		regions  []string
+		location CDNLocation
+		want     int
+	}{
+		{"country", []string{"DE", "EU"}, CDNLocation{Country: "DE", Continent: "EU"}, 3},
+		{"continent", []string{"EU"}, CDNLocation{Country: "FR", Continent: "EU"}, 2},
+		{"serves everyone", nil, CDNLocation{Country: "FR", Continent: "EU"}, 1},
+		{"other region", []string{"NA"}, CDNLocation{Country: "FR", Continent: "EU"}, 0},
+		{"unknown location", []string{"EU"}, CDNLocation{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin := &cdnOrigin{CDNOrigin: config.CDNOrigin{Regions: tt.regions}}
+			if got := originRank(origin, tt.location); got != tt.want {
+				t.Errorf("originRank = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCDNRouteFailover(t *testing.T) {
+	regional := &cdnOrigin{CDNOrigin: config.CDNOrigin{Name: "eu", Weight: 1, Regions: []string{"EU"}}, healthy: true}
+	global := &cdnOrigin{CDNOrigin: config.CDNOrigin{Name: "global", Weight: 1}, healthy: true}
+	cs := newTestCDNService(regional, global)
+
+	// Without a GeoIP database nothing matches a region, so everyone-origins win
+	if origin := cs.route("203.0.113.9"); origin != global {
+		t.Fatalf("route picked %v, want global", origin)
+	}
+
+	global.healthy = false
+	if origin := cs.route("203.0.113.9"); origin != regional {
+		t.Fatalf("route picked %v after global failed, want eu", origin)
+	}
+
+	regional.healthy = false
+	if origin := cs.route("203.0.113.9"); origin != nil {
+		t.Fatalf("route picked %v with every origin down", origin.Name)
+	}
+}
+
+func TestNewCDNOrigin(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key.pem")
+	der := x509.MarshalPKCS1PrivateKey(testRSAKey(t))
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := config.CDNOrigin{Name: "eu", BaseURL: "https://cdn.example.com", Weight: 1, Signing: CDNSigningNone}
+	with := func(change func(*config.CDNOrigin)) config.CDNOrigin {
+		origin := valid
+		change(&origin)
+		return origin
+	}
+
+	tests := []struct {
+		name    string
+		origin  config.CDNOrigin
+		wantErr bool
+	}{
+		{"unsigned", valid, false},
+		{"cloudfront", with(func(o *config.CDNOrigin) {
+			o.Signing, o.KeyPairID, o.PrivateKey = CDNSigningCloudFront, "KPID", keyPath
+		}), false},
+		{"token", with(func(o *config.CDNOrigin) { o.Signing, o.TokenKey = CDNSigningToken, "00ff" }), false},
+		{"missing URL", with(func(o *config.CDNOrigin) { o.BaseURL = "" }), true},
+		{"zero weight", with(func(o *config.CDNOrigin) { o.Weight = 0 }), true},
+		{"unknown signing", with(func(o *config.CDNOrigin) { o.Signing = "akamai" }), true},
+		{"cloudfront without key pair", with(func(o *config.CDNOrigin) {
+			o.Signing, o.PrivateKey = CDNSigningCloudFront, keyPath
+		}), true},
+		{"cloudfront missing key file", with(func(o *config.CDNOrigin) {
+			o.Signing, o.KeyPairID, o.PrivateKey = CDNSigningCloudFront, "KPID", filepath.Join(dir, "missing.pem")
+		}), true},
+		{"token key not hex", with(func(o *config.CDNOrigin) { o.Signing, o.TokenKey = CDNSigningToken, "zz" }), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newCDNOrigin(tt.origin)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newCDNOrigin error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCDNLinkLifetime(t *testing.T) {
+	tests := []struct {
+		expiry int
+		want   time.Duration
+	}{
+		{0, 15 * time.Minute},
+		{-5, 15 * time.Minute},
+		{120, 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		cs := newTestCDNService()
+		cs.config.CDN.URLExpiry = tt.expiry
+		if got := cs.LinkLifetime(); got != tt.want {
+			t.Errorf("LinkLifetime with CDN_URL_EXPIRY=%d = %s, want %s", tt.expiry, got, tt.want)
+		}
+	}
+}
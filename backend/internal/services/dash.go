@@ -0,0 +1,677 @@
+// backend/internal/services/dash.go
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	dashRootDir       = "dash"
+	dashManifestData  = "manifest.json"
+	dashFFmpegMPD     = "ffmpeg.mpd"
+	dashInitSegment   = "init.m4s"
+	dashMediaSegment  = "segment_$Number%05d$.m4s"
+	dashSingleFile    = "stream.mp4"
+	dashAudioDir      = "audio"
+	dashMPDNamespace  = "urn:mpeg:dash:schema:mpd:2011"
+	dashRoleScheme    = "urn:mpeg:dash:role:2011"
+	dashChannelScheme = "urn:mpeg:dash:23003:3:audio_channel_configuration:2011"
+	dashCENCNamespace = "urn:mpeg:cenc:2013"
+	dashIFNamespace   = "https://dashif.org/CPS"
+
+	dashMP4ProtectionScheme = "urn:mpeg:dash:mp4protection:2011"
+	dashClearKeyScheme      = "urn:uuid:e2719d58-a985-b3c9-781a-b030af78d30e"
+	dashCommonPSSHScheme    = "urn:uuid:1077efec-c0b2-4d02-ace3-3c1e52e2fb4b"
+
+	DASHProfileLive     = "urn:mpeg:dash:profile:isoff-live:2011"
+	DASHProfileOnDemand = "urn:mpeg:dash:profile:isoff-on-demand:2011"
+)
+
+// DASHPackage describes the fragmented MP4 output written for one title or episode
+type DASHPackage struct {
+	OwnerID      string        `json:"owner_id"`
+	ManifestPath string        `json:"manifest_path"`
+	Manifest     *DASHManifest `json:"manifest"`
+	PackagedAt   time.Time     `json:"packaged_at"`
+}
+
+// DASHDirectory returns the storage directory holding the packaged output for an owner
+func DASHDirectory(ownerID string) string {
+	return path.Join(dashRootDir, ownerID)
+}
+
+// PackageDASH remuxes every full-length rendition into fragmented MP4 and
+// records the resulting representations so GenerateDASHManifest can render
+// an MPD without re-reading the media. ownerID follows the PackageHLS convention.
+// Media is CENC encrypted with the owner's content key unless DASH encryption
+// is turned off.
+func (vs *VideoService) PackageDASH(ctx context.Context, contentID primitive.ObjectID, ownerID string, videos []models.ContentVideo) (*DASHPackage, error) {
+	if ownerID == "" {
+		return nil, fmt.Errorf("owner ID is required")
+	}
+
+	var sources []models.ContentVideo
+	for _, video := range videos {
+		if video.Type != models.VideoTypeFull {
+			continue
+		}
+		if _, ok := qualityDimensions[video.Quality]; ok {
+			sources = append(sources, video)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no full-length videos with a supported quality to package")
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return qualityBandwidth[sources[i].Quality] < qualityBandwidth[sources[j].Quality]
+	})
+
+	singleFile := vs.config.Video.DASHSegmentMode == "base"
+
+	manifest := &DASHManifest{
+		Type:          "static",
+		MinBufferTime: "PT2S",
+		Profiles:      DASHProfileLive,
+	}
+	if singleFile {
+		manifest.Profiles = DASHProfileOnDemand
+	}
+
+	var encryption []string
+	if vs.drm.DASHEncryptionEnabled() {
+		key, rawKey, err := vs.drm.ContentKey(ctx, ownerID, contentID)
+		if err != nil {
+			return nil, err
+		}
+		manifest.DefaultKID = key.KeyID
+
+		// Passed through the DASH muxer to the fragmented MP4 writer
+		encryption = []string{"-format_options", fmt.Sprintf(
+			"encryption_scheme=cenc-aes-ctr:encryption_key=%s:encryption_kid=%s",
+			hex.EncodeToString(rawKey), key.KeyID,
+		)}
+	}
+
+	videoSet := DASHAdaptationSet{
+		ID:                 "video",
+		ContentType:        "video",
+		MimeType:           "video/mp4",
+		SegmentAlignment:   true,
+		BitstreamSwitching: true,
+	}
+
+	var duration float64
+	for _, video := range sources {
+		representation, reprDuration, err := vs.packageDASHRepresentation(ctx, ownerID, video.FileURL, nil, string(video.Quality), singleFile, encryption)
+		if err != nil {
+			return nil, err
+		}
+
+		representation.ID = fmt.Sprintf("video_%s", video.Quality)
+		videoSet.Representations = append(videoSet.Representations, *representation)
+		duration = math.Max(duration, reprDuration)
+	}
+
+	// One stereo AAC track taken from the best source keeps every player
+	// happy; a muxed audio track only describes it
+	mainTrack := models.AudioTrack{Role: models.AudioRoleMain, Channels: 2}
+	var alternates []models.AudioTrack
+	for _, track := range titleAudioTracks(sources) {
+		if track.Muxed {
+			mainTrack.Language, mainTrack.Label = track.Language, track.Label
+		} else {
+			alternates = append(alternates, track)
+		}
+	}
+
+	best := sources[len(sources)-1]
+	audio, _, err := vs.packageDASHRepresentation(ctx, ownerID, best.FileURL, &mainTrack, dashAudioDir, singleFile, encryption)
+	if err != nil {
+		return nil, err
+	}
+	audio.ID = "audio_main"
+
+	audioSet := DASHAdaptationSet{
+		ID:               "audio",
+		ContentType:      "audio",
+		MimeType:         "audio/mp4",
+		Lang:             mainTrack.Language,
+		Role:             "main",
+		Label:            mainTrack.Label,
+		SegmentAlignment: true,
+		Representations:  []DASHRepresentation{*audio},
+	}
+
+	manifest.MediaDuration = formatISODuration(duration)
+	manifest.AdaptationSets = []DASHAdaptationSet{videoSet, audioSet}
+
+	// Each alternate track is its own set, so players offer it by language and role
+	for _, track := range alternates {
+		dir := audioTrackDir(track)
+		audio, _, err := vs.packageDASHRepresentation(ctx, ownerID, track.FileURL, &track, dir, singleFile, encryption)
+		if err != nil {
+			return nil, err
+		}
+		audio.ID = strings.ReplaceAll(dir, "-", "_")
+
+		manifest.AdaptationSets = append(manifest.AdaptationSets, DASHAdaptationSet{
+			ID:               dir,
+			ContentType:      "audio",
+			MimeType:         "audio/mp4",
+			Lang:             track.Language,
+			Role:             string(track.Role),
+			Label:            audioTrackName(track),
+			SegmentAlignment: true,
+			Representations:  []DASHRepresentation{*audio},
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %v", err)
+	}
+
+	manifestPath := path.Join(DASHDirectory(ownerID), dashManifestData)
+	if err := vs.writeStorageFile(ctx, manifestPath, data); err != nil {
+		return nil, err
+	}
+
+	return &DASHPackage{
+		OwnerID:      ownerID,
+		ManifestPath: manifestPath,
+		Manifest:     manifest,
+		PackagedAt:   time.Now(),
+	}, nil
+}
+
+// packageDASHRepresentation runs the ffmpeg DASH muxer for a single stream and
+// reads the representation back out of the MPD ffmpeg writes alongside it.
+// The file's video is packaged unless an audio track is given.
+func (vs *VideoService) packageDASHRepresentation(ctx context.Context, ownerID, fileURL string, audio *models.AudioTrack, dir string, singleFile bool, encryption []string) (*DASHRepresentation, float64, error) {
+	source, release, err := vs.storage.Fetch(ctx, vs.storage.PathFromURL(fileURL))
+	if err != nil {
+		return nil, 0, fmt.Errorf("source for %s not found: %v", dir, err)
+	}
+	defer release()
+
+	outputRel := path.Join(DASHDirectory(ownerID), dir)
+	outputDir, err := vs.storage.ResolvePath(outputRel)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := vs.storage.DeleteAll(ctx, outputRel); err != nil {
+		return nil, 0, fmt.Errorf("failed to clear %s: %v", outputDir, err)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, 0, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-i", source}
+	if audio != nil {
+		args = append(args, audioTrackArgs(*audio)...)
+	} else {
+		args = append(args, "-map", "0:v:0", "-c:v", "copy", "-an")
+	}
+
+	args = append(args,
+		"-f", "dash",
+		"-seg_duration", strconv.Itoa(segmentDuration(vs.config)),
+		"-use_template", "1",
+		"-use_timeline", "1",
+	)
+	if singleFile {
+		args = append(args, "-single_file", "1", "-global_sidx", "1", "-init_seg_name", dashSingleFile)
+	} else {
+		args = append(args, "-init_seg_name", dashInitSegment, "-media_seg_name", dashMediaSegment)
+	}
+	args = append(args, encryption...)
+	args = append(args, filepath.Join(outputDir, dashFFmpegMPD))
+
+	cmd := exec.CommandContext(ctx, vs.config.Video.FFmpegPath, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, 0, fmt.Errorf("ffmpeg failed for %s: %v: %s", dir, err, strings.TrimSpace(string(output)))
+	}
+
+	mpdPath := filepath.Join(outputDir, dashFFmpegMPD)
+	data, err := os.ReadFile(mpdPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ffmpeg did not produce a manifest for %s: %v", dir, err)
+	}
+	// Only our rendering of the manifest is ever served
+	os.Remove(mpdPath)
+
+	var doc mpdDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse ffmpeg manifest for %s: %v", dir, err)
+	}
+
+	if len(doc.Periods) == 0 || len(doc.Periods[0].AdaptationSets) == 0 || len(doc.Periods[0].AdaptationSets[0].Representations) == 0 {
+		return nil, 0, fmt.Errorf("ffmpeg manifest for %s has no representation", dir)
+	}
+
+	set := doc.Periods[0].AdaptationSets[0]
+	src := set.Representations[0]
+
+	representation := &DASHRepresentation{
+		Bandwidth:         src.Bandwidth,
+		Width:             src.Width,
+		Height:            src.Height,
+		FrameRate:         firstNonEmpty(src.FrameRate, set.FrameRate),
+		AudioSamplingRate: src.AudioSamplingRate,
+		MimeType:          firstNonEmpty(src.MimeType, set.MimeType),
+		Codecs:            src.Codecs,
+		BaseURL:           dir + "/",
+	}
+	if len(src.AudioChannels) > 0 {
+		representation.AudioChannels = src.AudioChannels[0].Value
+	}
+
+	if singleFile {
+		initRange, indexRange, err := mp4IndexRanges(filepath.Join(outputDir, dashSingleFile))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to index %s: %v", dir, err)
+		}
+		representation.BaseURL = dir + "/" + dashSingleFile
+		representation.SegmentBase = &DASHSegmentBase{
+			IndexRange:          indexRange,
+			InitializationRange: initRange,
+		}
+	} else {
+		template := src.SegmentTemplate
+		if template == nil {
+			template = set.SegmentTemplate
+		}
+		if template == nil {
+			return nil, 0, fmt.Errorf("ffmpeg manifest for %s has no segment template", dir)
+		}
+
+		representation.SegmentTemplate = &DASHSegmentTemplate{
+			Timescale:      template.Timescale,
+			Duration:       template.Duration,
+			StartNumber:    template.StartNumber,
+			Initialization: dashInitSegment,
+			Media:          dashMediaSegment,
+		}
+		if template.Timeline != nil {
+			for _, s := range template.Timeline.Segments {
+				representation.SegmentTemplate.Timeline = append(representation.SegmentTemplate.Timeline, DASHTimelineEntry{
+					Start:    s.T,
+					Duration: s.D,
+					Repeat:   s.R,
+				})
+			}
+		}
+	}
+
+	if err := vs.storage.Publish(ctx, outputRel); err != nil {
+		return nil, 0, err
+	}
+
+	return representation, parseISODuration(doc.MediaPresentationDuration), nil
+}
+
+// RenderMPD serialises the manifest as an MPEG-DASH (ISO/IEC 23009-1) MPD document
+func (m *DASHManifest) RenderMPD() ([]byte, error) {
+	doc := mpdDocument{
+		Xmlns:                     dashMPDNamespace,
+		Type:                      m.Type,
+		MediaPresentationDuration: m.MediaDuration,
+		MinBufferTime:             m.MinBufferTime,
+		Profiles:                  m.Profiles,
+	}
+
+	var protection []mpdContentProtection
+	if m.DefaultKID != "" {
+		doc.XmlnsCENC = dashCENCNamespace
+		doc.XmlnsDashIF = dashIFNamespace
+		protection = m.contentProtection()
+	}
+
+	period := mpdPeriod{ID: "0", Start: "PT0S"}
+
+	for i, set := range m.AdaptationSets {
+		adaptationSet := mpdAdaptationSet{
+			ID:          strconv.Itoa(i),
+			ContentType: set.ContentType,
+			MimeType:    set.MimeType,
+			Lang:        set.Lang,
+			Label:       set.Label,
+		}
+		if set.SegmentAlignment {
+			adaptationSet.SegmentAlignment = "true"
+		}
+		if set.BitstreamSwitching {
+			adaptationSet.BitstreamSwitching = "true"
+		}
+		if set.Role != "" {
+			adaptationSet.Roles = []mpdDescriptor{{SchemeIDURI: dashRoleScheme, Value: set.Role}}
+		}
+		if set.ContentType == "video" || set.ContentType == "audio" {
+			adaptationSet.ContentProtection = protection
+		}
+
+		for _, repr := range set.Representations {
+			representation := mpdRepresentation{
+				ID:                repr.ID,
+				Bandwidth:         repr.Bandwidth,
+				Width:             repr.Width,
+				Height:            repr.Height,
+				FrameRate:         repr.FrameRate,
+				AudioSamplingRate: repr.AudioSamplingRate,
+				Codecs:            repr.Codecs,
+				BaseURL:           repr.BaseURL,
+			}
+			if repr.MimeType != set.MimeType {
+				representation.MimeType = repr.MimeType
+			}
+			if repr.AudioChannels != "" {
+				representation.AudioChannels = []mpdDescriptor{{SchemeIDURI: dashChannelScheme, Value: repr.AudioChannels}}
+			}
+
+			if t := repr.SegmentTemplate; t != nil {
+				template := &mpdSegmentTemplate{
+					Timescale:      t.Timescale,
+					Duration:       t.Duration,
+					StartNumber:    t.StartNumber,
+					Initialization: t.Initialization,
+					Media:          t.Media,
+				}
+				if m.SegmentQuery != "" {
+					template.Initialization = appendURIQuery(template.Initialization, m.SegmentQuery)
+					template.Media = appendURIQuery(template.Media, m.SegmentQuery)
+				}
+				if len(t.Timeline) > 0 {
+					template.Timeline = &mpdSegmentTimeline{}
+					for _, entry := range t.Timeline {
+						template.Timeline.Segments = append(template.Timeline.Segments, mpdTimelineSegment{
+							T: entry.Start,
+							D: entry.Duration,
+							R: entry.Repeat,
+						})
+					}
+				}
+				representation.SegmentTemplate = template
+			}
+
+			if b := repr.SegmentBase; b != nil {
+				if m.SegmentQuery != "" {
+					representation.BaseURL = appendURIQuery(representation.BaseURL, m.SegmentQuery)
+				}
+				representation.SegmentBase = &mpdSegmentBase{
+					Timescale:      b.Timescale,
+					IndexRange:     b.IndexRange,
+					Initialization: &mpdURLRange{Range: b.InitializationRange},
+				}
+			}
+
+			adaptationSet.Representations = append(adaptationSet.Representations, representation)
+		}
+
+		period.AdaptationSets = append(period.AdaptationSets, adaptationSet)
+	}
+
+	doc.Periods = []mpdPeriod{period}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render MPD: %v", err)
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
+
+// contentProtection describes the CENC encryption and the ClearKey license
+// server in the forms dash.js and Shaka Player look for
+func (m *DASHManifest) contentProtection() []mpdContentProtection {
+	kid, _ := hex.DecodeString(m.DefaultKID)
+	pssh := base64.StdEncoding.EncodeToString(CommonPSSH(kid))
+
+	return []mpdContentProtection{
+		{
+			SchemeIDURI: dashMP4ProtectionScheme,
+			Value:       "cenc",
+			DefaultKID:  FormatKeyIDUUID(m.DefaultKID),
+		},
+		{
+			SchemeIDURI: dashClearKeyScheme,
+			Value:       "ClearKey1.0",
+			LicenseURL:  m.LicenseURL,
+		},
+		{
+			SchemeIDURI: dashCommonPSSHScheme,
+			PSSH:        pssh,
+			LicenseURL:  m.LicenseURL,
+		},
+	}
+}
+
+// MPD XML document model, shared by rendering and by reading ffmpeg's output
+type mpdDocument struct {
+	XMLName                   xml.Name    `xml:"MPD"`
+	Xmlns                     string      `xml:"xmlns,attr,omitempty"`
+	XmlnsCENC                 string      `xml:"xmlns:cenc,attr,omitempty"`
+	XmlnsDashIF               string      `xml:"xmlns:dashif,attr,omitempty"`
+	Type                      string      `xml:"type,attr"`
+	MediaPresentationDuration string      `xml:"mediaPresentationDuration,attr,omitempty"`
+	MinBufferTime             string      `xml:"minBufferTime,attr"`
+	Profiles                  string      `xml:"profiles,attr"`
+	Periods                   []mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	ID             string             `xml:"id,attr,omitempty"`
+	Start          string             `xml:"start,attr,omitempty"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	ID                 string                 `xml:"id,attr,omitempty"`
+	ContentType        string                 `xml:"contentType,attr,omitempty"`
+	MimeType           string                 `xml:"mimeType,attr,omitempty"`
+	Lang               string                 `xml:"lang,attr,omitempty"`
+	FrameRate          string                 `xml:"frameRate,attr,omitempty"`
+	SegmentAlignment   string                 `xml:"segmentAlignment,attr,omitempty"`
+	BitstreamSwitching string                 `xml:"bitstreamSwitching,attr,omitempty"`
+	ContentProtection  []mpdContentProtection `xml:"ContentProtection"`
+	Label              string                 `xml:"Label,omitempty"`
+	Roles              []mpdDescriptor        `xml:"Role"`
+	SegmentTemplate    *mpdSegmentTemplate    `xml:"SegmentTemplate"`
+	Representations    []mpdRepresentation    `xml:"Representation"`
+}
+
+type mpdRepresentation struct {
+	ID                string              `xml:"id,attr"`
+	MimeType          string              `xml:"mimeType,attr,omitempty"`
+	Codecs            string              `xml:"codecs,attr,omitempty"`
+	Bandwidth         int                 `xml:"bandwidth,attr"`
+	Width             int                 `xml:"width,attr,omitempty"`
+	Height            int                 `xml:"height,attr,omitempty"`
+	FrameRate         string              `xml:"frameRate,attr,omitempty"`
+	AudioSamplingRate string              `xml:"audioSamplingRate,attr,omitempty"`
+	AudioChannels     []mpdDescriptor     `xml:"AudioChannelConfiguration"`
+	BaseURL           string              `xml:"BaseURL,omitempty"`
+	SegmentTemplate   *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentBase       *mpdSegmentBase     `xml:"SegmentBase"`
+}
+
+type mpdDescriptor struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr,omitempty"`
+}
+
+type mpdContentProtection struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr,omitempty"`
+	DefaultKID  string `xml:"cenc:default_KID,attr,omitempty"`
+	PSSH        string `xml:"cenc:pssh,omitempty"`
+	LicenseURL  string `xml:"dashif:laurl,omitempty"`
+}
+
+type mpdSegmentTemplate struct {
+	Timescale      int                 `xml:"timescale,attr,omitempty"`
+	Duration       int                 `xml:"duration,attr,omitempty"`
+	StartNumber    int                 `xml:"startNumber,attr"`
+	Initialization string              `xml:"initialization,attr"`
+	Media          string              `xml:"media,attr"`
+	Timeline       *mpdSegmentTimeline `xml:"SegmentTimeline"`
+}
+
+type mpdSegmentTimeline struct {
+	Segments []mpdTimelineSegment `xml:"S"`
+}
+
+type mpdTimelineSegment struct {
+	T *int64 `xml:"t,attr,omitempty"`
+	D int64  `xml:"d,attr"`
+	R int    `xml:"r,attr,omitempty"`
+}
+
+type mpdSegmentBase struct {
+	Timescale      int          `xml:"timescale,attr,omitempty"`
+	IndexRange     string       `xml:"indexRange,attr"`
+	Initialization *mpdURLRange `xml:"Initialization"`
+}
+
+type mpdURLRange struct {
+	Range string `xml:"range,attr"`
+}
+
+// mp4IndexRanges locates the initialization (ftyp+moov) and sidx byte ranges
+// of a fragmented MP4 so it can be addressed with SegmentBase.
+func mp4IndexRanges(filePath string) (string, string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	var offset, initEnd int64
+	indexRange := ""
+	header := make([]byte, 16)
+
+	for {
+		if _, err := io.ReadFull(file, header[:8]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", "", fmt.Errorf("truncated box header at %d: %v", offset, err)
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		boxType := string(header[4:8])
+		headerSize := int64(8)
+
+		switch size {
+		case 1:
+			if _, err := io.ReadFull(file, header[8:16]); err != nil {
+				return "", "", fmt.Errorf("truncated box header at %d: %v", offset, err)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		case 0:
+			info, err := file.Stat()
+			if err != nil {
+				return "", "", err
+			}
+			size = info.Size() - offset
+		}
+
+		if size < headerSize {
+			return "", "", fmt.Errorf("invalid %s box size at %d", boxType, offset)
+		}
+
+		switch boxType {
+		case "ftyp", "moov":
+			initEnd = offset + size
+		case "sidx":
+			if indexRange == "" {
+				indexRange = fmt.Sprintf("%d-%d", offset, offset+size-1)
+			}
+		}
+
+		// Everything we need precedes the first fragment
+		if boxType == "moof" && indexRange != "" {
+			break
+		}
+
+		offset += size
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return "", "", err
+		}
+	}
+
+	if initEnd == 0 {
+		return "", "", fmt.Errorf("no moov box found")
+	}
+	if indexRange == "" {
+		return "", "", fmt.Errorf("no sidx box found")
+	}
+
+	return fmt.Sprintf("0-%d", initEnd-1), indexRange, nil
+}
+
+// formatISODuration renders seconds as an xs:duration such as PT1H2M3.5S
+func formatISODuration(seconds float64) string {
+	total := time.Duration(seconds * float64(time.Second))
+	hours := int(total / time.Hour)
+	total -= time.Duration(hours) * time.Hour
+	minutes := int(total / time.Minute)
+	total -= time.Duration(minutes) * time.Minute
+
+	secs := strconv.FormatFloat(total.Seconds(), 'f', -1, 64)
+	return fmt.Sprintf("PT%dH%dM%sS", hours, minutes, secs)
+}
+
+// parseISODuration reads the PTnHnMnS durations ffmpeg writes; other forms return 0
+func parseISODuration(value string) float64 {
+	value = strings.TrimPrefix(value, "PT")
+	var seconds float64
+
+	for _, unit := range []struct {
+		suffix     string
+		multiplier float64
+	}{{"H", 3600}, {"M", 60}, {"S", 1}} {
+		number, rest, found := strings.Cut(value, unit.suffix)
+		if !found {
+			continue
+		}
+		if n, err := strconv.ParseFloat(number, 64); err == nil {
+			seconds += n * unit.multiplier
+		}
+		value = rest
+	}
+
+	return seconds
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
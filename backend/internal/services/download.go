@@ -0,0 +1,591 @@
+// backend/internal/services/download.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	downloadsCollection   = "downloads"
+	downloadSweepInterval = time.Hour
+)
+
+var (
+	ErrDownloadsNotIncluded = errors.New("subscription plan does not include downloads")
+	ErrDownloadDeviceLimit  = errors.New("too many devices hold downloads on this account")
+	ErrDownloadNotFound     = errors.New("download not found")
+	ErrDownloadExpired      = errors.New("download has expired and must be downloaded again")
+	ErrDownloadRevoked      = errors.New("download has been revoked")
+)
+
+// DownloadLimitError is returned when a new download would exceed PlanLimits.MaxDownloads
+type DownloadLimitError struct {
+	Limit  int               `json:"limit"`
+	Active []models.Download `json:"active_downloads"`
+}
+
+func (e *DownloadLimitError) Error() string {
+	return fmt.Sprintf("download limit of %d reached", e.Limit)
+}
+
+// DownloadLicense is handed to the device with each download and renewal so
+// the player can check while offline that the file may still be played
+type DownloadLicense struct {
+	DownloadID        string     `json:"download_id"`
+	UserID            string     `json:"user_id"`
+	DeviceID          string     `json:"device_id"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	PlaybackHours     int        `json:"playback_hours"` // Playback window that starts at first play
+	PlaybackExpiresAt *time.Time `json:"playback_expires_at,omitempty"`
+	Signature         string     `json:"signature"`
+}
+
+// DownloadService keeps the per-device records of offline downloads, enforces
+// the plan's download limits and expires or revokes licences that may no
+// longer be played
+type DownloadService struct {
+	config *config.Config
+	db     *mongo.Database
+	video  *VideoService
+	cancel context.CancelFunc
+}
+
+func NewDownloadService(cfg *config.Config, db *mongo.Database, video *VideoService) *DownloadService {
+	return &DownloadService{
+		config: cfg,
+		db:     db,
+		video:  video,
+	}
+}
+
+// Start periodically expires lapsed licences and revokes the downloads of
+// accounts whose subscription has ended
+func (ds *DownloadService) Start() {
+	if ds.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ds.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(downloadSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := ds.expireLapsed(ctx, bson.M{}); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to expire downloads: %v\n", err)
+			}
+			if revoked, err := ds.RevokeLapsedSubscriptions(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to revoke downloads: %v\n", err)
+			} else if revoked > 0 {
+				fmt.Printf("Revoked %d downloads of lapsed subscriptions\n", revoked)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (ds *DownloadService) Close() {
+	if ds.cancel != nil {
+		ds.cancel()
+	}
+}
+
+// CreateDownload records a device saving a title for offline viewing.
+// Downloading a title the device already holds replaces that download instead
+// of using another slot.
+func (ds *DownloadService) CreateDownload(ctx context.Context, user *models.User, contentID primitive.ObjectID, episodeID *primitive.ObjectID, video models.ContentVideo, device StreamDevice) (*models.Download, error) {
+	if user.Subscription == nil {
+		return nil, ErrDownloadsNotIncluded
+	}
+
+	plan, err := ds.plan(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.Features.DownloadSupport {
+		return nil, ErrDownloadsNotIncluded
+	}
+
+	if device.ID == "" {
+		device.ID = fingerprintDevice(device)
+	}
+
+	if err := ds.expireLapsed(ctx, bson.M{"user_id": user.ID}); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	collection := ds.db.Collection(downloadsCollection)
+
+	var download models.Download
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"user_id":    user.ID,
+			"device_id":  device.ID,
+			"content_id": contentID,
+			"episode_id": episodeID,
+			"status":     models.DownloadStatusActive,
+		},
+		bson.M{
+			"$set": bson.M{
+				"video_id":           video.ID,
+				"quality":            video.Quality,
+				"file_size":          video.FileSize,
+				"device_name":        device.Name,
+				"license_expires_at": now.Add(ds.licenseDuration()),
+				"last_renewed_at":    now,
+				"updated_at":         now,
+			},
+			// A fresh copy of the file starts a fresh playback window
+			"$unset": bson.M{"first_played_at": "", "playback_expires_at": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&download)
+
+	if err == nil {
+		return &download, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to look up download: %v", err)
+	}
+
+	active, err := ds.ActiveDownloads(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := plan.Limits.MaxDownloads
+	if limit > 0 && len(active) >= limit {
+		return nil, &DownloadLimitError{Limit: limit, Active: active}
+	}
+
+	devices := map[string]bool{}
+	for _, d := range active {
+		devices[d.DeviceID] = true
+	}
+	if max := ds.config.Video.DownloadMaxDevices; max > 0 && !devices[device.ID] && len(devices) >= max {
+		return nil, ErrDownloadDeviceLimit
+	}
+
+	download = models.Download{
+		ID:               primitive.NewObjectID(),
+		UserID:           user.ID,
+		ContentID:        contentID,
+		EpisodeID:        episodeID,
+		VideoID:          video.ID,
+		Quality:          video.Quality,
+		FileSize:         video.FileSize,
+		DeviceID:         device.ID,
+		DeviceName:       device.Name,
+		Status:           models.DownloadStatusActive,
+		LicenseExpiresAt: now.Add(ds.licenseDuration()),
+		LastRenewedAt:    now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	over, err := claimSlot(ctx, collection, download.ID, download, limit, func() ([]primitive.ObjectID, error) {
+		if active, err = ds.ActiveDownloads(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		ids := make([]primitive.ObjectID, len(active))
+		for i := range active {
+			ids[i] = active[i].ID
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if over >= 0 {
+		return nil, &DownloadLimitError{Limit: limit, Active: append(active[:over:over], active[over+1:]...)}
+	}
+
+	ds.recordDownload(ctx, user)
+
+	return &download, nil
+}
+
+// Renew extends a download's licence when its device reconnects. The device
+// reports when it first played the file, which starts the playback window.
+// Downloads are revoked here if the account may no longer hold them.
+func (ds *DownloadService) Renew(ctx context.Context, user *models.User, downloadID primitive.ObjectID, firstPlayedAt *time.Time) (*models.Download, error) {
+	if err := ds.expireLapsed(ctx, bson.M{"_id": downloadID}); err != nil {
+		return nil, err
+	}
+
+	collection := ds.db.Collection(downloadsCollection)
+
+	var download models.Download
+	err := collection.FindOne(ctx, bson.M{"_id": downloadID, "user_id": user.ID}).Decode(&download)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrDownloadNotFound
+		}
+		return nil, fmt.Errorf("failed to load download: %v", err)
+	}
+
+	switch download.Status {
+	case models.DownloadStatusRevoked:
+		return nil, ErrDownloadRevoked
+	case models.DownloadStatusExpired:
+		return nil, ErrDownloadExpired
+	}
+
+	if !SubscriptionActive(user) {
+		if _, err := ds.RevokeUser(ctx, user.ID, "subscription_lapsed"); err != nil {
+			return nil, err
+		}
+		return nil, ErrDownloadRevoked
+	}
+
+	plan, err := ds.plan(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.Features.DownloadSupport {
+		if _, err := ds.RevokeUser(ctx, user.ID, "plan_without_downloads"); err != nil {
+			return nil, err
+		}
+		return nil, ErrDownloadRevoked
+	}
+
+	count, err := ds.db.Collection("content").CountDocuments(ctx, bson.M{
+		"_id":    download.ContentID,
+		"status": models.ContentStatusPublished,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load content: %v", err)
+	}
+	if count == 0 {
+		if err := ds.revoke(ctx, bson.M{"_id": download.ID}, "content_unavailable"); err != nil {
+			return nil, err
+		}
+		return nil, ErrDownloadRevoked
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"license_expires_at": now.Add(ds.licenseDuration()),
+		"last_renewed_at":    now,
+		"updated_at":         now,
+	}
+
+	if download.FirstPlayedAt == nil && firstPlayedAt != nil {
+		played := *firstPlayedAt
+		if played.After(now) {
+			played = now
+		}
+		if played.Before(download.CreatedAt) {
+			played = download.CreatedAt
+		}
+
+		playbackExpiresAt := played.Add(ds.playbackWindow())
+		set["first_played_at"] = played
+		set["playback_expires_at"] = playbackExpiresAt
+
+		if !playbackExpiresAt.After(now) {
+			set["status"] = models.DownloadStatusExpired
+		}
+	}
+
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": download.ID, "status": models.DownloadStatusActive},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&download)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrDownloadNotFound
+		}
+		return nil, fmt.Errorf("failed to renew download: %v", err)
+	}
+
+	if download.Status == models.DownloadStatusExpired {
+		return nil, ErrDownloadExpired
+	}
+
+	return &download, nil
+}
+
+// License returns the signed licence for a download
+func (ds *DownloadService) License(download *models.Download) (*DownloadLicense, error) {
+	license := &DownloadLicense{
+		DownloadID:        download.ID.Hex(),
+		UserID:            download.UserID.Hex(),
+		DeviceID:          download.DeviceID,
+		ExpiresAt:         download.LicenseExpiresAt,
+		PlaybackHours:     int(ds.playbackWindow().Hours()),
+		PlaybackExpiresAt: download.PlaybackExpiresAt,
+	}
+
+	message := fmt.Sprintf("%s:%s:%s:%d:%d", license.DownloadID, license.UserID, license.DeviceID, license.ExpiresAt.Unix(), license.PlaybackHours)
+	if license.PlaybackExpiresAt != nil {
+		message += fmt.Sprintf(":%d", license.PlaybackExpiresAt.Unix())
+	}
+
+	signature, err := ds.video.signData([]byte(message))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign download licence: %v", err)
+	}
+	license.Signature = signature
+
+	return license, nil
+}
+
+// ListDownloads returns a user's downloads, newest first, optionally only those on one device
+func (ds *DownloadService) ListDownloads(ctx context.Context, userID primitive.ObjectID, deviceID string) ([]models.Download, error) {
+	if err := ds.expireLapsed(ctx, bson.M{"user_id": userID}); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"user_id": userID}
+	if deviceID != "" {
+		filter["device_id"] = deviceID
+	}
+
+	cursor, err := ds.db.Collection(downloadsCollection).Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	downloads := []models.Download{}
+	if err := cursor.All(ctx, &downloads); err != nil {
+		return nil, fmt.Errorf("failed to decode downloads: %v", err)
+	}
+
+	return downloads, nil
+}
+
+// ActiveDownloads returns the downloads currently counting towards the limit, oldest first
+func (ds *DownloadService) ActiveDownloads(ctx context.Context, userID primitive.ObjectID) ([]models.Download, error) {
+	now := time.Now()
+
+	cursor, err := ds.db.Collection(downloadsCollection).Find(
+		ctx,
+		bson.M{
+			"user_id":            userID,
+			"status":             models.DownloadStatusActive,
+			"license_expires_at": bson.M{"$gt": now},
+			"$or": []bson.M{
+				{"playback_expires_at": bson.M{"$exists": false}},
+				{"playback_expires_at": bson.M{"$gt": now}},
+			},
+		},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	downloads := []models.Download{}
+	if err := cursor.All(ctx, &downloads); err != nil {
+		return nil, fmt.Errorf("failed to decode downloads: %v", err)
+	}
+
+	return downloads, nil
+}
+
+// RemoveDownload deletes a download from a device and frees its slot
+func (ds *DownloadService) RemoveDownload(ctx context.Context, userID, downloadID primitive.ObjectID) error {
+	result, err := ds.db.Collection(downloadsCollection).DeleteOne(ctx, bson.M{
+		"_id":     downloadID,
+		"user_id": userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove download: %v", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrDownloadNotFound
+	}
+
+	return nil
+}
+
+// RevokeUser revokes every active download of an account
+func (ds *DownloadService) RevokeUser(ctx context.Context, userID primitive.ObjectID, reason string) (int64, error) {
+	result, err := ds.db.Collection(downloadsCollection).UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "status": models.DownloadStatusActive},
+		revokeUpdate(reason),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke downloads: %v", err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// RevokeLapsedSubscriptions revokes the downloads held by accounts that are
+// disabled or no longer have a current subscription
+func (ds *DownloadService) RevokeLapsedSubscriptions(ctx context.Context) (int64, error) {
+	userIDs, err := ds.db.Collection(downloadsCollection).Distinct(ctx, "user_id", bson.M{"status": models.DownloadStatusActive})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list download holders: %v", err)
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	cursor, err := ds.db.Collection("users").Find(
+		ctx,
+		bson.M{
+			"_id": bson.M{"$in": userIDs},
+			"$or": []bson.M{
+				{"is_active": false},
+				{"$nor": []bson.M{{
+					"subscription.status":             bson.M{"$in": []models.SubscriptionStatus{models.SubscriptionStatusActive, models.SubscriptionStatusTrialing}},
+					"subscription.current_period_end": bson.M{"$gt": time.Now()},
+				}}},
+			},
+		},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load download holders: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var revoked int64
+	for cursor.Next(ctx) {
+		var user struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&user); err != nil {
+			return revoked, fmt.Errorf("failed to decode user: %v", err)
+		}
+
+		count, err := ds.RevokeUser(ctx, user.ID, "subscription_lapsed")
+		if err != nil {
+			return revoked, err
+		}
+		revoked += count
+	}
+
+	return revoked, cursor.Err()
+}
+
+func (ds *DownloadService) revoke(ctx context.Context, filter bson.M, reason string) error {
+	filter["status"] = models.DownloadStatusActive
+	if _, err := ds.db.Collection(downloadsCollection).UpdateMany(ctx, filter, revokeUpdate(reason)); err != nil {
+		return fmt.Errorf("failed to revoke downloads: %v", err)
+	}
+	return nil
+}
+
+func revokeUpdate(reason string) bson.M {
+	now := time.Now()
+	return bson.M{"$set": bson.M{
+		"status":         models.DownloadStatusRevoked,
+		"revoked_at":     now,
+		"revoked_reason": reason,
+		"updated_at":     now,
+	}}
+}
+
+// expireLapsed marks active downloads matching filter whose licence or
+// playback window has run out as expired
+func (ds *DownloadService) expireLapsed(ctx context.Context, filter bson.M) error {
+	now := time.Now()
+
+	filter["status"] = models.DownloadStatusActive
+	filter["$or"] = []bson.M{
+		{"license_expires_at": bson.M{"$lte": now}},
+		{"playback_expires_at": bson.M{"$lte": now}},
+	}
+
+	_, err := ds.db.Collection(downloadsCollection).UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+		"status":     models.DownloadStatusExpired,
+		"updated_at": now,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to expire downloads: %v", err)
+	}
+
+	return nil
+}
+
+func (ds *DownloadService) plan(ctx context.Context, user *models.User) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	err := ds.db.Collection("subscription_plans").FindOne(
+		ctx,
+		bson.M{"_id": user.Subscription.PlanID},
+	).Decode(&plan)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscription plan: %v", err)
+	}
+
+	return &plan, nil
+}
+
+// recordDownload counts a download in SubscriptionUsage for the current billing period
+func (ds *DownloadService) recordDownload(ctx context.Context, user *models.User) {
+	now := time.Now()
+
+	_, err := ds.db.Collection("subscription_usage").UpdateOne(
+		ctx,
+		bson.M{
+			"user_id":      user.ID,
+			"period.start": user.Subscription.CurrentPeriodStart,
+		},
+		bson.M{
+			"$inc": bson.M{"downloads": 1},
+			"$set": bson.M{"updated_at": now},
+			"$setOnInsert": bson.M{
+				"period.end":      user.Subscription.CurrentPeriodEnd,
+				"streaming_hours": 0,
+				"profiles_used":   len(user.Profiles),
+				"concurrent_peak": 0,
+				"created_at":      now,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+
+	if err != nil {
+		fmt.Printf("Failed to record download for user %s: %v\n", user.ID.Hex(), err)
+	}
+}
+
+func (ds *DownloadService) licenseDuration() time.Duration {
+	if ds.config.Video.DownloadLicenseDays > 0 {
+		return time.Duration(ds.config.Video.DownloadLicenseDays) * 24 * time.Hour
+	}
+	return 30 * 24 * time.Hour
+}
+
+func (ds *DownloadService) playbackWindow() time.Duration {
+	if ds.config.Video.DownloadPlaybackHours > 0 {
+		return time.Duration(ds.config.Video.DownloadPlaybackHours) * time.Hour
+	}
+	return 48 * time.Hour
+}
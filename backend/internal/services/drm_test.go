@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"onflix/internal/config"
+)
+
+func newTestDRMService(masterKey string) *DRMService {
+	cfg := &config.Config{}
+	cfg.DRM.MasterKey = masterKey
+	cfg.JWT.Secret = "test-secret"
+	return NewDRMService(cfg, nil)
+}
+
+func TestNewDRMServiceMasterKey(t *testing.T) {
+	raw := bytes.Repeat([]byte{7}, 32)
+
+	tests := []struct {
+		name      string
+		masterKey string
+		wantRaw   bool
+	}{
+		{"base64 32-byte key", base64.StdEncoding.EncodeToString(raw), true},
+		{"short key is derived", base64.StdEncoding.EncodeToString(raw[:16]), false},
+		{"passphrase is derived", "not base64!", false},
+		{"empty key is derived", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := newTestDRMService(tt.masterKey)
+			if len(ds.masterKey) != 32 {
+				t.Fatalf("master key is %d bytes, want 32", len(ds.masterKey))
+			}
+			if bytes.Equal(ds.masterKey, raw) != tt.wantRaw {
+				t.Errorf("master key used as given = %v, want %v", !tt.wantRaw, tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestDRMSealOpen(t *testing.T) {
+	ds := newTestDRMService("")
+	other := newTestDRMService("another key")
+	contentKey := bytes.Repeat([]byte{0xAB}, 16)
+
+	sealed, err := ds.seal(contentKey, "kid-1")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	again, err := ds.seal(contentKey, "kid-1")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if bytes.Equal(sealed, again) {
+		t.Error("sealing the same key twice produced identical ciphertext")
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		ds      *DRMService
+		sealed  []byte
+		keyID   string
+		wantErr bool
+	}{
+		{"round trip", ds, sealed, "kid-1", false},
+		{"moved to another key ID", ds, sealed, "kid-2", true},
+		{"other master key", other, sealed, "kid-1", true},
+		{"tampered", ds, tampered, "kid-1", true},
+		{"truncated", ds, sealed[:4], "kid-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.ds.open(tt.sealed, tt.keyID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("open succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			if !bytes.Equal(raw, contentKey) {
+				t.Errorf("open = %x, want %x", raw, contentKey)
+			}
+		})
+	}
+}
+
+func TestEncryptHLSSegment(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 16)
+
+	tests := []struct {
+		name     string
+		size     int
+		sequence int
+		iv       string
+	}{
+		{"empty segment", 0, 0, "00000000000000000000000000000000"},
+		{"partial block", 100, 1, "00000000000000000000000000000001"},
+		{"whole blocks get a full padding block", 188 * 16, 0x0102, "00000000000000000000000000000102"},
+		{"large sequence", 33, 1 << 40, "00000000000000000000010000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := bytes.Repeat([]byte{0x47}, tt.size)
+			encrypted, err := EncryptHLSSegment(data, key, tt.sequence)
+			if err != nil {
+				t.Fatalf("EncryptHLSSegment: %v", err)
+			}
+
+			wantSize := (tt.size/aes.BlockSize + 1) * aes.BlockSize
+			if len(encrypted) != wantSize {
+				t.Fatalf("ciphertext is %d bytes, want %d", len(encrypted), wantSize)
+			}
+
+			iv, _ := hex.DecodeString(tt.iv)
+			block, _ := aes.NewCipher(key)
+			plain := make([]byte, len(encrypted))
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, encrypted)
+
+			padding := int(plain[len(plain)-1])
+			if padding < 1 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+				t.Fatalf("invalid PKCS#7 padding %x", plain[len(plain)-aes.BlockSize:])
+			}
+			if !bytes.Equal(plain[:len(plain)-padding], data) {
+				t.Error("decrypting with the sequence number IV did not return the segment")
+			}
+		})
+	}
+
+	if _, err := EncryptHLSSegment([]byte("x"), key[:5], 0); err == nil {
+		t.Error("EncryptHLSSegment accepted a 5-byte key")
+	}
+}
+
+func TestCommonPSSH(t *testing.T) {
+	kid1 := bytes.Repeat([]byte{1}, 16)
+	kid2 := bytes.Repeat([]byte{2}, 16)
+
+	box := CommonPSSH(kid1, kid2)
+
+	if len(box) != 32+2*16+4 {
+		t.Fatalf("box is %d bytes", len(box))
+	}
+	if int(box[0])<<24|int(box[1])<<16|int(box[2])<<8|int(box[3]) != len(box) {
+		t.Error("box size header does not match its length")
+	}
+	if string(box[4:8]) != "pssh" || box[8] != 1 {
+		t.Errorf("box header = %q version %d", box[4:8], box[8])
+	}
+	if !bytes.Equal(box[12:28], commonPSSHSystemID) {
+		t.Error("wrong system ID")
+	}
+	if box[31] != 2 || !bytes.Equal(box[32:48], kid1) || !bytes.Equal(box[48:64], kid2) {
+		t.Error("key IDs are not listed in order")
+	}
+}
+
+func TestFormatKeyIDUUID(t *testing.T) {
+	tests := []struct {
+		keyID string
+		want  string
+	}{
+		{"0123456789abcdef0123456789abcdef", "01234567-89ab-cdef-0123-456789abcdef"},
+		{"short", "short"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatKeyIDUUID(tt.keyID); got != tt.want {
+			t.Errorf("FormatKeyIDUUID(%q) = %q, want %q", tt.keyID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,835 @@
+// backend/internal/services/hls.go
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	hlsRootDir           = "hls"
+	hlsMasterPlaylist    = "master.m3u8"
+	hlsMediaPlaylistName = "index.m3u8"
+	hlsSegmentPattern    = "segment_%05d.ts"
+	// Marked copies of a rendition's segments, served in place of the
+	// originals wherever a session's watermark selects variant B
+	hlsWatermarkDir = "b"
+	// EXT-X-PROGRAM-DATE-TIME values, ISO 8601 with milliseconds
+	hlsDateTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+	tsPacketSize      = 188
+)
+
+// HLSPackage describes the playlists and segments written for one title or episode
+type HLSPackage struct {
+	OwnerID    string         `json:"owner_id"`
+	MasterPath string         `json:"master_path"`
+	Renditions []HLSRendition `json:"renditions"`
+	// Alternate audio tracks, packaged apart from the video renditions
+	AudioTracks []HLSAudioRendition    `json:"audio_tracks,omitempty"`
+	KeyID       string                 `json:"key_id,omitempty"` // Set when segments are encrypted
+	Watermark   bool                   `json:"watermark"`        // Set when A/B segment variants were written
+	TrickPlay   *models.TrickPlayTrack `json:"trick_play,omitempty"`
+	PackagedAt  time.Time              `json:"packaged_at"`
+}
+
+type HLSRendition struct {
+	VideoID          primitive.ObjectID  `json:"video_id"`
+	Quality          models.VideoQuality `json:"quality"`
+	PlaylistPath     string              `json:"playlist_path"`
+	Segments         int                 `json:"segments"`
+	Duration         float64             `json:"duration"`
+	Bandwidth        int                 `json:"bandwidth"`
+	AverageBandwidth int                 `json:"average_bandwidth"`
+}
+
+// HLSDirectory returns the storage directory holding the packaged output for an owner
+func HLSDirectory(ownerID string) string {
+	return path.Join(hlsRootDir, ownerID)
+}
+
+func hlsPlaylistPath(ownerID string, quality models.VideoQuality) string {
+	return path.Join(HLSDirectory(ownerID), string(quality), hlsMediaPlaylistName)
+}
+
+// PackageHLS segments every full-length rendition of a title with ffmpeg and
+// writes the media playlists plus a master playlist under StorageService.
+// ownerID is the content ID for movies or the episode ID for TV episodes.
+// Segments are encrypted with the owner's content key unless HLS encryption
+// is turned off.
+func (vs *VideoService) PackageHLS(ctx context.Context, contentID primitive.ObjectID, ownerID string, videos []models.ContentVideo) (*HLSPackage, error) {
+	if ownerID == "" {
+		return nil, fmt.Errorf("owner ID is required")
+	}
+
+	pkg := &HLSPackage{
+		OwnerID:   ownerID,
+		Watermark: vs.watermarks.Enabled(),
+	}
+
+	var key *models.ContentKey
+	var rawKey []byte
+	if vs.drm.HLSEncryptionEnabled() {
+		var err error
+		if key, rawKey, err = vs.drm.ContentKey(ctx, ownerID, contentID); err != nil {
+			return nil, err
+		}
+		pkg.KeyID = key.KeyID
+	}
+
+	for _, video := range videos {
+		if video.Type != models.VideoTypeFull {
+			continue
+		}
+		if _, ok := qualityDimensions[video.Quality]; !ok {
+			continue
+		}
+
+		rendition, err := vs.packageRendition(ctx, ownerID, video, key, rawKey)
+		if err != nil {
+			return nil, err
+		}
+		pkg.Renditions = append(pkg.Renditions, *rendition)
+	}
+
+	if len(pkg.Renditions) == 0 {
+		return nil, fmt.Errorf("no full-length videos with a supported quality to package")
+	}
+
+	// Players pick the first variant to start with, so order the ladder low to high
+	sort.Slice(pkg.Renditions, func(i, j int) bool {
+		return qualityBandwidth[pkg.Renditions[i].Quality] < qualityBandwidth[pkg.Renditions[j].Quality]
+	})
+
+	qualities := make([]models.VideoQuality, len(pkg.Renditions))
+	for i, rendition := range pkg.Renditions {
+		qualities[i] = rendition.Quality
+	}
+
+	master, err := vs.GenerateHLSMasterPlaylist("", qualities)
+	if err != nil {
+		return nil, err
+	}
+
+	// The video renditions keep their muxed audio. Other tracks become
+	// renditions of an audio group, described alongside the muxed one.
+	var audioBandwidth int
+	if tracks := titleAudioTracks(videos); len(tracks) > 0 {
+		muxed := models.AudioTrack{Label: "Original", Role: models.AudioRoleMain, Channels: 2, Muxed: true}
+		var alternates []models.AudioTrack
+		for _, track := range tracks {
+			if track.Muxed {
+				muxed = track
+			} else {
+				alternates = append(alternates, track)
+			}
+		}
+
+		if len(alternates) > 0 {
+			media := hlsAudioMedia(muxed)
+			media.Default = true
+			master.Media = append(master.Media, media)
+
+			for _, track := range alternates {
+				audio, err := vs.packageAudioTrack(ctx, ownerID, track, key, rawKey)
+				if err != nil {
+					return nil, err
+				}
+				pkg.AudioTracks = append(pkg.AudioTracks, *audio)
+				master.Media = append(master.Media, hlsAudioMedia(track))
+				audioBandwidth = max(audioBandwidth, audio.Bandwidth)
+			}
+
+			for i := range master.Variants {
+				master.Variants[i].Audio = hlsAudioGroup
+			}
+		}
+	}
+
+	// Advertise measured bitrates rather than the nominal ladder, allowing
+	// for the largest audio rendition a player may add to a variant
+	for i, rendition := range pkg.Renditions {
+		master.Variants[i].Bandwidth = rendition.Bandwidth + audioBandwidth
+		master.Variants[i].AverageBandwidth = rendition.AverageBandwidth
+	}
+
+	// Thumbnails only need a small picture, so they come from the lowest rendition
+	if vs.TrickPlayEnabled() {
+		lowest := pkg.Renditions[0]
+		for _, video := range videos {
+			if video.ID != lowest.VideoID {
+				continue
+			}
+
+			track, err := vs.packageTrickPlay(ctx, ownerID, video, lowest.Duration)
+			if err != nil {
+				return nil, err
+			}
+			pkg.TrickPlay = track
+			master.ImageStreams = append(master.ImageStreams, trickPlayImageStream(track))
+			break
+		}
+	}
+
+	pkg.MasterPath = path.Join(HLSDirectory(ownerID), hlsMasterPlaylist)
+	if err := vs.writeStorageFile(ctx, pkg.MasterPath, master.Encode()); err != nil {
+		return nil, err
+	}
+
+	pkg.PackagedAt = time.Now()
+	return pkg, nil
+}
+
+func (vs *VideoService) packageRendition(ctx context.Context, ownerID string, video models.ContentVideo, key *models.ContentKey, rawKey []byte) (*HLSRendition, error) {
+	source, release, err := vs.storage.Fetch(ctx, vs.storage.PathFromURL(video.FileURL))
+	if err != nil {
+		return nil, fmt.Errorf("source for %s video not found: %v", video.Quality, err)
+	}
+	defer release()
+
+	playlistPath := hlsPlaylistPath(ownerID, video.Quality)
+	outputDir, err := vs.storage.ResolvePath(path.Dir(playlistPath))
+	if err != nil {
+		return nil, err
+	}
+
+	// Start from a clean directory so stale segments from a previous run are not served
+	if err := vs.storage.DeleteAll(ctx, path.Dir(playlistPath)); err != nil {
+		return nil, fmt.Errorf("failed to clear %s: %v", outputDir, err)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	if vs.watermarks.Enabled() {
+		err = vs.encodeWatermarkPair(ctx, source, outputDir, video.Quality)
+	} else {
+		err = vs.segmentRendition(ctx, source, outputDir, video.Quality)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, hlsMediaPlaylistName))
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg did not produce a playlist for %s video: %v", video.Quality, err)
+	}
+
+	playlist, err := ParseHLSMediaPlaylist(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if vs.watermarks.Enabled() {
+		if err := padWatermarkPairs(outputDir, video.Quality, playlist); err != nil {
+			return nil, err
+		}
+	}
+
+	if key != nil {
+		if err := encryptHLSSegments(outputDir, playlist, rawKey); err != nil {
+			return nil, err
+		}
+		if vs.watermarks.Enabled() {
+			if err := encryptHLSSegments(filepath.Join(outputDir, hlsWatermarkDir), playlist, rawKey); err != nil {
+				return nil, err
+			}
+		}
+		playlist.Key = &HLSKey{Method: "AES-128", URI: vs.drm.HLSKeyURI(key.KeyID)}
+	}
+
+	rendition := &HLSRendition{
+		VideoID:      video.ID,
+		Quality:      video.Quality,
+		PlaylistPath: playlistPath,
+		Segments:     len(playlist.Sequences),
+	}
+
+	rendition.Duration, rendition.Bandwidth, rendition.AverageBandwidth, err = measureHLSSegments(outputDir, playlist)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vs.storage.Publish(ctx, path.Dir(playlistPath)); err != nil {
+		return nil, err
+	}
+
+	// Rewrite the playlist in our canonical form
+	if err := vs.writeStorageFile(ctx, playlistPath, playlist.Encode()); err != nil {
+		return nil, err
+	}
+
+	return rendition, nil
+}
+
+// measureHLSSegments returns the duration of a playlist and the peak and
+// average bitrate of its segments on disk
+func measureHLSSegments(dir string, playlist *HLSPlaylist) (float64, int, int, error) {
+	var duration float64
+	var peak, average int
+	var totalBytes int64
+
+	for _, segment := range playlist.Sequences {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(segment.URI)))
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("missing segment %s: %v", segment.URI, err)
+		}
+
+		totalBytes += info.Size()
+		duration += segment.Duration
+
+		if segment.Duration > 0 {
+			bitrate := int(float64(info.Size()*8) / segment.Duration)
+			if bitrate > peak {
+				peak = bitrate
+			}
+		}
+	}
+
+	if duration > 0 {
+		average = int(float64(totalBytes*8) / duration)
+	}
+
+	return duration, peak, average, nil
+}
+
+// segmentRendition cuts a rendition's source into HLS segments as it is
+func (vs *VideoService) segmentRendition(ctx context.Context, source, outputDir string, quality models.VideoQuality) error {
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", source,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-c", "copy",
+	}
+	args = append(args, vs.hlsOutputArgs(outputDir)...)
+
+	cmd := exec.CommandContext(ctx, vs.config.Video.FFmpegPath, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed for %s video: %v: %s", quality, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// encodeWatermarkPair writes the A and B copies of a rendition's segments
+// from a single encode. The picture is split before the watermark filter and
+// both branches go through identically configured encoders with keyframes
+// forced onto the segment boundaries, so the copies share parameter sets,
+// timestamps and cut points and differ only by the mark.
+func (vs *VideoService) encodeWatermarkPair(ctx context.Context, source, outputDir string, quality models.VideoQuality) error {
+	variantDir := filepath.Join(outputDir, hlsWatermarkDir)
+	if err := os.MkdirAll(variantDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	bitrate := qualityBandwidth[quality]
+	encode := []string{
+		"-c:v", "libx264", "-preset", "medium", "-profile:v", "high",
+		"-b:v", strconv.Itoa(bitrate),
+		"-maxrate", strconv.Itoa(bitrate * 107 / 100),
+		"-bufsize", strconv.Itoa(bitrate * 3 / 2),
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentDuration(vs.config)),
+		"-sc_threshold", "0",
+		"-flags", "+cgop",
+		"-c:a", "copy",
+	}
+
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", source,
+		"-filter_complex", "[0:v:0]split=2[a][b];[b]" + vs.config.Video.WatermarkFilter + "[marked]",
+		"-map", "[a]", "-map", "0:a:0?",
+	}
+	args = append(args, encode...)
+	args = append(args, vs.hlsOutputArgs(outputDir)...)
+	args = append(args, "-map", "[marked]", "-map", "0:a:0?")
+	args = append(args, encode...)
+	args = append(args, vs.hlsOutputArgs(variantDir)...)
+
+	cmd := exec.CommandContext(ctx, vs.config.Video.FFmpegPath, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed for %s video and its watermark variant: %v: %s", quality, err, strings.TrimSpace(string(output)))
+	}
+
+	// Only the A playlist is served; B segments are swapped in by name
+	os.Remove(filepath.Join(variantDir, hlsMediaPlaylistName))
+
+	return nil
+}
+
+func (vs *VideoService) hlsOutputArgs(dir string) []string {
+	return []string{
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(segmentDuration(vs.config)),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, hlsSegmentPattern),
+		filepath.Join(dir, hlsMediaPlaylistName),
+	}
+}
+
+// padWatermarkPairs checks every A segment has its B counterpart and pads the
+// smaller of each pair with MPEG-TS null packets, which demuxers discard, so
+// the copies cannot be told apart by size
+func padWatermarkPairs(outputDir string, quality models.VideoQuality, playlist *HLSPlaylist) error {
+	variantDir := filepath.Join(outputDir, hlsWatermarkDir)
+
+	entries, err := os.ReadDir(variantDir)
+	if err != nil {
+		return fmt.Errorf("failed to read watermark variant: %v", err)
+	}
+	if len(entries) != len(playlist.Sequences) {
+		return fmt.Errorf("watermark variant for %s video has %d segments, expected %d", quality, len(entries), len(playlist.Sequences))
+	}
+
+	for _, segment := range playlist.Sequences {
+		a := filepath.Join(outputDir, filepath.FromSlash(segment.URI))
+		b := filepath.Join(variantDir, filepath.FromSlash(segment.URI))
+
+		aInfo, err := os.Stat(a)
+		if err != nil {
+			return fmt.Errorf("missing segment %s: %v", segment.URI, err)
+		}
+		bInfo, err := os.Stat(b)
+		if err != nil {
+			return fmt.Errorf("watermark variant for %s video is missing segment %s", quality, segment.URI)
+		}
+
+		shorter, diff := a, bInfo.Size()-aInfo.Size()
+		if diff < 0 {
+			shorter, diff = b, -diff
+		}
+		if err := padTransportStream(shorter, diff); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// padTransportStream appends n bytes of null packets to a transport stream
+func padTransportStream(file string, n int64) error {
+	if n == 0 {
+		return nil
+	}
+	if n%tsPacketSize != 0 {
+		return fmt.Errorf("cannot pad %s by %d bytes: not a whole number of packets", file, n)
+	}
+
+	packet := make([]byte, tsPacketSize)
+	packet[0], packet[1], packet[2], packet[3] = 0x47, 0x1f, 0xff, 0x10
+	for i := 4; i < tsPacketSize; i++ {
+		packet[i] = 0xff
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", file, err)
+	}
+	defer f.Close()
+
+	for ; n > 0; n -= tsPacketSize {
+		if _, err := f.Write(packet); err != nil {
+			return fmt.Errorf("failed to pad %s: %v", file, err)
+		}
+	}
+
+	return nil
+}
+
+// CDNPlaylist points a media playlist's segments at the CDN origin routed for
+// the client, all under one signature for the rendition's directory. Players
+// fetch a VOD playlist once, so the signature lasts as long as the title runs
+// on top of the CDN link lifetime. It reports false, leaving the playlist as
+// it was, when no origin is available.
+func (vs *VideoService) CDNPlaylist(playlist *HLSPlaylist, ownerID string, quality models.VideoQuality, clientIP string) (bool, error) {
+	var duration float64
+	for _, segment := range playlist.Sequences {
+		duration += segment.Duration
+	}
+	expiration := time.Now().Add(vs.cdn.LinkLifetime() + time.Duration(duration*float64(time.Second)))
+
+	baseURL, query, err := vs.cdn.SignedDirectory(path.Dir(hlsPlaylistPath(ownerID, quality)), clientIP, expiration)
+	if err != nil || baseURL == "" {
+		return false, err
+	}
+
+	for i := range playlist.Sequences {
+		playlist.Sequences[i].URI = baseURL + playlist.Sequences[i].URI
+		if query != "" {
+			playlist.Sequences[i].URI += "?" + query
+		}
+	}
+
+	return true, nil
+}
+
+// HLSSegmentPath returns the path, relative to the owner's HLS directory, of
+// the copy of a segment served to a watermark session. Requests that do not
+// name a rendition's segment are rejected so the B copies cannot be fetched
+// directly.
+func (vs *VideoService) HLSSegmentPath(ctx context.Context, ownerID, requestPath, watermarkID string) (string, error) {
+	quality, name := path.Split(requestPath)
+	quality = strings.TrimSuffix(quality, "/")
+	if quality == "" || strings.Contains(quality, "/") {
+		return "", fmt.Errorf("not a segment path: %s", requestPath)
+	}
+
+	var sequence int
+	if _, err := fmt.Sscanf(name, hlsSegmentPattern, &sequence); err != nil || fmt.Sprintf(hlsSegmentPattern, sequence) != name {
+		return "", fmt.Errorf("not a segment path: %s", requestPath)
+	}
+
+	if watermarkID != "" && vs.watermarks.Variant(watermarkID, sequence) == 1 {
+		variant := path.Join(quality, hlsWatermarkDir, name)
+		// Titles packaged before watermarking was enabled only have the originals
+		if _, err := vs.storage.Stat(ctx, path.Join(HLSDirectory(ownerID), variant)); err == nil {
+			return variant, nil
+		}
+	}
+
+	return path.Join(quality, name), nil
+}
+
+// encryptHLSSegments encrypts the segments ffmpeg wrote to dir in place
+func encryptHLSSegments(dir string, playlist *HLSPlaylist, key []byte) error {
+	for _, segment := range playlist.Sequences {
+		segmentPath := filepath.Join(dir, filepath.FromSlash(segment.URI))
+
+		data, err := os.ReadFile(segmentPath)
+		if err != nil {
+			return fmt.Errorf("missing segment %s: %v", segment.URI, err)
+		}
+
+		encrypted, err := EncryptHLSSegment(data, key, segment.Sequence)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(segmentPath, encrypted, 0644); err != nil {
+			return fmt.Errorf("failed to write segment %s: %v", segment.URI, err)
+		}
+	}
+
+	return nil
+}
+
+func (vs *VideoService) writeStorageFile(ctx context.Context, relativePath string, data []byte) error {
+	_, err := vs.storage.Put(ctx, relativePath, bytes.NewReader(data), int64(len(data)))
+	return err
+}
+
+// Encode renders the playlist as an m3u8 document. Playlists with variants
+// are rendered as master playlists, all others as media playlists.
+func (p *HLSPlaylist) Encode() []byte {
+	var b bytes.Buffer
+
+	version := p.Version
+	if version == 0 {
+		version = 3
+	}
+
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", version)
+
+	if len(p.Variants) > 0 {
+		b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+		for _, media := range p.Media {
+			attrs := []string{
+				"TYPE=" + media.Type,
+				fmt.Sprintf("GROUP-ID=\"%s\"", media.GroupID),
+				fmt.Sprintf("NAME=\"%s\"", media.Name),
+			}
+			if media.Language != "" {
+				attrs = append(attrs, fmt.Sprintf("LANGUAGE=\"%s\"", media.Language))
+			}
+			attrs = append(attrs, "DEFAULT="+hlsBool(media.Default), "AUTOSELECT="+hlsBool(media.Autoselect))
+			if media.Channels != "" {
+				attrs = append(attrs, fmt.Sprintf("CHANNELS=\"%s\"", media.Channels))
+			}
+			if media.Characteristics != "" {
+				attrs = append(attrs, fmt.Sprintf("CHARACTERISTICS=\"%s\"", media.Characteristics))
+			}
+			if media.URI != "" {
+				attrs = append(attrs, fmt.Sprintf("URI=\"%s\"", media.URI))
+			}
+			fmt.Fprintf(&b, "#EXT-X-MEDIA:%s\n", strings.Join(attrs, ","))
+		}
+		for _, variant := range p.Variants {
+			attrs := []string{fmt.Sprintf("BANDWIDTH=%d", variant.Bandwidth)}
+			if variant.AverageBandwidth > 0 {
+				attrs = append(attrs, fmt.Sprintf("AVERAGE-BANDWIDTH=%d", variant.AverageBandwidth))
+			}
+			if variant.Resolution != "" {
+				attrs = append(attrs, "RESOLUTION="+variant.Resolution)
+			}
+			if variant.Codecs != "" {
+				attrs = append(attrs, fmt.Sprintf("CODECS=\"%s\"", variant.Codecs))
+			}
+			if variant.Audio != "" {
+				attrs = append(attrs, fmt.Sprintf("AUDIO=\"%s\"", variant.Audio))
+			}
+			fmt.Fprintf(&b, "#EXT-X-STREAM-INF:%s\n%s\n", strings.Join(attrs, ","), variant.URI)
+		}
+		for _, image := range p.ImageStreams {
+			fmt.Fprintf(&b, "#EXT-X-IMAGE-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s,CODECS=\"%s\",URI=\"%s\"\n",
+				image.Bandwidth, image.Resolution, image.Codecs, image.URI)
+		}
+		return b.Bytes()
+	}
+
+	targetDuration := p.TargetDuration
+	for _, segment := range p.Sequences {
+		if d := int(math.Ceil(segment.Duration)); d > targetDuration {
+			targetDuration = d
+		}
+	}
+
+	mediaSequence := 0
+	if len(p.Sequences) > 0 {
+		mediaSequence = p.Sequences[0].Sequence
+	}
+
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", targetDuration)
+	if p.CanBlockReload {
+		control := "CAN-BLOCK-RELOAD=YES"
+		if p.PartTarget > 0 {
+			// Players should stay at least three parts behind the live edge
+			control += fmt.Sprintf(",PART-HOLD-BACK=%.3f", 3*p.PartTarget)
+		}
+		fmt.Fprintf(&b, "#EXT-X-SERVER-CONTROL:%s\n", control)
+	}
+	if p.PartTarget > 0 {
+		fmt.Fprintf(&b, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", p.PartTarget)
+	}
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", mediaSequence)
+	if p.PlaylistType != "" {
+		fmt.Fprintf(&b, "#EXT-X-PLAYLIST-TYPE:%s\n", p.PlaylistType)
+	}
+	if p.Map != "" {
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", p.Map)
+	}
+	if p.Key != nil {
+		attrs := []string{"METHOD=" + p.Key.Method}
+		if p.Key.URI != "" {
+			attrs = append(attrs, fmt.Sprintf("URI=\"%s\"", p.Key.URI))
+		}
+		if p.Key.IV != "" {
+			attrs = append(attrs, "IV="+p.Key.IV)
+		}
+		fmt.Fprintf(&b, "#EXT-X-KEY:%s\n", strings.Join(attrs, ","))
+	}
+
+	for _, segment := range p.Sequences {
+		if segment.Discontinuity {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		if segment.ProgramDateTime != nil {
+			fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", segment.ProgramDateTime.UTC().Format(hlsDateTimeFormat))
+		}
+		writeHLSParts(&b, segment.Parts)
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", segment.Duration, segment.URI)
+	}
+
+	writeHLSParts(&b, p.PendingParts)
+	if p.PreloadHint != "" {
+		fmt.Fprintf(&b, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s\"\n", p.PreloadHint)
+	}
+
+	if p.EndList {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+
+	return b.Bytes()
+}
+
+func writeHLSParts(b *bytes.Buffer, parts []HLSPart) {
+	for _, part := range parts {
+		fmt.Fprintf(b, "#EXT-X-PART:DURATION=%.3f,URI=\"%s\"", part.Duration, part.URI)
+		if part.Independent {
+			b.WriteString(",INDEPENDENT=YES")
+		}
+		b.WriteByte('\n')
+	}
+}
+
+func hlsBool(value bool) string {
+	if value {
+		return "YES"
+	}
+	return "NO"
+}
+
+// AppendHLSURIQuery adds a query string to every URI line of a playlist and
+// to the URI attributes of its tags, such as alternate audio renditions
+func AppendHLSURIQuery(data []byte, query string) []byte {
+	var b bytes.Buffer
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			line = appendURIQuery(line, query)
+		} else if start := strings.Index(line, `URI="`); start >= 0 {
+			start += len(`URI="`)
+			if end := strings.Index(line[start:], `"`); end >= 0 {
+				end += start
+				line = line[:start] + appendURIQuery(line[start:end], query) + line[end:]
+			}
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	return b.Bytes()
+}
+
+func appendURIQuery(uri, query string) string {
+	if strings.Contains(uri, "?") {
+		return uri + "&" + query
+	}
+	return uri + "?" + query
+}
+
+// ParseHLSMediaPlaylist reads the subset of RFC 8216 that ffmpeg emits for
+// VOD media playlists, plus the tags low-latency live encoders add
+func ParseHLSMediaPlaylist(data []byte) (*HLSPlaylist, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "#EXTM3U" {
+		return nil, fmt.Errorf("invalid playlist: missing #EXTM3U header")
+	}
+
+	playlist := &HLSPlaylist{Version: 3}
+	sequence := 0
+	pendingDuration := -1.0
+	var pending HLSSequence
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		tag, value, _ := strings.Cut(line, ":")
+		switch {
+		case tag == "#EXT-X-VERSION":
+			playlist.Version, _ = strconv.Atoi(value)
+		case tag == "#EXT-X-TARGETDURATION":
+			playlist.TargetDuration, _ = strconv.Atoi(value)
+		case tag == "#EXT-X-MEDIA-SEQUENCE":
+			sequence, _ = strconv.Atoi(value)
+		case tag == "#EXT-X-PLAYLIST-TYPE":
+			playlist.PlaylistType = value
+		case tag == "#EXT-X-ENDLIST":
+			playlist.EndList = true
+		case tag == "#EXT-X-KEY":
+			attrs := parseHLSAttributes(value)
+			if attrs["METHOD"] != "" && attrs["METHOD"] != "NONE" {
+				playlist.Key = &HLSKey{Method: attrs["METHOD"], URI: attrs["URI"], IV: attrs["IV"]}
+			} else {
+				playlist.Key = nil
+			}
+		case tag == "#EXT-X-SERVER-CONTROL":
+			playlist.CanBlockReload = parseHLSAttributes(value)["CAN-BLOCK-RELOAD"] == "YES"
+		case tag == "#EXT-X-PART-INF":
+			playlist.PartTarget, _ = strconv.ParseFloat(parseHLSAttributes(value)["PART-TARGET"], 64)
+		case tag == "#EXT-X-MAP":
+			playlist.Map = parseHLSAttributes(value)["URI"]
+		case tag == "#EXT-X-DISCONTINUITY":
+			pending.Discontinuity = true
+		case tag == "#EXT-X-PROGRAM-DATE-TIME":
+			at, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid program date time %q: %v", value, err)
+			}
+			pending.ProgramDateTime = &at
+		case tag == "#EXT-X-PART":
+			attrs := parseHLSAttributes(value)
+			duration, err := strconv.ParseFloat(attrs["DURATION"], 64)
+			if err != nil || attrs["URI"] == "" {
+				return nil, fmt.Errorf("invalid partial segment %q", value)
+			}
+			pending.Parts = append(pending.Parts, HLSPart{
+				Duration:    duration,
+				URI:         attrs["URI"],
+				Independent: attrs["INDEPENDENT"] == "YES",
+			})
+		case tag == "#EXT-X-PRELOAD-HINT":
+			if attrs := parseHLSAttributes(value); attrs["TYPE"] == "PART" {
+				playlist.PreloadHint = attrs["URI"]
+			}
+		case tag == "#EXTINF":
+			durationStr, _, _ := strings.Cut(value, ",")
+			duration, err := strconv.ParseFloat(durationStr, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid segment duration %q: %v", durationStr, err)
+			}
+			pendingDuration = duration
+		case strings.HasPrefix(line, "#"):
+			// Tags we do not model are dropped
+		default:
+			if pendingDuration < 0 {
+				return nil, fmt.Errorf("segment %q has no #EXTINF", line)
+			}
+			pending.Duration = pendingDuration
+			pending.URI = line
+			pending.Sequence = sequence
+			playlist.Sequences = append(playlist.Sequences, pending)
+			sequence++
+			pendingDuration = -1
+			pending = HLSSequence{}
+		}
+	}
+	// Parts after the last full segment belong to the one being written
+	playlist.PendingParts = pending.Parts
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %v", err)
+	}
+
+	return playlist, nil
+}
+
+// parseHLSAttributes splits an attribute list such as
+// METHOD=AES-128,URI="key?a=1,b=2" into its values, unquoting strings
+func parseHLSAttributes(list string) map[string]string {
+	attrs := make(map[string]string)
+
+	for list != "" {
+		name, rest, ok := strings.Cut(list, "=")
+		if !ok {
+			break
+		}
+
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			end := strings.Index(rest[1:], "\"")
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+
+		attrs[strings.TrimSpace(name)] = value
+		list = strings.TrimPrefix(rest, ",")
+	}
+
+	return attrs
+}
@@ -0,0 +1,507 @@
+// backend/internal/services/image.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"onflix/internal/config"
+	"onflix/internal/models"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+type ImageKind string
+
+const (
+	ImageKindPoster   ImageKind = "poster"
+	ImageKindBackdrop ImageKind = "backdrop"
+	ImageKindLogo     ImageKind = "logo"
+	ImageKindAvatar   ImageKind = "avatar"
+)
+
+type CropMode string
+
+const (
+	// Scale to fit inside the box, keeping the whole image
+	CropModeFit CropMode = "fit"
+	// Scale to cover the box and crop the overflow around the centre
+	CropModeFill CropMode = "fill"
+)
+
+// Decoding is refused above this many pixels to guard against decompression bombs
+const maxImagePixels = 50_000_000
+
+const jpegQuality = 85
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+	ErrInvalidCropMode  = errors.New("crop mode must be fit or fill")
+)
+
+type ImageVariantSpec struct {
+	Name   string
+	Width  int // 0 leaves the dimension unconstrained
+	Height int
+	Mode   CropMode
+}
+
+// Variants generated for each kind of image. Widths follow the TMDB size
+// names clients already know.
+var imageVariantSpecs = map[ImageKind][]ImageVariantSpec{
+	ImageKindPoster: {
+		{Name: "poster_w185", Width: 185, Mode: CropModeFit},
+		{Name: "poster_w342", Width: 342, Mode: CropModeFit},
+		{Name: "poster_w780", Width: 780, Mode: CropModeFit},
+	},
+	ImageKindBackdrop: {
+		{Name: "backdrop_w300", Width: 300, Mode: CropModeFit},
+		{Name: "backdrop_w780", Width: 780, Mode: CropModeFit},
+		{Name: "backdrop_w1280", Width: 1280, Mode: CropModeFit},
+	},
+	ImageKindLogo: {
+		{Name: "logo_w185", Width: 185, Mode: CropModeFit},
+		{Name: "logo_w500", Width: 500, Mode: CropModeFit},
+	},
+	ImageKindAvatar: {
+		{Name: "avatar_64", Width: 64, Height: 64, Mode: CropModeFill},
+		{Name: "avatar_128", Width: 128, Height: 128, Mode: CropModeFill},
+		{Name: "avatar_256", Width: 256, Height: 256, Mode: CropModeFill},
+	},
+}
+
+// ffmpeg encoder arguments for the optional modern formats
+var imageEncoderArgs = map[string][]string{
+	"webp": {"-c:v", "libwebp", "-quality", "80"},
+	"avif": {"-c:v", "libaom-av1", "-still-picture", "1", "-crf", "32", "-b:v", "0", "-pix_fmt", "yuv420p"},
+}
+
+type ProcessedImage struct {
+	Original models.ImageVariant   `json:"original"`
+	Variants []models.ImageVariant `json:"variants"`
+
+	ref    models.BlobRef
+	hashes []string // every blob the image uses
+	added  []string // blobs the owner did not reference before
+}
+
+type ImageService struct {
+	config *config.Config
+	blobs  *BlobService
+}
+
+func NewImageService(cfg *config.Config, blobs *BlobService) *ImageService {
+	return &ImageService{
+		config: cfg,
+		blobs:  blobs,
+	}
+}
+
+func (is *ImageService) Close() {
+	// Cleanup resources if needed
+}
+
+// ValidImageKind reports whether kind has a variant set
+func ValidImageKind(kind ImageKind) bool {
+	_, ok := imageVariantSpecs[kind]
+	return ok
+}
+
+// Process decodes an uploaded image, re-encodes it without metadata and stores
+// it together with every variant for its kind as blobs referenced by the
+// owner. The owner's previous image stays referenced until the caller has
+// saved the new URLs and calls Commit; if saving fails, Discard drops the new
+// references instead. An empty mode keeps each variant's default crop mode.
+func (is *ImageService) Process(ctx context.Context, kind ImageKind, ownerType models.BlobOwnerType, ownerID string, data []byte, mode CropMode) (*ProcessedImage, error) {
+	specs, ok := imageVariantSpecs[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown image kind %s", kind)
+	}
+	if mode != "" && mode != CropModeFit && mode != CropModeFill {
+		return nil, ErrInvalidCropMode
+	}
+
+	img, err := DecodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := models.BlobRef{OwnerType: ownerType, OwnerID: ownerID, Field: string(kind)}
+	held, err := is.blobs.Referenced(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProcessedImage{ref: ref}
+	stored := func(hash string) {
+		result.hashes = append(result.hashes, hash)
+		if !slices.Contains(held, hash) && !slices.Contains(result.added, hash) {
+			result.added = append(result.added, hash)
+		}
+	}
+
+	// Re-encoding drops EXIF and any other embedded metadata
+	original, hash, err := is.store(ctx, ref, "original", img, baseImageFormat(img))
+	if err != nil {
+		return nil, err
+	}
+	stored(hash)
+	result.Original = *original
+
+	extraFormats := is.extraFormats()
+
+	for _, spec := range specs {
+		variantMode := spec.Mode
+		if mode != "" {
+			variantMode = mode
+		}
+
+		resized := ResizeImage(img, spec.Width, spec.Height, variantMode)
+
+		variant, hash, err := is.store(ctx, ref, spec.Name, resized, baseImageFormat(resized))
+		if err != nil {
+			is.Discard(ctx, result)
+			return nil, err
+		}
+		result.Variants = append(result.Variants, *variant)
+		stored(hash)
+
+		for i := 0; i < len(extraFormats); i++ {
+			variant, hash, err := is.store(ctx, ref, spec.Name, resized, extraFormats[i])
+			if err != nil {
+				// Encoders are optional; drop the format rather than the upload
+				fmt.Printf("Skipping %s image variants: %v\n", extraFormats[i], err)
+				extraFormats = append(extraFormats[:i], extraFormats[i+1:]...)
+				i--
+				continue
+			}
+			result.Variants = append(result.Variants, *variant)
+			stored(hash)
+		}
+	}
+
+	return result, nil
+}
+
+// Commit releases the owner's previous image once the new one is saved
+func (is *ImageService) Commit(ctx context.Context, image *ProcessedImage) {
+	if err := is.blobs.Release(ctx, image.ref, image.hashes...); err != nil {
+		fmt.Printf("Failed to release previous %s images for %s: %v\n", image.ref.Field, image.ref.OwnerID, err)
+	}
+}
+
+// Discard releases the references Process added for an image that was not
+// saved, leaving the owner's previous image in place
+func (is *ImageService) Discard(ctx context.Context, image *ProcessedImage) {
+	if err := is.blobs.ReleaseBlobs(ctx, image.ref, image.added...); err != nil {
+		fmt.Printf("Failed to release unsaved %s images for %s: %v\n", image.ref.Field, image.ref.OwnerID, err)
+	}
+}
+
+func (is *ImageService) store(ctx context.Context, ref models.BlobRef, name string, img image.Image, format string) (*models.ImageVariant, string, error) {
+	var data []byte
+	var err error
+
+	switch format {
+	case "jpeg", "png":
+		data, err = EncodeImage(img, format)
+	default:
+		data, err = is.encodeWithFFmpeg(ctx, img, format)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	ext := "." + format
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+
+	blob, err := is.blobs.Store(ctx, "images", ext, bytes.NewReader(data), ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	bounds := img.Bounds()
+	return &models.ImageVariant{
+		Name:   name,
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Format: format,
+		URL:    is.blobs.URL(blob),
+		Size:   blob.Size,
+	}, blob.Hash, nil
+}
+
+// encodeWithFFmpeg produces formats the standard library cannot write
+func (is *ImageService) encodeWithFFmpeg(ctx context.Context, img image.Image, format string) ([]byte, error) {
+	encoderArgs, ok := imageEncoderArgs[format]
+	if !ok {
+		return nil, fmt.Errorf("no encoder for %s", format)
+	}
+
+	dir, err := os.MkdirTemp("", "onflix-image-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	source, err := EncodeImage(img, "png")
+	if err != nil {
+		return nil, err
+	}
+
+	input := filepath.Join(dir, "input.png")
+	if err := os.WriteFile(input, source, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file: %v", err)
+	}
+
+	output := filepath.Join(dir, "output."+format)
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", input}, encoderArgs...)
+	args = append(args, output)
+
+	cmd := exec.CommandContext(ctx, is.config.Video.FFmpegPath, args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return os.ReadFile(output)
+}
+
+func (is *ImageService) extraFormats() []string {
+	var formats []string
+	for _, ext := range is.config.Storage.ImageFormats {
+		format := strings.TrimPrefix(ext, ".")
+		if _, ok := imageEncoderArgs[format]; ok {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// DecodeImage decodes a JPEG, PNG, GIF or WebP image and applies its EXIF
+// orientation so the pixels are upright once the metadata is dropped
+func DecodeImage(data []byte) (image.Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s image: %v", format, err)
+	}
+
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	return img, nil
+}
+
+// EncodeImage writes img as "jpeg", "png" or "gif"
+func EncodeImage(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		return nil, fmt.Errorf("no encoder for %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s image: %v", format, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ResizeImage scales img into a width x height box. A zero dimension is left
+// unconstrained. Images are never upscaled.
+func ResizeImage(img image.Image, width, height int, mode CropMode) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 || (width <= 0 && height <= 0) {
+		return img
+	}
+
+	srcRect := bounds
+	var dstW, dstH int
+
+	if mode == CropModeFill && width > 0 && height > 0 {
+		// Largest centred region with the target aspect ratio
+		cropW, cropH := srcW, srcW*height/width
+		if cropH > srcH {
+			cropW, cropH = srcH*width/height, srcH
+		}
+		x0 := bounds.Min.X + (srcW-cropW)/2
+		y0 := bounds.Min.Y + (srcH-cropH)/2
+		srcRect = image.Rect(x0, y0, x0+cropW, y0+cropH)
+
+		dstW, dstH = width, height
+		if cropW < width {
+			dstW, dstH = cropW, cropH
+		}
+	} else {
+		scale := 1.0
+		if width > 0 {
+			scale = math.Min(scale, float64(width)/float64(srcW))
+		}
+		if height > 0 {
+			scale = math.Min(scale, float64(height)/float64(srcH))
+		}
+		dstW = int(math.Max(1, math.Round(float64(srcW)*scale)))
+		dstH = int(math.Max(1, math.Round(float64(srcH)*scale)))
+	}
+
+	if srcRect == bounds && dstW == srcW && dstH == srcH {
+		return img
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, srcRect, draw.Src, nil)
+	return dst
+}
+
+// baseImageFormat keeps transparency where the source has it
+func baseImageFormat(img image.Image) string {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		return "png"
+	}
+	return "jpeg"
+}
+
+// jpegOrientation reads the EXIF orientation tag (1-8) from a JPEG, or 1 when absent
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Metadata segments all come before the image data
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+
+		i += 2 + length
+	}
+
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+
+	return 1
+}
+
+// applyOrientation rotates and flips img so EXIF orientation 1 describes it
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs a 90 degree clockwise turn
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs a 90 degree anticlockwise turn
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
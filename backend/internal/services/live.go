@@ -0,0 +1,967 @@
+// backend/internal/services/live.go
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	livePlaylistsCollection = "live_playlists"
+	liveRootDir             = "live"
+	liveSweepInterval       = 15 * time.Second
+	// Live events still on air this long after their scheduled end are ended
+	liveOverrunGrace = 30 * time.Minute
+	// Blocking playlist reloads are answered within this many target durations
+	liveBlockingTargets = 3
+	livePollInterval    = 250 * time.Millisecond
+	// Encoder playlists are small; anything larger is not one
+	liveMaxPlaylistSize = 1 << 20
+)
+
+var (
+	ErrLiveContentNotFound = errors.New("content not found")
+	ErrLiveNotLiveContent  = errors.New("content is not a live title")
+	ErrLiveSchedule        = errors.New("live events need a mode of live or premiere, a start, and an end after the start")
+	ErrLiveStarted         = errors.New("the mode and start of an event cannot change once it has started")
+	ErrLivePremiereSource  = errors.New("premieres play the title's packaged HLS video, so it must be packaged first")
+	ErrLiveNotScheduled    = errors.New("live titles need a schedule before they can be published")
+	ErrLiveNotEncoded      = errors.New("only live events take a stream key")
+	ErrLiveStreamKey       = errors.New("invalid stream key")
+	ErrLiveIngestClosed    = errors.New("live event is not accepting ingest")
+	ErrLiveIngestPath      = errors.New("ingest paths are master.m3u8, or a rendition directory holding a playlist or segment")
+	ErrLivePlaylistInvalid = errors.New("invalid media playlist")
+	ErrLiveSegmentTooLarge = errors.New("segment exceeds the size limit")
+	ErrLiveNotStarted      = errors.New("live event has not started")
+	ErrLiveNoPlaylist      = errors.New("rendition is not being streamed")
+	ErrLiveBlockingRequest = errors.New("_HLS_msn must be at most two segments past the live edge, and _HLS_part needs _HLS_msn")
+	ErrLiveBlockingTimeout = errors.New("playlist did not reach the requested segment in time")
+)
+
+var (
+	liveRenditionName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	liveFileName      = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+	liveSegmentTypes  = map[string]bool{".ts": true, ".m4s": true, ".mp4": true, ".m4a": true, ".aac": true}
+)
+
+// LiveService schedules live titles, takes in the segments and playlists an
+// encoder pushes, and serves the rolling low-latency playlists built from
+// them. Premieres instead replay the title's packaged HLS on a shared clock.
+type LiveService struct {
+	config  *config.Config
+	db      *mongo.Database
+	storage *StorageService
+	cancel  context.CancelFunc
+
+	mu sync.Mutex
+	// Closed when a rendition's playlist changes, to wake blocking reloads
+	// waiting on this instance; others notice on their next poll
+	updates map[string]chan struct{}
+}
+
+// LiveSchedule is the part of a live event admins set
+type LiveSchedule struct {
+	Mode      models.LiveMode
+	Start     time.Time
+	End       time.Time // Premieres end with their video, so theirs is ignored
+	DVRWindow int       // Seconds; the configured default when zero
+}
+
+func NewLiveService(cfg *config.Config, db *mongo.Database, storage *StorageService) *LiveService {
+	return &LiveService{
+		config:  cfg,
+		db:      db,
+		storage: storage,
+		updates: make(map[string]chan struct{}),
+	}
+}
+
+// Start runs the sweep that puts premieres on air and ends events
+func (ls *LiveService) Start() {
+	if ls.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ls.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(liveSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := ls.sweep(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to update live events: %v\n", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (ls *LiveService) Close() {
+	if ls.cancel != nil {
+		ls.cancel()
+	}
+}
+
+// LiveDirectory returns the storage directory an event's pushed segments are kept in
+func LiveDirectory(contentID string) string {
+	return path.Join(liveRootDir, contentID)
+}
+
+// LiveOnly reports whether a title may only be watched through its live
+// HLS stream. Ended premieres become ordinary on-demand titles.
+func LiveOnly(content *models.Content) bool {
+	if content.Type != models.ContentTypeLive {
+		return false
+	}
+	return content.Live == nil || content.Live.Mode != models.LiveModePremiere || content.Live.State != models.LiveStateEnded
+}
+
+// CheckPublishable is the part of publishing specific to live titles
+func (ls *LiveService) CheckPublishable(content *models.Content) error {
+	if content.Type != models.ContentTypeLive {
+		return nil
+	}
+	if content.Live == nil {
+		return ErrLiveNotScheduled
+	}
+	if content.Live.Mode == models.LiveModePremiere && content.Streaming.HLSMaster == "" {
+		return ErrLivePremiereSource
+	}
+	return nil
+}
+
+// ScheduleEvent sets or changes a live title's schedule. A stream key is
+// returned the first time a live event is scheduled; it is only stored
+// hashed, so it cannot be shown again, only rotated.
+func (ls *LiveService) ScheduleEvent(ctx context.Context, contentID primitive.ObjectID, schedule LiveSchedule) (*models.LiveEvent, string, error) {
+	content, err := ls.liveContent(ctx, contentID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if (schedule.Mode != models.LiveModeLive && schedule.Mode != models.LiveModePremiere) || schedule.Start.IsZero() {
+		return nil, "", ErrLiveSchedule
+	}
+
+	event := models.LiveEvent{
+		Mode:           schedule.Mode,
+		State:          models.LiveStateScheduled,
+		ScheduledStart: schedule.Start,
+		ScheduledEnd:   schedule.End,
+		DVRWindow:      schedule.DVRWindow,
+	}
+	if event.DVRWindow <= 0 {
+		event.DVRWindow = ls.config.Video.LiveDVRWindow
+	}
+
+	if existing := content.Live; existing != nil {
+		if existing.State != models.LiveStateScheduled &&
+			(existing.Mode != schedule.Mode || !existing.ScheduledStart.Equal(schedule.Start)) {
+			return nil, "", ErrLiveStarted
+		}
+		event.State = existing.State
+		event.StartedAt = existing.StartedAt
+		event.EndedAt = existing.EndedAt
+		event.Renditions = existing.Renditions
+		event.StreamKeyHash = existing.StreamKeyHash
+	}
+
+	var streamKey string
+	if event.Mode == models.LiveModePremiere {
+		duration := premiereDuration(content)
+		if content.Streaming.HLSMaster == "" || duration == 0 {
+			return nil, "", ErrLivePremiereSource
+		}
+		event.ScheduledEnd = event.ScheduledStart.Add(duration)
+		event.StreamKeyHash = ""
+		event.Renditions = nil
+	} else {
+		if !event.ScheduledEnd.After(event.ScheduledStart) {
+			return nil, "", ErrLiveSchedule
+		}
+		if event.StreamKeyHash == "" {
+			if streamKey, event.StreamKeyHash, err = newStreamKey(); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	if err := ls.saveEvent(ctx, contentID, bson.M{"live": event}); err != nil {
+		return nil, "", err
+	}
+
+	return &event, streamKey, nil
+}
+
+// RotateStreamKey replaces a live event's stream key, cutting off any
+// encoder still using the old one
+func (ls *LiveService) RotateStreamKey(ctx context.Context, contentID primitive.ObjectID) (string, error) {
+	content, err := ls.liveContent(ctx, contentID)
+	if err != nil {
+		return "", err
+	}
+	if content.Live == nil {
+		return "", ErrLiveNotScheduled
+	}
+	if content.Live.Mode != models.LiveModeLive {
+		return "", ErrLiveNotEncoded
+	}
+
+	streamKey, hash, err := newStreamKey()
+	if err != nil {
+		return "", err
+	}
+
+	if err := ls.saveEvent(ctx, contentID, bson.M{"live.stream_key_hash": hash}); err != nil {
+		return "", err
+	}
+
+	return streamKey, nil
+}
+
+// End takes an event off air. Viewers keep the DVR window, now closed with
+// an end tag, and encoders are turned away.
+func (ls *LiveService) End(ctx context.Context, contentID primitive.ObjectID) error {
+	content, err := ls.liveContent(ctx, contentID)
+	if err != nil {
+		return err
+	}
+	if content.Live == nil {
+		return ErrLiveNotScheduled
+	}
+
+	return ls.end(ctx, contentID)
+}
+
+func (ls *LiveService) end(ctx context.Context, contentID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := ls.db.Collection("content").UpdateOne(
+		ctx,
+		bson.M{"_id": contentID, "live.state": bson.M{"$ne": models.LiveStateEnded}},
+		bson.M{"$set": bson.M{
+			"live.state":    models.LiveStateEnded,
+			"live.ended_at": now,
+			"updated_at":    now,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to end live event: %v", err)
+	}
+
+	_, err = ls.db.Collection(livePlaylistsCollection).UpdateMany(
+		ctx,
+		bson.M{"content_id": contentID},
+		bson.M{"$set": bson.M{"ended": true, "pending_parts": nil, "preload_hint": "", "updated_at": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to close live playlists: %v", err)
+	}
+
+	ls.notify(contentID.Hex(), "")
+	return nil
+}
+
+// Listings returns published live titles that are on air or still to come,
+// soonest first
+func (ls *LiveService) Listings(ctx context.Context) ([]models.Content, error) {
+	cursor, err := ls.db.Collection("content").Find(
+		ctx,
+		bson.M{
+			"type":       models.ContentTypeLive,
+			"status":     models.ContentStatusPublished,
+			"live.state": bson.M{"$in": []models.LiveState{models.LiveStateScheduled, models.LiveStateLive}},
+		},
+		options.Find().
+			SetSort(bson.D{{Key: "live.scheduled_start", Value: 1}}).
+			SetProjection(bson.M{"videos": 0, "seasons": 0}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list live events: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	listings := []models.Content{}
+	if err := cursor.All(ctx, &listings); err != nil {
+		return nil, fmt.Errorf("failed to decode live events: %v", err)
+	}
+
+	return listings, nil
+}
+
+// AuthorizeIngest finds the live event a stream key belongs to. Encoders
+// may connect from LiveIngestLead minutes before the start until the event ends.
+func (ls *LiveService) AuthorizeIngest(ctx context.Context, streamKey string) (*models.Content, error) {
+	if streamKey == "" {
+		return nil, ErrLiveStreamKey
+	}
+
+	var content models.Content
+	err := ls.db.Collection("content").FindOne(ctx, bson.M{
+		"type":                 models.ContentTypeLive,
+		"live.mode":            models.LiveModeLive,
+		"live.stream_key_hash": hashStreamKey(streamKey),
+	}, options.FindOne().SetProjection(bson.M{"videos": 0, "seasons": 0})).Decode(&content)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrLiveStreamKey
+		}
+		return nil, fmt.Errorf("failed to look up stream key: %v", err)
+	}
+
+	lead := time.Duration(ls.config.Video.LiveIngestLead) * time.Minute
+	if content.Live.State == models.LiveStateEnded || time.Now().Before(content.Live.ScheduledStart.Add(-lead)) {
+		return nil, ErrLiveIngestClosed
+	}
+
+	return &content, nil
+}
+
+// Ingest stores a file pushed by an encoder, laid out as ffmpeg's HLS muxer
+// writes it: an optional master.m3u8 at the root and a directory per
+// rendition holding its media playlist, segments and partial segments.
+// Segments must arrive before the playlist that lists them.
+func (ls *LiveService) Ingest(ctx context.Context, content *models.Content, filePath string, body io.Reader, size int64) error {
+	dir, name := path.Split(strings.TrimPrefix(path.Clean("/"+filePath), "/"))
+	dir = strings.TrimSuffix(dir, "/")
+	if !liveFileName.MatchString(name) || (dir != "" && !liveRenditionName.MatchString(dir)) {
+		return ErrLiveIngestPath
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case ext == ".m3u8":
+		data, err := io.ReadAll(io.LimitReader(body, liveMaxPlaylistSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to read playlist: %v", err)
+		}
+		if len(data) > liveMaxPlaylistSize {
+			return ErrLivePlaylistInvalid
+		}
+		if dir == "" {
+			return ls.updateRenditions(ctx, content, data)
+		}
+		return ls.updatePlaylist(ctx, content, dir, data)
+
+	case dir != "" && liveSegmentTypes[ext]:
+		limit := ls.config.Video.LiveSegmentMaxSize
+		if size > limit {
+			return ErrLiveSegmentTooLarge
+		}
+
+		relativePath := path.Join(LiveDirectory(content.ID.Hex()), dir, name)
+		metadata, err := ls.storage.Put(ctx, relativePath, io.LimitReader(body, limit+1), size)
+		if err != nil {
+			return fmt.Errorf("failed to store segment: %v", err)
+		}
+		if metadata.Size > limit {
+			ls.deleteLiveFiles(content.ID.Hex(), dir, []string{name})
+			return ErrLiveSegmentTooLarge
+		}
+		return nil
+	}
+
+	return ErrLiveIngestPath
+}
+
+// updateRenditions records the variants of a pushed master playlist
+func (ls *LiveService) updateRenditions(ctx context.Context, content *models.Content, data []byte) error {
+	var renditions []models.LiveRendition
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var pending map[string]string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if tag, value, _ := strings.Cut(line, ":"); tag == "#EXT-X-STREAM-INF" {
+			pending = parseHLSAttributes(value)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || pending == nil {
+			continue
+		}
+
+		name := liveRenditionOf(line)
+		if !liveRenditionName.MatchString(name) {
+			return ErrLiveIngestPath
+		}
+		bandwidth, _ := strconv.Atoi(pending["BANDWIDTH"])
+		average, _ := strconv.Atoi(pending["AVERAGE-BANDWIDTH"])
+		renditions = append(renditions, models.LiveRendition{
+			Name:             name,
+			Bandwidth:        bandwidth,
+			AverageBandwidth: average,
+			Resolution:       pending["RESOLUTION"],
+			Codecs:           pending["CODECS"],
+		})
+		pending = nil
+	}
+	if len(renditions) == 0 {
+		return ErrLivePlaylistInvalid
+	}
+
+	return ls.saveEvent(ctx, content.ID, bson.M{"live.renditions": renditions})
+}
+
+// updatePlaylist merges a pushed media playlist into the rendition's DVR
+// window. Encoders usually keep only a few segments in their own playlist,
+// so older segments are kept until they leave the window, at which point
+// their files are deleted.
+func (ls *LiveService) updatePlaylist(ctx context.Context, content *models.Content, rendition string, data []byte) error {
+	pushed, err := ParseHLSMediaPlaylist(data)
+	if err != nil {
+		return ErrLivePlaylistInvalid
+	}
+
+	collection := ls.db.Collection(livePlaylistsCollection)
+	filter := bson.M{"content_id": content.ID, "rendition": rendition}
+
+	stored := models.LivePlaylist{ContentID: content.ID, Rendition: rendition}
+	if err := collection.FindOne(ctx, filter).Decode(&stored); err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to load live playlist: %v", err)
+	}
+
+	var segments []models.LiveSegment
+	firstPushed := math.MaxInt
+	if len(pushed.Sequences) > 0 {
+		firstPushed = pushed.Sequences[0].Sequence
+	}
+	for _, segment := range stored.Segments {
+		if segment.Sequence < firstPushed {
+			segments = append(segments, segment)
+		}
+	}
+	for _, sequence := range pushed.Sequences {
+		segments = append(segments, models.LiveSegment{
+			Sequence:        sequence.Sequence,
+			Duration:        sequence.Duration,
+			URI:             liveURI(sequence.URI),
+			Parts:           liveParts(sequence.Parts),
+			ProgramDateTime: sequence.ProgramDateTime,
+			Discontinuity:   sequence.Discontinuity,
+		})
+	}
+
+	var expired []string
+	target := pushed.TargetDuration
+	for _, segment := range segments {
+		target = max(target, int(math.Ceil(segment.Duration)))
+	}
+
+	// Parts are only listed near the live edge, where players can use them
+	var recent float64
+	for i := len(segments) - 1; i >= 0; i-- {
+		if recent >= float64(liveBlockingTargets*target) {
+			for _, part := range segments[i].Parts {
+				expired = append(expired, part.URI)
+			}
+			segments[i].Parts = nil
+		}
+		recent += segments[i].Duration
+	}
+
+	var total float64
+	for _, segment := range segments {
+		total += segment.Duration
+	}
+	for len(segments) > 1 && total-segments[0].Duration >= float64(content.Live.DVRWindow) {
+		total -= segments[0].Duration
+		expired = append(expired, segments[0].URI)
+		for _, part := range segments[0].Parts {
+			expired = append(expired, part.URI)
+		}
+		segments = segments[1:]
+	}
+
+	stored.Segments = segments
+	stored.TargetDuration = target
+	stored.PartTarget = pushed.PartTarget
+	stored.MapURI = ""
+	if pushed.Map != "" {
+		stored.MapURI = liveURI(pushed.Map)
+	}
+	stored.PendingParts = liveParts(pushed.PendingParts)
+	stored.PreloadHint = ""
+	if pushed.PreloadHint != "" {
+		stored.PreloadHint = liveURI(pushed.PreloadHint)
+	}
+	stored.Ended = pushed.EndList
+	stored.UpdatedAt = time.Now()
+
+	if n := len(segments); n > 0 && segments[n-1].Duration > 0 {
+		newest := path.Join(LiveDirectory(content.ID.Hex()), rendition, segments[n-1].URI)
+		if metadata, err := ls.storage.Stat(ctx, newest); err == nil {
+			stored.Bandwidth = max(stored.Bandwidth, int(float64(metadata.Size*8)/segments[n-1].Duration))
+		}
+	}
+
+	_, err = collection.ReplaceOne(ctx, filter, stored, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save live playlist: %v", err)
+	}
+
+	ls.notify(content.ID.Hex(), rendition)
+	ls.deleteLiveFiles(content.ID.Hex(), rendition, expired)
+
+	// The event goes on air with the first segment after its scheduled start
+	if content.Live.State == models.LiveStateScheduled && len(segments) > 0 && !time.Now().Before(content.Live.ScheduledStart) {
+		now := time.Now()
+		_, err := ls.db.Collection("content").UpdateOne(
+			ctx,
+			bson.M{"_id": content.ID, "live.state": models.LiveStateScheduled},
+			bson.M{"$set": bson.M{"live.state": models.LiveStateLive, "live.started_at": now, "updated_at": now}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to start live event: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (ls *LiveService) deleteLiveFiles(contentID, rendition string, names []string) {
+	for _, name := range names {
+		if err := ls.storage.DeleteFile(path.Join(LiveDirectory(contentID), rendition, name)); err != nil {
+			fmt.Printf("Failed to delete live segment %s/%s of %s: %v\n", rendition, name, contentID, err)
+		}
+	}
+}
+
+// MasterPlaylist lists a live event's renditions, as announced by the
+// encoder or, failing that, as seen from the playlists it pushed
+func (ls *LiveService) MasterPlaylist(ctx context.Context, content *models.Content) ([]byte, error) {
+	if content.Live == nil || content.Live.State == models.LiveStateScheduled {
+		return nil, ErrLiveNotStarted
+	}
+
+	renditions := content.Live.Renditions
+	if len(renditions) == 0 {
+		cursor, err := ls.db.Collection(livePlaylistsCollection).Find(
+			ctx,
+			bson.M{"content_id": content.ID},
+			options.Find().SetSort(bson.D{{Key: "bandwidth", Value: -1}}),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list live playlists: %v", err)
+		}
+		defer cursor.Close(ctx)
+
+		var playlists []models.LivePlaylist
+		if err := cursor.All(ctx, &playlists); err != nil {
+			return nil, fmt.Errorf("failed to decode live playlists: %v", err)
+		}
+		for _, playlist := range playlists {
+			renditions = append(renditions, models.LiveRendition{Name: playlist.Rendition, Bandwidth: playlist.Bandwidth})
+		}
+	}
+	if len(renditions) == 0 {
+		return nil, ErrLiveNoPlaylist
+	}
+
+	master := &HLSPlaylist{Version: 6}
+	for _, rendition := range renditions {
+		master.Variants = append(master.Variants, HLSVariant{
+			Bandwidth:        max(rendition.Bandwidth, 1),
+			AverageBandwidth: rendition.AverageBandwidth,
+			Resolution:       rendition.Resolution,
+			Codecs:           rendition.Codecs,
+			URI:              rendition.Name + "/" + hlsMediaPlaylistName,
+		})
+	}
+
+	return master.Encode(), nil
+}
+
+// MediaPlaylist renders a rendition's DVR window. When msn is set (the
+// _HLS_msn of a blocking reload, -1 otherwise) the response waits until the
+// playlist holds that segment, or part of it when part is set too.
+func (ls *LiveService) MediaPlaylist(ctx context.Context, content *models.Content, rendition string, msn, part int) ([]byte, error) {
+	if content.Live == nil || content.Live.State == models.LiveStateScheduled {
+		return nil, ErrLiveNotStarted
+	}
+	if part >= 0 && msn < 0 {
+		return nil, ErrLiveBlockingRequest
+	}
+
+	var deadline time.Time
+	for {
+		var stored models.LivePlaylist
+		err := ls.db.Collection(livePlaylistsCollection).FindOne(ctx, bson.M{
+			"content_id": content.ID,
+			"rendition":  rendition,
+		}).Decode(&stored)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, ErrLiveNoPlaylist
+			}
+			return nil, fmt.Errorf("failed to load live playlist: %v", err)
+		}
+
+		ended := stored.Ended || content.Live.State == models.LiveStateEnded
+		if msn < 0 || ended || livePlaylistHas(&stored, msn, part) {
+			return renderLivePlaylist(&stored, ended).Encode(), nil
+		}
+
+		last := -1
+		if n := len(stored.Segments); n > 0 {
+			last = stored.Segments[n-1].Sequence
+		}
+		if msn > last+2 {
+			return nil, ErrLiveBlockingRequest
+		}
+
+		if deadline.IsZero() {
+			deadline = time.Now().Add(time.Duration(liveBlockingTargets*max(stored.TargetDuration, 1)) * time.Second)
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, ErrLiveBlockingTimeout
+		}
+
+		updated := ls.subscribe(content.ID.Hex(), rendition)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-updated:
+		case <-time.After(min(livePollInterval, remaining)):
+		}
+	}
+}
+
+// livePlaylistHas reports whether a blocking reload for segment msn, or
+// part of it, can be answered. A finished segment answers for all its parts.
+func livePlaylistHas(playlist *models.LivePlaylist, msn, part int) bool {
+	last := -1
+	if n := len(playlist.Segments); n > 0 {
+		last = playlist.Segments[n-1].Sequence
+	}
+	if msn <= last {
+		return true
+	}
+	return part >= 0 && msn == last+1 && len(playlist.PendingParts) > part
+}
+
+func renderLivePlaylist(stored *models.LivePlaylist, ended bool) *HLSPlaylist {
+	playlist := &HLSPlaylist{
+		Version:        6,
+		TargetDuration: stored.TargetDuration,
+		CanBlockReload: !ended,
+		PartTarget:     stored.PartTarget,
+		Map:            stored.MapURI,
+		EndList:        ended,
+	}
+
+	for _, segment := range stored.Segments {
+		sequence := HLSSequence{
+			Duration:        segment.Duration,
+			URI:             segment.URI,
+			Sequence:        segment.Sequence,
+			ProgramDateTime: segment.ProgramDateTime,
+			Discontinuity:   segment.Discontinuity,
+		}
+		if !ended {
+			sequence.Parts = hlsParts(segment.Parts)
+		}
+		playlist.Sequences = append(playlist.Sequences, sequence)
+	}
+
+	if !ended {
+		playlist.PendingParts = hlsParts(stored.PendingParts)
+		playlist.PreloadHint = stored.PreloadHint
+	} else {
+		playlist.PartTarget = 0
+	}
+
+	return playlist
+}
+
+// PremierePlaylist cuts a title's packaged media playlist down to what has
+// aired of its premiere. Segments are released as the shared clock passes
+// their end, stamped with the time they aired, and dropped once they fall
+// out of the DVR window. A blocking reload for a segment that has not aired
+// yet waits until it has. Once the whole title has aired the playlist is
+// served unchanged.
+func (ls *LiveService) PremierePlaylist(ctx context.Context, playlist *HLSPlaylist, event *models.LiveEvent, msn int) (*HLSPlaylist, error) {
+	if time.Now().Before(event.ScheduledStart) {
+		return nil, ErrLiveNotStarted
+	}
+
+	if msn >= 0 {
+		airs, ok := premiereAirTime(playlist, event, msn)
+		if !ok && (len(playlist.Sequences) == 0 || msn < playlist.Sequences[0].Sequence) {
+			return nil, ErrLiveBlockingRequest
+		}
+		if wait := time.Until(airs); ok && wait > 0 {
+			if wait > time.Duration(liveBlockingTargets*max(playlist.TargetDuration, 1))*time.Second {
+				return nil, ErrLiveBlockingRequest
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+	}
+
+	return premiereWindow(playlist, event, time.Now()), nil
+}
+
+// PremiereSegmentAired reports whether the segment a player asks for has
+// aired, so the packaged title cannot be fetched ahead of the premiere
+func PremiereSegmentAired(playlist *HLSPlaylist, event *models.LiveEvent, requestPath string) bool {
+	name := path.Base(requestPath)
+	for _, segment := range playlist.Sequences {
+		if path.Base(segment.URI) == name {
+			airs, _ := premiereAirTime(playlist, event, segment.Sequence)
+			return !time.Now().Before(airs)
+		}
+	}
+	return false
+}
+
+func premiereWindow(playlist *HLSPlaylist, event *models.LiveEvent, now time.Time) *HLSPlaylist {
+	elapsed := now.Sub(event.ScheduledStart).Seconds()
+
+	window := *playlist
+	window.Sequences = nil
+	window.PlaylistType = ""
+	window.EndList = false
+	window.CanBlockReload = true
+
+	var offset float64
+	for _, segment := range playlist.Sequences {
+		end := offset + segment.Duration
+		if end > elapsed {
+			return &window
+		}
+		if end > elapsed-float64(event.DVRWindow) {
+			aired := event.ScheduledStart.Add(time.Duration(offset * float64(time.Second)))
+			segment.ProgramDateTime = &aired
+			window.Sequences = append(window.Sequences, segment)
+		}
+		offset = end
+	}
+
+	return playlist
+}
+
+// premiereAirTime returns when segment sequence of a premiere has fully aired
+func premiereAirTime(playlist *HLSPlaylist, event *models.LiveEvent, sequence int) (time.Time, bool) {
+	var offset float64
+	for _, segment := range playlist.Sequences {
+		offset += segment.Duration
+		if segment.Sequence == sequence {
+			return event.ScheduledStart.Add(time.Duration(offset * float64(time.Second))), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// premiereDuration is the length of a title's longest full video
+func premiereDuration(content *models.Content) time.Duration {
+	var longest int
+	for _, video := range content.Videos {
+		if video.Type == models.VideoTypeFull {
+			longest = max(longest, video.Duration)
+		}
+	}
+	return time.Duration(longest) * time.Second
+}
+
+// sweep puts premieres on air at their start and ends events that are over
+func (ls *LiveService) sweep(ctx context.Context) error {
+	now := time.Now()
+	collection := ls.db.Collection("content")
+
+	_, err := collection.UpdateMany(ctx, bson.M{
+		"type":                 models.ContentTypeLive,
+		"live.mode":            models.LiveModePremiere,
+		"live.state":           models.LiveStateScheduled,
+		"live.scheduled_start": bson.M{"$lte": now},
+	}, bson.M{"$set": bson.M{"live.state": models.LiveStateLive, "live.started_at": now, "updated_at": now}})
+	if err != nil {
+		return fmt.Errorf("failed to start premieres: %v", err)
+	}
+
+	_, err = collection.UpdateMany(ctx, bson.M{
+		"type":               models.ContentTypeLive,
+		"live.mode":          models.LiveModePremiere,
+		"live.state":         bson.M{"$ne": models.LiveStateEnded},
+		"live.scheduled_end": bson.M{"$lte": now},
+	}, bson.M{"$set": bson.M{"live.state": models.LiveStateEnded, "live.ended_at": now, "updated_at": now}})
+	if err != nil {
+		return fmt.Errorf("failed to end premieres: %v", err)
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"type":       models.ContentTypeLive,
+		"live.mode":  models.LiveModeLive,
+		"live.state": bson.M{"$ne": models.LiveStateEnded},
+	}, options.Find().SetProjection(bson.M{"live": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to list live events: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.Content
+	if err := cursor.All(ctx, &events); err != nil {
+		return fmt.Errorf("failed to decode live events: %v", err)
+	}
+
+	for _, event := range events {
+		over := now.After(event.Live.ScheduledEnd.Add(liveOverrunGrace))
+		if !over && event.Live.State == models.LiveStateLive {
+			// The encoder has finished once every rendition has sent its end tag
+			total, err := ls.db.Collection(livePlaylistsCollection).CountDocuments(ctx, bson.M{"content_id": event.ID})
+			if err != nil {
+				return fmt.Errorf("failed to count live playlists: %v", err)
+			}
+			open, err := ls.db.Collection(livePlaylistsCollection).CountDocuments(ctx, bson.M{"content_id": event.ID, "ended": false})
+			if err != nil {
+				return fmt.Errorf("failed to count live playlists: %v", err)
+			}
+			over = total > 0 && open == 0
+		}
+
+		if over {
+			if err := ls.end(ctx, event.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ls *LiveService) liveContent(ctx context.Context, contentID primitive.ObjectID) (*models.Content, error) {
+	var content models.Content
+	err := ls.db.Collection("content").FindOne(ctx, bson.M{"_id": contentID}).Decode(&content)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrLiveContentNotFound
+		}
+		return nil, fmt.Errorf("failed to load content: %v", err)
+	}
+
+	if content.Type != models.ContentTypeLive {
+		return nil, ErrLiveNotLiveContent
+	}
+
+	return &content, nil
+}
+
+func (ls *LiveService) saveEvent(ctx context.Context, contentID primitive.ObjectID, set bson.M) error {
+	set["updated_at"] = time.Now()
+	result, err := ls.db.Collection("content").UpdateOne(ctx, bson.M{"_id": contentID}, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to save live event: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrLiveContentNotFound
+	}
+	return nil
+}
+
+// subscribe returns a channel closed on the next change to a rendition
+func (ls *LiveService) subscribe(contentID, rendition string) <-chan struct{} {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	key := contentID + "/" + rendition
+	ch, ok := ls.updates[key]
+	if !ok {
+		ch = make(chan struct{})
+		ls.updates[key] = ch
+	}
+	return ch
+}
+
+// notify wakes reloads waiting on a rendition, or on every rendition of the
+// event when rendition is empty
+func (ls *LiveService) notify(contentID, rendition string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	for key, ch := range ls.updates {
+		if key == contentID+"/"+rendition || (rendition == "" && strings.HasPrefix(key, contentID+"/")) {
+			close(ch)
+			delete(ls.updates, key)
+		}
+	}
+}
+
+func newStreamKey() (string, string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate stream key: %v", err)
+	}
+	key := "live_" + hex.EncodeToString(raw)
+	return key, hashStreamKey(key), nil
+}
+
+func hashStreamKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// liveURI reduces a URI in a pushed playlist to the file name it was
+// pushed under, since encoders may write absolute ingest URLs that carry
+// the stream key
+func liveURI(uri string) string {
+	if parsed, err := url.Parse(uri); err == nil {
+		uri = parsed.Path
+	}
+	return path.Base(uri)
+}
+
+// liveRenditionOf returns the rendition directory a master playlist URI points into
+func liveRenditionOf(uri string) string {
+	if parsed, err := url.Parse(uri); err == nil {
+		uri = parsed.Path
+	}
+	return path.Base(path.Dir(uri))
+}
+
+func liveParts(parts []HLSPart) []models.LivePart {
+	var converted []models.LivePart
+	for _, part := range parts {
+		converted = append(converted, models.LivePart{URI: liveURI(part.URI), Duration: part.Duration, Independent: part.Independent})
+	}
+	return converted
+}
+
+func hlsParts(parts []models.LivePart) []HLSPart {
+	var converted []HLSPart
+	for _, part := range parts {
+		converted = append(converted, HLSPart{URI: part.URI, Duration: part.Duration, Independent: part.Independent})
+	}
+	return converted
+}
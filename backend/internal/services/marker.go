@@ -0,0 +1,556 @@
+// backend/internal/services/marker.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Intros are looked for in this much of the start of each episode
+	introScanSeconds = 600
+	// Shorter shared stretches are usually a logo or a cold-open cut, not an intro
+	minIntroSeconds = 15
+	// Frames whose 64-bit difference hashes differ in at most this many bits match
+	maxFrameHashDistance = 8
+	// Frames with less contrast than this, such as black, carry no usable hash
+	minFrameContrast = 16
+)
+
+var (
+	ErrMarkerTargetNotFound = errors.New("video or episode not found")
+	ErrMarkerNotFound       = errors.New("marker not found")
+	ErrMarkerType           = errors.New("marker type must be intro, recap, credits or post_credits")
+	ErrMarkerRange          = errors.New("marker must end after it starts and lie within the video")
+	ErrMarkerExists         = errors.New("video already has a marker of this type")
+	ErrSeasonNotFound       = errors.New("season not found")
+	ErrIntroEpisodes        = errors.New("at least two episodes with video are needed to detect intros")
+)
+
+var markerTypes = map[models.MarkerType]bool{
+	models.MarkerTypeIntro:       true,
+	models.MarkerTypeRecap:       true,
+	models.MarkerTypeCredits:     true,
+	models.MarkerTypePostCredits: true,
+}
+
+// MarkerTarget names the content video or the episode a set of markers belongs to
+type MarkerTarget struct {
+	ContentID primitive.ObjectID
+	VideoID   *primitive.ObjectID
+	EpisodeID *primitive.ObjectID
+}
+
+// frameHash is the difference hash of one second of video
+type frameHash struct {
+	hash  uint64
+	valid bool
+}
+
+// MarkerService manages the intro, recap and credits markers of videos and
+// episodes and suggests intro ranges from the episodes of a season
+type MarkerService struct {
+	config  *config.Config
+	db      *mongo.Database
+	storage *StorageService
+}
+
+func NewMarkerService(cfg *config.Config, db *mongo.Database, storage *StorageService) *MarkerService {
+	return &MarkerService{
+		config:  cfg,
+		db:      db,
+		storage: storage,
+	}
+}
+
+func (ms *MarkerService) Close() {
+	// Cleanup resources if needed
+}
+
+// VideoMarkers returns the markers that apply to a video, falling back to
+// those of the video it was transcoded from
+func VideoMarkers(videos []models.ContentVideo, video models.ContentVideo) []models.VideoMarker {
+	if len(video.Markers) > 0 || video.SourceVideoID == nil {
+		return video.Markers
+	}
+
+	for _, source := range videos {
+		if source.ID == *video.SourceVideoID {
+			return source.Markers
+		}
+	}
+
+	return nil
+}
+
+func (ms *MarkerService) List(ctx context.Context, target MarkerTarget) ([]models.VideoMarker, error) {
+	markers, _, err := ms.load(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if markers == nil {
+		markers = []models.VideoMarker{}
+	}
+	return markers, nil
+}
+
+func (ms *MarkerService) Create(ctx context.Context, target MarkerTarget, marker models.VideoMarker) (*models.VideoMarker, error) {
+	markers, duration, err := ms.load(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	marker.ID = primitive.NewObjectID()
+	marker.CreatedAt = now
+	marker.UpdatedAt = now
+	if marker.Source == "" {
+		marker.Source = models.MarkerSourceManual
+	}
+
+	if err := validateMarker(marker, duration, markers); err != nil {
+		return nil, err
+	}
+
+	if err := ms.save(ctx, target, append(markers, marker)); err != nil {
+		return nil, err
+	}
+
+	return &marker, nil
+}
+
+func (ms *MarkerService) Update(ctx context.Context, target MarkerTarget, markerID primitive.ObjectID, marker models.VideoMarker) (*models.VideoMarker, error) {
+	markers, duration, err := ms.load(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range markers {
+		if markers[i].ID != markerID {
+			continue
+		}
+
+		marker.ID = markerID
+		marker.CreatedAt = markers[i].CreatedAt
+		marker.UpdatedAt = time.Now()
+		// Editing a detected marker makes it a manual one
+		marker.Source = models.MarkerSourceManual
+
+		if err := validateMarker(marker, duration, markers); err != nil {
+			return nil, err
+		}
+
+		markers[i] = marker
+		if err := ms.save(ctx, target, markers); err != nil {
+			return nil, err
+		}
+		return &marker, nil
+	}
+
+	return nil, ErrMarkerNotFound
+}
+
+func (ms *MarkerService) Delete(ctx context.Context, target MarkerTarget, markerID primitive.ObjectID) error {
+	markers, _, err := ms.load(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	for i := range markers {
+		if markers[i].ID == markerID {
+			return ms.save(ctx, target, append(markers[:i], markers[i+1:]...))
+		}
+	}
+
+	return ErrMarkerNotFound
+}
+
+// validateMarker checks a marker's type and range, and that no other marker
+// of the same type exists, since players act on the first one they find
+func validateMarker(marker models.VideoMarker, duration float64, markers []models.VideoMarker) error {
+	if !markerTypes[marker.Type] {
+		return ErrMarkerType
+	}
+
+	if marker.Start < 0 || marker.End <= marker.Start || (duration > 0 && marker.End > duration) {
+		return ErrMarkerRange
+	}
+
+	for _, existing := range markers {
+		if existing.ID != marker.ID && existing.Type == marker.Type {
+			return ErrMarkerExists
+		}
+	}
+
+	return nil
+}
+
+// load returns a target's markers and its duration in seconds, or zero when
+// the duration is not known yet
+func (ms *MarkerService) load(ctx context.Context, target MarkerTarget) ([]models.VideoMarker, float64, error) {
+	filter := bson.M{"_id": target.ContentID}
+	switch {
+	case target.VideoID != nil:
+		filter["videos._id"] = *target.VideoID
+	case target.EpisodeID != nil:
+		filter["seasons.episodes._id"] = *target.EpisodeID
+	default:
+		return nil, 0, ErrMarkerTargetNotFound
+	}
+
+	var content models.Content
+	if err := ms.db.Collection("content").FindOne(ctx, filter).Decode(&content); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, 0, ErrMarkerTargetNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to load content: %v", err)
+	}
+
+	if target.VideoID != nil {
+		for _, video := range content.Videos {
+			if video.ID == *target.VideoID {
+				return video.Markers, float64(video.Duration), nil
+			}
+		}
+		return nil, 0, ErrMarkerTargetNotFound
+	}
+
+	for _, season := range content.Seasons {
+		for _, episode := range season.Episodes {
+			if episode.ID != *target.EpisodeID {
+				continue
+			}
+
+			duration := float64(episode.Runtime * 60)
+			for _, video := range episode.Videos {
+				if video.Type == models.VideoTypeFull && video.Duration > 0 {
+					duration = float64(video.Duration)
+				}
+			}
+			return episode.Markers, duration, nil
+		}
+	}
+
+	return nil, 0, ErrMarkerTargetNotFound
+}
+
+func (ms *MarkerService) save(ctx context.Context, target MarkerTarget, markers []models.VideoMarker) error {
+	sort.Slice(markers, func(i, j int) bool {
+		return markers[i].Start < markers[j].Start
+	})
+
+	field := "videos.$[video].markers"
+	arrayFilter := bson.M{}
+	if target.VideoID != nil {
+		arrayFilter["video._id"] = *target.VideoID
+	} else {
+		field = "seasons.$[].episodes.$[episode].markers"
+		arrayFilter["episode._id"] = *target.EpisodeID
+	}
+
+	result, err := ms.db.Collection("content").UpdateOne(
+		ctx,
+		bson.M{"_id": target.ContentID},
+		bson.M{"$set": bson.M{
+			field:        markers,
+			"updated_at": time.Now(),
+		}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{arrayFilter},
+		}),
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to save markers: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrMarkerTargetNotFound
+	}
+
+	return nil
+}
+
+// IntroEpisodes returns the episodes of a season that have a full-length
+// video to fingerprint, or ErrIntroEpisodes when there are too few to compare
+func (ms *MarkerService) IntroEpisodes(ctx context.Context, contentID, seasonID primitive.ObjectID) ([]models.Episode, error) {
+	var content models.Content
+	err := ms.db.Collection("content").FindOne(ctx, bson.M{"_id": contentID, "seasons._id": seasonID}).Decode(&content)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSeasonNotFound
+		}
+		return nil, fmt.Errorf("failed to load content: %v", err)
+	}
+
+	var season *models.Season
+	for i := range content.Seasons {
+		if content.Seasons[i].ID == seasonID {
+			season = &content.Seasons[i]
+		}
+	}
+	if season == nil {
+		return nil, ErrSeasonNotFound
+	}
+
+	var episodes []models.Episode
+	for _, episode := range season.Episodes {
+		if lowestFullVideo(episode.Videos) != nil {
+			episodes = append(episodes, episode)
+		}
+	}
+
+	if len(episodes) < 2 {
+		return nil, ErrIntroEpisodes
+	}
+
+	return episodes, nil
+}
+
+// DetectIntros fingerprints the opening of every episode in a season, one
+// hash per second of video, and looks for the longest stretch each episode
+// shares with the others. Episodes without a match get no suggestion. It reads
+// the start of every episode, so it runs as a transcode job rather than in a
+// request; progress is called after each episode.
+func (ms *MarkerService) DetectIntros(ctx context.Context, contentID, seasonID primitive.ObjectID, progress func(float64)) ([]models.IntroSuggestion, error) {
+	episodes, err := ms.IntroEpisodes(ctx, contentID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints := make([][]frameHash, len(episodes))
+	for i, episode := range episodes {
+		fingerprint, err := ms.fingerprint(ctx, *lowestFullVideo(episode.Videos))
+		if err != nil {
+			return nil, fmt.Errorf("episode %d: %v", episode.EpisodeNumber, err)
+		}
+
+		fingerprints[i] = fingerprint
+		progress(float64(i+1) / float64(len(episodes)) * 100)
+	}
+
+	type match struct{ start, length int }
+	matches := make([][]match, len(episodes))
+	for i := range episodes {
+		matches[i] = make([]match, len(episodes))
+	}
+
+	for i := range episodes {
+		for j := i + 1; j < len(episodes); j++ {
+			startI, startJ, length := longestSharedRun(fingerprints[i], fingerprints[j])
+			if length >= minIntroSeconds {
+				matches[i][j] = match{startI, length}
+				matches[j][i] = match{startJ, length}
+			}
+		}
+	}
+
+	suggestions := []models.IntroSuggestion{}
+	for i, episode := range episodes {
+		// The longest match is the candidate; others that overlap most of it agree
+		best := match{}
+		for _, m := range matches[i] {
+			if m.length > best.length {
+				best = m
+			}
+		}
+		if best.length == 0 {
+			continue
+		}
+
+		agreeing := 0
+		for _, m := range matches[i] {
+			if m.length == 0 {
+				continue
+			}
+			overlap := min(m.start+m.length, best.start+best.length) - max(m.start, best.start)
+			if overlap*2 >= best.length {
+				agreeing++
+			}
+		}
+
+		suggestions = append(suggestions, models.IntroSuggestion{
+			EpisodeID:       episode.ID,
+			EpisodeNumber:   episode.EpisodeNumber,
+			Start:           float64(best.start),
+			End:             float64(best.start + best.length),
+			MatchedEpisodes: agreeing,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// ApplyIntros stores suggestions as detected intro markers. Episodes whose
+// intro was set by hand keep it.
+func (ms *MarkerService) ApplyIntros(ctx context.Context, contentID primitive.ObjectID, suggestions []models.IntroSuggestion) error {
+	for _, suggestion := range suggestions {
+		target := MarkerTarget{ContentID: contentID, EpisodeID: &suggestion.EpisodeID}
+
+		markers, duration, err := ms.load(ctx, target)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		marker := models.VideoMarker{
+			ID:        primitive.NewObjectID(),
+			Type:      models.MarkerTypeIntro,
+			Start:     suggestion.Start,
+			End:       suggestion.End,
+			Source:    models.MarkerSourceDetected,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		manual := false
+		kept := make([]models.VideoMarker, 0, len(markers)+1)
+		for _, existing := range markers {
+			if existing.Type == models.MarkerTypeIntro {
+				if existing.Source == models.MarkerSourceManual {
+					manual = true
+				}
+				continue
+			}
+			kept = append(kept, existing)
+		}
+		if manual {
+			continue
+		}
+
+		if err := validateMarker(marker, duration, kept); err != nil {
+			continue
+		}
+
+		if err := ms.save(ctx, target, append(kept, marker)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fingerprint decodes one frame per second of the start of a video at 9x8
+// grayscale and returns the difference hash of each. ffmpeg reads the video
+// where it is stored and stops after introScanSeconds, so only the opening of
+// a file on S3 is downloaded.
+func (ms *MarkerService) fingerprint(ctx context.Context, video models.ContentVideo) ([]frameHash, error) {
+	source, err := ms.storage.Source(ctx, ms.storage.PathFromURL(video.FileURL), time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("source for %s video not found: %v", video.Quality, err)
+	}
+
+	cmd := exec.CommandContext(ctx, ms.config.Video.FFmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-t", strconv.Itoa(introScanSeconds),
+		"-i", source,
+		"-map", "0:v:0",
+		"-vf", "fps=1,scale=9:8,format=gray",
+		"-f", "rawvideo",
+		"pipe:1",
+	)
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed to fingerprint video: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	const frameSize = 9 * 8
+	hashes := make([]frameHash, 0, len(output)/frameSize)
+	for offset := 0; offset+frameSize <= len(output); offset += frameSize {
+		frame := output[offset : offset+frameSize]
+
+		var hash uint64
+		for row := 0; row < 8; row++ {
+			for col := 0; col < 8; col++ {
+				hash <<= 1
+				if frame[row*9+col] < frame[row*9+col+1] {
+					hash |= 1
+				}
+			}
+		}
+
+		low, high := frame[0], frame[0]
+		for _, pixel := range frame {
+			low = min(low, pixel)
+			high = max(high, pixel)
+		}
+
+		hashes = append(hashes, frameHash{hash: hash, valid: int(high)-int(low) >= minFrameContrast})
+	}
+
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("ffmpeg returned no frames: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return hashes, nil
+}
+
+// longestSharedRun finds the longest stretch of consecutive seconds two
+// fingerprints have in common. Blank frames continue a run but at least half
+// of it must be frames with content, so shared fades to black do not count.
+func longestSharedRun(a, b []frameHash) (int, int, int) {
+	bestA, bestB, bestLength := 0, 0, 0
+
+	runs := make([]int, len(b)+1)
+	valid := make([]int, len(b)+1)
+	prevRuns := make([]int, len(b)+1)
+	prevValid := make([]int, len(b)+1)
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			x, y := a[i-1], b[j-1]
+
+			switch {
+			case x.valid && y.valid && bits.OnesCount64(x.hash^y.hash) <= maxFrameHashDistance:
+				runs[j] = prevRuns[j-1] + 1
+				valid[j] = prevValid[j-1] + 1
+			case !x.valid && !y.valid:
+				runs[j] = prevRuns[j-1] + 1
+				valid[j] = prevValid[j-1]
+			default:
+				runs[j], valid[j] = 0, 0
+			}
+
+			if runs[j] > bestLength && valid[j]*2 >= runs[j] {
+				bestA, bestB, bestLength = i-runs[j], j-runs[j], runs[j]
+			}
+		}
+
+		runs, prevRuns = prevRuns, runs
+		valid, prevValid = prevValid, valid
+	}
+
+	return bestA, bestB, bestLength
+}
+
+// lowestFullVideo returns the cheapest full-length rendition to decode
+func lowestFullVideo(videos []models.ContentVideo) *models.ContentVideo {
+	var lowest *models.ContentVideo
+	for i := range videos {
+		if videos[i].Type != models.VideoTypeFull || videos[i].FileURL == "" {
+			continue
+		}
+		if lowest == nil || qualityBandwidth[videos[i].Quality] < qualityBandwidth[lowest.Quality] {
+			lowest = &videos[i]
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,837 @@
+// backend/internal/services/party.go
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	watchPartiesCollection = "watch_parties"
+	partyChatHistory       = 100
+	partyChatMaxLength     = 500
+	partyChatInterval      = 500 * time.Millisecond
+	partyTicketLifetime    = time.Minute
+	partyMessageLimit      = 4096
+	partySendBuffer        = 32
+	partyWriteTimeout      = 10 * time.Second
+	partyPongTimeout       = 60 * time.Second
+	// Pings also extend the participant's stream session, so this must stay
+	// well inside StreamTimeout
+	partyPingInterval = 25 * time.Second
+	// Rates a drifting player is nudged to until it is back in sync
+	partyCatchUpRate  = 1.05
+	partyFallBackRate = 0.95
+)
+
+var (
+	ErrPartyNotFound = errors.New("watch party not found")
+	ErrPartyNotHost  = errors.New("only the host can do that")
+	ErrPartyFull     = errors.New("watch party is full")
+	ErrPartyTicket   = errors.New("invalid or expired watch party ticket")
+)
+
+// PartyInviteError lists invited emails that do not belong to an active account
+type PartyInviteError struct {
+	Emails []string `json:"emails"`
+}
+
+func (e *PartyInviteError) Error() string {
+	return fmt.Sprintf("no active account for %s", strings.Join(e.Emails, ", "))
+}
+
+// PartyCommand is a message from a participant's socket:
+//
+//	play, pause   {"type":"play","position":12.5}   position is optional
+//	seek          {"type":"seek","position":300}
+//	position      {"type":"position","position":42.1}   sent every few seconds while watching
+//	chat          {"type":"chat","text":"..."}
+//	transfer_host {"type":"transfer_host","user_id":"..."}
+//	ping          {"type":"ping","client_time":1700000000000}
+//
+// Only the host may play, pause and seek unless the party allows guest control.
+type PartyCommand struct {
+	Type       string   `json:"type"`
+	Position   *float64 `json:"position"`
+	Text       string   `json:"text"`
+	UserID     string   `json:"user_id"`
+	ClientTime int64    `json:"client_time"`
+}
+
+// PartyEvent is a message sent to participants. Types are welcome, state,
+// sync, presence, chat, pong, error and ended. A sync event asks one player
+// to seek to position, or to play at rate until told to return to 1.
+type PartyEvent struct {
+	Type       string                     `json:"type"`
+	Party      *models.WatchParty         `json:"party,omitempty"`
+	Playback   *models.WatchPartyPlayback `json:"playback,omitempty"`
+	HostID     string                     `json:"host_id,omitempty"`
+	UserID     string                     `json:"user_id,omitempty"`
+	Members    []PartyPresence            `json:"members,omitempty"`
+	Message    *models.WatchPartyMessage  `json:"message,omitempty"`
+	Action     string                     `json:"action,omitempty"`
+	Position   *float64                   `json:"position,omitempty"`
+	Rate       float64                    `json:"rate,omitempty"`
+	ClientTime int64                      `json:"client_time,omitempty"`
+	ServerTime int64                      `json:"server_time"` // Unix milliseconds
+	Error      string                     `json:"error,omitempty"`
+}
+
+// PartyPresence is a participant currently connected to a party
+type PartyPresence struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+// WatchPartyService keeps watch parties and runs the WebSocket rooms that
+// sync them. A room lives in the process holding its connections, so all
+// participants of a party must reach the same instance.
+type WatchPartyService struct {
+	config  *config.Config
+	db      *mongo.Database
+	streams *StreamService
+
+	mu    sync.Mutex
+	rooms map[primitive.ObjectID]*partyRoom
+}
+
+type partyRoom struct {
+	id primitive.ObjectID
+
+	mu           sync.Mutex
+	clients      []*partyClient // In the order they connected
+	hostID       primitive.ObjectID
+	guestControl bool
+	playback     models.WatchPartyPlayback
+	closed       bool
+}
+
+type partyClient struct {
+	conn      *websocket.Conn
+	userID    primitive.ObjectID
+	name      string
+	sessionID primitive.ObjectID
+	// Guarded by the room's lock
+	send     chan []byte
+	closed   bool
+	rate     float64
+	lastChat time.Time
+}
+
+func NewWatchPartyService(cfg *config.Config, db *mongo.Database, streams *StreamService) *WatchPartyService {
+	return &WatchPartyService{
+		config:  cfg,
+		db:      db,
+		streams: streams,
+		rooms:   make(map[primitive.ObjectID]*partyRoom),
+	}
+}
+
+// Close ends every open connection
+func (ps *WatchPartyService) Close() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for id, room := range ps.rooms {
+		room.mu.Lock()
+		room.closed = true
+		for _, client := range room.clients {
+			room.drop(client)
+		}
+		room.mu.Unlock()
+		delete(ps.rooms, id)
+	}
+}
+
+// CreateParty starts a party hosted by host for a title, or an episode of
+// it, and invites the accounts behind emails
+func (ps *WatchPartyService) CreateParty(ctx context.Context, host *models.User, contentID primitive.ObjectID, episodeID *primitive.ObjectID, title string, emails []string, guestControl bool) (*models.WatchParty, error) {
+	invited, err := ps.resolveInvitees(ctx, host, emails)
+	if err != nil {
+		return nil, err
+	}
+	if len(invited)+1 > ps.config.Video.PartyMaxMembers {
+		return nil, ErrPartyFull
+	}
+
+	now := time.Now()
+	party := models.WatchParty{
+		ID:           primitive.NewObjectID(),
+		HostID:       host.ID,
+		ContentID:    contentID,
+		EpisodeID:    episodeID,
+		Title:        title,
+		Invited:      invited,
+		Members:      []models.WatchPartyMember{},
+		GuestControl: guestControl,
+		Status:       models.WatchPartyStatusActive,
+		Playback:     models.WatchPartyPlayback{UpdatedAt: now},
+		Chat:         []models.WatchPartyMessage{},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		ExpiresAt:    now.Add(ps.idleTimeout()),
+	}
+
+	if _, err := ps.db.Collection(watchPartiesCollection).InsertOne(ctx, party); err != nil {
+		return nil, fmt.Errorf("failed to create watch party: %v", err)
+	}
+
+	return &party, nil
+}
+
+// Invite adds accounts to a party. Only the host may invite.
+func (ps *WatchPartyService) Invite(ctx context.Context, partyID primitive.ObjectID, host *models.User, emails []string) (*models.WatchParty, error) {
+	party, err := ps.Party(ctx, partyID, host)
+	if err != nil {
+		return nil, err
+	}
+	if party.HostID != host.ID {
+		return nil, ErrPartyNotHost
+	}
+
+	invitees, err := ps.resolveInvitees(ctx, host, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	invited := party.Invited
+	for _, id := range invitees {
+		if !containsObjectID(invited, id) {
+			invited = append(invited, id)
+		}
+	}
+	if len(invited)+1 > ps.config.Video.PartyMaxMembers {
+		return nil, ErrPartyFull
+	}
+
+	if err := ps.saveParty(ctx, partyID, bson.M{"invited": invited}); err != nil {
+		return nil, err
+	}
+
+	party.Invited = invited
+	return party, nil
+}
+
+// Party loads an active party the user hosts or is invited to
+func (ps *WatchPartyService) Party(ctx context.Context, partyID primitive.ObjectID, user *models.User) (*models.WatchParty, error) {
+	var party models.WatchParty
+	err := ps.db.Collection(watchPartiesCollection).FindOne(ctx, bson.M{
+		"_id":        partyID,
+		"status":     models.WatchPartyStatusActive,
+		"expires_at": bson.M{"$gt": time.Now()},
+		"$or": []bson.M{
+			{"host_id": user.ID},
+			{"invited": user.ID},
+		},
+	}).Decode(&party)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPartyNotFound
+		}
+		return nil, fmt.Errorf("failed to load watch party: %v", err)
+	}
+
+	return &party, nil
+}
+
+// Parties lists the active parties a user hosts or is invited to, newest first
+func (ps *WatchPartyService) Parties(ctx context.Context, userID primitive.ObjectID) ([]models.WatchParty, error) {
+	cursor, err := ps.db.Collection(watchPartiesCollection).Find(
+		ctx,
+		bson.M{
+			"status":     models.WatchPartyStatusActive,
+			"expires_at": bson.M{"$gt": time.Now()},
+			"$or": []bson.M{
+				{"host_id": userID},
+				{"invited": userID},
+			},
+		},
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetProjection(bson.M{"chat": 0}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watch parties: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	parties := []models.WatchParty{}
+	if err := cursor.All(ctx, &parties); err != nil {
+		return nil, fmt.Errorf("failed to decode watch parties: %v", err)
+	}
+
+	return parties, nil
+}
+
+// End closes a party for everyone. Only the host may end it.
+func (ps *WatchPartyService) End(ctx context.Context, partyID primitive.ObjectID, host *models.User) error {
+	party, err := ps.Party(ctx, partyID, host)
+	if err != nil {
+		return err
+	}
+	if party.HostID != host.ID {
+		return ErrPartyNotHost
+	}
+
+	now := time.Now()
+	if err := ps.saveParty(ctx, partyID, bson.M{"status": models.WatchPartyStatusEnded, "ended_at": now}); err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if room, ok := ps.rooms[partyID]; ok {
+		room.mu.Lock()
+		room.closed = true
+		room.broadcast(PartyEvent{Type: "ended"})
+		for _, client := range room.clients {
+			room.drop(client)
+		}
+		room.mu.Unlock()
+		delete(ps.rooms, partyID)
+	}
+
+	return nil
+}
+
+// IssueTicket signs the short-lived ticket a participant opens the party
+// socket with. Browsers cannot send the API's bearer token on a WebSocket
+// handshake, and the ticket also carries the stream session the
+// participant was given when joining.
+func (ps *WatchPartyService) IssueTicket(partyID, userID, sessionID primitive.ObjectID) string {
+	payload := fmt.Sprintf("%s:%s:%s:%d", partyID.Hex(), userID.Hex(), sessionID.Hex(), time.Now().Add(partyTicketLifetime).Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + ps.signTicket(payload)
+}
+
+// ParseTicket checks a ticket for partyID and returns the user and stream session it names
+func (ps *WatchPartyService) ParseTicket(ticket string, partyID primitive.ObjectID) (primitive.ObjectID, primitive.ObjectID, error) {
+	encoded, signature, ok := strings.Cut(ticket, ".")
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrPartyTicket
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(ps.signTicket(string(raw)))) {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrPartyTicket
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[0] != partyID.Hex() {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrPartyTicket
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrPartyTicket
+	}
+
+	userID, err1 := primitive.ObjectIDFromHex(parts[1])
+	sessionID, err2 := primitive.ObjectIDFromHex(parts[2])
+	if err1 != nil || err2 != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, ErrPartyTicket
+	}
+
+	return userID, sessionID, nil
+}
+
+func (ps *WatchPartyService) signTicket(payload string) string {
+	mac := hmac.New(sha256.New, []byte(ps.config.JWT.Secret))
+	mac.Write([]byte("watch-party:" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Serve runs a participant's socket until it disconnects or the party
+// ends. The participant's stream session is kept alive while connected
+// and stopped when they leave.
+func (ps *WatchPartyService) Serve(conn *websocket.Conn, partyID primitive.ObjectID, user *models.User, sessionID primitive.ObjectID) {
+	defer conn.Close()
+	defer ps.streams.StopStream(context.Background(), user.ID, sessionID)
+
+	party, err := ps.Party(context.Background(), partyID, user)
+	if err != nil {
+		closeParty(conn, websocket.ClosePolicyViolation, err.Error())
+		return
+	}
+
+	client := &partyClient{
+		conn:      conn,
+		userID:    user.ID,
+		name:      strings.TrimSpace(user.FirstName + " " + user.LastName),
+		sessionID: sessionID,
+		send:      make(chan []byte, partySendBuffer),
+		rate:      1,
+	}
+
+	room := ps.join(party, client)
+	go ps.writeLoop(client)
+	ps.readLoop(room, client)
+	ps.leave(room, client)
+}
+
+func (ps *WatchPartyService) join(party *models.WatchParty, client *partyClient) *partyRoom {
+	ps.mu.Lock()
+	room, ok := ps.rooms[party.ID]
+	if !ok {
+		room = &partyRoom{
+			id:           party.ID,
+			hostID:       party.HostID,
+			guestControl: party.GuestControl,
+			playback:     party.Playback,
+		}
+		ps.rooms[party.ID] = room
+	}
+
+	room.mu.Lock()
+	room.clients = append(room.clients, client)
+	playback := room.playback
+	room.send(client, PartyEvent{
+		Type:     "welcome",
+		Party:    party,
+		Playback: &playback,
+		HostID:   room.hostID.Hex(),
+		UserID:   client.userID.Hex(),
+		Members:  room.presence(),
+	})
+	room.broadcast(PartyEvent{Type: "presence", HostID: room.hostID.Hex(), Members: room.presence()})
+	room.mu.Unlock()
+	ps.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), partyWriteTimeout)
+	defer cancel()
+
+	now := time.Now()
+	_, err := ps.db.Collection(watchPartiesCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": party.ID, "members.user_id": bson.M{"$ne": client.userID}},
+		bson.M{"$push": bson.M{"members": models.WatchPartyMember{UserID: client.userID, Name: client.name, JoinedAt: now}}},
+	)
+	if err != nil {
+		fmt.Printf("Failed to record watch party member for %s: %v\n", party.ID.Hex(), err)
+	}
+	ps.touch(party.ID, bson.M{})
+
+	return room
+}
+
+func (ps *WatchPartyService) leave(room *partyRoom, client *partyClient) {
+	ps.mu.Lock()
+	room.mu.Lock()
+
+	for i, c := range room.clients {
+		if c == client {
+			room.clients = append(room.clients[:i], room.clients[i+1:]...)
+			break
+		}
+	}
+	room.drop(client)
+
+	set := bson.M{}
+	if !room.closed {
+		if len(room.clients) == 0 {
+			// Nobody is left to keep the clock running
+			room.playback = models.WatchPartyPlayback{Position: partyPosition(room.playback, time.Now()), UpdatedAt: time.Now()}
+			room.closed = true
+			delete(ps.rooms, room.id)
+			set["playback"] = room.playback
+		} else {
+			if client.userID == room.hostID && !room.connected(room.hostID) {
+				// The host role passes to whoever has been connected longest
+				room.hostID = room.clients[0].userID
+				set["host_id"] = room.hostID
+			}
+			room.broadcast(PartyEvent{Type: "presence", HostID: room.hostID.Hex(), Members: room.presence()})
+		}
+	}
+
+	room.mu.Unlock()
+	ps.mu.Unlock()
+
+	ps.touch(room.id, set)
+}
+
+func (ps *WatchPartyService) readLoop(room *partyRoom, client *partyClient) {
+	conn := client.conn
+	conn.SetReadLimit(partyMessageLimit)
+	conn.SetReadDeadline(time.Now().Add(partyPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(partyPongTimeout))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(partyPongTimeout))
+
+		var command PartyCommand
+		if err := json.Unmarshal(data, &command); err != nil {
+			ps.reply(room, client, PartyEvent{Type: "error", Error: "Invalid message"})
+			continue
+		}
+
+		ps.handle(room, client, command)
+	}
+}
+
+// writeLoop sends queued events, pings the player and keeps its stream
+// session alive. It closes the connection when the queue is closed or the
+// session can no longer be extended.
+func (ps *WatchPartyService) writeLoop(client *partyClient) {
+	conn := client.conn
+	ticker := time.NewTicker(partyPingInterval)
+	defer ticker.Stop()
+	defer conn.Close()
+
+	for {
+		select {
+		case data, ok := <-client.send:
+			if !ok {
+				closeParty(conn, websocket.CloseNormalClosure, "")
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(partyWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(partyWriteTimeout)); err != nil {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), partyWriteTimeout)
+			_, err := ps.streams.Heartbeat(ctx, client.userID, client.sessionID)
+			cancel()
+			if err != nil {
+				closeParty(conn, websocket.ClosePolicyViolation, "stream session ended")
+				return
+			}
+		}
+	}
+}
+
+func (ps *WatchPartyService) handle(room *partyRoom, client *partyClient, command PartyCommand) {
+	now := time.Now()
+
+	switch command.Type {
+	case "play", "pause", "seek":
+		if command.Type == "seek" && command.Position == nil {
+			ps.reply(room, client, PartyEvent{Type: "error", Error: "seek needs a position"})
+			return
+		}
+
+		room.mu.Lock()
+		if room.hostID != client.userID && !room.guestControl {
+			room.send(client, PartyEvent{Type: "error", Error: ErrPartyNotHost.Error()})
+			room.mu.Unlock()
+			return
+		}
+
+		position := partyPosition(room.playback, now)
+		if command.Position != nil {
+			position = math.Max(*command.Position, 0)
+		}
+		room.playback = models.WatchPartyPlayback{
+			Playing:   command.Type == "play" || (command.Type == "seek" && room.playback.Playing),
+			Position:  position,
+			UpdatedAt: now,
+		}
+		// Everyone restarts from the new anchor at normal speed
+		for _, c := range room.clients {
+			c.rate = 1
+		}
+		playback := room.playback
+		room.broadcast(PartyEvent{Type: "state", Playback: &playback, HostID: room.hostID.Hex(), UserID: client.userID.Hex()})
+		room.mu.Unlock()
+
+		ps.touch(room.id, bson.M{"playback": playback})
+
+	case "position":
+		if command.Position == nil {
+			return
+		}
+		room.mu.Lock()
+		ps.correctDrift(room, client, *command.Position, now)
+		room.mu.Unlock()
+
+	case "chat":
+		text := strings.TrimSpace(command.Text)
+		if text == "" || utf8.RuneCountInString(text) > partyChatMaxLength {
+			ps.reply(room, client, PartyEvent{Type: "error", Error: fmt.Sprintf("Messages must be between 1 and %d characters", partyChatMaxLength)})
+			return
+		}
+
+		room.mu.Lock()
+		if now.Sub(client.lastChat) < partyChatInterval {
+			room.send(client, PartyEvent{Type: "error", Error: "You are sending messages too quickly"})
+			room.mu.Unlock()
+			return
+		}
+		client.lastChat = now
+		message := models.WatchPartyMessage{
+			ID:     primitive.NewObjectID(),
+			UserID: client.userID,
+			Name:   client.name,
+			Text:   text,
+			SentAt: now,
+		}
+		room.broadcast(PartyEvent{Type: "chat", Message: &message})
+		room.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), partyWriteTimeout)
+		defer cancel()
+		_, err := ps.db.Collection(watchPartiesCollection).UpdateOne(ctx, bson.M{"_id": room.id}, bson.M{
+			"$push": bson.M{"chat": bson.M{"$each": []models.WatchPartyMessage{message}, "$slice": -partyChatHistory}},
+		})
+		if err != nil {
+			fmt.Printf("Failed to save watch party chat for %s: %v\n", room.id.Hex(), err)
+		}
+
+	case "transfer_host":
+		target, err := primitive.ObjectIDFromHex(command.UserID)
+
+		room.mu.Lock()
+		switch {
+		case room.hostID != client.userID:
+			room.send(client, PartyEvent{Type: "error", Error: ErrPartyNotHost.Error()})
+		case err != nil || !room.connected(target):
+			room.send(client, PartyEvent{Type: "error", Error: "The new host must be connected to the party"})
+		default:
+			room.hostID = target
+			room.broadcast(PartyEvent{Type: "presence", HostID: room.hostID.Hex(), Members: room.presence()})
+		}
+		hostID := room.hostID
+		room.mu.Unlock()
+
+		ps.touch(room.id, bson.M{"host_id": hostID})
+
+	case "ping":
+		ps.reply(room, client, PartyEvent{Type: "pong", ClientTime: command.ClientTime})
+
+	default:
+		ps.reply(room, client, PartyEvent{Type: "error", Error: "Unknown message type"})
+	}
+}
+
+// correctDrift compares a player's reported position with the shared clock.
+// Small drift is corrected by nudging the playback rate, large drift by a
+// seek. The host's player is the reference instead: when it drifts, for
+// example while buffering, the clock is re-anchored to it so guests follow.
+// Caller holds the room lock.
+func (ps *WatchPartyService) correctDrift(room *partyRoom, client *partyClient, reported float64, now time.Time) {
+	expected := partyPosition(room.playback, now)
+	drift := reported - expected
+	tolerance := float64(ps.config.Video.PartyDriftTolerance) / 1000
+	seekThreshold := float64(ps.config.Video.PartySeekThreshold) / 1000
+
+	if client.userID == room.hostID && room.playback.Playing {
+		if math.Abs(drift) > tolerance {
+			room.playback.Position = math.Max(reported, 0)
+			room.playback.UpdatedAt = now
+		}
+		return
+	}
+
+	switch {
+	case math.Abs(drift) >= seekThreshold || (!room.playback.Playing && math.Abs(drift) > tolerance):
+		client.rate = 1
+		room.send(client, PartyEvent{Type: "sync", Action: "seek", Position: &expected, Rate: 1})
+	case math.Abs(drift) > tolerance:
+		rate := partyCatchUpRate
+		if drift > 0 {
+			rate = partyFallBackRate
+		}
+		if client.rate != rate {
+			client.rate = rate
+			room.send(client, PartyEvent{Type: "sync", Action: "rate", Position: &expected, Rate: rate})
+		}
+	case client.rate != 1 && math.Abs(drift) < tolerance/2:
+		client.rate = 1
+		room.send(client, PartyEvent{Type: "sync", Action: "rate", Position: &expected, Rate: 1})
+	}
+}
+
+func (ps *WatchPartyService) reply(room *partyRoom, client *partyClient, event PartyEvent) {
+	room.mu.Lock()
+	room.send(client, event)
+	room.mu.Unlock()
+}
+
+// touch saves set on a party and pushes back its expiry
+func (ps *WatchPartyService) touch(partyID primitive.ObjectID, set bson.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), partyWriteTimeout)
+	defer cancel()
+
+	if err := ps.saveParty(ctx, partyID, set); err != nil {
+		fmt.Printf("Failed to update watch party %s: %v\n", partyID.Hex(), err)
+	}
+}
+
+func (ps *WatchPartyService) saveParty(ctx context.Context, partyID primitive.ObjectID, set bson.M) error {
+	now := time.Now()
+	set["updated_at"] = now
+	set["expires_at"] = now.Add(ps.idleTimeout())
+
+	result, err := ps.db.Collection(watchPartiesCollection).UpdateOne(ctx, bson.M{"_id": partyID}, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to save watch party: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrPartyNotFound
+	}
+	return nil
+}
+
+// resolveInvitees looks up the active accounts behind emails, leaving out the host
+func (ps *WatchPartyService) resolveInvitees(ctx context.Context, host *models.User, emails []string) ([]primitive.ObjectID, error) {
+	wanted := []string{}
+	seen := map[string]bool{strings.ToLower(host.Email): true}
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email != "" && !seen[strings.ToLower(email)] {
+			seen[strings.ToLower(email)] = true
+			wanted = append(wanted, email)
+		}
+	}
+	if len(wanted) == 0 {
+		return []primitive.ObjectID{}, nil
+	}
+
+	cursor, err := ps.db.Collection("users").Find(
+		ctx,
+		bson.M{"email": bson.M{"$in": wanted}, "is_active": true},
+		options.Find().SetProjection(bson.M{"_id": 1, "email": 1}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up invitees: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode invitees: %v", err)
+	}
+
+	found := make(map[string]bool)
+	ids := []primitive.ObjectID{}
+	for _, user := range users {
+		found[user.Email] = true
+		ids = append(ids, user.ID)
+	}
+
+	var missing []string
+	for _, email := range wanted {
+		if !found[email] {
+			missing = append(missing, email)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &PartyInviteError{Emails: missing}
+	}
+
+	return ids, nil
+}
+
+func (ps *WatchPartyService) idleTimeout() time.Duration {
+	return time.Duration(ps.config.Video.PartyIdleHours) * time.Hour
+}
+
+// send queues an event for one client. A client too slow to keep up with
+// its queue is disconnected. Caller holds the room lock.
+func (r *partyRoom) send(client *partyClient, event PartyEvent) {
+	if client.closed {
+		return
+	}
+
+	event.ServerTime = time.Now().UnixMilli()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+		r.drop(client)
+	}
+}
+
+func (r *partyRoom) broadcast(event PartyEvent) {
+	for _, client := range r.clients {
+		r.send(client, event)
+	}
+}
+
+// drop closes a client's queue, which makes its write loop hang up
+func (r *partyRoom) drop(client *partyClient) {
+	if !client.closed {
+		client.closed = true
+		close(client.send)
+	}
+}
+
+func (r *partyRoom) connected(userID primitive.ObjectID) bool {
+	for _, client := range r.clients {
+		if client.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// presence lists connected participants once each, however many devices they use
+func (r *partyRoom) presence() []PartyPresence {
+	members := []PartyPresence{}
+	seen := make(map[primitive.ObjectID]bool)
+	for _, client := range r.clients {
+		if !seen[client.userID] {
+			seen[client.userID] = true
+			members = append(members, PartyPresence{UserID: client.userID.Hex(), Name: client.name})
+		}
+	}
+	return members
+}
+
+// partyPosition is where the shared clock is at now
+func partyPosition(playback models.WatchPartyPlayback, now time.Time) float64 {
+	if !playback.Playing {
+		return playback.Position
+	}
+	return playback.Position + now.Sub(playback.UpdatedAt).Seconds()
+}
+
+func closeParty(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(partyWriteTimeout))
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
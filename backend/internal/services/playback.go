@@ -0,0 +1,743 @@
+// backend/internal/services/playback.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	playbackSessionsCollection = "playback_sessions"
+	playbackEventsCollection   = "playback_events"
+	playbackRollupsCollection  = "playback_rollups"
+	playbackRollupInterval     = 10 * time.Minute
+	// Players should report at least this often so a session shows up in
+	// every minute of the peak concurrency count
+	playbackHeartbeatInterval = 30 * time.Second
+	// Longest stretch of playback a single event may account for
+	maxPlaybackEventSeconds = 10 * 60
+	// Share of a title that must have been reached for a session to count as completed
+	playbackCompletionThreshold = 0.9
+	// Weight of each new throughput sample in a session's bandwidth estimate
+	bandwidthSmoothing = 0.3
+)
+
+var (
+	ErrPlaybackSessionNotFound = errors.New("playback session not found")
+	ErrPlaybackSessionEnded    = errors.New("playback session has already ended")
+	ErrPlaybackEventType       = errors.New("event type must be heartbeat, bitrate_switch, rebuffer, error or stop")
+)
+
+// PlaybackStart describes the player state when playback began
+type PlaybackStart struct {
+	ProfileID     *primitive.ObjectID
+	ContentID     primitive.ObjectID
+	EpisodeID     *primitive.ObjectID
+	Autoplayed    bool
+	DeviceID      string
+	DeviceType    string
+	Quality       models.VideoQuality
+	BitrateKbps   int
+	StartupTimeMs int64
+	Position      float64
+	Duration      float64
+}
+
+// PlaybackEventInput is an event reported by a player after the session started
+type PlaybackEventInput struct {
+	Type          models.PlaybackEventType `json:"type"`
+	Timestamp     *time.Time               `json:"timestamp"`
+	Quality       models.VideoQuality      `json:"quality"`
+	BitrateKbps   int                      `json:"bitrate_kbps"`
+	PlayedSeconds float64                  `json:"played_seconds"` // Time spent playing since the previous event
+	Position      *float64                 `json:"position"`
+	DurationMs    int64                    `json:"duration_ms"` // Stall length for rebuffer events
+	ErrorCode     string                   `json:"error_code"`
+	ErrorMessage  string                   `json:"error_message"`
+}
+
+// ThroughputSample is one segment download measured by a player
+type ThroughputSample struct {
+	Bytes      int64 `json:"bytes"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// PlaybackConditions is what a player reports when asking for ABR advice
+type PlaybackConditions struct {
+	Samples            []ThroughputSample     `json:"samples"`
+	BufferHealth       float64                `json:"buffer_health"` // Share of the target buffer filled, 0 to 1
+	DeviceCapabilities map[string]interface{} `json:"device_capabilities"`
+}
+
+// PlaybackAdvice is the rendition a player should switch to
+type PlaybackAdvice struct {
+	Quality        models.VideoQuality `json:"quality"`
+	CurrentQuality models.VideoQuality `json:"current_quality"`
+	MaxQuality     models.VideoQuality `json:"max_quality"` // Highest rendition the plan, data saver and device allow
+	BandwidthBps   int                 `json:"bandwidth_bps"`
+}
+
+// PlaybackQuery selects rollups by day (UTC, inclusive) and optional dimensions
+type PlaybackQuery struct {
+	From       time.Time
+	To         time.Time
+	ContentID  *primitive.ObjectID
+	Quality    models.VideoQuality
+	DeviceType string
+}
+
+// PlaybackSummary combines the rollups matched by a PlaybackQuery
+type PlaybackSummary struct {
+	Sessions           int     `json:"sessions" bson:"sessions"`
+	UniqueViewers      int     `json:"unique_viewers" bson:"unique_viewers"`
+	PlayedSeconds      float64 `json:"played_seconds" bson:"played_seconds"`
+	RebufferSeconds    float64 `json:"rebuffer_seconds" bson:"rebuffer_seconds"`
+	RebufferRatio      float64 `json:"rebuffer_ratio" bson:"rebuffer_ratio"`
+	AverageStartupMs   float64 `json:"average_startup_ms" bson:"average_startup_ms"`
+	AverageBitrateKbps float64 `json:"average_bitrate_kbps" bson:"average_bitrate_kbps"`
+	CompletionRate     float64 `json:"completion_rate" bson:"completion_rate"`
+	Errors             int     `json:"errors" bson:"errors"`
+	PeakConcurrency    int     `json:"peak_concurrency" bson:"peak_concurrency"`
+}
+
+// PlaybackService records playback sessions reported by players and rolls
+// their events up into daily quality-of-experience aggregates
+type PlaybackService struct {
+	config *config.Config
+	db     *mongo.Database
+	video  *VideoService
+	cancel context.CancelFunc
+}
+
+func NewPlaybackService(cfg *config.Config, db *mongo.Database, video *VideoService) *PlaybackService {
+	return &PlaybackService{
+		config: cfg,
+		db:     db,
+		video:  video,
+	}
+}
+
+// Start periodically rolls up today's and yesterday's events, so events that
+// arrive late from reconnecting players still reach the previous day
+func (ps *PlaybackService) Start() {
+	if ps.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ps.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(playbackRollupInterval)
+		defer ticker.Stop()
+
+		for {
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+				if err := ps.RollupDay(ctx, day); err != nil && ctx.Err() == nil {
+					fmt.Printf("Failed to roll up playback events for %s: %v\n", day.Format("2006-01-02"), err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (ps *PlaybackService) Close() {
+	if ps.cancel != nil {
+		ps.cancel()
+	}
+}
+
+// HeartbeatInterval is how often players should send heartbeat events
+func (ps *PlaybackService) HeartbeatInterval() time.Duration {
+	return playbackHeartbeatInterval
+}
+
+// StartSession opens a playback session and records its start event
+func (ps *PlaybackService) StartSession(ctx context.Context, userID primitive.ObjectID, start PlaybackStart) (*models.PlaybackSession, error) {
+	now := time.Now()
+
+	session := models.PlaybackSession{
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		ProfileID:     start.ProfileID,
+		ContentID:     start.ContentID,
+		EpisodeID:     start.EpisodeID,
+		Autoplayed:    start.Autoplayed,
+		DeviceID:      start.DeviceID,
+		DeviceType:    start.DeviceType,
+		Quality:       start.Quality,
+		BitrateKbps:   start.BitrateKbps,
+		StartupTimeMs: max(start.StartupTimeMs, 0),
+		Position:      start.Position,
+		Duration:      start.Duration,
+		StartedAt:     now,
+		LastEventAt:   now,
+	}
+
+	if _, err := ps.db.Collection(playbackSessionsCollection).InsertOne(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create playback session: %v", err)
+	}
+
+	event := models.PlaybackEvent{
+		ID:          primitive.NewObjectID(),
+		SessionID:   session.ID,
+		UserID:      userID,
+		ContentID:   session.ContentID,
+		DeviceType:  session.DeviceType,
+		Type:        models.PlaybackEventStart,
+		Quality:     session.Quality,
+		BitrateKbps: session.BitrateKbps,
+		Position:    session.Position,
+		DurationMs:  session.StartupTimeMs,
+		Timestamp:   now,
+	}
+
+	if _, err := ps.db.Collection(playbackEventsCollection).InsertOne(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to record playback event: %v", err)
+	}
+
+	return &session, nil
+}
+
+// RecordEvents applies a batch of events, in the order the player sent them,
+// to a session. A stop event ends the session; anything after it is dropped.
+func (ps *PlaybackService) RecordEvents(ctx context.Context, userID, sessionID primitive.ObjectID, inputs []PlaybackEventInput) (*models.PlaybackSession, error) {
+	collection := ps.db.Collection(playbackSessionsCollection)
+
+	var session models.PlaybackSession
+	err := collection.FindOne(ctx, bson.M{"_id": sessionID, "user_id": userID}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPlaybackSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to load playback session: %v", err)
+	}
+
+	if session.EndedAt != nil {
+		return nil, ErrPlaybackSessionEnded
+	}
+
+	now := time.Now()
+	var events []interface{}
+	var played, rebuffered float64
+	var rebuffers, switches, errorCount int
+	var endedAt *time.Time
+
+	for _, input := range inputs {
+		if endedAt != nil {
+			break
+		}
+
+		timestamp := now
+		if input.Timestamp != nil && !input.Timestamp.After(now) && !input.Timestamp.Before(session.StartedAt) {
+			timestamp = *input.Timestamp
+		}
+
+		if input.Quality != "" {
+			session.Quality = input.Quality
+		}
+		if input.BitrateKbps > 0 {
+			session.BitrateKbps = input.BitrateKbps
+		}
+		if input.Position != nil && *input.Position >= 0 {
+			session.Position = *input.Position
+		}
+
+		event := models.PlaybackEvent{
+			ID:            primitive.NewObjectID(),
+			SessionID:     session.ID,
+			UserID:        userID,
+			ContentID:     session.ContentID,
+			DeviceType:    session.DeviceType,
+			Type:          input.Type,
+			Quality:       session.Quality,
+			BitrateKbps:   session.BitrateKbps,
+			PlayedSeconds: min(max(input.PlayedSeconds, 0), maxPlaybackEventSeconds),
+			Position:      session.Position,
+			Timestamp:     timestamp,
+		}
+
+		switch input.Type {
+		case models.PlaybackEventHeartbeat:
+		case models.PlaybackEventBitrateSwitch:
+			switches++
+		case models.PlaybackEventRebuffer:
+			event.DurationMs = max(input.DurationMs, 0)
+			rebuffered += float64(event.DurationMs) / 1000
+			rebuffers++
+		case models.PlaybackEventError:
+			event.ErrorCode = input.ErrorCode
+			event.ErrorMessage = input.ErrorMessage
+			errorCount++
+		case models.PlaybackEventStop:
+			event.Completed = session.Duration > 0 && session.Position >= session.Duration*playbackCompletionThreshold
+			session.Completed = event.Completed
+			endedAt = &timestamp
+		default:
+			return nil, ErrPlaybackEventType
+		}
+
+		played += event.PlayedSeconds
+		session.LastEventAt = timestamp
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		return &session, nil
+	}
+
+	set := bson.M{
+		"quality":       session.Quality,
+		"bitrate_kbps":  session.BitrateKbps,
+		"position":      session.Position,
+		"completed":     session.Completed,
+		"last_event_at": session.LastEventAt,
+	}
+	if endedAt != nil {
+		set["ended_at"] = *endedAt
+	}
+
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": session.ID, "ended_at": bson.M{"$exists": false}},
+		bson.M{
+			"$set": set,
+			"$inc": bson.M{
+				"played_seconds":   played,
+				"rebuffer_seconds": rebuffered,
+				"rebuffer_count":   rebuffers,
+				"bitrate_switches": switches,
+				"errors":           errorCount,
+			},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&session)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPlaybackSessionEnded
+		}
+		return nil, fmt.Errorf("failed to update playback session: %v", err)
+	}
+
+	if _, err := ps.db.Collection(playbackEventsCollection).InsertMany(ctx, events); err != nil {
+		return nil, fmt.Errorf("failed to record playback events: %v", err)
+	}
+
+	return &session, nil
+}
+
+// Advise folds a player's throughput samples into the session's bandwidth
+// estimate and recommends a rendition. The recommendation steps at most one
+// rendition from the current one, never exceeds what the estimate sustains
+// when stepping up, and is capped by the plan, data saver and the device.
+func (ps *PlaybackService) Advise(ctx context.Context, user *models.User, sessionID primitive.ObjectID, conditions PlaybackConditions) (*PlaybackAdvice, error) {
+	collection := ps.db.Collection(playbackSessionsCollection)
+
+	var session models.PlaybackSession
+	err := collection.FindOne(ctx, bson.M{"_id": sessionID, "user_id": user.ID}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPlaybackSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to load playback session: %v", err)
+	}
+
+	if session.EndedAt != nil {
+		return nil, ErrPlaybackSessionEnded
+	}
+
+	bandwidth := float64(session.BandwidthBps)
+	for _, sample := range conditions.Samples {
+		if sample.Bytes <= 0 || sample.DurationMs <= 0 {
+			continue
+		}
+
+		measured := float64(ps.video.EstimateBandwidth(sample.Bytes, time.Duration(sample.DurationMs)*time.Millisecond))
+		if bandwidth == 0 {
+			bandwidth = measured
+		} else {
+			bandwidth = bandwidthSmoothing*measured + (1-bandwidthSmoothing)*bandwidth
+		}
+	}
+
+	if int(bandwidth) != session.BandwidthBps {
+		session.BandwidthBps = int(bandwidth)
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": session.ID}, bson.M{
+			"$set": bson.M{"bandwidth_bps": session.BandwidthBps},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update bandwidth estimate: %v", err)
+		}
+	}
+
+	ceiling, err := ps.maxQuality(ctx, user, conditions.DeviceCapabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	sustainable := ps.video.GetOptimalQuality(session.BandwidthBps, conditions.DeviceCapabilities)
+
+	current := session.Quality
+	if _, ok := qualityBandwidth[current]; !ok {
+		current = sustainable
+	}
+
+	quality := ps.video.AdaptQualityBasedOnConditions(current, conditions.BufferHealth, session.BandwidthBps)
+	if qualityBandwidth[quality] > qualityBandwidth[current] {
+		quality = lowerQuality(quality, sustainable)
+	}
+	quality = lowerQuality(quality, ceiling)
+
+	return &PlaybackAdvice{
+		Quality:        quality,
+		CurrentQuality: session.Quality,
+		MaxQuality:     ceiling,
+		BandwidthBps:   session.BandwidthBps,
+	}, nil
+}
+
+// maxQuality returns the highest rendition the user's plan, data saver
+// preference and device allow
+func (ps *PlaybackService) maxQuality(ctx context.Context, user *models.User, capabilities map[string]interface{}) (models.VideoQuality, error) {
+	ceiling := models.Quality480p
+
+	if user.Subscription != nil {
+		var plan models.SubscriptionPlan
+		err := ps.db.Collection("subscription_plans").FindOne(
+			ctx,
+			bson.M{"_id": user.Subscription.PlanID},
+		).Decode(&plan)
+
+		if err != nil {
+			return "", fmt.Errorf("failed to load subscription plan: %v", err)
+		}
+
+		for _, quality := range plan.Features.VideoQuality {
+			if qualityBandwidth[quality] > qualityBandwidth[ceiling] {
+				ceiling = quality
+			}
+		}
+	}
+
+	if user.Preferences.DataSaver {
+		ceiling = models.Quality480p
+	}
+
+	if capabilities["4k_support"] != true {
+		ceiling = lowerQuality(ceiling, models.Quality1080p)
+	}
+
+	if limit, ok := capabilities["max_quality"].(string); ok {
+		if _, known := qualityBandwidth[models.VideoQuality(limit)]; known {
+			ceiling = lowerQuality(ceiling, models.VideoQuality(limit))
+		}
+	}
+
+	return ceiling, nil
+}
+
+// lowerQuality returns the lower of two renditions on the encoding ladder
+func lowerQuality(a, b models.VideoQuality) models.VideoQuality {
+	if qualityBandwidth[b] < qualityBandwidth[a] {
+		return b
+	}
+	return a
+}
+
+// RollupDay aggregates one UTC day of playback events into playback_rollups,
+// one document per title, rendition and device type
+func (ps *PlaybackService) RollupDay(ctx context.Context, day time.Time) error {
+	start := day.UTC().Truncate(24 * time.Hour)
+	match := bson.M{"$match": bson.M{"timestamp": bson.M{"$gte": start, "$lt": start.AddDate(0, 0, 1)}}}
+	merge := bson.M{"$merge": bson.M{
+		"into":           playbackRollupsCollection,
+		"on":             "_id",
+		"whenMatched":    "merge",
+		"whenNotMatched": "insert",
+	}}
+
+	// Rollup keys are documents, so their fields must always be in the same order
+	key := func(prefix string) bson.D {
+		return bson.D{
+			{Key: "day", Value: start},
+			{Key: "content_id", Value: prefix + "content_id"},
+			{Key: "quality", Value: prefix + "quality"},
+			{Key: "device_type", Value: prefix + "device_type"},
+		}
+	}
+
+	isType := func(eventType models.PlaybackEventType) bson.M {
+		return bson.M{"$eq": bson.A{"$type", eventType}}
+	}
+	rebufferSeconds := bson.M{"$divide": bson.A{"$rebuffer_ms", 1000}}
+	watchedSeconds := bson.M{"$add": bson.A{"$played_seconds", rebufferSeconds}}
+
+	metrics := []bson.M{
+		match,
+		{"$group": bson.M{
+			"_id":             key("$"),
+			"sessions":        bson.M{"$addToSet": "$session_id"},
+			"viewers":         bson.M{"$addToSet": "$user_id"},
+			"played_seconds":  bson.M{"$sum": "$played_seconds"},
+			"rebuffer_ms":     bson.M{"$sum": bson.M{"$cond": bson.A{isType(models.PlaybackEventRebuffer), "$duration_ms", 0}}},
+			"bitrate_seconds": bson.M{"$sum": bson.M{"$multiply": bson.A{"$played_seconds", "$bitrate_kbps"}}},
+			"startup_ms":      bson.M{"$avg": bson.M{"$cond": bson.A{isType(models.PlaybackEventStart), "$duration_ms", nil}}},
+			"completed": bson.M{"$addToSet": bson.M{"$cond": bson.A{
+				bson.M{"$and": bson.A{isType(models.PlaybackEventStop), "$completed"}},
+				"$session_id",
+				"$$REMOVE",
+			}}},
+			"errors": bson.M{"$sum": bson.M{"$cond": bson.A{isType(models.PlaybackEventError), 1, 0}}},
+		}},
+		{"$project": bson.M{
+			"sessions":         bson.M{"$size": "$sessions"},
+			"unique_viewers":   bson.M{"$size": "$viewers"},
+			"played_seconds":   1,
+			"rebuffer_seconds": rebufferSeconds,
+			"rebuffer_ratio": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{watchedSeconds, 0}},
+				bson.M{"$divide": bson.A{rebufferSeconds, watchedSeconds}},
+				0,
+			}},
+			"average_startup_ms": bson.M{"$ifNull": bson.A{"$startup_ms", 0}},
+			"average_bitrate_kbps": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$played_seconds", 0}},
+				bson.M{"$divide": bson.A{"$bitrate_seconds", "$played_seconds"}},
+				0,
+			}},
+			"completed_sessions": bson.M{"$size": "$completed"},
+			"completion_rate":    bson.M{"$divide": bson.A{bson.M{"$size": "$completed"}, bson.M{"$size": "$sessions"}}},
+			"errors":             1,
+			"updated_at":         "$$NOW",
+		}},
+		merge,
+	}
+
+	// Peak concurrency is the most sessions reporting within any one minute
+	concurrency := []bson.M{
+		match,
+		{"$group": bson.M{
+			"_id": bson.D{
+				{Key: "content_id", Value: "$content_id"},
+				{Key: "quality", Value: "$quality"},
+				{Key: "device_type", Value: "$device_type"},
+				{Key: "minute", Value: bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": "minute"}}},
+			},
+			"sessions": bson.M{"$addToSet": "$session_id"},
+		}},
+		{"$group": bson.M{
+			"_id":              key("$_id."),
+			"peak_concurrency": bson.M{"$max": bson.M{"$size": "$sessions"}},
+		}},
+		merge,
+	}
+
+	for _, pipeline := range [][]bson.M{metrics, concurrency} {
+		cursor, err := ps.db.Collection(playbackEventsCollection).Aggregate(ctx, pipeline)
+		if err != nil {
+			return fmt.Errorf("failed to aggregate playback events: %v", err)
+		}
+		cursor.Close(ctx)
+	}
+
+	return nil
+}
+
+// Rollups returns the daily rollups matching a query, oldest first
+func (ps *PlaybackService) Rollups(ctx context.Context, query PlaybackQuery) ([]models.PlaybackRollup, error) {
+	cursor, err := ps.db.Collection(playbackRollupsCollection).Find(
+		ctx,
+		ps.rollupFilter(query),
+		options.Find().SetSort(bson.D{{Key: "_id.day", Value: 1}, {Key: "_id.content_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load playback rollups: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	rollups := []models.PlaybackRollup{}
+	if err := cursor.All(ctx, &rollups); err != nil {
+		return nil, fmt.Errorf("failed to decode playback rollups: %v", err)
+	}
+
+	return rollups, nil
+}
+
+// Summary combines the rollups matching a query. Playback time, rebuffering,
+// bitrate and errors add up across rollups. Sessions, completions, startup
+// time, unique viewers and peak concurrency are counted from the events
+// instead, since a session that switches rendition or device type appears in
+// several rollups and concurrent sessions are split between them.
+func (ps *PlaybackService) Summary(ctx context.Context, query PlaybackQuery) (*PlaybackSummary, error) {
+	pipeline := []bson.M{
+		{"$match": ps.rollupFilter(query)},
+		{"$group": bson.M{
+			"_id":              nil,
+			"played_seconds":   bson.M{"$sum": "$played_seconds"},
+			"rebuffer_seconds": bson.M{"$sum": "$rebuffer_seconds"},
+			"bitrate_seconds":  bson.M{"$sum": bson.M{"$multiply": bson.A{"$average_bitrate_kbps", "$played_seconds"}}},
+			"errors":           bson.M{"$sum": "$errors"},
+		}},
+		{"$project": bson.M{
+			"played_seconds":   1,
+			"rebuffer_seconds": 1,
+			"errors":           1,
+			"rebuffer_ratio": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$add": bson.A{"$played_seconds", "$rebuffer_seconds"}}, 0}},
+				bson.M{"$divide": bson.A{"$rebuffer_seconds", bson.M{"$add": bson.A{"$played_seconds", "$rebuffer_seconds"}}}},
+				0,
+			}},
+			"average_bitrate_kbps": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$played_seconds", 0}},
+				bson.M{"$divide": bson.A{"$bitrate_seconds", "$played_seconds"}},
+				0,
+			}},
+		}},
+	}
+
+	summary := &PlaybackSummary{}
+
+	cursor, err := ps.db.Collection(playbackRollupsCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize playback rollups: %v", err)
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(summary); err != nil {
+			cursor.Close(ctx)
+			return nil, fmt.Errorf("failed to decode playback summary: %v", err)
+		}
+	}
+	cursor.Close(ctx)
+
+	from, to := ps.queryRange(query)
+	eventFilter := bson.M{"timestamp": bson.M{"$gte": from, "$lt": to}}
+	if query.ContentID != nil {
+		eventFilter["content_id"] = *query.ContentID
+	}
+	if query.Quality != "" {
+		eventFilter["quality"] = query.Quality
+	}
+	if query.DeviceType != "" {
+		eventFilter["device_type"] = query.DeviceType
+	}
+
+	isType := func(eventType models.PlaybackEventType) bson.M {
+		return bson.M{"$eq": bson.A{"$type", eventType}}
+	}
+
+	counts := []bson.M{
+		{"$match": eventFilter},
+		{"$facet": bson.M{
+			"sessions": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":        "$session_id",
+					"startup_ms": bson.M{"$avg": bson.M{"$cond": bson.A{isType(models.PlaybackEventStart), "$duration_ms", nil}}},
+					"completed":  bson.M{"$max": bson.M{"$and": bson.A{isType(models.PlaybackEventStop), "$completed"}}},
+				}},
+				bson.M{"$group": bson.M{
+					"_id":        nil,
+					"sessions":   bson.M{"$sum": 1},
+					"completed":  bson.M{"$sum": bson.M{"$cond": bson.A{"$completed", 1, 0}}},
+					"startup_ms": bson.M{"$avg": "$startup_ms"},
+				}},
+			},
+			"viewers": bson.A{
+				bson.M{"$group": bson.M{"_id": "$user_id"}},
+				bson.M{"$count": "count"},
+			},
+			// Peak concurrency is the most sessions reporting within any one minute
+			"concurrency": bson.A{
+				bson.M{"$group": bson.M{"_id": bson.D{
+					{Key: "minute", Value: bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": "minute"}}},
+					{Key: "session_id", Value: "$session_id"},
+				}}},
+				bson.M{"$group": bson.M{"_id": "$_id.minute", "sessions": bson.M{"$sum": 1}}},
+				bson.M{"$group": bson.M{"_id": nil, "peak": bson.M{"$max": "$sessions"}}},
+			},
+		}},
+	}
+
+	cursor, err = ps.db.Collection(playbackEventsCollection).Aggregate(ctx, counts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count playback sessions: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Sessions []struct {
+			Sessions  int      `bson:"sessions"`
+			Completed int      `bson:"completed"`
+			StartupMs *float64 `bson:"startup_ms"`
+		} `bson:"sessions"`
+		Viewers []struct {
+			Count int `bson:"count"`
+		} `bson:"viewers"`
+		Concurrency []struct {
+			Peak int `bson:"peak"`
+		} `bson:"concurrency"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode playback session counts: %v", err)
+		}
+	}
+
+	if len(result.Sessions) > 0 {
+		sessions := result.Sessions[0]
+		summary.Sessions = sessions.Sessions
+		if sessions.Sessions > 0 {
+			summary.CompletionRate = float64(sessions.Completed) / float64(sessions.Sessions)
+		}
+		if sessions.StartupMs != nil {
+			summary.AverageStartupMs = *sessions.StartupMs
+		}
+	}
+	if len(result.Viewers) > 0 {
+		summary.UniqueViewers = result.Viewers[0].Count
+	}
+	if len(result.Concurrency) > 0 {
+		summary.PeakConcurrency = result.Concurrency[0].Peak
+	}
+
+	return summary, nil
+}
+
+func (ps *PlaybackService) rollupFilter(query PlaybackQuery) bson.M {
+	from, to := ps.queryRange(query)
+
+	filter := bson.M{"_id.day": bson.M{"$gte": from, "$lt": to}}
+	if query.ContentID != nil {
+		filter["_id.content_id"] = *query.ContentID
+	}
+	if query.Quality != "" {
+		filter["_id.quality"] = query.Quality
+	}
+	if query.DeviceType != "" {
+		filter["_id.device_type"] = query.DeviceType
+	}
+
+	return filter
+}
+
+// queryRange returns the half-open interval of days a query covers
+func (ps *PlaybackService) queryRange(query PlaybackQuery) (time.Time, time.Time) {
+	return query.From.UTC().Truncate(24 * time.Hour), query.To.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+}
@@ -9,36 +9,81 @@ import (
 
 // Services holds all service dependencies
 type Services struct {
-	DB             *mongo.Database
-	Config         *config.Config
-	EmailService   *EmailService
-	StripeService  *StripeService
-	TMDBService    *TMDBService
-	VideoService   *VideoService
-	StorageService *StorageService
-	AuthService    *AuthService
+	DB               *mongo.Database
+	Config           *config.Config
+	EmailService     *EmailService
+	StripeService    *StripeService
+	TMDBService      *TMDBService
+	VideoService     *VideoService
+	StorageService   *StorageService
+	AuthService      *AuthService
+	StreamService    *StreamService
+	TranscodeService *TranscodeService
+	UploadService    *UploadService
+	ImageService     *ImageService
+	BlobService      *BlobService
+	DRMService       *DRMService
+	WatermarkService *WatermarkService
+	DownloadService  *DownloadService
+	PlaybackService  *PlaybackService
+	MarkerService    *MarkerService
+	LiveService      *LiveService
+	PartyService     *WatchPartyService
+	UpNextService    *UpNextService
+	CDNService       *CDNService
 }
 
 // NewServices initializes all services
-func NewServices(db *mongo.Database, cfg *config.Config) *Services {
+func NewServices(db *mongo.Database, cfg *config.Config) (*Services, error) {
 	// Initialize individual services
 	emailService := NewEmailService(cfg)
 	stripeService := NewStripeService(cfg, db)
 	tmdbService := NewTMDBService(cfg)
-	videoService := NewVideoService(cfg, db)
-	storageService := NewStorageService(cfg)
+	storageService, err := NewStorageService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	drmService := NewDRMService(cfg, db)
+	watermarkService := NewWatermarkService(cfg, db)
+	cdnService := NewCDNService(cfg)
+	videoService := NewVideoService(cfg, db, storageService, drmService, watermarkService, cdnService)
 	authService := NewAuthService(cfg, db)
+	streamService := NewStreamService(cfg, db)
+	downloadService := NewDownloadService(cfg, db, videoService)
+	playbackService := NewPlaybackService(cfg, db, videoService)
+	markerService := NewMarkerService(cfg, db, storageService)
+	transcodeService := NewTranscodeService(cfg, db, storageService, markerService)
+	blobService := NewBlobService(cfg, db, storageService)
+	uploadService := NewUploadService(cfg, db, storageService, blobService)
+	imageService := NewImageService(cfg, blobService)
+	liveService := NewLiveService(cfg, db, storageService)
+	partyService := NewWatchPartyService(cfg, db, streamService)
+	upNextService := NewUpNextService(cfg, db)
 
 	return &Services{
-		DB:             db,
-		Config:         cfg,
-		EmailService:   emailService,
-		StripeService:  stripeService,
-		TMDBService:    tmdbService,
-		VideoService:   videoService,
-		StorageService: storageService,
-		AuthService:    authService,
-	}
+		DB:               db,
+		Config:           cfg,
+		EmailService:     emailService,
+		StripeService:    stripeService,
+		TMDBService:      tmdbService,
+		VideoService:     videoService,
+		StorageService:   storageService,
+		AuthService:      authService,
+		StreamService:    streamService,
+		TranscodeService: transcodeService,
+		UploadService:    uploadService,
+		ImageService:     imageService,
+		BlobService:      blobService,
+		DRMService:       drmService,
+		WatermarkService: watermarkService,
+		DownloadService:  downloadService,
+		PlaybackService:  playbackService,
+		MarkerService:    markerService,
+		LiveService:      liveService,
+		PartyService:     partyService,
+		UpNextService:    upNextService,
+		CDNService:       cdnService,
+	}, nil
 }
 
 // Cleanup performs cleanup of all services
@@ -53,4 +98,43 @@ func (s *Services) Cleanup() {
 	if s.StorageService != nil {
 		s.StorageService.Close()
 	}
+	if s.StreamService != nil {
+		s.StreamService.Close()
+	}
+	if s.TranscodeService != nil {
+		s.TranscodeService.Close()
+	}
+	if s.UploadService != nil {
+		s.UploadService.Close()
+	}
+	if s.ImageService != nil {
+		s.ImageService.Close()
+	}
+	if s.BlobService != nil {
+		s.BlobService.Close()
+	}
+	if s.DRMService != nil {
+		s.DRMService.Close()
+	}
+	if s.WatermarkService != nil {
+		s.WatermarkService.Close()
+	}
+	if s.DownloadService != nil {
+		s.DownloadService.Close()
+	}
+	if s.PlaybackService != nil {
+		s.PlaybackService.Close()
+	}
+	if s.MarkerService != nil {
+		s.MarkerService.Close()
+	}
+	if s.LiveService != nil {
+		s.LiveService.Close()
+	}
+	if s.PartyService != nil {
+		s.PartyService.Close()
+	}
+	if s.CDNService != nil {
+		s.CDNService.Close()
+	}
 }
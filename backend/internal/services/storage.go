@@ -3,12 +3,16 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime"
+	"net/url"
 	"onflix/internal/config"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -19,6 +23,25 @@ type StorageService struct {
 	basePath     string
 	maxSize      int64 // Maximum file size in bytes
 	allowedTypes map[string]bool
+	backend      Storage
+}
+
+// Storage is a backend StorageService keeps files in. Keys are slash-separated
+// paths relative to the storage root.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Stat(ctx context.Context, key string) (*StorageObject, error)
+	Delete(ctx context.Context, key string) error
+	DeletePrefix(ctx context.Context, prefix string) error
+	List(ctx context.Context, prefix string, recursive bool) ([]StorageObject, error)
+}
+
+type StorageObject struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
 }
 
 type StorageConfig struct {
@@ -46,7 +69,7 @@ type UploadResult struct {
 	Error    string        `json:"error,omitempty"`
 }
 
-func NewStorageService(cfg *config.Config) *StorageService {
+func NewStorageService(cfg *config.Config) (*StorageService, error) {
 	// Default configuration
 	basePath := "./uploads"
 	if cfg.Storage.BasePath != "" {
@@ -84,17 +107,34 @@ func NewStorageService(cfg *config.Config) *StorageService {
 		}
 	}
 
-	// Ensure base directory exists
+	// Ensure base directory exists. With a remote driver it holds staging
+	// files such as in-progress uploads and ffmpeg output.
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		fmt.Printf("Warning: Could not create storage directory %s: %v\n", basePath, err)
 	}
 
+	var backend Storage = newLocalStorage(basePath)
+	if cfg.Storage.Driver == "s3" {
+		s3Backend, err := newS3Storage(cfg.AWS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize S3 storage: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s3Backend.HealthCheck(ctx); err != nil {
+			return nil, fmt.Errorf("failed to initialize S3 storage: %v", err)
+		}
+		backend = s3Backend
+	}
+
 	return &StorageService{
 		config:       cfg,
 		basePath:     basePath,
 		maxSize:      maxSize,
 		allowedTypes: allowedTypes,
-	}
+		backend:      backend,
+	}, nil
 }
 
 func (ss *StorageService) Close() {
@@ -115,24 +155,14 @@ func (ss *StorageService) UploadFile(relativePath string, fileData []byte) (stri
 	}
 
 	// Generate unique filename
-	fileName := ss.generateUniqueFileName(relativePath)
-	fullPath := filepath.Join(ss.basePath, fileName)
-
-	// Ensure directory exists
-	dir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %v", err)
-	}
+	fileName := filepath.ToSlash(ss.generateUniqueFileName(relativePath))
 
-	// Write file
-	if err := os.WriteFile(fullPath, fileData, 0644); err != nil {
-		return "", fmt.Errorf("failed to write file: %v", err)
+	metadata, err := ss.Put(context.Background(), fileName, bytes.NewReader(fileData), int64(len(fileData)))
+	if err != nil {
+		return "", err
 	}
 
-	// Generate public URL
-	publicURL := ss.generatePublicURL(fileName)
-
-	return publicURL, nil
+	return metadata.PublicURL, nil
 }
 
 // Upload with metadata
@@ -155,48 +185,17 @@ func (ss *StorageService) UploadFileWithMetadata(originalName, relativePath stri
 	}
 
 	// Generate unique filename
-	fileName := ss.generateUniqueFileName(relativePath)
-	fullPath := filepath.Join(ss.basePath, fileName)
+	fileName := filepath.ToSlash(ss.generateUniqueFileName(relativePath))
 
-	// Ensure directory exists
-	dir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return &UploadResult{
-			Success: false,
-			Error:   fmt.Sprintf("failed to create directory: %v", err),
-		}, nil
-	}
-
-	// Calculate MD5 hash
-	hash := md5.Sum(fileData)
-	md5Hash := fmt.Sprintf("%x", hash)
-
-	// Detect content type
-	contentType := mime.TypeByExtension(ext)
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-
-	// Write file
-	if err := os.WriteFile(fullPath, fileData, 0644); err != nil {
+	metadata, err := ss.Put(context.Background(), fileName, bytes.NewReader(fileData), int64(len(fileData)))
+	if err != nil {
 		return &UploadResult{
 			Success: false,
-			Error:   fmt.Sprintf("failed to write file: %v", err),
+			Error:   err.Error(),
 		}, nil
 	}
-
-	// Create metadata
-	metadata := &FileMetadata{
-		FileName:     fileName,
-		OriginalName: originalName,
-		Size:         int64(len(fileData)),
-		ContentType:  contentType,
-		Extension:    ext,
-		MD5Hash:      md5Hash,
-		StoragePath:  fullPath,
-		PublicURL:    ss.generatePublicURL(fileName),
-		UploadedAt:   time.Now(),
-	}
+	metadata.FileName = fileName
+	metadata.OriginalName = originalName
 
 	return &UploadResult{
 		Success:  true,
@@ -251,14 +250,13 @@ func (ss *StorageService) UploadThumbnail(contentID string, imageData []byte) (s
 
 // File operations
 func (ss *StorageService) GetFile(filePath string) ([]byte, error) {
-	fullPath := filepath.Join(ss.basePath, filePath)
-
-	// Security check - ensure path is within base directory
-	if !strings.HasPrefix(fullPath, ss.basePath) {
-		return nil, fmt.Errorf("invalid file path")
+	file, err := ss.Open(context.Background(), filePath)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
 
-	data, err := os.ReadFile(fullPath)
+	data, err := io.ReadAll(file)
 	if err != nil {
 		return nil, fmt.Errorf("file not found or cannot be read: %v", err)
 	}
@@ -267,74 +265,415 @@ func (ss *StorageService) GetFile(filePath string) ([]byte, error) {
 }
 
 func (ss *StorageService) DeleteFile(filePath string) error {
-	fullPath := filepath.Join(ss.basePath, filePath)
+	key, err := storageKey(filePath)
+	if err != nil {
+		return err
+	}
 
-	// Security check
-	if !strings.HasPrefix(fullPath, ss.basePath) {
-		return fmt.Errorf("invalid file path")
+	return ss.backend.Delete(context.Background(), key)
+}
+
+func (ss *StorageService) FileExists(filePath string) bool {
+	_, err := ss.Stat(context.Background(), filePath)
+	return err == nil
+}
+
+func (ss *StorageService) GetFileInfo(filePath string) (*FileMetadata, error) {
+	metadata, err := ss.Stat(context.Background(), filePath)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := os.Remove(fullPath); err != nil {
-		return fmt.Errorf("failed to delete file: %v", err)
+	// Calculate MD5 if file is small enough
+	if metadata.Size < 100*1024*1024 { // Only for files < 100MB
+		if file, err := ss.Open(context.Background(), filePath); err == nil {
+			hasher := md5.New()
+			if _, err := io.Copy(hasher, file); err == nil {
+				metadata.MD5Hash = hex.EncodeToString(hasher.Sum(nil))
+			}
+			file.Close()
+		}
 	}
 
-	return nil
+	return metadata, nil
 }
 
-func (ss *StorageService) FileExists(filePath string) bool {
-	fullPath := filepath.Join(ss.basePath, filePath)
+// Streaming file operations
+// Put writes r to relativePath, computing the size and MD5 while copying so
+// the data is never held in memory. size is the expected length, or -1 when
+// unknown. The file only appears at relativePath once it is fully written.
+func (ss *StorageService) Put(ctx context.Context, relativePath string, r io.Reader, size int64) (*FileMetadata, error) {
+	key, err := storageKey(relativePath)
+	if err != nil {
+		return nil, err
+	}
 
-	if !strings.HasPrefix(fullPath, ss.basePath) {
-		return false
+	ext := strings.ToLower(path.Ext(key))
+	hasher := md5.New()
+	counter := &countingReader{r: io.TeeReader(&contextReader{ctx: ctx, r: r}, hasher)}
+
+	if err := ss.backend.Put(ctx, key, counter, size, contentTypeForExtension(ext)); err != nil {
+		return nil, err
 	}
 
-	_, err := os.Stat(fullPath)
-	return !os.IsNotExist(err)
+	return &FileMetadata{
+		FileName:     path.Base(key),
+		OriginalName: path.Base(key),
+		Size:         counter.n,
+		ContentType:  contentTypeForExtension(ext),
+		Extension:    ext,
+		MD5Hash:      hex.EncodeToString(hasher.Sum(nil)),
+		StoragePath:  ss.storagePath(key),
+		PublicURL:    ss.generatePublicURL(key),
+		UploadedAt:   time.Now(),
+	}, nil
 }
 
-func (ss *StorageService) GetFileInfo(filePath string) (*FileMetadata, error) {
-	fullPath := filepath.Join(ss.basePath, filePath)
-
-	if !strings.HasPrefix(fullPath, ss.basePath) {
-		return nil, fmt.Errorf("invalid file path")
+// Open returns a seekable reader over a stored file. Callers must close it.
+func (ss *StorageService) Open(ctx context.Context, relativePath string) (io.ReadSeekCloser, error) {
+	key, err := storageKey(relativePath)
+	if err != nil {
+		return nil, err
 	}
 
-	info, err := os.Stat(fullPath)
+	return ss.backend.Open(ctx, key)
+}
+
+// Stat describes a stored file without reading it; MD5Hash is left empty
+func (ss *StorageService) Stat(ctx context.Context, relativePath string) (*FileMetadata, error) {
+	key, err := storageKey(relativePath)
 	if err != nil {
-		return nil, fmt.Errorf("file not found: %v", err)
+		return nil, err
 	}
 
-	ext := strings.ToLower(filepath.Ext(filePath))
-	contentType := mime.TypeByExtension(ext)
-	if contentType == "" {
-		contentType = "application/octet-stream"
+	object, err := ss.backend.Stat(ctx, key)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate MD5 if file is small enough
-	var md5Hash string
-	if info.Size() < 100*1024*1024 { // Only for files < 100MB
-		data, err := os.ReadFile(fullPath)
-		if err == nil {
-			hash := md5.Sum(data)
-			md5Hash = fmt.Sprintf("%x", hash)
-		}
+	ext := strings.ToLower(path.Ext(key))
+	contentType := object.ContentType
+	if contentType == "" {
+		contentType = contentTypeForExtension(ext)
 	}
 
-	metadata := &FileMetadata{
-		FileName:     filepath.Base(filePath),
-		OriginalName: filepath.Base(filePath),
-		Size:         info.Size(),
+	return &FileMetadata{
+		FileName:     path.Base(key),
+		OriginalName: path.Base(key),
+		Size:         object.Size,
 		ContentType:  contentType,
 		Extension:    ext,
-		MD5Hash:      md5Hash,
-		StoragePath:  fullPath,
-		PublicURL:    ss.generatePublicURL(filePath),
-		UploadedAt:   info.ModTime(),
+		StoragePath:  ss.storagePath(key),
+		PublicURL:    ss.generatePublicURL(key),
+		UploadedAt:   object.ModTime,
+	}, nil
+}
+
+// UploadStream is the streaming counterpart of UploadFileWithMetadata: it
+// applies the same type and size checks and stores r under a unique name
+func (ss *StorageService) UploadStream(ctx context.Context, originalName, relativePath string, r io.Reader, size int64) (*FileMetadata, error) {
+	if size > ss.maxSize {
+		return nil, fmt.Errorf("file size exceeds maximum allowed size of %d bytes", ss.maxSize)
+	}
+
+	ext := strings.ToLower(filepath.Ext(originalName))
+	if !ss.allowedTypes[ext] {
+		return nil, fmt.Errorf("file type %s is not allowed", ext)
+	}
+
+	fileName := filepath.ToSlash(ss.generateUniqueFileName(relativePath))
+
+	// Guard against a size that was not declared up front
+	metadata, err := ss.Put(ctx, fileName, io.LimitReader(r, ss.maxSize+1), size)
+	if err != nil {
+		return nil, err
 	}
 
+	if metadata.Size > ss.maxSize {
+		ss.DeleteFile(fileName)
+		return nil, fmt.Errorf("file size exceeds maximum allowed size of %d bytes", ss.maxSize)
+	}
+
+	metadata.OriginalName = originalName
 	return metadata, nil
 }
 
+// contextReader stops a copy once its context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// Media types the system MIME table often gets wrong (.ts is TypeScript or Qt
+// translations on many hosts)
+var mediaContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".mpd":  "application/dash+xml",
+	".ts":   "video/mp2t",
+	".m4s":  "video/iso.segment",
+	".vtt":  "text/vtt",
+	".srt":  "application/x-subrip",
+}
+
+func contentTypeForExtension(ext string) string {
+	if contentType, ok := mediaContentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// ResolvePath maps a storage-relative path onto the local filesystem,
+// rejecting paths that escape the storage root.
+func (ss *StorageService) ResolvePath(relativePath string) (string, error) {
+	base := filepath.Clean(ss.basePath)
+	fullPath := filepath.Join(base, filepath.FromSlash(relativePath))
+
+	if fullPath != base && !strings.HasPrefix(fullPath, base+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid file path")
+	}
+
+	return fullPath, nil
+}
+
+// PathFromURL converts a public URL produced by generatePublicURL back
+// into a storage-relative path.
+func (ss *StorageService) PathFromURL(fileURL string) string {
+	if prefix := ss.deliveryURL(""); strings.HasPrefix(fileURL, prefix) {
+		return strings.TrimPrefix(fileURL, prefix)
+	}
+
+	if cdn := ss.config.AWS.CloudFrontURL; cdn != "" && strings.HasPrefix(fileURL, strings.TrimSuffix(cdn, "/")+"/") {
+		return strings.TrimPrefix(fileURL, strings.TrimSuffix(cdn, "/")+"/")
+	}
+
+	return strings.TrimPrefix(fileURL, "/")
+}
+
+// storageKey normalizes a storage-relative path into a backend key
+func storageKey(relativePath string) (string, error) {
+	key := path.Clean(strings.ReplaceAll(relativePath, "\\", "/"))
+	key = strings.TrimPrefix(key, "/")
+
+	if key == "." || key == "" || key == ".." || strings.HasPrefix(key, "../") {
+		return "", fmt.Errorf("invalid file path")
+	}
+
+	return key, nil
+}
+
+func (ss *StorageService) storagePath(key string) string {
+	if s3Backend, ok := ss.backend.(*s3Storage); ok {
+		return fmt.Sprintf("s3://%s/%s", s3Backend.bucket, key)
+	}
+
+	fullPath, _ := ss.ResolvePath(key)
+	return fullPath
+}
+
+// Local staging
+// Fetch returns a local path for a stored file, for tools such as ffmpeg that
+// cannot read from the backend directly. release removes any temporary copy.
+func (ss *StorageService) Fetch(ctx context.Context, relativePath string) (string, func(), error) {
+	key, err := storageKey(relativePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s3Backend, ok := ss.backend.(*s3Storage)
+	if !ok {
+		fullPath, err := ss.ResolvePath(key)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := os.Stat(fullPath); err != nil {
+			return "", nil, fmt.Errorf("file not found: %v", err)
+		}
+		return fullPath, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "onflix-fetch-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	release := func() { os.RemoveAll(dir) }
+
+	// Keep the file name so tools can still sniff the extension
+	localPath := filepath.Join(dir, path.Base(key))
+	if err := s3Backend.Download(ctx, key, localPath); err != nil {
+		release()
+		return "", nil, err
+	}
+
+	return localPath, release, nil
+}
+
+// Source returns an input ffmpeg can read a stored file from without staging
+// a copy: a presigned URL on S3, which ffmpeg reads with range requests, or
+// the file's local path
+func (ss *StorageService) Source(ctx context.Context, relativePath string, expiry time.Duration) (string, error) {
+	signedURL, err := ss.SignedURL(ctx, relativePath, expiry, nil)
+	if err != nil || signedURL != "" {
+		return signedURL, err
+	}
+
+	key, err := storageKey(relativePath)
+	if err != nil {
+		return "", err
+	}
+
+	fullPath, err := ss.ResolvePath(key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(fullPath); err != nil {
+		return "", fmt.Errorf("file not found: %v", err)
+	}
+
+	return fullPath, nil
+}
+
+// Publish moves files written under ResolvePath(relativePath) by external
+// tools into the storage backend. relativePath may be a file or a directory.
+// The local driver stores files in place, so there is nothing to move.
+func (ss *StorageService) Publish(ctx context.Context, relativePath string) error {
+	if _, ok := ss.backend.(*localStorage); ok {
+		return nil
+	}
+
+	root, err := ss.ResolvePath(relativePath)
+	if err != nil {
+		return err
+	}
+
+	base := filepath.Clean(ss.basePath)
+	err = filepath.WalkDir(root, func(p string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		return ss.backend.Put(ctx, key, file, info.Size(), contentTypeForExtension(strings.ToLower(path.Ext(key))))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s: %v", relativePath, err)
+	}
+
+	return os.RemoveAll(root)
+}
+
+// Import moves a file staged on local disk, normally under the storage base
+// path, into storage at relativePath
+func (ss *StorageService) Import(ctx context.Context, localPath, relativePath string) error {
+	destination, err := ss.ResolvePath(relativePath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	if err := os.Rename(localPath, destination); err != nil {
+		return fmt.Errorf("failed to store file: %v", err)
+	}
+
+	return ss.Publish(ctx, relativePath)
+}
+
+// DeleteAll removes a file or every file under a directory, along with any
+// staged local copies
+func (ss *StorageService) DeleteAll(ctx context.Context, relativePath string) error {
+	key, err := storageKey(relativePath)
+	if err != nil {
+		return err
+	}
+
+	if err := ss.backend.DeletePrefix(ctx, key); err != nil {
+		return err
+	}
+
+	if _, ok := ss.backend.(*localStorage); !ok {
+		if fullPath, err := ss.ResolvePath(key); err == nil {
+			os.RemoveAll(fullPath)
+		}
+	}
+
+	return nil
+}
+
+// Presigned URLs
+// SignedURL returns a time-limited URL that reads the file straight from the
+// backend, or "" when the backend cannot sign URLs and files are served by the
+// API. Params are added to the URL under its signature.
+func (ss *StorageService) SignedURL(ctx context.Context, relativePath string, expiry time.Duration, params url.Values) (string, error) {
+	s3Backend, ok := ss.backend.(*s3Storage)
+	if !ok {
+		return "", nil
+	}
+
+	key, err := storageKey(relativePath)
+	if err != nil {
+		return "", err
+	}
+
+	return s3Backend.PresignGet(ctx, key, expiry, params)
+}
+
+// PresignedUploadURL returns a URL clients can PUT a file to directly
+func (ss *StorageService) PresignedUploadURL(ctx context.Context, relativePath string, expiry time.Duration) (string, error) {
+	s3Backend, ok := ss.backend.(*s3Storage)
+	if !ok {
+		return "", fmt.Errorf("direct uploads require the s3 storage driver")
+	}
+	if expiry <= 0 {
+		expiry = ss.urlExpiry()
+	}
+
+	key, err := storageKey(relativePath)
+	if err != nil {
+		return "", err
+	}
+
+	return s3Backend.PresignPut(ctx, key, expiry)
+}
+
 // Batch operations
 func (ss *StorageService) UploadMultipleFiles(files map[string][]byte) (map[string]*UploadResult, error) {
 	results := make(map[string]*UploadResult)
@@ -377,83 +716,88 @@ func (ss *StorageService) CreateDirectory(dirPath string) error {
 }
 
 func (ss *StorageService) ListFiles(dirPath string) ([]FileMetadata, error) {
-	fullPath := filepath.Join(ss.basePath, dirPath)
-
-	if !strings.HasPrefix(fullPath, ss.basePath) {
-		return nil, fmt.Errorf("invalid directory path")
+	prefix := strings.Trim(filepath.ToSlash(dirPath), "/")
+	if prefix != "" {
+		key, err := storageKey(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid directory path")
+		}
+		prefix = key
 	}
 
-	entries, err := os.ReadDir(fullPath)
+	objects, err := ss.backend.List(context.Background(), prefix, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: %v", err)
+		return nil, err
 	}
 
 	var files []FileMetadata
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			info, err := entry.Info()
-			if err != nil {
-				continue
-			}
-
-			relativePath := filepath.Join(dirPath, entry.Name())
-			ext := strings.ToLower(filepath.Ext(entry.Name()))
-			contentType := mime.TypeByExtension(ext)
-			if contentType == "" {
-				contentType = "application/octet-stream"
-			}
-
-			metadata := FileMetadata{
-				FileName:     entry.Name(),
-				OriginalName: entry.Name(),
-				Size:         info.Size(),
-				ContentType:  contentType,
-				Extension:    ext,
-				StoragePath:  filepath.Join(fullPath, entry.Name()),
-				PublicURL:    ss.generatePublicURL(relativePath),
-				UploadedAt:   info.ModTime(),
-			}
-
-			files = append(files, metadata)
+	for _, object := range objects {
+		ext := strings.ToLower(path.Ext(object.Key))
+		contentType := object.ContentType
+		if contentType == "" {
+			contentType = contentTypeForExtension(ext)
 		}
+
+		files = append(files, FileMetadata{
+			FileName:     path.Base(object.Key),
+			OriginalName: path.Base(object.Key),
+			Size:         object.Size,
+			ContentType:  contentType,
+			Extension:    ext,
+			StoragePath:  ss.storagePath(object.Key),
+			PublicURL:    ss.generatePublicURL(object.Key),
+			UploadedAt:   object.ModTime,
+		})
 	}
 
 	return files, nil
 }
 
 func (ss *StorageService) GetDirectorySize(dirPath string) (int64, error) {
-	fullPath := filepath.Join(ss.basePath, dirPath)
+	prefix := strings.Trim(filepath.ToSlash(dirPath), "/")
+	if prefix != "" {
+		key, err := storageKey(prefix)
+		if err != nil {
+			return 0, fmt.Errorf("invalid directory path")
+		}
+		prefix = key
+	}
 
-	if !strings.HasPrefix(fullPath, ss.basePath) {
-		return 0, fmt.Errorf("invalid directory path")
+	objects, err := ss.backend.List(context.Background(), prefix, true)
+	if err != nil {
+		return 0, err
 	}
 
 	var totalSize int64
-	err := filepath.Walk(fullPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			totalSize += info.Size()
-		}
-		return nil
-	})
+	for _, object := range objects {
+		totalSize += object.Size
+	}
 
-	return totalSize, err
+	return totalSize, nil
 }
 
 // Image processing helpers
+// ResizeImage fits an image inside width x height, re-encoding it as JPEG
+// (or PNG when it has transparency) without its metadata
 func (ss *StorageService) ResizeImage(imageData []byte, width, height int) ([]byte, error) {
-	// This is a placeholder - in production, use an image processing library
-	// like github.com/disintegration/imaging or github.com/h2non/bimg
+	img, err := DecodeImage(imageData)
+	if err != nil {
+		return nil, err
+	}
 
-	// For now, return original image
-	return imageData, nil
+	resized := ResizeImage(img, width, height, CropModeFit)
+	return EncodeImage(resized, baseImageFormat(resized))
 }
 
+// GenerateThumbnail produces a square thumbnail cropped around the centre
 func (ss *StorageService) GenerateThumbnail(imageData []byte, size int) ([]byte, error) {
-	// This is a placeholder - in production, generate actual thumbnail
-	return ss.ResizeImage(imageData, size, size)
+	img, err := DecodeImage(imageData)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail := ResizeImage(img, size, size, CropModeFill)
+	return EncodeImage(thumbnail, baseImageFormat(thumbnail))
 }
 
 // Helper methods
@@ -473,8 +817,24 @@ func (ss *StorageService) generateUniqueFileName(originalPath string) string {
 	return filepath.Join(dir, unique)
 }
 
+// generatePublicURL returns the URL a file is recorded under in the database,
+// so it must not expire: the CloudFront URL when one fronts the bucket,
+// otherwise the API's own address, where DeliverFile presigns S3 links per
+// request.
 func (ss *StorageService) generatePublicURL(filePath string) string {
-	// Generate URL based on configuration
+	urlPath := strings.ReplaceAll(filePath, "\\", "/")
+
+	if _, ok := ss.backend.(*s3Storage); ok {
+		if cdn := ss.config.AWS.CloudFrontURL; cdn != "" {
+			return strings.TrimSuffix(cdn, "/") + "/" + urlPath
+		}
+	}
+
+	return ss.deliveryURL(urlPath)
+}
+
+// deliveryURL is the URL the API serves a stored file under
+func (ss *StorageService) deliveryURL(filePath string) string {
 	baseURL := ss.config.Server.AppURL
 	if baseURL == "" {
 		baseURL = "http://localhost:8080"
@@ -547,6 +907,7 @@ func (ss *StorageService) GetStorageStats() (map[string]interface{}, error) {
 	}
 
 	stats := map[string]interface{}{
+		"driver":           ss.driverName(),
 		"total_size_bytes": totalSize,
 		"total_size_mb":    float64(totalSize) / (1024 * 1024),
 		"base_path":        ss.basePath,
@@ -566,27 +927,6 @@ func (ss *StorageService) getAllowedTypes() []string {
 }
 
 // Cleanup operations
-func (ss *StorageService) CleanupOldFiles(olderThan time.Duration) (int, error) {
-	cutoff := time.Now().Add(-olderThan)
-	deletedCount := 0
-
-	err := filepath.Walk(ss.basePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() && info.ModTime().Before(cutoff) {
-			if err := os.Remove(path); err == nil {
-				deletedCount++
-			}
-		}
-
-		return nil
-	})
-
-	return deletedCount, err
-}
-
 func (ss *StorageService) CleanupEmptyDirectories() error {
 	return filepath.Walk(ss.basePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -602,8 +942,31 @@ func (ss *StorageService) CleanupEmptyDirectories() error {
 	})
 }
 
+func (ss *StorageService) driverName() string {
+	if _, ok := ss.backend.(*s3Storage); ok {
+		return "s3"
+	}
+	return "local"
+}
+
+// Presigned S3 URLs are valid for at most seven days
+func (ss *StorageService) urlExpiry() time.Duration {
+	expiry := time.Duration(ss.config.AWS.S3URLExpiryHours) * time.Hour
+	if expiry <= 0 {
+		expiry = 24 * time.Hour
+	}
+	if expiry > 7*24*time.Hour {
+		expiry = 7 * 24 * time.Hour
+	}
+	return expiry
+}
+
 // Health check
 func (ss *StorageService) HealthCheck() error {
+	if s3Backend, ok := ss.backend.(*s3Storage); ok {
+		return s3Backend.HealthCheck(context.Background())
+	}
+
 	// Test write access
 	testFile := filepath.Join(ss.basePath, ".health_check")
 	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
@@ -623,15 +986,9 @@ func (ss *StorageService) HealthCheck() error {
 
 // Stream file for download
 func (ss *StorageService) StreamFile(filePath string, writer io.Writer) error {
-	fullPath := filepath.Join(ss.basePath, filePath)
-
-	if !strings.HasPrefix(fullPath, ss.basePath) {
-		return fmt.Errorf("invalid file path")
-	}
-
-	file, err := os.Open(fullPath)
+	file, err := ss.Open(context.Background(), filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return err
 	}
 	defer file.Close()
 
@@ -0,0 +1,182 @@
+// backend/internal/services/storage_local.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localStorage keeps objects as plain files under a root directory
+type localStorage struct {
+	root string
+}
+
+func newLocalStorage(root string) *localStorage {
+	return &localStorage{root: filepath.Clean(root)}
+}
+
+func (ls *localStorage) resolve(key string) (string, error) {
+	fullPath := filepath.Join(ls.root, filepath.FromSlash(key))
+
+	if fullPath != ls.root && !strings.HasPrefix(fullPath, ls.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid file path")
+	}
+
+	return fullPath, nil
+}
+
+// Put writes to a temporary file next to the destination and renames it into
+// place, so readers never see a partially written file
+func (ls *localStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	fullPath, err := ls.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(fullPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+
+	if size >= 0 && written != size {
+		return fmt.Errorf("expected %d bytes but received %d", size, written)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return fmt.Errorf("failed to store file: %v", err)
+	}
+
+	return nil
+}
+
+func (ls *localStorage) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	fullPath, err := ls.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("file not found or cannot be read: %v", err)
+	}
+
+	return file, nil
+}
+
+func (ls *localStorage) Stat(ctx context.Context, key string) (*StorageObject, error) {
+	fullPath, err := ls.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %v", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", key)
+	}
+
+	return &StorageObject{
+		Key:     key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+func (ls *localStorage) Delete(ctx context.Context, key string) error {
+	fullPath, err := ls.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(fullPath); err != nil {
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+
+	return nil
+}
+
+func (ls *localStorage) DeletePrefix(ctx context.Context, prefix string) error {
+	fullPath, err := ls.resolve(prefix)
+	if err != nil {
+		return err
+	}
+	if fullPath == ls.root {
+		return fmt.Errorf("refusing to delete the storage root")
+	}
+
+	if err := os.RemoveAll(fullPath); err != nil {
+		return fmt.Errorf("failed to delete %s: %v", prefix, err)
+	}
+
+	return nil
+}
+
+func (ls *localStorage) List(ctx context.Context, prefix string, recursive bool) ([]StorageObject, error) {
+	fullPath, err := ls.resolve(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []StorageObject
+	err = filepath.WalkDir(fullPath, func(p string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if p != fullPath && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Skip files still being written by Put
+		if strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ls.root, p)
+		if err != nil {
+			return nil
+		}
+
+		objects = append(objects, StorageObject{
+			Key:     filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %v", err)
+	}
+
+	return objects, nil
+}
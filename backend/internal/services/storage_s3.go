@@ -0,0 +1,225 @@
+// backend/internal/services/storage_s3.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"onflix/internal/config"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 rejects multipart parts smaller than 5MB
+const s3MinPartSize = 5 * 1024 * 1024
+
+// s3Storage keeps objects in an S3 bucket. Any S3-compatible service works,
+// so a local MinIO server can stand in for AWS during development and tests.
+type s3Storage struct {
+	client   *minio.Client
+	bucket   string
+	partSize uint64
+}
+
+func newS3Storage(cfg config.AWSConfig) (*s3Storage, error) {
+	if cfg.S3Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is not configured")
+	}
+
+	endpoint := "s3.amazonaws.com"
+	secure := true
+	if cfg.S3Endpoint != "" {
+		endpoint = cfg.S3Endpoint
+		if strings.Contains(endpoint, "://") {
+			parsed, err := url.Parse(endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("invalid S3 endpoint: %v", err)
+			}
+			endpoint = parsed.Host
+			secure = parsed.Scheme == "https"
+		}
+	}
+
+	lookup := minio.BucketLookupAuto
+	if cfg.S3ForcePathStyle {
+		lookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure:       secure,
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %v", err)
+	}
+
+	partSize := uint64(s3MinPartSize)
+	if cfg.S3PartSize > s3MinPartSize {
+		partSize = uint64(cfg.S3PartSize)
+	}
+
+	return &s3Storage{
+		client:   client,
+		bucket:   cfg.S3Bucket,
+		partSize: partSize,
+	}, nil
+}
+
+// Put streams r to the bucket. Objects larger than one part, or of unknown
+// size, are sent as a multipart upload that is aborted if the copy fails.
+func (s3s *s3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	info, err := s3s.client.PutObject(ctx, s3s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+		PartSize:    s3s.partSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %v", key, err)
+	}
+
+	if size >= 0 && info.Size != size {
+		s3s.client.RemoveObject(ctx, s3s.bucket, key, minio.RemoveObjectOptions{})
+		return fmt.Errorf("expected %d bytes but received %d", size, info.Size)
+	}
+
+	return nil
+}
+
+// Open returns a reader that issues ranged GETs as it is read and seeked
+func (s3s *s3Storage) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	object, err := s3s.client.GetObject(ctx, s3s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("file not found or cannot be read: %v", err)
+	}
+
+	// GetObject is lazy; surface a missing key here rather than on first read
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("file not found or cannot be read: %v", err)
+	}
+
+	return object, nil
+}
+
+func (s3s *s3Storage) Stat(ctx context.Context, key string) (*StorageObject, error) {
+	info, err := s3s.client.StatObject(ctx, s3s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %v", err)
+	}
+
+	return &StorageObject{
+		Key:         info.Key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ModTime:     info.LastModified,
+	}, nil
+}
+
+func (s3s *s3Storage) Delete(ctx context.Context, key string) error {
+	if err := s3s.client.RemoveObject(ctx, s3s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+
+	return nil
+}
+
+func (s3s *s3Storage) DeletePrefix(ctx context.Context, prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("refusing to delete the storage root")
+	}
+
+	objects := s3s.client.ListObjects(ctx, s3s.bucket, minio.ListObjectsOptions{
+		Prefix:    strings.TrimSuffix(prefix, "/") + "/",
+		Recursive: true,
+	})
+
+	for result := range s3s.client.RemoveObjects(ctx, s3s.bucket, objects, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil {
+			return fmt.Errorf("failed to delete %s: %v", result.ObjectName, result.Err)
+		}
+	}
+
+	// The prefix may also name a single object
+	err := s3s.client.RemoveObject(ctx, s3s.bucket, prefix, minio.RemoveObjectOptions{})
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("failed to delete %s: %v", prefix, err)
+	}
+
+	return nil
+}
+
+func (s3s *s3Storage) List(ctx context.Context, prefix string, recursive bool) ([]StorageObject, error) {
+	if prefix != "" {
+		prefix = strings.TrimSuffix(prefix, "/") + "/"
+	}
+
+	var objects []StorageObject
+	for info := range s3s.client.ListObjects(ctx, s3s.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: recursive,
+	}) {
+		if info.Err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", prefix, info.Err)
+		}
+		// Non-recursive listings report sub-prefixes as keys ending in "/"
+		if strings.HasSuffix(info.Key, "/") {
+			continue
+		}
+
+		objects = append(objects, StorageObject{
+			Key:         info.Key,
+			Size:        info.Size,
+			ContentType: info.ContentType,
+			ModTime:     info.LastModified,
+		})
+	}
+
+	return objects, nil
+}
+
+// Download copies an object to a local file, for tools such as ffmpeg that
+// need a real path
+func (s3s *s3Storage) Download(ctx context.Context, key, filePath string) error {
+	if err := s3s.client.FGetObject(ctx, s3s.bucket, key, filePath, minio.GetObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to download %s: %v", key, err)
+	}
+
+	return nil
+}
+
+// PresignGet signs a download URL. Any params are covered by the signature,
+// so they cannot be removed from the link without breaking it.
+func (s3s *s3Storage) PresignGet(ctx context.Context, key string, expiry time.Duration, params url.Values) (string, error) {
+	u, err := s3s.client.PresignedGetObject(ctx, s3s.bucket, key, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s: %v", key, err)
+	}
+
+	return u.String(), nil
+}
+
+func (s3s *s3Storage) PresignPut(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	u, err := s3s.client.PresignedPutObject(ctx, s3s.bucket, key, expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s: %v", key, err)
+	}
+
+	return u.String(), nil
+}
+
+func (s3s *s3Storage) HealthCheck(ctx context.Context) error {
+	exists, err := s3s.client.BucketExists(ctx, s3s.bucket)
+	if err != nil {
+		return fmt.Errorf("storage check failed: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", s3s.bucket)
+	}
+
+	return nil
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"onflix/internal/config"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// newMinIOStorage connects to the MinIO server named by MINIO_ENDPOINT, e.g.
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	MINIO_ENDPOINT=http://localhost:9000 go test ./internal/services -run S3
+//
+// and gives the test a bucket of its own that is emptied and removed afterwards.
+// As with MongoDB, a missing server fails the test when CI is set.
+func newMinIOStorage(t *testing.T) (*s3Storage, config.AWSConfig) {
+	t.Helper()
+
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("MINIO_ENDPOINT must be set when CI is set")
+		}
+		t.Skip("MINIO_ENDPOINT is not set")
+	}
+
+	cfg := config.AWSConfig{
+		AccessKeyID:      getTestEnv("MINIO_ACCESS_KEY", "minioadmin"),
+		SecretAccessKey:  getTestEnv("MINIO_SECRET_KEY", "minioadmin"),
+		Region:           "us-east-1",
+		S3Bucket:         fmt.Sprintf("onflix-test-%d", time.Now().UnixNano()),
+		S3Endpoint:       endpoint,
+		S3ForcePathStyle: true,
+	}
+
+	s3s, err := newS3Storage(cfg)
+	if err != nil {
+		t.Fatalf("newS3Storage: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := s3s.client.MakeBucket(ctx, cfg.S3Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+		t.Fatalf("MakeBucket: %v", err)
+	}
+	t.Cleanup(func() {
+		objects := s3s.client.ListObjects(ctx, cfg.S3Bucket, minio.ListObjectsOptions{Recursive: true})
+		for range s3s.client.RemoveObjects(ctx, cfg.S3Bucket, objects, minio.RemoveObjectsOptions{}) {
+		}
+		s3s.client.RemoveBucket(ctx, cfg.S3Bucket)
+	})
+
+	return s3s, cfg
+}
+
+func getTestEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestS3StoragePutAndOpen(t *testing.T) {
+	s3s, _ := newMinIOStorage(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		size int
+		// sendSize is passed to Put; -1 means unknown
+		sendSize int64
+	}{
+		{"single part", 1024, 1024},
+		{"empty object", 0, 0},
+		{"multipart", 2*s3MinPartSize + 1024, 2*s3MinPartSize + 1024},
+		{"unknown size", s3MinPartSize + 1024, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "videos/" + strings.ReplaceAll(tt.name, " ", "-") + ".mp4"
+			data := randomBytes(t, tt.size)
+
+			if err := s3s.Put(ctx, key, bytes.NewReader(data), tt.sendSize, "video/mp4"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			info, err := s3s.Stat(ctx, key)
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			if info.Size != int64(tt.size) || info.ContentType != "video/mp4" {
+				t.Errorf("Stat = %d bytes %q, want %d bytes video/mp4", info.Size, info.ContentType, tt.size)
+			}
+
+			object, err := s3s.Open(ctx, key)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer object.Close()
+
+			got, err := io.ReadAll(object)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("read back %d bytes that differ from the %d written", len(got), len(data))
+			}
+
+			if tt.size > 16 {
+				offset := int64(tt.size / 2)
+				if _, err := object.Seek(offset, io.SeekStart); err != nil {
+					t.Fatalf("Seek: %v", err)
+				}
+				chunk := make([]byte, 16)
+				if _, err := io.ReadFull(object, chunk); err != nil {
+					t.Fatalf("ReadFull after Seek: %v", err)
+				}
+				if !bytes.Equal(chunk, data[offset:offset+16]) {
+					t.Errorf("ranged read at %d returned the wrong bytes", offset)
+				}
+			}
+		})
+	}
+}
+
+func TestS3StorageShortPutIsRemoved(t *testing.T) {
+	s3s, _ := newMinIOStorage(t)
+	ctx := context.Background()
+
+	err := s3s.Put(ctx, "short.bin", bytes.NewReader(randomBytes(t, 10)), 20, "application/octet-stream")
+	if err == nil {
+		t.Fatal("Put with fewer bytes than declared succeeded")
+	}
+	if _, err := s3s.Stat(ctx, "short.bin"); err == nil {
+		t.Error("short upload was left in the bucket")
+	}
+}
+
+func TestS3StorageOpenMissing(t *testing.T) {
+	s3s, _ := newMinIOStorage(t)
+
+	if _, err := s3s.Open(context.Background(), "missing.mp4"); err == nil {
+		t.Fatal("Open of a missing key succeeded")
+	}
+}
+
+func TestS3StorageListAndDeletePrefix(t *testing.T) {
+	s3s, _ := newMinIOStorage(t)
+	ctx := context.Background()
+
+	keys := []string{
+		"hls/title/master.m3u8",
+		"hls/title/720p/playlist.m3u8",
+		"hls/title/720p/segment_000.ts",
+		"hls/titles.json",
+		"hls/other/master.m3u8",
+	}
+	for _, key := range keys {
+		if err := s3s.Put(ctx, key, strings.NewReader(key), int64(len(key)), "text/plain"); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+
+	tests := []struct {
+		prefix    string
+		recursive bool
+		want      []string
+	}{
+		{"hls/title", false, []string{"hls/title/master.m3u8"}},
+		{"hls/title/", true, []string{"hls/title/720p/playlist.m3u8", "hls/title/720p/segment_000.ts", "hls/title/master.m3u8"}},
+		{"hls/missing", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s recursive=%v", tt.prefix, tt.recursive), func(t *testing.T) {
+			objects, err := s3s.List(ctx, tt.prefix, tt.recursive)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+
+			var got []string
+			for _, object := range objects {
+				got = append(got, object.Key)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := s3s.DeletePrefix(ctx, "hls/title"); err != nil {
+		t.Fatalf("DeletePrefix: %v", err)
+	}
+
+	// hls/titles.json shares the prefix string but not the directory
+	for _, key := range keys {
+		_, err := s3s.Stat(ctx, key)
+		kept := !strings.HasPrefix(key, "hls/title/")
+		if kept && err != nil {
+			t.Errorf("%s was deleted", key)
+		}
+		if !kept && err == nil {
+			t.Errorf("%s was not deleted", key)
+		}
+	}
+
+	if err := s3s.DeletePrefix(ctx, ""); err == nil {
+		t.Error("DeletePrefix accepted the storage root")
+	}
+}
+
+func TestS3StoragePresignedURLs(t *testing.T) {
+	s3s, _ := newMinIOStorage(t)
+	ctx := context.Background()
+	data := randomBytes(t, 4096)
+
+	putURL, err := s3s.PresignPut(ctx, "uploads/presigned.bin", time.Minute)
+	if err != nil {
+		t.Fatalf("PresignPut: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, putURL, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT returned %d", resp.StatusCode)
+	}
+
+	getURL, err := s3s.PresignGet(ctx, "uploads/presigned.bin", time.Minute, nil)
+	if err != nil {
+		t.Fatalf("PresignGet: %v", err)
+	}
+
+	resp, err = http.Get(getURL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, data) {
+		t.Fatalf("GET returned %d with %d bytes, want 200 with the uploaded %d bytes", resp.StatusCode, len(got), len(data))
+	}
+
+	expired, err := s3s.PresignGet(ctx, "uploads/presigned.bin", time.Second, nil)
+	if err != nil {
+		t.Fatalf("PresignGet: %v", err)
+	}
+	time.Sleep(2 * time.Second)
+	resp, err = http.Get(expired)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expired link returned %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestNewStorageServiceS3(t *testing.T) {
+	tests := []struct {
+		name    string
+		aws     func(config.AWSConfig) config.AWSConfig
+		wantErr bool
+	}{
+		{"bucket exists", func(cfg config.AWSConfig) config.AWSConfig { return cfg }, false},
+		{"missing bucket", func(cfg config.AWSConfig) config.AWSConfig {
+			cfg.S3Bucket += "-missing"
+			return cfg
+		}, true},
+		{"bad credentials", func(cfg config.AWSConfig) config.AWSConfig {
+			cfg.SecretAccessKey = "not-the-secret"
+			return cfg
+		}, true},
+	}
+
+	_, awsCfg := newMinIOStorage(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Storage: config.StorageConfig{Driver: "s3", BasePath: t.TempDir()},
+				AWS:     tt.aws(awsCfg),
+			}
+
+			ss, err := NewStorageService(cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewStorageService fell back instead of failing")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewStorageService: %v", err)
+			}
+			if _, ok := ss.backend.(*s3Storage); !ok {
+				t.Fatalf("backend is %T, want *s3Storage", ss.backend)
+			}
+		})
+	}
+}
+
+func TestNewStorageServiceS3Unconfigured(t *testing.T) {
+	cfg := &config.Config{
+		Storage: config.StorageConfig{Driver: "s3", BasePath: t.TempDir()},
+	}
+
+	if _, err := NewStorageService(cfg); err == nil {
+		t.Fatal("NewStorageService without a bucket fell back to local storage")
+	}
+}
+
+func TestS3StoredURLs(t *testing.T) {
+	tests := []struct {
+		name       string
+		cloudFront string
+		want       string
+	}{
+		{"served by the API", "", "https://api.example.com/uploads/images/ab/cd.webp"},
+		{"behind CloudFront", "https://d1.cloudfront.net/", "https://d1.cloudfront.net/images/ab/cd.webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Server.AppURL = "https://api.example.com"
+			cfg.AWS.CloudFrontURL = tt.cloudFront
+			ss := &StorageService{config: cfg, backend: &s3Storage{}}
+
+			// Stored URLs outlive any presigned link, so they must not carry a signature
+			got := ss.generatePublicURL("images/ab/cd.webp")
+			if got != tt.want {
+				t.Errorf("generatePublicURL = %s, want %s", got, tt.want)
+			}
+			if key := ss.PathFromURL(got); key != "images/ab/cd.webp" {
+				t.Errorf("PathFromURL(%s) = %s", got, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// backend/internal/services/stream.go
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const streamSessionsCollection = "stream_sessions"
+
+// StreamService tracks which devices are playing on an account so
+// PlanLimits.MaxConcurrentStreams can be enforced
+type StreamService struct {
+	config *config.Config
+	db     *mongo.Database
+}
+
+var ErrStreamSessionInactive = errors.New("stream session not found or expired")
+
+// StreamDevice identifies the player starting a stream. ID and Name are
+// client-supplied labels; only SessionID, issued by StartStream, lets a
+// player reuse its slot.
+type StreamDevice struct {
+	ID        string
+	Name      string
+	IPAddress string
+	UserAgent string
+	SessionID primitive.ObjectID // Session the player was issued earlier, if any
+}
+
+// StreamLimitError is returned when starting a stream would exceed the plan limit
+type StreamLimitError struct {
+	Limit  int                    `json:"limit"`
+	Active []models.StreamSession `json:"active_streams"`
+}
+
+func (e *StreamLimitError) Error() string {
+	return fmt.Sprintf("concurrent stream limit of %d reached", e.Limit)
+}
+
+func NewStreamService(cfg *config.Config, db *mongo.Database) *StreamService {
+	return &StreamService{
+		config: cfg,
+		db:     db,
+	}
+}
+
+func (ss *StreamService) Close() {
+	// Cleanup resources if needed
+}
+
+// StartStream opens a playback session for a device. A player presenting a
+// session it was issued earlier has that session moved to the new title
+// instead of using another slot.
+func (ss *StreamService) StartStream(ctx context.Context, user *models.User, contentID primitive.ObjectID, episodeID *primitive.ObjectID, device StreamDevice) (*models.StreamSession, error) {
+	if user.Subscription == nil {
+		return nil, fmt.Errorf("user has no subscription")
+	}
+
+	if device.ID == "" {
+		device.ID = fingerprintDevice(device)
+	}
+
+	now := time.Now()
+	collection := ss.db.Collection(streamSessionsCollection)
+
+	var session models.StreamSession
+	if !device.SessionID.IsZero() {
+		err := collection.FindOneAndUpdate(
+			ctx,
+			bson.M{
+				"_id":        device.SessionID,
+				"user_id":    user.ID,
+				"ended_at":   bson.M{"$exists": false},
+				"expires_at": bson.M{"$gt": now},
+			},
+			bson.M{"$set": bson.M{
+				"content_id":        contentID,
+				"episode_id":        episodeID,
+				"device_id":         device.ID,
+				"device_name":       device.Name,
+				"ip_address":        device.IPAddress,
+				"user_agent":        device.UserAgent,
+				"last_heartbeat_at": now,
+				"expires_at":        now.Add(ss.timeout()),
+			}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&session)
+
+		if err == nil {
+			return &session, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to look up stream session: %v", err)
+		}
+		// A lapsed session takes a slot like any new one
+	}
+
+	limit, err := ss.streamLimit(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := ss.ActiveStreams(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(active) >= limit {
+		return nil, &StreamLimitError{Limit: limit, Active: active}
+	}
+
+	session = models.StreamSession{
+		ID:              primitive.NewObjectID(),
+		UserID:          user.ID,
+		ContentID:       contentID,
+		EpisodeID:       episodeID,
+		DeviceID:        device.ID,
+		DeviceName:      device.Name,
+		IPAddress:       device.IPAddress,
+		UserAgent:       device.UserAgent,
+		StartedAt:       now,
+		LastHeartbeatAt: now,
+		ExpiresAt:       now.Add(ss.timeout()),
+	}
+
+	over, err := claimSlot(ctx, collection, session.ID, session, limit, func() ([]primitive.ObjectID, error) {
+		if active, err = ss.ActiveStreams(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		ids := make([]primitive.ObjectID, len(active))
+		for i := range active {
+			ids[i] = active[i].ID
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if over >= 0 {
+		return nil, &StreamLimitError{Limit: limit, Active: append(active[:over:over], active[over+1:]...)}
+	}
+
+	ss.recordConcurrentPeak(ctx, user, len(active))
+
+	return &session, nil
+}
+
+// claimSlot inserts doc, whose _id is id, then re-reads the holder's active
+// IDs, oldest first, through reload. Two requests can both pass a limit check
+// made before either inserted, so a doc that landed at or beyond limit is
+// deleted again and its position in the re-read set returned; otherwise
+// claimSlot returns -1.
+func claimSlot(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, doc interface{}, limit int, reload func() ([]primitive.ObjectID, error)) (int, error) {
+	if _, err := collection.InsertOne(ctx, doc); err != nil {
+		return -1, fmt.Errorf("failed to insert into %s: %v", collection.Name(), err)
+	}
+
+	ids, err := reload()
+	if err != nil {
+		return -1, err
+	}
+
+	if limit > 0 {
+		for i, activeID := range ids {
+			if activeID == id && i >= limit {
+				collection.DeleteOne(ctx, bson.M{"_id": id})
+				return i, nil
+			}
+		}
+	}
+
+	return -1, nil
+}
+
+// Heartbeat extends a live session. Sessions that have already lapsed must be restarted.
+func (ss *StreamService) Heartbeat(ctx context.Context, userID, sessionID primitive.ObjectID) (*models.StreamSession, error) {
+	now := time.Now()
+
+	var session models.StreamSession
+	err := ss.db.Collection(streamSessionsCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"_id":        sessionID,
+			"user_id":    userID,
+			"ended_at":   bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{
+			"last_heartbeat_at": now,
+			"expires_at":        now.Add(ss.timeout()),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&session)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrStreamSessionInactive
+		}
+		return nil, fmt.Errorf("failed to update stream session: %v", err)
+	}
+
+	return &session, nil
+}
+
+// ActiveSession returns one of the user's sessions if it has not been
+// stopped and its heartbeat has not lapsed
+func (ss *StreamService) ActiveSession(ctx context.Context, userID, sessionID primitive.ObjectID) (*models.StreamSession, error) {
+	var session models.StreamSession
+	err := ss.db.Collection(streamSessionsCollection).FindOne(
+		ctx,
+		bson.M{
+			"_id":        sessionID,
+			"user_id":    userID,
+			"ended_at":   bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+	).Decode(&session)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrStreamSessionInactive
+		}
+		return nil, fmt.Errorf("failed to load stream session: %v", err)
+	}
+
+	return &session, nil
+}
+
+// StopStream ends a session and frees its slot immediately
+func (ss *StreamService) StopStream(ctx context.Context, userID, sessionID primitive.ObjectID) error {
+	now := time.Now()
+
+	result, err := ss.db.Collection(streamSessionsCollection).UpdateOne(
+		ctx,
+		bson.M{
+			"_id":      sessionID,
+			"user_id":  userID,
+			"ended_at": bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{
+			"ended_at":   now,
+			"expires_at": now,
+		}},
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to stop stream session: %v", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("stream session not found")
+	}
+
+	return nil
+}
+
+// ActiveStreams returns the sessions currently counting towards the limit, oldest first
+func (ss *StreamService) ActiveStreams(ctx context.Context, userID primitive.ObjectID) ([]models.StreamSession, error) {
+	cursor, err := ss.db.Collection(streamSessionsCollection).Find(
+		ctx,
+		bson.M{
+			"user_id":    userID,
+			"ended_at":   bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": time.Now()},
+		},
+		options.Find().SetSort(bson.D{{Key: "started_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stream sessions: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.StreamSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode stream sessions: %v", err)
+	}
+
+	return sessions, nil
+}
+
+// SubscriptionActive reports whether the user's subscription is active or
+// trialing and inside its billing period
+func SubscriptionActive(user *models.User) bool {
+	if user.Subscription == nil {
+		return false
+	}
+
+	if user.Subscription.Status != models.SubscriptionStatusActive &&
+		user.Subscription.Status != models.SubscriptionStatusTrialing {
+		return false
+	}
+
+	return time.Now().Before(user.Subscription.CurrentPeriodEnd)
+}
+
+// streamLimit returns the plan's MaxConcurrentStreams, where 0 means unlimited
+func (ss *StreamService) streamLimit(ctx context.Context, user *models.User) (int, error) {
+	var plan models.SubscriptionPlan
+	err := ss.db.Collection("subscription_plans").FindOne(
+		ctx,
+		bson.M{"_id": user.Subscription.PlanID},
+	).Decode(&plan)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to load subscription plan: %v", err)
+	}
+
+	return plan.Limits.MaxConcurrentStreams, nil
+}
+
+// recordConcurrentPeak raises SubscriptionUsage.ConcurrentPeak for the current billing period
+func (ss *StreamService) recordConcurrentPeak(ctx context.Context, user *models.User, active int) {
+	now := time.Now()
+
+	_, err := ss.db.Collection("subscription_usage").UpdateOne(
+		ctx,
+		bson.M{
+			"user_id":      user.ID,
+			"period.start": user.Subscription.CurrentPeriodStart,
+		},
+		bson.M{
+			"$max": bson.M{"concurrent_peak": active},
+			"$set": bson.M{"updated_at": now},
+			"$setOnInsert": bson.M{
+				"period.end":      user.Subscription.CurrentPeriodEnd,
+				"streaming_hours": 0,
+				"downloads":       0,
+				"profiles_used":   len(user.Profiles),
+				"created_at":      now,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+
+	if err != nil {
+		fmt.Printf("Failed to record concurrent stream peak for user %s: %v\n", user.ID.Hex(), err)
+	}
+}
+
+func (ss *StreamService) timeout() time.Duration {
+	if ss.config.Video.StreamTimeout > 0 {
+		return time.Duration(ss.config.Video.StreamTimeout) * time.Second
+	}
+	return 90 * time.Second
+}
+
+// fingerprintDevice derives a device label for players that do not send one
+func fingerprintDevice(device StreamDevice) string {
+	h := sha256.Sum256([]byte(device.UserAgent + "|" + device.IPAddress))
+	return hex.EncodeToString(h[:])[:16]
+}
@@ -0,0 +1,274 @@
+// backend/internal/services/subtitle.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"onflix/internal/models"
+	"onflix/internal/subtitle"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrSubtitleContentNotFound = errors.New("content not found")
+	ErrSubtitleNotFound        = errors.New("subtitle not found")
+	ErrSubtitleInvalid         = errors.New("subtitle needs a language tag such as en or pt-BR")
+	ErrSubtitleNoVideo         = errors.New("content has no full-length video to attach subtitles to")
+)
+
+// SubtitleUpload describes how an uploaded subtitle file is read and retimed
+type SubtitleUpload struct {
+	Language string
+	Label    string
+	Format   subtitle.Format // Detected from the file when empty
+	Charset  string          // Detected from the file when empty
+	Offset   time.Duration
+	// Converts timing authored against one frame rate to another when both are set
+	FromFrameRate float64
+	ToFrameRate   float64
+	FixOverlaps   bool
+}
+
+// PrepareSubtitle decodes and parses an uploaded file, then drops unusable
+// cues and applies the retiming in upload. The issues returned were found
+// before cleaning, so they describe the file as it was uploaded.
+func PrepareSubtitle(data []byte, upload SubtitleUpload) (*subtitle.Document, []subtitle.Issue, error) {
+	text, err := subtitle.Decode(data, upload.Charset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := subtitle.Parse(text, upload.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	issues := doc.Validate()
+	doc.Clean()
+
+	if upload.Offset != 0 {
+		doc.Shift(upload.Offset)
+	}
+	if upload.FromFrameRate != 0 || upload.ToFrameRate != 0 {
+		if err := doc.ConvertFrameRate(upload.FromFrameRate, upload.ToFrameRate); err != nil {
+			return nil, nil, err
+		}
+	}
+	if upload.FixOverlaps {
+		doc.ResolveOverlaps()
+	}
+
+	if len(doc.Cues) == 0 {
+		return nil, nil, subtitle.ErrNoCues
+	}
+
+	return doc, issues, nil
+}
+
+// Subtitles lists a title's subtitles, one per language. They are stored on
+// every full-length video, so the first video carrying a language wins.
+func (vs *VideoService) Subtitles(ctx context.Context, contentID primitive.ObjectID) ([]models.Subtitle, error) {
+	content, err := vs.subtitleContent(ctx, contentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return TitleSubtitles(content.Videos), nil
+}
+
+// AddSubtitle stores a document as WebVTT and attaches it to every
+// full-length video of a title, replacing any subtitle in the same language
+func (vs *VideoService) AddSubtitle(ctx context.Context, contentID primitive.ObjectID, doc *subtitle.Document, language, label string) (*models.Subtitle, error) {
+	if !languageTag.MatchString(language) {
+		return nil, ErrSubtitleInvalid
+	}
+
+	content, err := vs.subtitleContent(ctx, contentID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasVideo := false
+	for _, video := range content.Videos {
+		hasVideo = hasVideo || video.Type == models.VideoTypeFull
+	}
+	if !hasVideo {
+		return nil, ErrSubtitleNoVideo
+	}
+
+	vtt, err := doc.Encode(subtitle.FormatVTT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode subtitle: %v", err)
+	}
+
+	fileURL, err := vs.storage.UploadSubtitle(contentID.Hex(), language, vtt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store subtitle: %v", err)
+	}
+
+	if label == "" {
+		label = language
+	}
+	track := models.Subtitle{
+		ID:       primitive.NewObjectID(),
+		Language: language,
+		Label:    label,
+		FileURL:  fileURL,
+	}
+
+	for i, video := range content.Videos {
+		if video.Type != models.VideoTypeFull {
+			continue
+		}
+		tracks := []models.Subtitle{}
+		for _, existing := range video.Subtitles {
+			if !strings.EqualFold(existing.Language, language) {
+				tracks = append(tracks, existing)
+			}
+		}
+		content.Videos[i].Subtitles = append(tracks, track)
+	}
+
+	if err := vs.saveSubtitles(ctx, contentID, content.Videos); err != nil {
+		return nil, err
+	}
+
+	return &track, nil
+}
+
+func (vs *VideoService) DeleteSubtitle(ctx context.Context, contentID, subtitleID primitive.ObjectID) error {
+	content, err := vs.subtitleContent(ctx, contentID)
+	if err != nil {
+		return err
+	}
+
+	var removed *models.Subtitle
+	for i, video := range content.Videos {
+		tracks := []models.Subtitle{}
+		for _, track := range video.Subtitles {
+			if track.ID == subtitleID {
+				removed = &track
+				continue
+			}
+			tracks = append(tracks, track)
+		}
+		content.Videos[i].Subtitles = tracks
+	}
+	if removed == nil {
+		return ErrSubtitleNotFound
+	}
+
+	if err := vs.saveSubtitles(ctx, contentID, content.Videos); err != nil {
+		return err
+	}
+
+	// The file is shared by every video, and a newer upload in the same
+	// language would have replaced the track, so nothing else refers to it
+	if err := vs.storage.DeleteFile(vs.storage.PathFromURL(removed.FileURL)); err != nil {
+		fmt.Printf("Failed to delete subtitle file %s: %v\n", removed.FileURL, err)
+	}
+	return nil
+}
+
+// SubtitleVTT loads the subtitle in language from a title's videos and
+// returns it as clean WebVTT, whatever format it was stored in
+func (vs *VideoService) SubtitleVTT(videos []models.ContentVideo, language string) ([]byte, error) {
+	for _, track := range TitleSubtitles(videos) {
+		if !strings.EqualFold(track.Language, language) {
+			continue
+		}
+
+		data, err := vs.storage.GetFile(vs.storage.PathFromURL(track.FileURL))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read subtitle: %v", err)
+		}
+		return vs.ConvertSubtitleFormat(data, subtitle.FormatVTT)
+	}
+
+	return nil, ErrSubtitleNotFound
+}
+
+// ConvertSubtitleFormat reads a subtitle file in any supported format and
+// character set and writes it out in format
+func (vs *VideoService) ConvertSubtitleFormat(data []byte, format subtitle.Format) ([]byte, error) {
+	text, err := subtitle.Decode(data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := subtitle.Parse(text, "")
+	if err != nil {
+		return nil, err
+	}
+	doc.Clean()
+
+	return doc.Encode(format)
+}
+
+// TitleSubtitles gathers the subtitles of a title's full-length videos, one
+// per language
+func TitleSubtitles(videos []models.ContentVideo) []models.Subtitle {
+	tracks := []models.Subtitle{}
+	seen := make(map[string]bool)
+
+	for _, video := range videos {
+		if video.Type != models.VideoTypeFull {
+			continue
+		}
+		for _, track := range video.Subtitles {
+			language := strings.ToLower(track.Language)
+			if !seen[language] {
+				seen[language] = true
+				tracks = append(tracks, track)
+			}
+		}
+	}
+
+	return tracks
+}
+
+func (vs *VideoService) subtitleContent(ctx context.Context, contentID primitive.ObjectID) (*models.Content, error) {
+	var content models.Content
+	err := vs.db.Collection("content").FindOne(ctx, bson.M{"_id": contentID}).Decode(&content)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSubtitleContentNotFound
+		}
+		return nil, fmt.Errorf("failed to load content: %v", err)
+	}
+
+	return &content, nil
+}
+
+func (vs *VideoService) saveSubtitles(ctx context.Context, contentID primitive.ObjectID, videos []models.ContentVideo) error {
+	set := bson.M{"updated_at": time.Now()}
+	var filters []interface{}
+	for i, video := range videos {
+		name := fmt.Sprintf("v%d", i)
+		set["videos.$["+name+"].subtitles"] = video.Subtitles
+		filters = append(filters, bson.M{name + "._id": video.ID})
+	}
+
+	update := options.Update()
+	if len(filters) > 0 {
+		update.SetArrayFilters(options.ArrayFilters{Filters: filters})
+	}
+
+	result, err := vs.db.Collection("content").UpdateOne(ctx, bson.M{"_id": contentID}, bson.M{"$set": set}, update)
+	if err != nil {
+		return fmt.Errorf("failed to save subtitles: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrSubtitleContentNotFound
+	}
+
+	return nil
+}
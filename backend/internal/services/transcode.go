@@ -0,0 +1,726 @@
+// backend/internal/services/transcode.go
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	transcodeJobsCollection = "transcode_jobs"
+	transcodePollInterval   = 5 * time.Second
+	transcodeLease          = 2 * time.Minute
+	transcodeRenewInterval  = 30 * time.Second
+	transcodeReportInterval = 2 * time.Second
+)
+
+// TranscodeService runs queued transcode jobs from Mongo on a pool of worker
+// goroutines. Jobs are leased, so a job held by a crashed process is picked up
+// again once its lease lapses. Season intro detection runs on the same queue.
+type TranscodeService struct {
+	config   *config.Config
+	db       *mongo.Database
+	storage  *StorageService
+	markers  *MarkerService
+	workerID string
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+type transcodeProbe struct {
+	Duration float64
+	Height   int
+}
+
+func NewTranscodeService(cfg *config.Config, db *mongo.Database, storage *StorageService, markers *MarkerService) *TranscodeService {
+	hostname, _ := os.Hostname()
+
+	return &TranscodeService{
+		config:   cfg,
+		db:       db,
+		storage:  storage,
+		markers:  markers,
+		workerID: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Start launches the configured number of workers
+func (ts *TranscodeService) Start() {
+	if ts.cancel != nil {
+		return
+	}
+
+	workers := ts.config.Video.TranscodeWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ts.cancel = cancel
+
+	for i := 0; i < workers; i++ {
+		ts.wg.Add(1)
+		go ts.worker(ctx, fmt.Sprintf("%s-%d", ts.workerID, i))
+	}
+}
+
+// Close stops the workers. Jobs they were running go back on the queue.
+func (ts *TranscodeService) Close() {
+	if ts.cancel != nil {
+		ts.cancel()
+		ts.wg.Wait()
+		ts.cancel = nil
+	}
+}
+
+// Enqueue queues a job transcoding the given video into each quality, lowest first.
+// An empty quality list means the full ladder.
+func (ts *TranscodeService) Enqueue(ctx context.Context, contentID primitive.ObjectID, video models.ContentVideo, qualities []models.VideoQuality) (*models.TranscodeJob, error) {
+	if video.FileURL == "" {
+		return nil, fmt.Errorf("video has no source file")
+	}
+
+	if len(qualities) == 0 {
+		for quality := range qualityDimensions {
+			qualities = append(qualities, quality)
+		}
+	}
+
+	seen := make(map[models.VideoQuality]bool)
+	var targets []models.VideoQuality
+	for _, quality := range qualities {
+		if _, ok := qualityDimensions[quality]; !ok {
+			return nil, fmt.Errorf("unsupported video quality: %s", quality)
+		}
+		if !seen[quality] {
+			seen[quality] = true
+			targets = append(targets, quality)
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return qualityBandwidth[targets[i]] < qualityBandwidth[targets[j]]
+	})
+
+	job := &models.TranscodeJob{
+		Type:          models.TranscodeJobVideo,
+		ContentID:     contentID,
+		SourceVideoID: video.ID,
+		SourceURL:     video.FileURL,
+		Qualities:     targets,
+		Outputs:       []models.TranscodeOutput{},
+	}
+
+	if err := ts.queue(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// EnqueueIntros queues intro detection for a season. With apply the
+// suggestions are also saved, except over intros that were set by hand.
+func (ts *TranscodeService) EnqueueIntros(ctx context.Context, contentID, seasonID primitive.ObjectID, apply bool) (*models.TranscodeJob, error) {
+	job := &models.TranscodeJob{
+		Type:        models.TranscodeJobIntros,
+		ContentID:   contentID,
+		SeasonID:    &seasonID,
+		ApplyIntros: apply,
+		Outputs:     []models.TranscodeOutput{},
+	}
+
+	if err := ts.queue(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Job returns a job by ID
+func (ts *TranscodeService) Job(ctx context.Context, jobID primitive.ObjectID) (*models.TranscodeJob, error) {
+	var job models.TranscodeJob
+	err := ts.db.Collection(transcodeJobsCollection).FindOne(ctx, bson.M{"_id": jobID}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (ts *TranscodeService) queue(ctx context.Context, job *models.TranscodeJob) error {
+	maxAttempts := ts.config.Video.TranscodeAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	now := time.Now()
+	job.ID = primitive.NewObjectID()
+	job.Status = models.TranscodeStatusQueued
+	job.MaxAttempts = maxAttempts
+	job.NextAttemptAt = now
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if _, err := ts.db.Collection(transcodeJobsCollection).InsertOne(ctx, job); err != nil {
+		return fmt.Errorf("failed to queue transcode job: %v", err)
+	}
+
+	return nil
+}
+
+// Cancel stops a job. Queued jobs are cancelled at once; running jobs are
+// flagged and stopped by their worker at the next progress report.
+func (ts *TranscodeService) Cancel(ctx context.Context, jobID primitive.ObjectID) (*models.TranscodeJob, error) {
+	now := time.Now()
+	collection := ts.db.Collection(transcodeJobsCollection)
+
+	var job models.TranscodeJob
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": jobID, "status": models.TranscodeStatusQueued},
+		bson.M{"$set": bson.M{
+			"status":           models.TranscodeStatusCancelled,
+			"cancel_requested": true,
+			"completed_at":     now,
+			"updated_at":       now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+
+	if err == nil {
+		return &job, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to cancel transcode job: %v", err)
+	}
+
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": jobID, "status": models.TranscodeStatusProcessing},
+		bson.M{"$set": bson.M{
+			"cancel_requested": true,
+			"updated_at":       now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("transcode job is not running")
+		}
+		return nil, fmt.Errorf("failed to cancel transcode job: %v", err)
+	}
+
+	return &job, nil
+}
+
+func (ts *TranscodeService) worker(ctx context.Context, workerID string) {
+	defer ts.wg.Done()
+
+	for {
+		job, err := ts.claim(ctx, workerID)
+		if err != nil && ctx.Err() == nil {
+			fmt.Printf("Transcode worker %s failed to claim a job: %v\n", workerID, err)
+		}
+
+		if job != nil {
+			ts.run(ctx, workerID, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(transcodePollInterval):
+		}
+	}
+}
+
+// claim leases the oldest runnable job, including jobs whose previous worker
+// disappeared. A job whose worker disappeared on its last attempt, e.g. because
+// ffmpeg took the process down, is failed instead of being retried forever.
+func (ts *TranscodeService) claim(ctx context.Context, workerID string) (*models.TranscodeJob, error) {
+	now := time.Now()
+	collection := ts.db.Collection(transcodeJobsCollection)
+	attemptsLeft := bson.M{"$lt": bson.A{"$attempts", "$max_attempts"}}
+	attemptsUsed := bson.M{"$gte": bson.A{"$attempts", "$max_attempts"}}
+
+	_, err := collection.UpdateMany(
+		ctx,
+		bson.M{
+			"status":           models.TranscodeStatusProcessing,
+			"lease_expires_at": bson.M{"$lt": now},
+			"$expr":            attemptsUsed,
+		},
+		bson.M{"$set": bson.M{
+			"status":       models.TranscodeStatusFailed,
+			"error":        "worker stopped responding",
+			"completed_at": now,
+			"updated_at":   now,
+		}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail abandoned transcode jobs: %v", err)
+	}
+
+	var job models.TranscodeJob
+	err = collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"$or": []bson.M{
+			{"status": models.TranscodeStatusQueued, "next_attempt_at": bson.M{"$lte": now}},
+			{"status": models.TranscodeStatusProcessing, "lease_expires_at": bson.M{"$lt": now}, "$expr": attemptsLeft},
+		}},
+		bson.M{
+			"$set": bson.M{
+				"status":           models.TranscodeStatusProcessing,
+				"worker_id":        workerID,
+				"lease_expires_at": now.Add(transcodeLease),
+				"started_at":       now,
+				"updated_at":       now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (ts *TranscodeService) run(ctx context.Context, workerID string, job *models.TranscodeJob) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	collection := ts.db.Collection(transcodeJobsCollection)
+	owned := bson.M{"_id": job.ID, "worker_id": workerID}
+
+	if job.CancelRequested {
+		cancel()
+	}
+
+	// The lease is renewed for the whole run, not only while ffmpeg reports
+	// progress, so a long fetch or upload is not mistaken for a dead worker
+	stopRenewing := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(transcodeRenewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopRenewing:
+				return
+			case <-ticker.C:
+			}
+
+			var current models.TranscodeJob
+			err := collection.FindOneAndUpdate(
+				ctx,
+				owned,
+				bson.M{"$set": bson.M{"lease_expires_at": time.Now().Add(transcodeLease)}},
+				options.FindOneAndUpdate().SetReturnDocument(options.After),
+			).Decode(&current)
+
+			// Losing the job to another worker is treated like a cancellation
+			if err == mongo.ErrNoDocuments || current.CancelRequested {
+				cancel()
+			}
+		}
+	}()
+
+	// report stores progress and picks up cancellation requests
+	lastReport := time.Time{}
+	report := func(quality models.VideoQuality, progress float64) {
+		if time.Since(lastReport) < transcodeReportInterval {
+			return
+		}
+		lastReport = time.Now()
+
+		var current models.TranscodeJob
+		err := collection.FindOneAndUpdate(
+			ctx,
+			owned,
+			bson.M{"$set": bson.M{
+				"progress":        math.Round(progress*10) / 10,
+				"current_quality": quality,
+				"updated_at":      time.Now(),
+			}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&current)
+
+		if err == mongo.ErrNoDocuments || current.CancelRequested {
+			cancel()
+		}
+	}
+
+	// Each job type stores its results on completion
+	results := bson.M{}
+	var err error
+	if job.Type == models.TranscodeJobIntros {
+		var intros []models.IntroSuggestion
+		intros, err = ts.detectIntros(ctx, jobCtx, job, report)
+		results["intros"] = intros
+	} else {
+		var outputs []models.TranscodeOutput
+		var probe *transcodeProbe
+		outputs, probe, err = ts.transcode(jobCtx, job, report)
+		if err == nil {
+			err = jobCtx.Err()
+		}
+		if err == nil {
+			err = ts.attachOutputs(ctx, job, outputs, probe)
+		}
+		results["outputs"] = outputs
+	}
+
+	close(stopRenewing)
+	<-renewed
+
+	now := time.Now()
+	update := bson.M{
+		"updated_at": now,
+	}
+
+	switch {
+	case err == nil:
+		for field, value := range results {
+			update[field] = value
+		}
+		update["status"] = models.TranscodeStatusCompleted
+		update["progress"] = 100
+		update["completed_at"] = now
+		update["error"] = ""
+	case ctx.Err() != nil:
+		// Shutting down: hand the job back without using up an attempt
+		collection.UpdateOne(context.Background(), owned, bson.M{
+			"$set": bson.M{"status": models.TranscodeStatusQueued, "next_attempt_at": now, "updated_at": now},
+			"$inc": bson.M{"attempts": -1},
+		})
+		return
+	case jobCtx.Err() != nil:
+		update["status"] = models.TranscodeStatusCancelled
+		update["completed_at"] = now
+	case job.Attempts < job.MaxAttempts:
+		update["status"] = models.TranscodeStatusQueued
+		update["error"] = err.Error()
+		update["next_attempt_at"] = now.Add(time.Duration(job.Attempts*job.Attempts) * 30 * time.Second)
+	default:
+		update["status"] = models.TranscodeStatusFailed
+		update["error"] = err.Error()
+		update["completed_at"] = now
+	}
+
+	if _, err := collection.UpdateOne(ctx, owned, bson.M{"$set": update}); err != nil {
+		fmt.Printf("Failed to update transcode job %s: %v\n", job.ID.Hex(), err)
+	}
+}
+
+// detectIntros fingerprints the job's season and, if asked, saves the
+// suggestions. Saving uses ctx so a late cancellation cannot stop it midway.
+func (ts *TranscodeService) detectIntros(ctx, jobCtx context.Context, job *models.TranscodeJob, report func(models.VideoQuality, float64)) ([]models.IntroSuggestion, error) {
+	if job.SeasonID == nil {
+		return nil, fmt.Errorf("intro detection job has no season")
+	}
+
+	intros, err := ts.markers.DetectIntros(jobCtx, job.ContentID, *job.SeasonID, func(progress float64) {
+		report("", progress)
+	})
+	if err == nil {
+		err = jobCtx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if job.ApplyIntros {
+		if err := ts.markers.ApplyIntros(ctx, job.ContentID, intros); err != nil {
+			return nil, err
+		}
+	}
+
+	return intros, nil
+}
+
+// transcode encodes each target quality into videos/<content>/<source>/<quality>.mp4
+func (ts *TranscodeService) transcode(ctx context.Context, job *models.TranscodeJob, report func(models.VideoQuality, float64)) ([]models.TranscodeOutput, *transcodeProbe, error) {
+	source, release, err := ts.storage.Fetch(ctx, ts.storage.PathFromURL(job.SourceURL))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid source: %v", err)
+	}
+	defer release()
+
+	probe, err := ts.probe(ctx, source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Never upscale; a source below the whole ladder still gets the lowest rung
+	var targets []models.VideoQuality
+	for _, quality := range job.Qualities {
+		if probe.Height == 0 || qualityDimensions[quality][1] <= probe.Height {
+			targets = append(targets, quality)
+		}
+	}
+	if len(targets) == 0 {
+		targets = job.Qualities[:1]
+	}
+
+	var outputs []models.TranscodeOutput
+	for i, quality := range targets {
+		relativePath := path.Join("videos", job.ContentID.Hex(), job.SourceVideoID.Hex(), string(quality)+".mp4")
+		outputPath, err := ts.storage.ResolvePath(relativePath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create directory: %v", err)
+		}
+
+		progress := func(fraction float64) {
+			report(quality, (float64(i)+fraction)/float64(len(targets))*100)
+		}
+
+		if err := ts.encode(ctx, source, outputPath, quality, probe.Duration, progress); err != nil {
+			return nil, nil, err
+		}
+
+		info, err := os.Stat(outputPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("missing %s output: %v", quality, err)
+		}
+
+		if err := ts.storage.Publish(ctx, relativePath); err != nil {
+			return nil, nil, err
+		}
+
+		outputs = append(outputs, models.TranscodeOutput{
+			VideoID:  primitive.NewObjectID(),
+			Quality:  quality,
+			FileURL:  ts.storage.generatePublicURL(relativePath),
+			FileSize: info.Size(),
+			Duration: int(math.Round(probe.Duration)),
+		})
+	}
+
+	return outputs, probe, nil
+}
+
+// encode runs ffmpeg for one rendition, writing to a temporary file that only
+// replaces the output once encoding succeeded
+func (ts *TranscodeService) encode(ctx context.Context, source, outputPath string, quality models.VideoQuality, duration float64, progress func(float64)) error {
+	dimensions := qualityDimensions[quality]
+	bitrate := qualityBandwidth[quality]
+	tmpPath := outputPath + ".part"
+	defer os.Remove(tmpPath)
+
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-nostats", "-y",
+		"-i", source,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale=-2:%d", dimensions[1]),
+		"-c:v", "libx264", "-preset", "medium", "-profile:v", "high",
+		"-b:v", strconv.Itoa(bitrate),
+		"-maxrate", strconv.Itoa(bitrate * 107 / 100),
+		"-bufsize", strconv.Itoa(bitrate * 3 / 2),
+		// Keyframes on segment boundaries so every rendition packages identically
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentDuration(ts.config)),
+		"-c:a", "aac", "-b:a", "128k", "-ac", "2",
+		"-movflags", "+faststart",
+		"-progress", "pipe:1",
+		"-f", "mp4", tmpPath,
+	}
+
+	cmd := exec.CommandContext(ctx, ts.config.Video.FFmpegPath, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to read ffmpeg progress: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %v", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		if key != "out_time_us" && key != "out_time_ms" {
+			continue
+		}
+		// Both keys carry microseconds
+		if micros, err := strconv.ParseFloat(value, 64); err == nil && duration > 0 {
+			progress(math.Min(micros/1e6/duration, 1))
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("ffmpeg failed for %s: %v: %s", quality, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return fmt.Errorf("failed to store %s output: %v", quality, err)
+	}
+
+	return nil
+}
+
+func (ts *TranscodeService) probe(ctx context.Context, source string) (*transcodeProbe, error) {
+	cmd := exec.CommandContext(ctx, ts.config.Video.FFprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=height:format=duration",
+		"-of", "json",
+		source,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %v", err)
+	}
+
+	var result struct {
+		Streams []struct {
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %v", err)
+	}
+
+	probe := &transcodeProbe{}
+	probe.Duration, _ = strconv.ParseFloat(result.Format.Duration, 64)
+	if len(result.Streams) > 0 {
+		probe.Height = result.Streams[0].Height
+	}
+
+	if probe.Duration <= 0 {
+		return nil, fmt.Errorf("could not determine source duration")
+	}
+
+	return probe, nil
+}
+
+// attachOutputs replaces the renditions a previous run produced from the same
+// source with the new ones and records the measured source duration. Each step
+// is a targeted update of the videos array, so edits made to other videos,
+// markers or audio tracks while the job ran are kept.
+func (ts *TranscodeService) attachOutputs(ctx context.Context, job *models.TranscodeJob, outputs []models.TranscodeOutput, probe *transcodeProbe) error {
+	collection := ts.db.Collection("content")
+	withSource := bson.M{"_id": job.ContentID, "videos._id": job.SourceVideoID}
+
+	var content models.Content
+	err := collection.FindOne(
+		ctx,
+		withSource,
+		options.FindOne().SetProjection(bson.M{"videos.$": 1}),
+	).Decode(&content)
+	if err == mongo.ErrNoDocuments || len(content.Videos) == 0 {
+		return fmt.Errorf("source video no longer exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load content: %v", err)
+	}
+	source := content.Videos[0]
+
+	now := time.Now()
+	videos := make([]models.ContentVideo, 0, len(outputs))
+	for _, output := range outputs {
+		videos = append(videos, models.ContentVideo{
+			ID:            output.VideoID,
+			Title:         source.Title,
+			Type:          source.Type,
+			Quality:       output.Quality,
+			Duration:      output.Duration,
+			FileURL:       output.FileURL,
+			FileSize:      output.FileSize,
+			Subtitles:     source.Subtitles,
+			SourceVideoID: &job.SourceVideoID,
+			CreatedAt:     now,
+		})
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		withSource,
+		bson.M{"$pull": bson.M{"videos": bson.M{"source_video_id": job.SourceVideoID}}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove previous renditions: %v", err)
+	}
+
+	result, err := collection.UpdateOne(
+		ctx,
+		withSource,
+		bson.M{
+			"$push": bson.M{"videos": bson.M{"$each": videos}},
+			"$set":  bson.M{"updated_at": now},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update content videos: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("source video no longer exists")
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": job.ContentID},
+		bson.M{"$set": bson.M{"videos.$[video].duration": int(math.Round(probe.Duration))}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"video._id": job.SourceVideoID}},
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record source duration: %v", err)
+	}
+
+	return nil
+}
+
+// segmentDuration is the HLS/DASH segment length, which encoders align keyframes to
+func segmentDuration(cfg *config.Config) int {
+	if cfg.Video.HLSSegmentDuration > 0 {
+		return cfg.Video.HLSSegmentDuration
+	}
+	return 6
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newTestDatabase connects to the MongoDB server named by MONGODB_TEST_URI,
+// e.g. mongodb://localhost:27017, and gives the test a database of its own
+// that is dropped afterwards. Without the variable the test is skipped,
+// unless CI is set, where a missing database is a failure rather than a
+// silently skipped test.
+func newTestDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("MONGODB_TEST_URI must be set when CI is set")
+		}
+		t.Skip("MONGODB_TEST_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("failed to reach MongoDB: %v", err)
+	}
+
+	db := client.Database(fmt.Sprintf("onflix_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	return db
+}
+
+func TestTranscodeClaim(t *testing.T) {
+	db := newTestDatabase(t)
+	ts := NewTranscodeService(&config.Config{}, db, nil, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	live := now.Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		job       models.TranscodeJob
+		wantClaim bool
+		wantFail  bool // left unclaimed and marked failed
+	}{
+		{"queued and due", models.TranscodeJob{Status: models.TranscodeStatusQueued, NextAttemptAt: now.Add(-time.Second), Attempts: 1}, true, false},
+		{"queued for a later retry", models.TranscodeJob{Status: models.TranscodeStatusQueued, NextAttemptAt: now.Add(time.Minute), Attempts: 1}, false, false},
+		{"lease lapsed", models.TranscodeJob{Status: models.TranscodeStatusProcessing, WorkerID: "crashed", LeaseExpiresAt: &expired, Attempts: 1}, true, false},
+		{"lease lapsed on the last attempt", models.TranscodeJob{Status: models.TranscodeStatusProcessing, WorkerID: "crashed", LeaseExpiresAt: &expired, Attempts: 3}, false, true},
+		{"lease held", models.TranscodeJob{Status: models.TranscodeStatusProcessing, WorkerID: "busy", LeaseExpiresAt: &live, Attempts: 1}, false, false},
+		{"completed", models.TranscodeJob{Status: models.TranscodeStatusCompleted, Attempts: 1}, false, false},
+		{"failed", models.TranscodeJob{Status: models.TranscodeStatusFailed, Attempts: 3}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := db.Collection(transcodeJobsCollection)
+			if err := collection.Drop(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.job.ID = primitive.NewObjectID()
+			tt.job.MaxAttempts = 3
+			tt.job.CreatedAt = now
+			if _, err := collection.InsertOne(ctx, tt.job); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := ts.claim(ctx, "worker-1")
+			if err != nil {
+				t.Fatalf("claim: %v", err)
+			}
+			if !tt.wantClaim {
+				if job != nil {
+					t.Fatalf("claimed a %s job", tt.job.Status)
+				}
+
+				var stored models.TranscodeJob
+				if err := collection.FindOne(ctx, bson.M{"_id": tt.job.ID}).Decode(&stored); err != nil {
+					t.Fatal(err)
+				}
+				wantStatus := tt.job.Status
+				if tt.wantFail {
+					wantStatus = models.TranscodeStatusFailed
+				}
+				if stored.Status != wantStatus || stored.Attempts != tt.job.Attempts {
+					t.Errorf("job left %s after %d attempts, want %s after %d", stored.Status, stored.Attempts, wantStatus, tt.job.Attempts)
+				}
+				return
+			}
+			if job == nil {
+				t.Fatal("job was not claimed")
+			}
+
+			if job.ID != tt.job.ID || job.Status != models.TranscodeStatusProcessing || job.WorkerID != "worker-1" {
+				t.Errorf("claimed job = %s %s by %s", job.ID.Hex(), job.Status, job.WorkerID)
+			}
+			if job.Attempts != tt.job.Attempts+1 {
+				t.Errorf("attempts = %d, want %d", job.Attempts, tt.job.Attempts+1)
+			}
+			if job.LeaseExpiresAt == nil || job.LeaseExpiresAt.Before(now.Add(transcodeLease-time.Second)) {
+				t.Errorf("lease expires at %v, want about %s from now", job.LeaseExpiresAt, transcodeLease)
+			}
+
+			if again, err := ts.claim(ctx, "worker-2"); err != nil || again != nil {
+				t.Errorf("a second worker claimed the leased job: %v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestTranscodeClaimOldestFirst(t *testing.T) {
+	db := newTestDatabase(t)
+	ts := NewTranscodeService(&config.Config{}, db, nil, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	newer := models.TranscodeJob{ID: primitive.NewObjectID(), Status: models.TranscodeStatusQueued, NextAttemptAt: now, CreatedAt: now}
+	older := models.TranscodeJob{ID: primitive.NewObjectID(), Status: models.TranscodeStatusQueued, NextAttemptAt: now, CreatedAt: now.Add(-time.Hour)}
+	if _, err := db.Collection(transcodeJobsCollection).InsertMany(ctx, []interface{}{newer, older}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []primitive.ObjectID{older.ID, newer.ID} {
+		job, err := ts.claim(ctx, "worker-1")
+		if err != nil || job == nil {
+			t.Fatalf("claim = %v, %v", job, err)
+		}
+		if job.ID != want {
+			t.Errorf("claimed %s, want %s", job.ID.Hex(), want.Hex())
+		}
+	}
+}
+
+func TestTranscodeAttachOutputs(t *testing.T) {
+	db := newTestDatabase(t)
+	ts := NewTranscodeService(&config.Config{}, db, nil, nil)
+	ctx := context.Background()
+
+	sourceID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+	staleID := primitive.NewObjectID()
+	markers := []models.VideoMarker{{Type: models.MarkerTypeIntro, Start: 10, End: 70}}
+
+	content := models.Content{
+		ID: primitive.NewObjectID(),
+		Videos: []models.ContentVideo{
+			{ID: sourceID, Title: "Main", Type: models.VideoTypeFull, Quality: models.Quality1080p, Markers: markers},
+			{ID: otherID, Title: "Trailer", Type: models.VideoTypeTrailer},
+			{ID: staleID, Quality: models.Quality480p, SourceVideoID: &sourceID},
+		},
+	}
+	if _, err := db.Collection("content").InsertOne(ctx, content); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &models.TranscodeJob{ContentID: content.ID, SourceVideoID: sourceID}
+	outputs := []models.TranscodeOutput{
+		{VideoID: primitive.NewObjectID(), Quality: models.Quality480p, FileURL: "videos/480p.mp4", Duration: 125},
+		{VideoID: primitive.NewObjectID(), Quality: models.Quality720p, FileURL: "videos/720p.mp4", Duration: 125},
+	}
+	if err := ts.attachOutputs(ctx, job, outputs, &transcodeProbe{Duration: 124.6}); err != nil {
+		t.Fatalf("attachOutputs: %v", err)
+	}
+
+	var stored models.Content
+	if err := db.Collection("content").FindOne(ctx, bson.M{"_id": content.ID}).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+
+	videos := map[primitive.ObjectID]models.ContentVideo{}
+	for _, video := range stored.Videos {
+		videos[video.ID] = video
+	}
+	if len(stored.Videos) != 4 {
+		t.Fatalf("content has %d videos, want source, trailer and two renditions", len(stored.Videos))
+	}
+	if _, ok := videos[staleID]; ok {
+		t.Error("the previous rendition was kept")
+	}
+	if _, ok := videos[otherID]; !ok {
+		t.Error("an unrelated video was removed")
+	}
+	if source := videos[sourceID]; source.Duration != 125 || len(source.Markers) != 1 {
+		t.Errorf("source duration %d with %d markers, want 125 with its marker", source.Duration, len(source.Markers))
+	}
+	for _, output := range outputs {
+		video, ok := videos[output.VideoID]
+		if !ok || video.SourceVideoID == nil || *video.SourceVideoID != sourceID || video.Title != "Main" {
+			t.Errorf("rendition %s = %+v", output.Quality, video)
+		}
+	}
+
+	job.SourceVideoID = primitive.NewObjectID()
+	if err := ts.attachOutputs(ctx, job, outputs, &transcodeProbe{Duration: 1}); err == nil {
+		t.Error("attachOutputs accepted a missing source video")
+	}
+}
+
+func TestTranscodeIntroJob(t *testing.T) {
+	db := newTestDatabase(t)
+	cfg := &config.Config{}
+	ts := NewTranscodeService(cfg, db, nil, NewMarkerService(cfg, db, nil))
+	ctx := context.Background()
+
+	// One episode with video is too few to compare, which fails the job
+	seasonID := primitive.NewObjectID()
+	content := models.Content{
+		ID: primitive.NewObjectID(),
+		Seasons: []models.Season{{
+			ID: seasonID,
+			Episodes: []models.Episode{{
+				ID:     primitive.NewObjectID(),
+				Videos: []models.ContentVideo{{ID: primitive.NewObjectID(), Type: models.VideoTypeFull, FileURL: "videos/1.mp4"}},
+			}},
+		}},
+	}
+	if _, err := db.Collection("content").InsertOne(ctx, content); err != nil {
+		t.Fatal(err)
+	}
+
+	queued, err := ts.EnqueueIntros(ctx, content.ID, seasonID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := ts.claim(ctx, "worker-1")
+	if err != nil || job == nil || job.ID != queued.ID {
+		t.Fatalf("claim = %v, %v", job, err)
+	}
+	if job.Type != models.TranscodeJobIntros || job.SeasonID == nil || *job.SeasonID != seasonID || !job.ApplyIntros {
+		t.Fatalf("claimed job lost its intro settings: %+v", job)
+	}
+
+	ts.run(ctx, "worker-1", job)
+
+	stored, err := ts.Job(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.TranscodeStatusFailed || stored.Error != ErrIntroEpisodes.Error() {
+		t.Errorf("job finished %s with error %q, want failed with %q", stored.Status, stored.Error, ErrIntroEpisodes)
+	}
+}
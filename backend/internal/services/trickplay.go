@@ -0,0 +1,195 @@
+// backend/internal/services/trickplay.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"onflix/internal/models"
+)
+
+const (
+	trickPlayDir          = "thumbs"
+	trickPlayVTTName      = "thumbnails.vtt"
+	trickPlayPlaylistName = "images.m3u8"
+	trickPlaySheetPattern = "sprite_%03d.jpg"
+	trickPlayColumns      = 5
+	trickPlayRows         = 5
+)
+
+// TrickPlayEnabled reports whether packaging generates seek-preview thumbnails
+func (vs *VideoService) TrickPlayEnabled() bool {
+	return vs.config.Video.TrickPlayInterval > 0
+}
+
+// packageTrickPlay grabs a thumbnail every TrickPlayInterval seconds of a
+// rendition, tiles them into JPEG sprite sheets and writes a WebVTT thumbnail
+// track plus an HLS image playlist next to the owner's HLS renditions
+func (vs *VideoService) packageTrickPlay(ctx context.Context, ownerID string, video models.ContentVideo, duration float64) (*models.TrickPlayTrack, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("cannot generate thumbnails for a video without a duration")
+	}
+
+	source, release, err := vs.storage.Fetch(ctx, vs.storage.PathFromURL(video.FileURL))
+	if err != nil {
+		return nil, fmt.Errorf("source for %s video not found: %v", video.Quality, err)
+	}
+	defer release()
+
+	dir := path.Join(HLSDirectory(ownerID), trickPlayDir)
+	outputDir, err := vs.storage.ResolvePath(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vs.storage.DeleteAll(ctx, dir); err != nil {
+		return nil, fmt.Errorf("failed to clear %s: %v", outputDir, err)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	track := &models.TrickPlayTrack{
+		VTTPath:      path.Join(dir, trickPlayVTTName),
+		PlaylistPath: path.Join(dir, trickPlayPlaylistName),
+		Interval:     vs.config.Video.TrickPlayInterval,
+		Width:        vs.config.Video.TrickPlayWidth,
+		Columns:      trickPlayColumns,
+		Rows:         trickPlayRows,
+	}
+	if track.Width <= 0 {
+		track.Width = 240
+	}
+	// Tiles are letterboxed to 16:9 so every sheet has the same grid
+	track.Height = (track.Width * 9 / 16) &^ 1
+
+	filter := fmt.Sprintf(
+		"fps=1/%d,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,tile=%dx%d",
+		track.Interval, track.Width, track.Height, track.Width, track.Height, track.Columns, track.Rows,
+	)
+
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", source,
+		"-map", "0:v:0",
+		"-vf", filter,
+		"-q:v", "5",
+		"-start_number", "1",
+		filepath.Join(outputDir, trickPlaySheetPattern),
+	}
+
+	cmd := exec.CommandContext(ctx, vs.config.Video.FFmpegPath, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed for thumbnails: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// ffmpeg may round the last thumbnail away, so only describe tiles that were written
+	perSheet := track.Columns * track.Rows
+	track.Thumbnails = int(math.Ceil(duration / float64(track.Interval)))
+	sheetDuration := float64(perSheet * track.Interval)
+	for track.Sheets*perSheet < track.Thumbnails {
+		info, err := os.Stat(filepath.Join(outputDir, fmt.Sprintf(trickPlaySheetPattern, track.Sheets+1)))
+		if err != nil {
+			break
+		}
+		track.Sheets++
+
+		if bandwidth := int(float64(info.Size()*8) / sheetDuration); bandwidth > track.Bandwidth {
+			track.Bandwidth = bandwidth
+		}
+	}
+
+	if track.Sheets == 0 {
+		return nil, fmt.Errorf("ffmpeg did not produce any thumbnail sheets")
+	}
+	track.Thumbnails = min(track.Thumbnails, track.Sheets*perSheet)
+
+	if err := vs.storage.Publish(ctx, dir); err != nil {
+		return nil, err
+	}
+
+	if err := vs.writeStorageFile(ctx, track.VTTPath, encodeTrickPlayVTT(track, duration)); err != nil {
+		return nil, err
+	}
+
+	if err := vs.writeStorageFile(ctx, track.PlaylistPath, encodeTrickPlayPlaylist(track, duration)); err != nil {
+		return nil, err
+	}
+
+	return track, nil
+}
+
+// encodeTrickPlayVTT writes one cue per thumbnail, pointing at its tile with
+// a media fragment: sprite_001.jpg#xywh=x,y,w,h
+func encodeTrickPlayVTT(track *models.TrickPlayTrack, duration float64) []byte {
+	var b bytes.Buffer
+	b.WriteString("WEBVTT\n")
+
+	perSheet := track.Columns * track.Rows
+	for i := 0; i < track.Thumbnails; i++ {
+		start := float64(i * track.Interval)
+		end := math.Min(float64((i+1)*track.Interval), duration)
+		if i == track.Thumbnails-1 {
+			end = duration
+		}
+
+		tile := i % perSheet
+		fmt.Fprintf(&b, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end),
+			fmt.Sprintf(trickPlaySheetPattern, i/perSheet+1),
+			(tile%track.Columns)*track.Width, (tile/track.Columns)*track.Height, track.Width, track.Height,
+		)
+	}
+
+	return b.Bytes()
+}
+
+// encodeTrickPlayPlaylist writes an HLS image media playlist with one entry
+// per sprite sheet, as referenced by EXT-X-IMAGE-STREAM-INF
+func encodeTrickPlayPlaylist(track *models.TrickPlayTrack, duration float64) []byte {
+	var b bytes.Buffer
+
+	perSheet := track.Columns * track.Rows
+	sheetDuration := float64(perSheet * track.Interval)
+
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:7\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(math.Min(sheetDuration, duration))))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:1\n")
+	b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	b.WriteString("#EXT-X-IMAGES-ONLY\n")
+
+	for sheet := 0; sheet < track.Sheets; sheet++ {
+		start := float64(sheet) * sheetDuration
+		length := math.Min(sheetDuration, duration-start)
+		fmt.Fprintf(&b, "#EXT-X-TILES:RESOLUTION=%dx%d,LAYOUT=%dx%d,DURATION=%d\n",
+			track.Width, track.Height, track.Columns, track.Rows, track.Interval)
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", length, fmt.Sprintf(trickPlaySheetPattern, sheet+1))
+	}
+
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.Bytes()
+}
+
+// trickPlayImageStream returns the master playlist entry for a track, with its
+// URI relative to the master playlist
+func trickPlayImageStream(track *models.TrickPlayTrack) HLSImageStream {
+	return HLSImageStream{
+		Bandwidth:  max(track.Bandwidth, 1),
+		Resolution: fmt.Sprintf("%dx%d", track.Width, track.Height),
+		Codecs:     "jpeg",
+		URI:        path.Join(trickPlayDir, trickPlayPlaylistName),
+	}
+}
+
+func vttTimestamp(seconds float64) string {
+	millis := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
@@ -0,0 +1,461 @@
+// backend/internal/services/upload.go
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	videoUploadsCollection = "video_uploads"
+	uploadIncomingDir      = "incoming"
+	uploadCleanupInterval  = time.Hour
+)
+
+// Errors the tus handlers map onto protocol status codes
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadExpired        = errors.New("upload has expired")
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match")
+	ErrUploadChanged        = errors.New("upload changed while it was being removed")
+	ErrUploadTooLarge       = errors.New("upload exceeds the maximum size")
+	ErrChecksumMismatch     = errors.New("chunk checksum does not match")
+	ErrChecksumAlgorithm    = errors.New("unsupported checksum algorithm")
+)
+
+// UploadChecksumAlgorithms lists the algorithms accepted in Upload-Checksum
+var UploadChecksumAlgorithms = []string{"md5", "sha1", "sha256"}
+
+// UploadService implements resumable video uploads. Chunks are appended to a
+// file under the storage base path and the upload becomes a ContentVideo once
+// the last byte arrives.
+type UploadService struct {
+	config  *config.Config
+	db      *mongo.Database
+	storage *StorageService
+	blobs   *BlobService
+	locks   sync.Map // upload ID -> *sync.Mutex
+	cancel  context.CancelFunc
+}
+
+// VideoUploadRequest carries the fields a client sends when creating an upload
+type VideoUploadRequest struct {
+	ContentID  primitive.ObjectID
+	UploadedBy primitive.ObjectID
+	Length     int64
+	Filename   string
+	Title      string
+	Type       models.VideoType
+	Quality    models.VideoQuality
+}
+
+func NewUploadService(cfg *config.Config, db *mongo.Database, storage *StorageService, blobs *BlobService) *UploadService {
+	return &UploadService{
+		config:  cfg,
+		db:      db,
+		storage: storage,
+		blobs:   blobs,
+	}
+}
+
+// Start launches the periodic removal of abandoned uploads
+func (us *UploadService) Start() {
+	if us.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	us.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(uploadCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			if removed, err := us.CleanupExpired(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to clean up expired uploads: %v\n", err)
+			} else if removed > 0 {
+				fmt.Printf("Removed %d expired uploads\n", removed)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (us *UploadService) Close() {
+	if us.cancel != nil {
+		us.cancel()
+		us.cancel = nil
+	}
+}
+
+// MaxSize is the largest upload accepted, in bytes
+func (us *UploadService) MaxSize() int64 {
+	return us.config.Video.UploadMaxSize
+}
+
+// CreateUpload registers a new upload and creates its empty data file
+func (us *UploadService) CreateUpload(ctx context.Context, req VideoUploadRequest) (*models.VideoUpload, error) {
+	if req.Length < 0 {
+		return nil, fmt.Errorf("upload length is required")
+	}
+	if limit := us.MaxSize(); limit > 0 && req.Length > limit {
+		return nil, ErrUploadTooLarge
+	}
+
+	now := time.Now()
+	upload := &models.VideoUpload{
+		ID:         primitive.NewObjectID(),
+		ContentID:  req.ContentID,
+		UploadedBy: req.UploadedBy,
+		Filename:   req.Filename,
+		Title:      req.Title,
+		Type:       req.Type,
+		Quality:    req.Quality,
+		Length:     req.Length,
+		Status:     models.UploadStatusUploading,
+		ExpiresAt:  now.Add(us.expiry()),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	upload.StoragePath = path.Join(uploadIncomingDir, upload.ID.Hex()+".part")
+
+	fullPath, err := us.storage.ResolvePath(upload.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %v", err)
+	}
+	file.Close()
+
+	if _, err := us.db.Collection(videoUploadsCollection).InsertOne(ctx, upload); err != nil {
+		os.Remove(fullPath)
+		return nil, fmt.Errorf("failed to create upload: %v", err)
+	}
+
+	// Zero-length uploads are complete as soon as they exist
+	if upload.Length == 0 {
+		return us.complete(ctx, upload)
+	}
+
+	return upload, nil
+}
+
+// GetUpload returns an upload that belongs to the given content
+func (us *UploadService) GetUpload(ctx context.Context, contentID, uploadID primitive.ObjectID) (*models.VideoUpload, error) {
+	var upload models.VideoUpload
+	err := us.db.Collection(videoUploadsCollection).FindOne(
+		ctx,
+		bson.M{"_id": uploadID, "content_id": contentID},
+	).Decode(&upload)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to load upload: %v", err)
+	}
+
+	if upload.Status == models.UploadStatusUploading && time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+
+	return &upload, nil
+}
+
+// WriteChunk appends the body of a PATCH request at offset. checksum is the
+// raw Upload-Checksum header value; when present the chunk is discarded
+// unless it matches. Without a checksum, bytes received before a dropped
+// connection are kept so the client can resume from them.
+func (us *UploadService) WriteChunk(ctx context.Context, upload *models.VideoUpload, offset int64, body io.Reader, checksum string) (*models.VideoUpload, error) {
+	lock, _ := us.locks.LoadOrStore(upload.ID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// Re-read under the lock so concurrent PATCH requests see each other's progress
+	upload, err := us.GetUpload(ctx, upload.ContentID, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.Status != models.UploadStatusUploading || offset != upload.Offset {
+		return nil, ErrUploadOffsetMismatch
+	}
+
+	var hasher hash.Hash
+	var expected []byte
+	if checksum != "" {
+		hasher, expected, err = parseUploadChecksum(checksum)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fullPath, err := us.storage.ResolvePath(upload.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(fullPath, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek upload file: %v", err)
+	}
+
+	var writer io.Writer = file
+	if hasher != nil {
+		writer = io.MultiWriter(file, hasher)
+	}
+
+	// Never accept bytes past the declared length
+	written, copyErr := io.Copy(writer, io.LimitReader(body, upload.Length-offset))
+
+	if hasher != nil && (copyErr != nil || string(hasher.Sum(nil)) != string(expected)) {
+		file.Truncate(offset)
+		if copyErr != nil {
+			return nil, fmt.Errorf("failed to receive chunk: %v", copyErr)
+		}
+		return nil, ErrChecksumMismatch
+	}
+
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to write upload file: %v", err)
+	}
+
+	now := time.Now()
+	upload.Offset = offset + written
+	upload.ExpiresAt = now.Add(us.expiry())
+	upload.UpdatedAt = now
+
+	// The lock only serialises this instance; the offset compare-and-set also
+	// loses to a chunk or removal that reached the record from another one
+	result, err := us.db.Collection(videoUploadsCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": upload.ID, "status": models.UploadStatusUploading, "offset": offset},
+		bson.M{"$set": bson.M{
+			"offset":     upload.Offset,
+			"expires_at": upload.ExpiresAt,
+			"updated_at": now,
+		}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record upload offset: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrUploadOffsetMismatch
+	}
+
+	if copyErr != nil {
+		return upload, fmt.Errorf("failed to receive chunk: %v", copyErr)
+	}
+
+	if upload.Offset == upload.Length {
+		return us.complete(ctx, upload)
+	}
+
+	return upload, nil
+}
+
+// complete moves the finished file next to the other videos of the content and attaches it
+func (us *UploadService) complete(ctx context.Context, upload *models.VideoUpload) (*models.VideoUpload, error) {
+	source, err := us.storage.ResolvePath(upload.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunks are always staged on local disk; hand the finished file to blob storage
+	videoID := primitive.NewObjectID()
+	blob, err := us.blobs.StoreFile(ctx, "videos", filepath.Ext(upload.Filename), source, models.BlobRef{
+		OwnerType: models.BlobOwnerVideo,
+		OwnerID:   videoID.Hex(),
+		Field:     "file",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store upload: %v", err)
+	}
+
+	now := time.Now()
+	video := models.ContentVideo{
+		ID:        videoID,
+		Title:     upload.Title,
+		Type:      upload.Type,
+		Quality:   upload.Quality,
+		FileURL:   us.blobs.URL(blob),
+		FileSize:  blob.Size,
+		CreatedAt: now,
+	}
+
+	_, err = us.db.Collection("content").UpdateOne(
+		ctx,
+		bson.M{"_id": upload.ContentID},
+		bson.M{
+			"$push": bson.M{"videos": video},
+			"$set":  bson.M{"updated_at": now},
+		},
+	)
+	if err != nil {
+		us.blobs.ReleaseOwner(ctx, models.BlobOwnerVideo, videoID.Hex())
+		return nil, fmt.Errorf("failed to attach video to content: %v", err)
+	}
+
+	upload.Status = models.UploadStatusCompleted
+	upload.StoragePath = blob.StoragePath
+	upload.VideoID = &video.ID
+	upload.UpdatedAt = now
+
+	_, err = us.db.Collection(videoUploadsCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": upload.ID},
+		bson.M{"$set": bson.M{
+			"status":       upload.Status,
+			"storage_path": upload.StoragePath,
+			"video_id":     upload.VideoID,
+			"updated_at":   now,
+		}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete upload: %v", err)
+	}
+
+	us.locks.Delete(upload.ID)
+	return upload, nil
+}
+
+// TerminateUpload discards an unfinished upload and its data
+func (us *UploadService) TerminateUpload(ctx context.Context, upload *models.VideoUpload) error {
+	if upload.Status != models.UploadStatusUploading {
+		return fmt.Errorf("completed uploads cannot be terminated")
+	}
+
+	return us.removeUpload(ctx, upload, bson.M{"status": models.UploadStatusUploading})
+}
+
+// CleanupExpired removes unfinished uploads that have not received data within the expiry window
+func (us *UploadService) CleanupExpired(ctx context.Context) (int, error) {
+	cursor, err := us.db.Collection(videoUploadsCollection).Find(ctx, bson.M{
+		"status":     models.UploadStatusUploading,
+		"expires_at": bson.M{"$lt": time.Now()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	removed := 0
+	for cursor.Next(ctx) {
+		var upload models.VideoUpload
+		if err := cursor.Decode(&upload); err != nil {
+			continue
+		}
+
+		// A chunk that arrived since the query moved the offset and expiry on
+		err := us.removeUpload(ctx, &upload, bson.M{
+			"status":     models.UploadStatusUploading,
+			"offset":     upload.Offset,
+			"expires_at": bson.M{"$lt": time.Now()},
+		})
+		if err == nil {
+			removed++
+		}
+	}
+
+	return removed, cursor.Err()
+}
+
+// removeUpload deletes the upload record if it still matches condition, then
+// its file. It holds the upload's lock so no chunk is being written here, and
+// the conditional delete keeps it from racing chunks on other instances.
+func (us *UploadService) removeUpload(ctx context.Context, upload *models.VideoUpload, condition bson.M) error {
+	lock, _ := us.locks.LoadOrStore(upload.ID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	filter := bson.M{"_id": upload.ID}
+	for key, value := range condition {
+		filter[key] = value
+	}
+
+	result, err := us.db.Collection(videoUploadsCollection).DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to remove upload: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrUploadChanged
+	}
+	us.locks.Delete(upload.ID)
+
+	if fullPath, err := us.storage.ResolvePath(upload.StoragePath); err == nil {
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove upload file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (us *UploadService) expiry() time.Duration {
+	if us.config.Video.UploadExpiryHours > 0 {
+		return time.Duration(us.config.Video.UploadExpiryHours) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// parseUploadChecksum reads an Upload-Checksum header ("<algorithm> <base64 digest>")
+func parseUploadChecksum(header string) (hash.Hash, []byte, error) {
+	algorithm, encoded, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found {
+		return nil, nil, fmt.Errorf("invalid Upload-Checksum header")
+	}
+
+	digest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid Upload-Checksum digest: %v", err)
+	}
+
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		return md5.New(), digest, nil
+	case "sha1":
+		return sha1.New(), digest, nil
+	case "sha256":
+		return sha256.New(), digest, nil
+	default:
+		return nil, nil, ErrChecksumAlgorithm
+	}
+}
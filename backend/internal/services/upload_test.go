@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseUploadChecksum(t *testing.T) {
+	chunk := []byte("chunk data")
+	md5Sum := md5.Sum(chunk)
+	sha1Sum := sha1.Sum(chunk)
+	sha256Sum := sha256.Sum256(chunk)
+
+	tests := []struct {
+		name    string
+		header  string
+		want    []byte
+		wantErr error
+	}{
+		{"md5", "md5 " + base64.StdEncoding.EncodeToString(md5Sum[:]), md5Sum[:], nil},
+		{"sha1", "sha1 " + base64.StdEncoding.EncodeToString(sha1Sum[:]), sha1Sum[:], nil},
+		{"sha256 upper case", " SHA256 " + base64.StdEncoding.EncodeToString(sha256Sum[:]), sha256Sum[:], nil},
+		{"unsupported algorithm", "crc32 AAAAAA==", nil, ErrChecksumAlgorithm},
+		{"missing digest", "sha1", nil, nil},
+		{"digest not base64", "sha1 !!!", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, digest, err := parseUploadChecksum(tt.header)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatal("parseUploadChecksum accepted the header")
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseUploadChecksum: %v", err)
+			}
+
+			hasher.Write(chunk)
+			if string(hasher.Sum(nil)) != string(digest) || string(digest) != string(tt.want) {
+				t.Error("digest does not match a hash of the chunk")
+			}
+		})
+	}
+}
+
+func TestWriteChunkOffsets(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Storage.BasePath = t.TempDir()
+	storage, err := NewStorageService(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	us := NewUploadService(cfg, db, storage, nil)
+
+	upload, err := us.CreateUpload(ctx, VideoUploadRequest{
+		ContentID: primitive.NewObjectID(),
+		Length:    12,
+		Filename:  "movie.mp4",
+	})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+
+	sha1Of := func(s string) string {
+		sum := sha1.Sum([]byte(s))
+		return "sha1 " + base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	// The steps stop two bytes short of the declared length so the upload
+	// stays in progress throughout
+	steps := []struct {
+		name       string
+		offset     int64
+		body       string
+		checksum   string
+		wantErr    error
+		wantOffset int64
+	}{
+		{"first chunk", 0, "abcd", "", nil, 4},
+		{"stale offset", 0, "abcd", "", ErrUploadOffsetMismatch, 4},
+		{"offset ahead", 8, "ijkl", "", ErrUploadOffsetMismatch, 4},
+		{"checksum mismatch is discarded", 4, "efgh", sha1Of("other"), ErrChecksumMismatch, 4},
+		{"checksum match", 4, "efgh", sha1Of("efgh"), nil, 8},
+		{"unsupported checksum", 8, "ij", "crc32 AAAAAA==", ErrChecksumAlgorithm, 8},
+		{"next chunk", 8, "ij", "", nil, 10},
+	}
+
+	for _, step := range steps {
+		got, err := us.WriteChunk(ctx, upload, step.offset, strings.NewReader(step.body), step.checksum)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: WriteChunk error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if err == nil && got.Offset != step.wantOffset {
+			t.Fatalf("%s: offset = %d, want %d", step.name, got.Offset, step.wantOffset)
+		}
+
+		stored, err := us.GetUpload(ctx, upload.ContentID, upload.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Offset != step.wantOffset || stored.Status != models.UploadStatusUploading {
+			t.Fatalf("%s: stored offset = %d (%s), want %d", step.name, stored.Offset, stored.Status, step.wantOffset)
+		}
+
+		fullPath, _ := storage.ResolvePath(stored.StoragePath)
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "abcdefghij"[:step.wantOffset]; string(data) != want {
+			t.Fatalf("%s: file holds %q, want %q", step.name, data, want)
+		}
+	}
+}
+
+func TestRemoveUploadRacingChunk(t *testing.T) {
+	db := newTestDatabase(t)
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Storage.BasePath = t.TempDir()
+	storage, err := NewStorageService(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	us := NewUploadService(cfg, db, storage, nil)
+
+	upload, err := us.CreateUpload(ctx, VideoUploadRequest{
+		ContentID: primitive.NewObjectID(),
+		Length:    12,
+		Filename:  "movie.mp4",
+	})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+	fullPath, _ := storage.ResolvePath(upload.StoragePath)
+
+	// The sweep read the upload as expired, then a chunk landed before it removed it
+	stale := *upload
+	if _, err := us.WriteChunk(ctx, upload, 0, strings.NewReader("abcd"), ""); err != nil {
+		t.Fatalf("WriteChunk: %v", err)
+	}
+	err = us.removeUpload(ctx, &stale, bson.M{"offset": stale.Offset})
+	if !errors.Is(err, ErrUploadChanged) {
+		t.Fatalf("removeUpload of a stale upload = %v, want ErrUploadChanged", err)
+	}
+	if _, err := os.Stat(fullPath); err != nil {
+		t.Fatalf("the upload file was removed: %v", err)
+	}
+
+	// Once it really expires the sweep removes it
+	_, err = db.Collection(videoUploadsCollection).UpdateOne(ctx, bson.M{"_id": upload.ID},
+		bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Minute)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := us.CleanupExpired(ctx); err != nil || removed != 1 {
+		t.Fatalf("CleanupExpired = %d, %v, want 1", removed, err)
+	}
+	if _, err := os.Stat(fullPath); !os.IsNotExist(err) {
+		t.Errorf("the upload file was kept: %v", err)
+	}
+
+	// A chunk for the removed upload is refused rather than written
+	if _, err := us.WriteChunk(ctx, upload, 4, strings.NewReader("efgh"), ""); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("WriteChunk after removal = %v, want ErrUploadNotFound", err)
+	}
+}
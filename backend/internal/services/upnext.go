@@ -0,0 +1,355 @@
+// backend/internal/services/upnext.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Highest rated titles considered when recommending what follows a movie
+const upNextCandidates = 20
+
+var ErrUpNextEpisode = errors.New("episode does not belong to this show")
+
+// maturityLevels orders content ratings by audience, so a profile rated at one
+// level may be recommended titles at that level or below
+var maturityLevels = map[string]int{
+	"G": 0, "TV-Y": 0, "TV-G": 0,
+	"PG": 1, "TV-Y7": 1, "TV-PG": 1,
+	"PG-13": 2, "TV-14": 2,
+	"R": 3, "TV-MA": 3,
+	"NC-17": 4,
+}
+
+// Kids profiles are never recommended anything above PG or TV-PG
+const kidsMaturityLevel = 1
+
+// UpNextItem is a title, or an episode of a show, to play next
+type UpNextItem struct {
+	Content      *models.Content `json:"content"`
+	Episode      *models.Episode `json:"episode,omitempty"`
+	SeasonNumber int             `json:"season_number,omitempty"`
+	Reason       string          `json:"reason"` // "next_episode" or "recommendation"
+}
+
+// UpNext is what a player offers when the current title or episode ends
+type UpNext struct {
+	Next             *UpNextItem `json:"next"`
+	Autoplay         bool        `json:"autoplay"` // Play Next when the countdown runs out
+	CountdownSeconds int         `json:"countdown_seconds"`
+	AutoplayStreak   int         `json:"autoplay_streak"` // Sessions in a row the countdown started, up to the one ending
+	StillWatching    bool        `json:"still_watching"`  // Ask whether anyone is still watching instead of autoplaying
+}
+
+// upNextHistory is what a profile has already finished
+type upNextHistory struct {
+	titles   map[primitive.ObjectID]bool // Movies watched through, and shows with a finished episode
+	episodes map[primitive.ObjectID]bool
+}
+
+// UpNextService picks what plays after a title or episode: the next unwatched
+// episode of a show, otherwise a recommendation
+type UpNextService struct {
+	config *config.Config
+	db     *mongo.Database
+}
+
+func NewUpNextService(cfg *config.Config, db *mongo.Database) *UpNextService {
+	return &UpNextService{
+		config: cfg,
+		db:     db,
+	}
+}
+
+// Next returns what follows content, or episodeID of it, for the user's
+// profile. With no profile, the whole account's viewing counts.
+func (us *UpNextService) Next(ctx context.Context, user *models.User, profileID *primitive.ObjectID, content *models.Content, episodeID *primitive.ObjectID) (*UpNext, error) {
+	if episodeID != nil && findEpisode(content, *episodeID) == nil {
+		return nil, ErrUpNextEpisode
+	}
+
+	history, err := us.history(ctx, user, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	autoplay := user.Preferences.AutoPlay
+	maturityRating, kids := user.Preferences.MaturityRating, false
+	if profileID != nil {
+		if profile := FindProfile(user, *profileID); profile != nil {
+			autoplay = profile.Preferences.AutoPlay
+			maturityRating, kids = profile.Preferences.MaturityRating, profile.IsKidsProfile
+		}
+	}
+
+	var next *UpNextItem
+	if content.Type == models.ContentTypeTVShow {
+		next = nextEpisode(content, episodeID, history)
+	}
+	if next == nil {
+		if next, err = us.recommend(ctx, content, history, allowedMaturityRatings(maturityRating, kids)); err != nil {
+			return nil, err
+		}
+	}
+
+	streak, err := us.autoplayStreak(ctx, user.ID, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	upNext := &UpNext{
+		Next:             next,
+		CountdownSeconds: us.config.Video.AutoplayCountdown,
+		AutoplayStreak:   streak,
+		StillWatching:    us.config.Video.AutoplayLimit > 0 && streak >= us.config.Video.AutoplayLimit,
+	}
+	upNext.Autoplay = autoplay && next != nil && !upNext.StillWatching
+
+	return upNext, nil
+}
+
+// FindProfile returns the user's profile with the given ID, or nil
+func FindProfile(user *models.User, profileID primitive.ObjectID) *models.UserProfile {
+	for i := range user.Profiles {
+		if user.Profiles[i].ID == profileID {
+			return &user.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// history collects the titles and episodes whose playback sessions reached
+// the completion threshold, plus movies the profile's watch history has
+// nearly finished
+func (us *UpNextService) history(ctx context.Context, user *models.User, profileID *primitive.ObjectID) (*upNextHistory, error) {
+	filter := bson.M{
+		"user_id":   user.ID,
+		"completed": true,
+	}
+	if profileID != nil {
+		filter["profile_id"] = *profileID
+	}
+
+	cursor, err := us.db.Collection(playbackSessionsCollection).Find(ctx, filter,
+		options.Find().SetProjection(bson.M{"content_id": 1, "episode_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load watched titles: %v", err)
+	}
+
+	var sessions []models.PlaybackSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode watched titles: %v", err)
+	}
+
+	history := &upNextHistory{
+		titles:   make(map[primitive.ObjectID]bool),
+		episodes: make(map[primitive.ObjectID]bool),
+	}
+	for _, session := range sessions {
+		history.titles[session.ContentID] = true
+		if session.EpisodeID != nil {
+			history.episodes[*session.EpisodeID] = true
+		}
+	}
+
+	if profileID != nil {
+		if profile := FindProfile(user, *profileID); profile != nil {
+			for _, item := range profile.WatchHistory {
+				if item.Progress >= playbackCompletionThreshold*100 {
+					history.titles[item.ContentID] = true
+				}
+			}
+		}
+	}
+
+	return history, nil
+}
+
+// autoplayStreak counts the most recent sessions the up-next countdown
+// started, stopping at the last one the viewer started themselves
+func (us *UpNextService) autoplayStreak(ctx context.Context, userID primitive.ObjectID, profileID *primitive.ObjectID) (int, error) {
+	limit := us.config.Video.AutoplayLimit
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	filter := bson.M{"user_id": userID}
+	if profileID != nil {
+		filter["profile_id"] = *profileID
+	}
+
+	cursor, err := us.db.Collection(playbackSessionsCollection).Find(ctx, filter,
+		options.Find().
+			SetSort(bson.M{"started_at": -1}).
+			SetLimit(int64(limit)).
+			SetProjection(bson.M{"autoplayed": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to load recent playback sessions: %v", err)
+	}
+
+	var sessions []models.PlaybackSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return 0, fmt.Errorf("failed to decode recent playback sessions: %v", err)
+	}
+
+	streak := 0
+	for _, session := range sessions {
+		if !session.Autoplayed {
+			break
+		}
+		streak++
+	}
+	return streak, nil
+}
+
+// allowedMaturityRatings lists the ratings a profile may be recommended, or nil
+// when it is unrestricted. Kids profiles are capped at kidsMaturityLevel even
+// if their own rating is higher.
+func allowedMaturityRatings(rating string, kids bool) []string {
+	limit, ok := maturityLevels[rating]
+	if kids && (!ok || limit > kidsMaturityLevel) {
+		limit, ok = kidsMaturityLevel, true
+	}
+	if !ok {
+		return nil
+	}
+
+	var allowed []string
+	for name, level := range maturityLevels {
+		if level <= limit {
+			allowed = append(allowed, name)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// recommend picks the best rated playable title sharing a genre with content
+// that the profile has not watched, falling back to the best rated overall.
+// With allowedRatings set, titles rated otherwise (or not rated) are skipped.
+func (us *UpNextService) recommend(ctx context.Context, content *models.Content, history *upNextHistory, allowedRatings []string) (*UpNextItem, error) {
+	exclude := []primitive.ObjectID{content.ID}
+	for id := range history.titles {
+		exclude = append(exclude, id)
+	}
+
+	filter := bson.M{
+		"_id":    bson.M{"$nin": exclude},
+		"status": models.ContentStatusPublished,
+		"type":   bson.M{"$in": []models.ContentType{models.ContentTypeMovie, models.ContentTypeTVShow}},
+	}
+	if allowedRatings != nil {
+		filter["maturity_rating"] = bson.M{"$in": allowedRatings}
+	}
+
+	filters := []bson.M{filter}
+	if len(content.Genres) > 0 {
+		similar := bson.M{"genres": bson.M{"$in": content.Genres}}
+		for key, value := range filter {
+			similar[key] = value
+		}
+		filters = []bson.M{similar, filter}
+	}
+
+	for _, f := range filters {
+		cursor, err := us.db.Collection("content").Find(ctx, f,
+			options.Find().
+				SetSort(bson.D{{Key: "rating", Value: -1}, {Key: "view_count", Value: -1}}).
+				SetLimit(upNextCandidates))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load recommendations: %v", err)
+		}
+
+		var candidates []models.Content
+		if err := cursor.All(ctx, &candidates); err != nil {
+			return nil, fmt.Errorf("failed to decode recommendations: %v", err)
+		}
+
+		for i := range candidates {
+			candidate := &candidates[i]
+			if candidate.Type == models.ContentTypeTVShow {
+				if item := nextEpisode(candidate, nil, history); item != nil {
+					item.Reason = "recommendation"
+					return item, nil
+				}
+				continue
+			}
+			if playable(candidate.Videos, candidate.Streaming) {
+				return &UpNextItem{Content: candidate, Reason: "recommendation"}, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// nextEpisode returns the first playable, unwatched episode after episodeID
+// in season and episode order, or from the start of the show without one
+func nextEpisode(show *models.Content, episodeID *primitive.ObjectID, history *upNextHistory) *UpNextItem {
+	seasons := make([]models.Season, len(show.Seasons))
+	copy(seasons, show.Seasons)
+	sort.SliceStable(seasons, func(i, j int) bool {
+		return seasons[i].SeasonNumber < seasons[j].SeasonNumber
+	})
+
+	passed := episodeID == nil
+	for _, season := range seasons {
+		episodes := make([]models.Episode, len(season.Episodes))
+		copy(episodes, season.Episodes)
+		sort.SliceStable(episodes, func(i, j int) bool {
+			return episodes[i].EpisodeNumber < episodes[j].EpisodeNumber
+		})
+
+		for i := range episodes {
+			episode := &episodes[i]
+			if !passed {
+				passed = episode.ID == *episodeID
+				continue
+			}
+			if history.episodes[episode.ID] || !playable(episode.Videos, episode.Streaming) {
+				continue
+			}
+			return &UpNextItem{
+				Content:      show,
+				Episode:      episode,
+				SeasonNumber: season.SeasonNumber,
+				Reason:       "next_episode",
+			}
+		}
+	}
+	return nil
+}
+
+func findEpisode(show *models.Content, episodeID primitive.ObjectID) *models.Episode {
+	for i := range show.Seasons {
+		for j := range show.Seasons[i].Episodes {
+			if show.Seasons[i].Episodes[j].ID == episodeID {
+				return &show.Seasons[i].Episodes[j]
+			}
+		}
+	}
+	return nil
+}
+
+// playable reports whether a title or episode has a full-length video to stream
+func playable(videos []models.ContentVideo, streaming models.StreamingAssets) bool {
+	if streaming.HLSMaster != "" {
+		return true
+	}
+	for _, video := range videos {
+		if video.Type == models.VideoTypeFull {
+			return true
+		}
+	}
+	return false
+}
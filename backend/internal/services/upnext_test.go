@@ -0,0 +1,40 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllowedMaturityRatings(t *testing.T) {
+	tests := []struct {
+		name   string
+		rating string
+		kids   bool
+		want   string // comma-joined, sorted; "" means unrestricted
+	}{
+		{"unrated adult profile", "", false, ""},
+		{"unknown rating", "18+", false, ""},
+		{"top rating", "NC-17", false, "G,NC-17,PG,PG-13,R,TV-14,TV-G,TV-MA,TV-PG,TV-Y,TV-Y7"},
+		{"teen profile", "PG-13", false, "G,PG,PG-13,TV-14,TV-G,TV-PG,TV-Y,TV-Y7"},
+		{"tv rating", "TV-Y7", false, "G,PG,TV-G,TV-PG,TV-Y,TV-Y7"},
+		{"youngest", "TV-Y", false, "G,TV-G,TV-Y"},
+		{"kids profile without rating", "", true, "G,PG,TV-G,TV-PG,TV-Y,TV-Y7"},
+		{"kids profile rated too high", "TV-MA", true, "G,PG,TV-G,TV-PG,TV-Y,TV-Y7"},
+		{"kids profile rated lower", "G", true, "G,TV-G,TV-Y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := allowedMaturityRatings(tt.rating, tt.kids)
+			if tt.want == "" {
+				if allowed != nil {
+					t.Fatalf("allowedMaturityRatings(%q, %v) = %v, want unrestricted", tt.rating, tt.kids, allowed)
+				}
+				return
+			}
+			if got := strings.Join(allowed, ","); got != tt.want {
+				t.Errorf("allowedMaturityRatings(%q, %v) = %s, want %s", tt.rating, tt.kids, got, tt.want)
+			}
+		})
+	}
+}
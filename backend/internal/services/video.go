@@ -2,14 +2,16 @@
 package services
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/url"
-	"path/filepath"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -17,12 +19,34 @@ import (
 	"onflix/internal/config"
 	"onflix/internal/models"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type VideoService struct {
-	config *config.Config
-	db     *mongo.Database
+	config     *config.Config
+	db         *mongo.Database
+	storage    *StorageService
+	drm        *DRMService
+	watermarks *WatermarkService
+	cdn        *CDNService
+}
+
+// Nominal encoding ladder shared by playlist and manifest generation
+var qualityBandwidth = map[models.VideoQuality]int{
+	models.Quality480p:  1000000,  // 1 Mbps
+	models.Quality720p:  3000000,  // 3 Mbps
+	models.Quality1080p: 6000000,  // 6 Mbps
+	models.Quality4K:    15000000, // 15 Mbps
+}
+
+var qualityDimensions = map[models.VideoQuality][2]int{
+	models.Quality480p:  {854, 480},
+	models.Quality720p:  {1280, 720},
+	models.Quality1080p: {1920, 1080},
+	models.Quality4K:    {3840, 2160},
 }
 
 type StreamingToken struct {
@@ -30,40 +54,81 @@ type StreamingToken struct {
 	ContentID   string    `json:"content_id"`
 	Quality     string    `json:"quality"`
 	ExpiresAt   time.Time `json:"expires_at"`
+	WatermarkID string    `json:"watermark_id"` // Selects the session's A/B segment variants
+	SessionID   string    `json:"session_id"`   // Stream session the token stops working with
 	Signature   string    `json:"signature"`
 	TokenString string    `json:"token"`
 }
 
-type VideoMetrics struct {
-	ContentID     string        `json:"content_id"`
-	UserID        string        `json:"user_id"`
-	Quality       string        `json:"quality"`
-	WatchDuration time.Duration `json:"watch_duration"`
-	TotalDuration time.Duration `json:"total_duration"`
-	BufferEvents  int           `json:"buffer_events"`
-	BitrateKbps   int           `json:"bitrate_kbps"`
-	CDNNode       string        `json:"cdn_node"`
-	ClientIP      string        `json:"client_ip"`
-	UserAgent     string        `json:"user_agent"`
-	Timestamp     time.Time     `json:"timestamp"`
-}
-
 type HLSPlaylist struct {
 	Version        int           `json:"version"`
 	Sequences      []HLSSequence `json:"sequences"`
 	TargetDuration int           `json:"target_duration"`
 	EndList        bool          `json:"end_list"`
 	PlaylistType   string        `json:"playlist_type"`
+	Key            *HLSKey       `json:"key,omitempty"`
 	Variants       []HLSVariant  `json:"variants,omitempty"`
+	// Alternate renditions listed in a master playlist
+	Media []HLSMedia `json:"media,omitempty"`
+	// Trick-play image playlists listed in a master playlist
+	ImageStreams []HLSImageStream `json:"image_streams,omitempty"`
+	// Live playlists. Partial segments make them low-latency, and blocking
+	// reloads let players wait on the server for the next one.
+	CanBlockReload bool      `json:"can_block_reload,omitempty"`
+	PartTarget     float64   `json:"part_target,omitempty"`
+	Map            string    `json:"map,omitempty"`           // URI of the fMP4 initialization section
+	PendingParts   []HLSPart `json:"pending_parts,omitempty"` // Parts of the segment still being written
+	PreloadHint    string    `json:"preload_hint,omitempty"`  // URI of the next part
 }
 
 type HLSSequence struct {
-	Duration float64 `json:"duration"`
-	URI      string  `json:"uri"`
-	Sequence int     `json:"sequence"`
+	Duration        float64    `json:"duration"`
+	URI             string     `json:"uri"`
+	Sequence        int        `json:"sequence"`
+	Parts           []HLSPart  `json:"parts,omitempty"`
+	ProgramDateTime *time.Time `json:"program_date_time,omitempty"`
+	Discontinuity   bool       `json:"discontinuity,omitempty"`
+}
+
+// HLSPart is an EXT-X-PART partial segment of a low-latency playlist
+type HLSPart struct {
+	Duration    float64 `json:"duration"`
+	URI         string  `json:"uri"`
+	Independent bool    `json:"independent,omitempty"`
+}
+
+// HLSKey is the EXT-X-KEY that applies to every segment of a media playlist
+type HLSKey struct {
+	Method string `json:"method"`
+	URI    string `json:"uri"`
+	IV     string `json:"iv,omitempty"`
 }
 
 type HLSVariant struct {
+	Bandwidth        int    `json:"bandwidth"`
+	AverageBandwidth int    `json:"average_bandwidth,omitempty"`
+	Resolution       string `json:"resolution"`
+	Codecs           string `json:"codecs"`
+	Audio            string `json:"audio,omitempty"` // GROUP-ID of the variant's audio renditions
+	URI              string `json:"uri"`
+}
+
+// HLSMedia is an EXT-X-MEDIA entry. A rendition without a URI is carried in
+// the variant streams themselves.
+type HLSMedia struct {
+	Type            string `json:"type"`
+	GroupID         string `json:"group_id"`
+	Name            string `json:"name"`
+	Language        string `json:"language,omitempty"`
+	Default         bool   `json:"default"`
+	Autoselect      bool   `json:"autoselect"`
+	Channels        string `json:"channels,omitempty"`
+	Characteristics string `json:"characteristics,omitempty"`
+	URI             string `json:"uri,omitempty"`
+}
+
+// HLSImageStream is an EXT-X-IMAGE-STREAM-INF entry pointing at an image media playlist
+type HLSImageStream struct {
 	Bandwidth  int    `json:"bandwidth"`
 	Resolution string `json:"resolution"`
 	Codecs     string `json:"codecs"`
@@ -76,30 +141,70 @@ type DASHManifest struct {
 	MinBufferTime  string              `json:"min_buffer_time"`
 	Profiles       string              `json:"profiles"`
 	AdaptationSets []DASHAdaptationSet `json:"adaptation_sets"`
+	// Hex key ID of the CENC key audio and video are encrypted with
+	DefaultKID string `json:"default_kid,omitempty"`
+	// ClearKey license server for the requesting player, set per request
+	LicenseURL string `json:"-"`
+	// Query added to every media segment URL, set per request
+	SegmentQuery string `json:"-"`
 }
 
 type DASHAdaptationSet struct {
 	ID                 string               `json:"id"`
 	ContentType        string               `json:"content_type"`
+	MimeType           string               `json:"mime_type,omitempty"`
+	Lang               string               `json:"lang,omitempty"`
+	Role               string               `json:"role,omitempty"`
+	Label              string               `json:"label,omitempty"`
 	SegmentAlignment   bool                 `json:"segment_alignment"`
 	BitstreamSwitching bool                 `json:"bitstream_switching"`
 	Representations    []DASHRepresentation `json:"representations"`
 }
 
 type DASHRepresentation struct {
-	ID        string `json:"id"`
-	Bandwidth int    `json:"bandwidth"`
-	Width     int    `json:"width,omitempty"`
-	Height    int    `json:"height,omitempty"`
-	FrameRate string `json:"frame_rate,omitempty"`
-	Codecs    string `json:"codecs"`
-	BaseURL   string `json:"base_url"`
-}
-
-func NewVideoService(cfg *config.Config, db *mongo.Database) *VideoService {
+	ID                string               `json:"id"`
+	Bandwidth         int                  `json:"bandwidth"`
+	Width             int                  `json:"width,omitempty"`
+	Height            int                  `json:"height,omitempty"`
+	FrameRate         string               `json:"frame_rate,omitempty"`
+	AudioSamplingRate string               `json:"audio_sampling_rate,omitempty"`
+	AudioChannels     string               `json:"audio_channels,omitempty"`
+	MimeType          string               `json:"mime_type,omitempty"`
+	Codecs            string               `json:"codecs"`
+	BaseURL           string               `json:"base_url"`
+	SegmentTemplate   *DASHSegmentTemplate `json:"segment_template,omitempty"`
+	SegmentBase       *DASHSegmentBase     `json:"segment_base,omitempty"`
+}
+
+type DASHSegmentTemplate struct {
+	Timescale      int                 `json:"timescale"`
+	Duration       int                 `json:"duration,omitempty"`
+	StartNumber    int                 `json:"start_number"`
+	Initialization string              `json:"initialization"`
+	Media          string              `json:"media"`
+	Timeline       []DASHTimelineEntry `json:"timeline,omitempty"`
+}
+
+type DASHTimelineEntry struct {
+	Start    *int64 `json:"t,omitempty"`
+	Duration int64  `json:"d"`
+	Repeat   int    `json:"r,omitempty"`
+}
+
+type DASHSegmentBase struct {
+	Timescale           int    `json:"timescale,omitempty"`
+	IndexRange          string `json:"index_range"`
+	InitializationRange string `json:"initialization_range"`
+}
+
+func NewVideoService(cfg *config.Config, db *mongo.Database, storage *StorageService, drm *DRMService, watermarks *WatermarkService, cdn *CDNService) *VideoService {
 	return &VideoService{
-		config: cfg,
-		db:     db,
+		config:     cfg,
+		db:         db,
+		storage:    storage,
+		drm:        drm,
+		watermarks: watermarks,
+		cdn:        cdn,
 	}
 }
 
@@ -108,9 +213,9 @@ func (vs *VideoService) Close() {
 }
 
 // Streaming URL Generation
-func (vs *VideoService) GenerateStreamingURL(videoFileURL, userID string) (string, error) {
-	if videoFileURL == "" || userID == "" {
-		return "", fmt.Errorf("video URL and user ID are required")
+func (vs *VideoService) GenerateStreamingURL(videoFileURL, contentID, userID, clientIP string) (string, error) {
+	if videoFileURL == "" || contentID == "" || userID == "" {
+		return "", fmt.Errorf("video URL, content ID and user ID are required")
 	}
 
 	// Parse the original URL
@@ -121,7 +226,35 @@ func (vs *VideoService) GenerateStreamingURL(videoFileURL, userID string) (strin
 
 	// Generate signed URL parameters
 	expiration := time.Now().Add(6 * time.Hour) // 6 hour expiration
-	signature, err := vs.generateSignature(videoFileURL, userID, expiration)
+
+	// Each link is its own watermark session, so a leaked link names its
+	// user whichever of the CDN, the bucket or the API serves it
+	watermarkID, err := vs.watermarks.StartSession(context.Background(), userID, contentID, expiration)
+	if err != nil {
+		return "", err
+	}
+
+	// A CDN in front of storage serves the file when one is up for the client.
+	// Its links are short-lived because the edge cannot re-check the
+	// subscription; players come back for a new one with their stream session.
+	cdnURL, err := vs.cdn.SignedURL(vs.storage.PathFromURL(videoFileURL), clientIP, time.Now().Add(vs.cdn.LinkLifetime()), watermarkID)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate signature: %v", err)
+	}
+	if cdnURL != "" {
+		return cdnURL, nil
+	}
+
+	// Remote storage signs its own links, so players fetch straight from the bucket
+	signedURL, err := vs.storage.SignedURL(context.Background(), vs.storage.PathFromURL(videoFileURL), time.Until(expiration), url.Values{"wm": {watermarkID}})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate signature: %v", err)
+	}
+	if signedURL != "" {
+		return signedURL, nil
+	}
+
+	signature, err := vs.generateSignature(videoFileURL, userID, watermarkID, expiration)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate signature: %v", err)
 	}
@@ -129,6 +262,7 @@ func (vs *VideoService) GenerateStreamingURL(videoFileURL, userID string) (strin
 	// Add security parameters
 	query := parsedURL.Query()
 	query.Set("user_id", userID)
+	query.Set("wm", watermarkID)
 	query.Set("expires", strconv.FormatInt(expiration.Unix(), 10))
 	query.Set("signature", signature)
 	query.Set("token", vs.generateAccessToken(userID, expiration))
@@ -138,9 +272,50 @@ func (vs *VideoService) GenerateStreamingURL(videoFileURL, userID string) (strin
 	return parsedURL.String(), nil
 }
 
-func (vs *VideoService) GenerateStreamingToken(contentID, userID string) (string, error) {
-	if contentID == "" || userID == "" {
-		return "", fmt.Errorf("content ID and user ID are required")
+// VerifyStreamingURL checks a request for a storage file against the signature,
+// expiry and access token added by GenerateStreamingURL and returns the user
+// the link was issued to.
+func (vs *VideoService) VerifyStreamingURL(relativePath string, query url.Values) (string, error) {
+	userID := query.Get("user_id")
+	signature := query.Get("signature")
+	if userID == "" || signature == "" {
+		return "", fmt.Errorf("missing signature parameters")
+	}
+
+	expiresUnix, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid expiration time: %v", err)
+	}
+
+	expiration := time.Unix(expiresUnix, 0)
+	if time.Now().After(expiration) {
+		return "", fmt.Errorf("link has expired")
+	}
+
+	// The signature covers the public URL the file was stored under
+	expectedSignature, err := vs.generateSignature(vs.storage.deliveryURL(relativePath), userID, query.Get("wm"), expiration)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify signature: %v", err)
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
+		return "", fmt.Errorf("invalid signature")
+	}
+
+	if query.Get("token") != vs.generateAccessToken(userID, expiration) {
+		return "", fmt.Errorf("access token does not match user")
+	}
+
+	return userID, nil
+}
+
+// GenerateStreamingToken issues a token for one playback session. Every token
+// starts a new watermark session, so players should reuse the token they were
+// handed for the rest of the session. The token is only honoured while the
+// stream session it names is live.
+func (vs *VideoService) GenerateStreamingToken(contentID, userID, sessionID string) (string, error) {
+	if contentID == "" || userID == "" || sessionID == "" {
+		return "", fmt.Errorf("content ID, user ID and stream session ID are required")
 	}
 
 	token := &StreamingToken{
@@ -148,10 +323,17 @@ func (vs *VideoService) GenerateStreamingToken(contentID, userID string) (string
 		ContentID: contentID,
 		Quality:   "auto",
 		ExpiresAt: time.Now().Add(6 * time.Hour),
+		SessionID: sessionID,
 	}
 
+	watermarkID, err := vs.watermarks.StartSession(context.Background(), userID, contentID, token.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	token.WatermarkID = watermarkID
+
 	// Generate token string
-	tokenData := fmt.Sprintf("%s:%s:%s:%d", token.UserID, token.ContentID, token.Quality, token.ExpiresAt.Unix())
+	tokenData := fmt.Sprintf("%s:%s:%s:%d:%s:%s", token.UserID, token.ContentID, token.Quality, token.ExpiresAt.Unix(), token.WatermarkID, token.SessionID)
 	tokenBytes := []byte(tokenData)
 
 	// Create signature
@@ -176,7 +358,7 @@ func (vs *VideoService) ValidateStreamingToken(tokenString string) (*StreamingTo
 	// Parse token components
 	tokenStr := string(tokenBytes)
 	parts := strings.Split(tokenStr, ":")
-	if len(parts) != 5 {
+	if len(parts) != 7 {
 		return nil, fmt.Errorf("invalid token structure")
 	}
 
@@ -187,7 +369,9 @@ func (vs *VideoService) ValidateStreamingToken(tokenString string) (*StreamingTo
 	if err != nil {
 		return nil, fmt.Errorf("invalid expiration time: %v", err)
 	}
-	signature := parts[4]
+	watermarkID := parts[4]
+	sessionID := parts[5]
+	signature := parts[6]
 
 	// Check expiration
 	if time.Now().Unix() > expiresAt {
@@ -195,7 +379,7 @@ func (vs *VideoService) ValidateStreamingToken(tokenString string) (*StreamingTo
 	}
 
 	// Verify signature
-	tokenData := fmt.Sprintf("%s:%s:%s:%d", userID, contentID, quality, expiresAt)
+	tokenData := fmt.Sprintf("%s:%s:%s:%d:%s:%s", userID, contentID, quality, expiresAt, watermarkID, sessionID)
 	expectedSignature, err := vs.signData([]byte(tokenData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify signature: %v", err)
@@ -210,68 +394,40 @@ func (vs *VideoService) ValidateStreamingToken(tokenString string) (*StreamingTo
 		ContentID:   contentID,
 		Quality:     quality,
 		ExpiresAt:   time.Unix(expiresAt, 0),
+		WatermarkID: watermarkID,
+		SessionID:   sessionID,
 		Signature:   signature,
 		TokenString: tokenString,
 	}, nil
 }
 
 // HLS Playlist Generation
-func (vs *VideoService) GenerateHLSPlaylist(videoFileURL string, quality models.VideoQuality) (*HLSPlaylist, error) {
-	playlist := &HLSPlaylist{
-		Version:        3,
-		TargetDuration: 10,
-		PlaylistType:   "VOD",
-		EndList:        true,
-	}
-
-	// Generate base URL without file extension
-	baseURL := strings.TrimSuffix(videoFileURL, filepath.Ext(videoFileURL))
-
-	// Generate segments (simulate 10-second segments)
-	totalDuration := 7200 // 2 hours in seconds (example)
-	segmentDuration := 10
-	numSegments := totalDuration / segmentDuration
-
-	for i := 0; i < numSegments; i++ {
-		sequence := HLSSequence{
-			Duration: float64(segmentDuration),
-			URI:      fmt.Sprintf("%s_%s_seg_%d.ts", baseURL, quality, i),
-			Sequence: i,
-		}
-		playlist.Sequences = append(playlist.Sequences, sequence)
+func (vs *VideoService) GenerateHLSPlaylist(ownerID string, quality models.VideoQuality) (*HLSPlaylist, error) {
+	// Load the media playlist written by PackageHLS
+	data, err := vs.storage.GetFile(hlsPlaylistPath(ownerID, quality))
+	if err != nil {
+		return nil, fmt.Errorf("no packaged %s playlist: %v", quality, err)
 	}
 
-	return playlist, nil
+	return ParseHLSMediaPlaylist(data)
 }
 
-func (vs *VideoService) GenerateHLSMasterPlaylist(videoFileURL string, availableQualities []models.VideoQuality) (*HLSPlaylist, error) {
+func (vs *VideoService) GenerateHLSMasterPlaylist(baseURI string, availableQualities []models.VideoQuality) (*HLSPlaylist, error) {
 	playlist := &HLSPlaylist{
 		Version: 3,
 	}
 
-	baseURL := strings.TrimSuffix(videoFileURL, filepath.Ext(videoFileURL))
-
-	// Quality to bitrate mapping
-	qualityBandwidth := map[models.VideoQuality]int{
-		models.Quality480p:  1000000,  // 1 Mbps
-		models.Quality720p:  3000000,  // 3 Mbps
-		models.Quality1080p: 6000000,  // 6 Mbps
-		models.Quality4K:    15000000, // 15 Mbps
-	}
-
-	qualityResolution := map[models.VideoQuality]string{
-		models.Quality480p:  "854x480",
-		models.Quality720p:  "1280x720",
-		models.Quality1080p: "1920x1080",
-		models.Quality4K:    "3840x2160",
-	}
-
 	for _, quality := range availableQualities {
+		dimensions, ok := qualityDimensions[quality]
+		if !ok {
+			return nil, fmt.Errorf("unsupported video quality: %s", quality)
+		}
+
 		variant := HLSVariant{
 			Bandwidth:  qualityBandwidth[quality],
-			Resolution: qualityResolution[quality],
+			Resolution: fmt.Sprintf("%dx%d", dimensions[0], dimensions[1]),
 			Codecs:     "avc1.42E01E,mp4a.40.2",
-			URI:        fmt.Sprintf("%s_%s.m3u8", baseURL, quality),
+			URI:        path.Join(baseURI, string(quality), hlsMediaPlaylistName),
 		}
 		playlist.Variants = append(playlist.Variants, variant)
 	}
@@ -280,108 +436,46 @@ func (vs *VideoService) GenerateHLSMasterPlaylist(videoFileURL string, available
 }
 
 // DASH Manifest Generation
-func (vs *VideoService) GenerateDASHManifest(videoFileURL string, availableQualities []models.VideoQuality) (*DASHManifest, error) {
-	manifest := &DASHManifest{
-		Type:          "static",
-		MediaDuration: "PT2H0M0S", // 2 hours
-		MinBufferTime: "PT2S",     // 2 seconds
-		Profiles:      "urn:mpeg:dash:profile:isoff-main:2011",
-	}
-
-	baseURL := strings.TrimSuffix(videoFileURL, filepath.Ext(videoFileURL))
-
-	// Video adaptation set
-	videoAdaptationSet := DASHAdaptationSet{
-		ID:                 "video",
-		ContentType:        "video",
-		SegmentAlignment:   true,
-		BitstreamSwitching: true,
-	}
-
-	qualityBandwidth := map[models.VideoQuality]int{
-		models.Quality480p:  1000000,
-		models.Quality720p:  3000000,
-		models.Quality1080p: 6000000,
-		models.Quality4K:    15000000,
-	}
-
-	qualityDimensions := map[models.VideoQuality][2]int{
-		models.Quality480p:  {854, 480},
-		models.Quality720p:  {1280, 720},
-		models.Quality1080p: {1920, 1080},
-		models.Quality4K:    {3840, 2160},
-	}
-
-	for i, quality := range availableQualities {
-		dimensions := qualityDimensions[quality]
-		representation := DASHRepresentation{
-			ID:        fmt.Sprintf("video_%d", i),
-			Bandwidth: qualityBandwidth[quality],
-			Width:     dimensions[0],
-			Height:    dimensions[1],
-			FrameRate: "24000/1001",
-			Codecs:    "avc1.42E01E",
-			BaseURL:   fmt.Sprintf("%s_%s.mpd", baseURL, quality),
-		}
-		videoAdaptationSet.Representations = append(videoAdaptationSet.Representations, representation)
-	}
-
-	// Audio adaptation set
-	audioAdaptationSet := DASHAdaptationSet{
-		ID:                 "audio",
-		ContentType:        "audio",
-		SegmentAlignment:   true,
-		BitstreamSwitching: true,
-		Representations: []DASHRepresentation{
-			{
-				ID:        "audio_0",
-				Bandwidth: 128000,
-				Codecs:    "mp4a.40.2",
-				BaseURL:   fmt.Sprintf("%s_audio.mpd", baseURL),
-			},
-		},
+func (vs *VideoService) GenerateDASHManifest(ownerID string, subtitles []models.Subtitle, language string) (*DASHManifest, error) {
+	// Start from the video and audio sets recorded by PackageDASH
+	data, err := vs.storage.GetFile(path.Join(DASHDirectory(ownerID), dashManifestData))
+	if err != nil {
+		return nil, fmt.Errorf("content has not been packaged for DASH: %v", err)
 	}
 
-	manifest.AdaptationSets = []DASHAdaptationSet{videoAdaptationSet, audioAdaptationSet}
-
-	return manifest, nil
-}
-
-// Video Analytics and Metrics
-func (vs *VideoService) RecordVideoMetrics(metrics VideoMetrics) error {
-	// Store metrics in database for analytics
-	// In production, you might use a time-series database or analytics service
-
-	// Basic validation
-	if metrics.ContentID == "" || metrics.UserID == "" {
-		return fmt.Errorf("content ID and user ID are required")
+	var manifest DASHManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid packaged manifest: %v", err)
 	}
 
-	// Set timestamp if not provided
-	if metrics.Timestamp.IsZero() {
-		metrics.Timestamp = time.Now()
-	}
+	// The default audio follows the viewer's language
+	manifest.selectDASHAudio(language)
 
-	// Store in MongoDB (simplified)
-	_, err := vs.db.Collection("video_metrics").InsertOne(nil, metrics)
-	return err
-}
+	// Subtitles can change after packaging, so they are attached per request
+	for i, subtitle := range subtitles {
+		mimeType := "text/vtt"
+		if strings.EqualFold(path.Ext(subtitle.FileURL), ".srt") {
+			mimeType = "application/x-subrip"
+		}
 
-func (vs *VideoService) GetVideoAnalytics(contentID string, startDate, endDate time.Time) (map[string]interface{}, error) {
-	// Aggregate video metrics for analytics
-	analytics := map[string]interface{}{
-		"content_id":       contentID,
-		"total_views":      0,
-		"total_watch_time": 0,
-		"average_quality":  "720p",
-		"buffer_ratio":     0.05,
-		"completion_rate":  0.75,
-		"unique_viewers":   0,
-		"peak_concurrent":  0,
+		manifest.AdaptationSets = append(manifest.AdaptationSets, DASHAdaptationSet{
+			ID:          fmt.Sprintf("text_%d", i),
+			ContentType: "text",
+			MimeType:    mimeType,
+			Lang:        subtitle.Language,
+			Label:       subtitle.Label,
+			Role:        "subtitle",
+			Representations: []DASHRepresentation{
+				{
+					ID:        fmt.Sprintf("subtitle_%s", subtitle.Language),
+					Bandwidth: 256,
+					BaseURL:   subtitle.FileURL,
+				},
+			},
+		})
 	}
 
-	// In production, implement proper aggregation queries
-	return analytics, nil
+	return &manifest, nil
 }
 
 // Quality Adaptation
@@ -444,74 +538,39 @@ func (vs *VideoService) GenerateSubtitlePlaylist(subtitles []models.Subtitle) ma
 	return playlist
 }
 
-func (vs *VideoService) ConvertSubtitleFormat(inputFormat, outputFormat, content string) (string, error) {
-	// Basic subtitle format conversion
-	// In production, use a proper subtitle library
-
-	switch inputFormat {
-	case "srt":
-		if outputFormat == "vtt" {
-			return vs.convertSRTtoVTT(content), nil
-		}
-	case "vtt":
-		if outputFormat == "srt" {
-			return vs.convertVTTtoSRT(content), nil
-		}
-	}
-
-	return content, nil
-}
-
-// Content Delivery Network (CDN) Integration
-func (vs *VideoService) GetCDNURL(originalURL, userLocation string) string {
-	// Simple CDN URL generation based on user location
-	// In production, integrate with actual CDN service (CloudFront, CloudFlare, etc.)
-
-	cdnDomains := map[string]string{
-		"US": "us-cdn.example.com",
-		"EU": "eu-cdn.example.com",
-		"AS": "asia-cdn.example.com",
+// DRM Integration
+// GenerateDRMLicense returns where players fetch the keys for a title: the
+// HLS AES-128 key URL and the ClearKey license server for EME, each with a
+// streaming token that authorizes the request
+func (vs *VideoService) GenerateDRMLicense(contentID, userID, sessionID string) (map[string]string, error) {
+	contentObjID, err := primitive.ObjectIDFromHex(contentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content ID: %v", err)
 	}
 
-	region := "US" // Default
-	if userLocation != "" {
-		// Simple location to region mapping
-		if strings.Contains(userLocation, "EU") || strings.Contains(userLocation, "Europe") {
-			region = "EU"
-		} else if strings.Contains(userLocation, "AS") || strings.Contains(userLocation, "Asia") {
-			region = "AS"
-		}
+	key, _, err := vs.drm.ContentKey(context.Background(), contentID, contentObjID)
+	if err != nil {
+		return nil, err
 	}
 
-	if cdnDomain, exists := cdnDomains[region]; exists {
-		parsedURL, err := url.Parse(originalURL)
-		if err == nil {
-			parsedURL.Host = cdnDomain
-			return parsedURL.String()
-		}
+	token, err := vs.GenerateStreamingToken(contentID, userID, sessionID)
+	if err != nil {
+		return nil, err
 	}
 
-	return originalURL
-}
-
-// DRM Integration (simplified)
-func (vs *VideoService) GenerateDRMLicense(contentID, userID string) (map[string]string, error) {
-	// Simplified DRM license generation
-	// In production, integrate with actual DRM services (Widevine, FairPlay, PlayReady)
-
 	license := map[string]string{
-		"license_url": fmt.Sprintf("https://drm.example.com/license/%s", contentID),
-		"key_id":      vs.generateKeyID(contentID),
-		"key":         vs.generateEncryptionKey(contentID, userID),
-		"expires_at":  time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+		"key_id":               key.KeyID,
+		"hls_key_url":          vs.drm.HLSKeyURI(key.KeyID) + "?token=" + url.QueryEscape(token),
+		"clearkey_license_url": vs.drm.ClearKeyLicenseURL() + "?token=" + url.QueryEscape(token),
+		"expires_at":           time.Now().Add(6 * time.Hour).Format(time.RFC3339),
 	}
 
 	return license, nil
 }
 
 // Helper methods
-func (vs *VideoService) generateSignature(videoURL, userID string, expiration time.Time) (string, error) {
-	message := fmt.Sprintf("%s:%s:%d", videoURL, userID, expiration.Unix())
+func (vs *VideoService) generateSignature(videoURL, userID, watermarkID string, expiration time.Time) (string, error) {
+	message := fmt.Sprintf("%s:%s:%s:%d", videoURL, userID, watermarkID, expiration.Unix())
 	return vs.signData([]byte(message))
 }
 
@@ -527,18 +586,6 @@ func (vs *VideoService) generateAccessToken(userID string, expiration time.Time)
 	return base64.URLEncoding.EncodeToString([]byte(data))
 }
 
-func (vs *VideoService) generateKeyID(contentID string) string {
-	h := sha256.New()
-	h.Write([]byte(contentID))
-	return hex.EncodeToString(h.Sum(nil))[:16]
-}
-
-func (vs *VideoService) generateEncryptionKey(contentID, userID string) string {
-	h := sha256.New()
-	h.Write([]byte(contentID + userID + vs.config.JWT.Secret))
-	return hex.EncodeToString(h.Sum(nil))[:32]
-}
-
 func (vs *VideoService) generateRandomToken(length int) (string, error) {
 	bytes := make([]byte, length)
 	if _, err := rand.Read(bytes); err != nil {
@@ -547,62 +594,29 @@ func (vs *VideoService) generateRandomToken(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// Subtitle conversion helpers
-func (vs *VideoService) convertSRTtoVTT(srtContent string) string {
-	// Basic SRT to VTT conversion
-	vttContent := "WEBVTT\n\n"
-
-	lines := strings.Split(srtContent, "\n")
-	for _, line := range lines {
-		// Convert timestamp format
-		if strings.Contains(line, " --> ") {
-			line = strings.ReplaceAll(line, ",", ".")
-		}
-		vttContent += line + "\n"
+// Video processing status
+func (vs *VideoService) GetProcessingStatus(videoID string) (*models.TranscodeJob, error) {
+	videoObjID, err := primitive.ObjectIDFromHex(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid video ID: %v", err)
 	}
 
-	return vttContent
-}
-
-func (vs *VideoService) convertVTTtoSRT(vttContent string) string {
-	// Basic VTT to SRT conversion
-	lines := strings.Split(vttContent, "\n")
-	srtContent := ""
-	counter := 1
-
-	for i, line := range lines {
-		if line == "WEBVTT" {
-			continue
-		}
+	// The most recent job reflects the video's current state
+	var job models.TranscodeJob
+	err = vs.db.Collection(transcodeJobsCollection).FindOne(
+		context.Background(),
+		bson.M{"source_video_id": videoObjID},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	).Decode(&job)
 
-		if strings.Contains(line, " --> ") {
-			srtContent += fmt.Sprintf("%d\n", counter)
-			counter++
-			line = strings.ReplaceAll(line, ".", ",")
-		}
-
-		if i > 0 {
-			srtContent += line + "\n"
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("video has not been processed")
 		}
+		return nil, fmt.Errorf("failed to load processing status: %v", err)
 	}
 
-	return srtContent
-}
-
-// Video processing status
-func (vs *VideoService) GetProcessingStatus(videoID string) (map[string]interface{}, error) {
-	status := map[string]interface{}{
-		"video_id":            videoID,
-		"status":              "completed",
-		"progress":            100,
-		"qualities_available": []string{"480p", "720p", "1080p"},
-		"duration":            7200,
-		"file_size":           2048000000, // 2GB
-		"created_at":          time.Now().Add(-2 * time.Hour),
-		"updated_at":          time.Now(),
-	}
-
-	return status, nil
+	return &job, nil
 }
 
 // Bandwidth estimation
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"onflix/internal/config"
+)
+
+// signedTestToken builds a streaming token the way GenerateStreamingToken does,
+// without starting a watermark session
+func signedTestToken(t *testing.T, vs *VideoService, fields string) string {
+	t.Helper()
+	signature, err := vs.signData([]byte(fields))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.URLEncoding.EncodeToString([]byte(fields + ":" + signature))
+}
+
+func TestValidateStreamingToken(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	vs := &VideoService{config: cfg}
+
+	otherCfg := &config.Config{}
+	otherCfg.JWT.Secret = "other-secret"
+	other := &VideoService{config: otherCfg}
+
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+	fields := func(expires int64) string {
+		return fmt.Sprintf("user1:content1:auto:%d:wm1:session1", expires)
+	}
+	valid := signedTestToken(t, vs, fields(future))
+
+	raw, _ := base64.URLEncoding.DecodeString(valid)
+	swapped := base64.URLEncoding.EncodeToString([]byte(strings.Replace(string(raw), "session1", "session2", 1)))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"expired", signedTestToken(t, vs, fields(past)), "token expired"},
+		{"signed with another secret", signedTestToken(t, other, fields(future)), "invalid token signature"},
+		{"session swapped", swapped, "invalid token signature"},
+		{"old six-part token", signedTestToken(t, vs, fmt.Sprintf("user1:content1:auto:%d:wm1", future)), "invalid token structure"},
+		{"bad expiry", signedTestToken(t, vs, "user1:content1:auto:soon:wm1:session1"), "invalid expiration time"},
+		{"not base64", "%%%", "invalid token format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := vs.ValidateStreamingToken(tt.token)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ValidateStreamingToken error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateStreamingToken: %v", err)
+			}
+			if token.UserID != "user1" || token.ContentID != "content1" || token.WatermarkID != "wm1" || token.SessionID != "session1" {
+				t.Errorf("token = %+v", token)
+			}
+			if token.ExpiresAt.Unix() != future {
+				t.Errorf("expires at %d, want %d", token.ExpiresAt.Unix(), future)
+			}
+		})
+	}
+}
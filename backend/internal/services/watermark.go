@@ -0,0 +1,232 @@
+// backend/internal/services/watermark.go
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"onflix/internal/config"
+	"onflix/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	watermarkSessionsCollection = "watermark_sessions"
+	watermarkIDSize             = 8
+	// Segments misread from a re-encoded leak are tolerated up to this rate;
+	// an unrelated session disagrees on about half of them
+	maxWatermarkErrorRate = 0.15
+	// A capture must be long enough that, across every session of the title,
+	// fewer than this many unrelated sessions are expected to pass by chance
+	maxWatermarkFalsePositives = 1e-6
+	maxWatermarkMatches        = 10
+)
+
+var (
+	ErrWatermarkSequence = errors.New("sequence may only contain A, B, or ? for segments that could not be read")
+	ErrWatermarkTooShort = errors.New("sequence has too few readable segments to identify a session")
+)
+
+// WatermarkMatch is a session whose A/B sequence agrees with a captured one.
+// ExpectedFalsePositives is how many of the title's sessions would agree at
+// least this well by chance; the smaller it is, the stronger the match.
+type WatermarkMatch struct {
+	Session                models.WatermarkSession `json:"session"`
+	MatchedBits            int                     `json:"matched_bits"`
+	KnownBits              int                     `json:"known_bits"`
+	ExpectedFalsePositives float64                 `json:"expected_false_positives"`
+}
+
+// WatermarkService issues the per-session watermark IDs embedded in streaming
+// tokens. Each ID selects the A or B copy of every HLS segment, so a captured
+// copy of a title carries a sequence that identifies the session it came from.
+type WatermarkService struct {
+	config *config.Config
+	db     *mongo.Database
+}
+
+func NewWatermarkService(cfg *config.Config, db *mongo.Database) *WatermarkService {
+	return &WatermarkService{
+		config: cfg,
+		db:     db,
+	}
+}
+
+func (ws *WatermarkService) Close() {
+	// Cleanup resources if needed
+}
+
+// Enabled reports whether packaging writes A/B segment variants
+func (ws *WatermarkService) Enabled() bool {
+	return ws.config.Video.ForensicWatermark == "ab"
+}
+
+// StartSession records a new watermark session for a user watching a title
+// and returns its watermark ID
+func (ws *WatermarkService) StartSession(ctx context.Context, userID, contentID string, expiresAt time.Time) (string, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return "", fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	contentObjID, err := primitive.ObjectIDFromHex(contentID)
+	if err != nil {
+		return "", fmt.Errorf("invalid content ID: %v", err)
+	}
+
+	raw := make([]byte, watermarkIDSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate watermark ID: %v", err)
+	}
+	watermarkID := hex.EncodeToString(raw)
+
+	session := models.WatermarkSession{
+		ID:          primitive.NewObjectID(),
+		WatermarkID: watermarkID,
+		UserID:      userObjID,
+		ContentID:   contentObjID,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   expiresAt,
+	}
+
+	if _, err := ws.db.Collection(watermarkSessionsCollection).InsertOne(ctx, session); err != nil {
+		return "", fmt.Errorf("failed to record watermark session: %v", err)
+	}
+
+	return watermarkID, nil
+}
+
+// Variant returns which copy of a segment a session is served: 0 for A, 1 for B
+func (ws *WatermarkService) Variant(watermarkID string, sequence int) int {
+	h := hmac.New(sha256.New, []byte(ws.config.JWT.Secret))
+	h.Write([]byte("watermark:" + watermarkID + ":" + strconv.Itoa(sequence)))
+	return int(h.Sum(nil)[0] & 1)
+}
+
+// Decode finds the sessions of a title whose segment variants agree with a
+// captured sequence. sequence holds one A or B per segment starting at media
+// sequence number firstSegment, with ? for segments that could not be read.
+func (ws *WatermarkService) Decode(ctx context.Context, contentID primitive.ObjectID, sequence string, firstSegment int) ([]WatermarkMatch, error) {
+	var captured []int
+	knownBits := 0
+	for _, r := range strings.ToUpper(sequence) {
+		switch r {
+		case 'A':
+			captured = append(captured, 0)
+			knownBits++
+		case 'B':
+			captured = append(captured, 1)
+			knownBits++
+		case '?':
+			captured = append(captured, -1)
+		case ' ', ',', '-':
+			// Separators investigators use to group segments
+		default:
+			return nil, ErrWatermarkSequence
+		}
+	}
+
+	collection := ws.db.Collection(watermarkSessionsCollection)
+	candidates, err := collection.CountDocuments(ctx, bson.M{"content_id": contentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count watermark sessions: %v", err)
+	}
+
+	if knownBits < requiredWatermarkBits(candidates) {
+		return nil, ErrWatermarkTooShort
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"content_id": contentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load watermark sessions: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	matches := []WatermarkMatch{}
+	for cursor.Next(ctx) {
+		var session models.WatermarkSession
+		if err := cursor.Decode(&session); err != nil {
+			return nil, fmt.Errorf("failed to decode watermark session: %v", err)
+		}
+
+		matched := 0
+		for i, bit := range captured {
+			if bit >= 0 && ws.Variant(session.WatermarkID, firstSegment+i) == bit {
+				matched++
+			}
+		}
+
+		if float64(knownBits-matched) > float64(knownBits)*maxWatermarkErrorRate {
+			continue
+		}
+
+		matches = append(matches, WatermarkMatch{
+			Session:                session,
+			MatchedBits:            matched,
+			KnownBits:              knownBits,
+			ExpectedFalsePositives: float64(candidates) * chanceWatermarkMatch(knownBits, knownBits-matched),
+		})
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load watermark sessions: %v", err)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].MatchedBits > matches[j].MatchedBits
+	})
+
+	if len(matches) > maxWatermarkMatches {
+		matches = matches[:maxWatermarkMatches]
+	}
+
+	return matches, nil
+}
+
+// requiredWatermarkBits is the fewest known segments for which Decode expects
+// fewer than maxWatermarkFalsePositives of candidates unrelated sessions to
+// pass the error-rate check
+func requiredWatermarkBits(candidates int64) int {
+	if candidates < 1 {
+		candidates = 1
+	}
+
+	bits := 1
+	for float64(candidates)*chanceWatermarkMatch(bits, maxWatermarkErrors(bits)) >= maxWatermarkFalsePositives {
+		bits++
+	}
+	return bits
+}
+
+// maxWatermarkErrors is how many of bits known segments may disagree before
+// a session is rejected
+func maxWatermarkErrors(bits int) int {
+	return int(math.Floor(float64(bits) * maxWatermarkErrorRate))
+}
+
+// chanceWatermarkMatch is the probability that an unrelated session, which
+// picks A or B at random for each segment, disagrees on at most mismatches of
+// bits segments
+func chanceWatermarkMatch(bits, mismatches int) float64 {
+	lgBits, _ := math.Lgamma(float64(bits + 1))
+	p := 0.0
+	for e := 0; e <= mismatches; e++ {
+		lgE, _ := math.Lgamma(float64(e + 1))
+		lgRest, _ := math.Lgamma(float64(bits - e + 1))
+		p += math.Exp(lgBits - lgE - lgRest - float64(bits)*math.Ln2)
+	}
+	return math.Min(p, 1)
+}
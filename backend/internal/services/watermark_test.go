@@ -0,0 +1,43 @@
+package services
+
+import "testing"
+
+func TestRequiredWatermarkBits(t *testing.T) {
+	previous := 0
+	for _, candidates := range []int64{0, 1, 100, 10000, 1000000} {
+		bits := requiredWatermarkBits(candidates)
+
+		expected := float64(max(candidates, 1)) * chanceWatermarkMatch(bits, maxWatermarkErrors(bits))
+		if expected >= maxWatermarkFalsePositives {
+			t.Errorf("%d sessions, %d bits: expected %g false positives", candidates, bits, expected)
+		}
+		if bits < previous {
+			t.Errorf("%d sessions need %d bits, fewer than a smaller title's %d", candidates, bits, previous)
+		}
+		previous = bits
+	}
+
+	// The old fixed minimum let about one in ten thousand unrelated sessions through
+	if bits := requiredWatermarkBits(1); bits <= 16 {
+		t.Errorf("a single session needs %d bits, want more than 16", bits)
+	}
+}
+
+func TestChanceWatermarkMatch(t *testing.T) {
+	tests := []struct {
+		bits, mismatches int
+		want             float64
+	}{
+		{bits: 1, mismatches: 0, want: 0.5},
+		{bits: 4, mismatches: 0, want: 1.0 / 16},
+		{bits: 4, mismatches: 1, want: 5.0 / 16},
+		{bits: 4, mismatches: 4, want: 1},
+	}
+
+	for _, tt := range tests {
+		got := chanceWatermarkMatch(tt.bits, tt.mismatches)
+		if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("chanceWatermarkMatch(%d, %d) = %g, want %g", tt.bits, tt.mismatches, got, tt.want)
+		}
+	}
+}
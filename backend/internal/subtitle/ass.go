@@ -0,0 +1,254 @@
+// backend/internal/subtitle/ass.go
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns of a Dialogue line when the [Events] section has no Format line
+var assDefaultEvents = []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+
+// assStyle is the part of an ASS style that carries over to other formats
+type assStyle struct {
+	top, italic, bold, underline bool
+}
+
+// parseASS reads Advanced SubStation Alpha and its SSA predecessor. Style
+// italics, bold, underline and top alignment are applied to each line,
+// override tags are reduced to the same, and vector drawings are dropped.
+func parseASS(text string) (*Document, error) {
+	doc := &Document{}
+	styles := make(map[string]assStyle)
+	legacy := false
+
+	var section string
+	var styleFormat []string
+	eventFormat := assDefaultEvents
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(line)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch {
+		case section == "[script info]" && key == "scripttype":
+			legacy = !strings.Contains(strings.ToLower(value), "+")
+
+		case strings.HasSuffix(section, "styles]") && key == "format":
+			styleFormat = assColumns(value)
+
+		case strings.HasSuffix(section, "styles]") && key == "style":
+			fields := splitASSFields(value, len(styleFormat))
+			get := func(column string) string {
+				if i := indexOf(styleFormat, column); i >= 0 && i < len(fields) {
+					return fields[i]
+				}
+				return ""
+			}
+			styles[get("name")] = assStyle{
+				top:       assTopAlignment(get("alignment"), legacy || section == "[v4 styles]"),
+				italic:    assFlag(get("italic")),
+				bold:      assFlag(get("bold")),
+				underline: assFlag(get("underline")),
+			}
+
+		case section == "[events]" && key == "format":
+			eventFormat = assColumns(value)
+
+		case section == "[events]" && key == "dialogue":
+			fields := splitASSFields(value, len(eventFormat))
+			get := func(column string) string {
+				if i := indexOf(eventFormat, column); i >= 0 && i < len(fields) {
+					return fields[i]
+				}
+				return ""
+			}
+
+			start, err := parseClock(get("start"))
+			if err != nil {
+				return nil, fmt.Errorf("ass line %d: %v", i+1, err)
+			}
+			end, err := parseClock(get("end"))
+			if err != nil {
+				return nil, fmt.Errorf("ass line %d: %v", i+1, err)
+			}
+
+			style := styles[strings.TrimPrefix(get("style"), "*")]
+			cue := Cue{Start: start, End: end, Top: style.top}
+			cue.Text = assText(get("text"), style, &cue)
+			if cue.Text != "" {
+				doc.Cues = append(doc.Cues, cue)
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+func assColumns(value string) []string {
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		columns = append(columns, strings.ToLower(strings.TrimSpace(column)))
+	}
+	return columns
+}
+
+// splitASSFields splits on the first n-1 commas, since the last column, the
+// text, may itself contain commas
+func splitASSFields(value string, n int) []string {
+	fields := strings.SplitN(value, ",", max(n, 1))
+	for i := range fields[:len(fields)-1] {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+func assFlag(value string) bool {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	return err == nil && n != 0
+}
+
+// assTopAlignment reads an alignment, which is numpad style (7 to 9 at the
+// top) in ASS. SSA numbers the bottom 1 to 3 and adds 4 for the top or 8
+// for the middle.
+func assTopAlignment(value string, legacy bool) bool {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	if legacy {
+		return n >= 5 && n <= 7
+	}
+	return n >= 7 && n <= 9
+}
+
+// assText converts dialogue text and its override blocks into cue markup
+func assText(text string, style assStyle, cue *Cue) string {
+	var b strings.Builder
+	drawing := false
+
+	// Open the style's own tags
+	state := assStyle{}
+	writeStyleChange(&b, &state, style)
+
+	for text != "" {
+		if strings.HasPrefix(text, "{") {
+			end := strings.IndexByte(text, '}')
+			if end < 0 {
+				end = len(text) - 1
+			}
+			next := state
+			for _, tag := range strings.Split(text[1:end], `\`)[1:] {
+				switch {
+				case strings.HasPrefix(tag, "an"):
+					cue.Top = assTopAlignment(tag[2:], false)
+				case strings.HasPrefix(tag, "a") && !strings.HasPrefix(tag, "alpha"):
+					cue.Top = assTopAlignment(tag[1:], true)
+				case tag == "i1", tag == "i0", tag == "i":
+					next.italic = tag == "i1"
+				case tag == "u1", tag == "u0", tag == "u":
+					next.underline = tag == "u1"
+				case tag == "b0", tag == "b":
+					next.bold = false
+				case strings.HasPrefix(tag, "b") && len(tag) > 1 && tag[1] >= '1' && tag[1] <= '9':
+					next.bold = true
+				case strings.HasPrefix(tag, "p") && len(tag) > 1 && tag[1] >= '0' && tag[1] <= '9':
+					drawing = tag != "p0"
+				case strings.HasPrefix(tag, "r"):
+					next = style
+				}
+			}
+			writeStyleChange(&b, &state, next)
+			text = text[end+1:]
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, `\N`), strings.HasPrefix(text, `\n`):
+			if !drawing {
+				b.WriteByte('\n')
+			}
+			text = text[2:]
+		case strings.HasPrefix(text, `\h`):
+			if !drawing {
+				b.WriteByte(' ')
+			}
+			text = text[2:]
+		default:
+			if !drawing {
+				b.WriteByte(text[0])
+			}
+			text = text[1:]
+		}
+	}
+	writeStyleChange(&b, &state, assStyle{})
+
+	return normalizeMarkup(b.String(), keepText)
+}
+
+func encodeASS(d *Document) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("[Script Info]\n")
+	b.WriteString("ScriptType: v4.00+\n")
+	b.WriteString("PlayResX: 1920\n")
+	b.WriteString("PlayResY: 1080\n")
+	b.WriteString("WrapStyle: 0\n\n")
+
+	b.WriteString("[V4+ Styles]\n")
+	b.WriteString("Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	b.WriteString("Style: Default,Arial,64,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,3,1,2,60,60,50,1\n\n")
+
+	b.WriteString("[Events]\n")
+	b.WriteString("Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+
+	for _, cue := range d.Cues {
+		var text strings.Builder
+		if cue.Top {
+			text.WriteString(`{\an8}`)
+		}
+		for _, span := range splitMarkup(strings.ReplaceAll(cleanLines(cue.Text), "\n", `\N`)) {
+			var tags string
+			if span.italic {
+				tags += `\i1`
+			}
+			if span.bold {
+				tags += `\b1`
+			}
+			if span.underline {
+				tags += `\u1`
+			}
+			if tags != "" {
+				text.WriteString("{" + tags + "}" + span.text + "{\\r}")
+			} else {
+				text.WriteString(span.text)
+			}
+		}
+
+		fmt.Fprintf(&b, "Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n", assClock(cue.Start), assClock(cue.End), text.String())
+	}
+
+	return b.Bytes()
+}
+
+// assClock writes H:MM:SS.cc
+func assClock(d time.Duration) string {
+	return strings.TrimPrefix(formatClock(d, ".", 2), "0")
+}
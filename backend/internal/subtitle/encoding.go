@@ -0,0 +1,63 @@
+// backend/internal/subtitle/encoding.go
+package subtitle
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Decode converts a subtitle file to UTF-8. charset names the encoding of
+// files in a legacy code page, such as windows-1251 or iso-8859-2. Without
+// one, UTF-8 and UTF-16 are recognised by their byte order mark or content
+// and anything else is read as windows-1252, the most common legacy choice.
+func Decode(data []byte, charset string) (string, error) {
+	var decoder encoding.Encoding
+
+	switch {
+	case charset != "":
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return "", ErrCharset
+		}
+		decoder = enc
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), looksUTF16(data, 1):
+		decoder = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}), looksUTF16(data, 0):
+		decoder = unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	case utf8.Valid(data):
+		return string(data), nil
+	default:
+		decoder = charmap.Windows1252
+	}
+
+	out, err := decoder.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimPrefix(out, []byte("\ufeff"))), nil
+}
+
+// looksUTF16 reports whether most of the leading characters have a zero
+// byte at offset zero (big endian) or one (little endian), as ASCII text
+// encoded as UTF-16 without a byte order mark does
+func looksUTF16(data []byte, zero int) bool {
+	n := min(len(data)/2, 64)
+	if n < 4 {
+		return false
+	}
+
+	zeros := 0
+	for i := 0; i < n; i++ {
+		if data[2*i+zero] == 0 && data[2*i+1-zero] != 0 {
+			zeros++
+		}
+	}
+	return zeros*4 >= n*3
+}
@@ -0,0 +1,238 @@
+// backend/internal/subtitle/markup.go
+package subtitle
+
+import (
+	"html"
+	"strings"
+)
+
+// Tags kept in cue text; everything else is dropped
+var styleTags = map[string]bool{"i": true, "b": true, "u": true}
+
+// Tags whose content is dropped along with them
+var hiddenTags = map[string]bool{"rt": true, "rp": true}
+
+// normalizeMarkup reduces HTML-like cue markup to balanced <i>, <b> and <u>
+// tags. Text between tags is passed through unescape, so WebVTT entities
+// can be decoded while SRT text is kept as written.
+func normalizeMarkup(text string, unescape func(string) string) string {
+	var b strings.Builder
+	var open []string
+	hidden := 0
+
+	for text != "" {
+		lt := strings.IndexByte(text, '<')
+		gt := -1
+		if lt >= 0 {
+			gt = strings.IndexByte(text[lt:], '>')
+		}
+		if lt < 0 || gt < 0 {
+			if hidden == 0 {
+				b.WriteString(unescape(text))
+			}
+			break
+		}
+
+		if hidden == 0 {
+			b.WriteString(unescape(text[:lt]))
+		}
+		tag := text[lt+1 : lt+gt]
+		text = text[lt+gt+1:]
+
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimPrefix(tag, "/"))
+		if i := strings.IndexAny(name, " .\t"); i >= 0 {
+			name = name[:i]
+		}
+
+		switch {
+		case hiddenTags[name]:
+			if closing {
+				hidden = max(hidden-1, 0)
+			} else {
+				hidden++
+			}
+		case !styleTags[name] || hidden > 0:
+		case !closing:
+			if !contains(open, name) {
+				open = append(open, name)
+				b.WriteString("<" + name + ">")
+			}
+		default:
+			if i := indexOf(open, name); i >= 0 {
+				// Close anything opened inside it so the nesting stays valid
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				reopen := append([]string(nil), open[i+1:]...)
+				open = open[:i]
+				for _, tag := range reopen {
+					open = append(open, tag)
+					b.WriteString("<" + tag + ">")
+				}
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+
+	return cleanLines(b.String())
+}
+
+// cleanLines trims each line and drops empty ones, which would end a cue
+// early in SRT and WebVTT
+func cleanLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line != "<i></i>" && line != "<b></b>" && line != "<u></u>" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeStyleChange writes the tags that take text from one style to another
+func writeStyleChange(b *strings.Builder, from *assStyle, to assStyle) {
+	for _, tag := range []struct {
+		name string
+		on   bool
+		set  bool
+	}{
+		{"u", from.underline, to.underline},
+		{"b", from.bold, to.bold},
+		{"i", from.italic, to.italic},
+	} {
+		if tag.on && !tag.set {
+			b.WriteString("</" + tag.name + ">")
+		}
+	}
+	for _, tag := range []struct {
+		name string
+		on   bool
+		set  bool
+	}{
+		{"i", from.italic, to.italic},
+		{"b", from.bold, to.bold},
+		{"u", from.underline, to.underline},
+	} {
+		if !tag.on && tag.set {
+			b.WriteString("<" + tag.name + ">")
+		}
+	}
+	*from = to
+}
+
+// markupSpan is a run of cue text with the styles that apply to it
+type markupSpan struct {
+	text                    string
+	italic, bold, underline bool
+}
+
+// splitMarkup breaks normalized cue text into styled runs, for formats that
+// do not use HTML-like tags
+func splitMarkup(text string) []markupSpan {
+	var spans []markupSpan
+	var current markupSpan
+
+	for text != "" {
+		tag, length := leadingTag(text)
+		if length == 0 {
+			next := strings.IndexByte(text[1:], '<')
+			if next < 0 {
+				current.text += text
+				break
+			}
+			current.text += text[:next+1]
+			text = text[next+1:]
+			continue
+		}
+
+		if current.text != "" {
+			spans = append(spans, current)
+			current.text = ""
+		}
+		on := !strings.HasPrefix(tag, "/")
+		switch strings.TrimPrefix(tag, "/") {
+		case "i":
+			current.italic = on
+		case "b":
+			current.bold = on
+		case "u":
+			current.underline = on
+		}
+		text = text[length:]
+	}
+
+	if current.text != "" {
+		spans = append(spans, current)
+	}
+	return spans
+}
+
+// leadingTag returns the style tag text starts with, if any, and its length
+func leadingTag(text string) (string, int) {
+	for _, tag := range []string{"i", "b", "u", "/i", "/b", "/u"} {
+		if strings.HasPrefix(text, "<"+tag+">") {
+			return tag, len(tag) + 2
+		}
+	}
+	return "", 0
+}
+
+// escapeMarkup escapes cue text for WebVTT, leaving its style tags intact
+func escapeMarkup(text string) string {
+	var b strings.Builder
+	for text != "" {
+		if _, length := leadingTag(text); length > 0 {
+			b.WriteString(text[:length])
+			text = text[length:]
+			continue
+		}
+
+		switch text[0] {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		default:
+			b.WriteByte(text[0])
+		}
+		text = text[1:]
+	}
+	return b.String()
+}
+
+// stripMarkup returns cue text without its style tags
+func stripMarkup(text string) string {
+	var b strings.Builder
+	for _, span := range splitMarkup(text) {
+		b.WriteString(span.text)
+	}
+	return b.String()
+}
+
+func keepText(s string) string {
+	return s
+}
+
+func unescapeEntities(s string) string {
+	return strings.ReplaceAll(html.UnescapeString(s), "\u00a0", " ")
+}
+
+func contains(list []string, value string) bool {
+	return indexOf(list, value) >= 0
+}
+
+func indexOf(list []string, value string) int {
+	for i, item := range list {
+		if item == value {
+			return i
+		}
+	}
+	return -1
+}
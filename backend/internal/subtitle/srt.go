@@ -0,0 +1,103 @@
+// backend/internal/subtitle/srt.go
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ASS override blocks such as {\an8} that many SRT files carry
+var srtOverride = regexp.MustCompile(`\{\\[^}]*\}`)
+
+var srtTopAlignment = regexp.MustCompile(`\{\\(an[789]|a[567])\}`)
+
+// parseSRT reads SubRip. Numbering is ignored, and a text block without a
+// timing line is taken to be a stray blank line inside the previous cue.
+func parseSRT(text string) (*Document, error) {
+	doc := &Document{}
+
+	for _, block := range splitBlocks(text) {
+		arrow := -1
+		for i, line := range block.lines {
+			if strings.Contains(line, "-->") {
+				arrow = i
+				break
+			}
+		}
+
+		if arrow < 0 {
+			if n := len(doc.Cues); n > 0 {
+				doc.Cues[n-1].Text = strings.TrimSpace(doc.Cues[n-1].Text + "\n" + srtText(block.lines, &doc.Cues[n-1]))
+			}
+			continue
+		}
+
+		start, end, _, err := parseArrow(block.lines[arrow])
+		if err != nil {
+			return nil, fmt.Errorf("srt line %d: %v", block.start+arrow, err)
+		}
+
+		cue := Cue{Start: start, End: end}
+		cue.Text = srtText(block.lines[arrow+1:], &cue)
+		doc.Cues = append(doc.Cues, cue)
+	}
+
+	return doc, nil
+}
+
+func srtText(lines []string, cue *Cue) string {
+	text := strings.Join(lines, "\n")
+	if srtTopAlignment.MatchString(text) {
+		cue.Top = true
+	}
+	return normalizeMarkup(srtOverride.ReplaceAllString(text, ""), keepText)
+}
+
+func encodeSRT(d *Document) []byte {
+	var b bytes.Buffer
+
+	for i, cue := range d.Cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", i+1, formatClock(cue.Start, ",", 3), formatClock(cue.End, ",", 3))
+		if cue.Top {
+			b.WriteString(`{\an8}`)
+		}
+		b.WriteString(cleanLines(cue.Text))
+		b.WriteString("\n\n")
+	}
+
+	return b.Bytes()
+}
+
+// textBlock is a run of non-blank lines and the line number it starts on
+type textBlock struct {
+	start int
+	lines []string
+}
+
+func splitBlocks(text string) []textBlock {
+	var blocks []textBlock
+	var current textBlock
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			if len(current.lines) > 0 {
+				blocks = append(blocks, current)
+			}
+			current = textBlock{}
+			continue
+		}
+
+		if len(current.lines) == 0 {
+			current.start = i + 1
+		}
+		current.lines = append(current.lines, line)
+	}
+
+	if len(current.lines) > 0 {
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
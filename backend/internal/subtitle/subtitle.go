@@ -0,0 +1,287 @@
+// backend/internal/subtitle/subtitle.go
+
+// Package subtitle reads and writes SRT, WebVTT, ASS/SSA and TTML/DFXP
+// subtitles through one cue model, so files can be validated, retimed and
+// converted between formats.
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatSRT  Format = "srt"
+	FormatVTT  Format = "vtt"
+	FormatASS  Format = "ass"
+	FormatTTML Format = "ttml"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unrecognised subtitle format")
+	ErrNoCues        = errors.New("subtitle file has no cues")
+	ErrCharset       = errors.New("unknown character encoding")
+	ErrFrameRate     = errors.New("frame rates must be positive")
+)
+
+// Cue is one timed caption. Text holds its lines separated by \n, with
+// italic, bold and underline marked up as <i>, <b> and <u>; every other
+// character is literal.
+type Cue struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Text  string        `json:"text"`
+	// Top places the cue at the top of the frame, usually so it does not
+	// cover on-screen text or a second speaker at the bottom
+	Top bool `json:"top,omitempty"`
+}
+
+// Document is a parsed subtitle file
+type Document struct {
+	Language string `json:"language,omitempty"` // Only TTML declares one
+	Cues     []Cue  `json:"cues"`
+}
+
+// ParseFormat accepts a format name or file extension such as "webvtt",
+// ".ssa" or "dfxp"
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), ".")) {
+	case "srt", "subrip":
+		return FormatSRT, nil
+	case "vtt", "webvtt":
+		return FormatVTT, nil
+	case "ass", "ssa":
+		return FormatASS, nil
+	case "ttml", "dfxp", "xml":
+		return FormatTTML, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Detect recognises a format from the start of a decoded file
+func Detect(text string) (Format, error) {
+	text = strings.TrimSpace(strings.TrimPrefix(text, "\ufeff"))
+
+	switch {
+	case strings.HasPrefix(text, "WEBVTT"):
+		return FormatVTT, nil
+	case strings.HasPrefix(text, "[Script Info]") || strings.Contains(text, "\n[Events]"):
+		return FormatASS, nil
+	case strings.HasPrefix(text, "<"):
+		if strings.Contains(text, "<tt") {
+			return FormatTTML, nil
+		}
+	case strings.Contains(text, "-->"):
+		return FormatSRT, nil
+	}
+
+	return "", ErrUnknownFormat
+}
+
+// Parse reads a decoded subtitle file, detecting its format when format is
+// empty. Cues come back sorted by start time.
+func Parse(text string, format Format) (*Document, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	if format == "" {
+		var err error
+		if format, err = Detect(text); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc *Document
+	var err error
+	switch format {
+	case FormatSRT:
+		doc, err = parseSRT(text)
+	case FormatVTT:
+		doc, err = parseVTT(text)
+	case FormatASS:
+		doc, err = parseASS(text)
+	case FormatTTML:
+		doc, err = parseTTML(text)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(doc.Cues) == 0 {
+		return nil, ErrNoCues
+	}
+
+	doc.Sort()
+	return doc, nil
+}
+
+// Encode writes the document in format
+func (d *Document) Encode(format Format) ([]byte, error) {
+	switch format {
+	case FormatSRT:
+		return encodeSRT(d), nil
+	case FormatVTT:
+		return encodeVTT(d), nil
+	case FormatASS:
+		return encodeASS(d), nil
+	case FormatTTML:
+		return encodeTTML(d)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType returns the MIME type files of a format are served with
+func ContentType(format Format) string {
+	switch format {
+	case FormatSRT:
+		return "application/x-subrip"
+	case FormatVTT:
+		return "text/vtt; charset=utf-8"
+	case FormatASS:
+		return "text/x-ssa; charset=utf-8"
+	case FormatTTML:
+		return "application/ttml+xml"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func (d *Document) Sort() {
+	sort.SliceStable(d.Cues, func(i, j int) bool {
+		return d.Cues[i].Start < d.Cues[j].Start
+	})
+}
+
+// Shift moves every cue by offset. Cues pushed entirely before zero are
+// dropped and those straddling it start at zero.
+func (d *Document) Shift(offset time.Duration) {
+	cues := d.Cues[:0]
+	for _, cue := range d.Cues {
+		cue.Start += offset
+		cue.End += offset
+		if cue.End <= 0 {
+			continue
+		}
+		cue.Start = max(cue.Start, 0)
+		cues = append(cues, cue)
+	}
+	d.Cues = cues
+}
+
+// ConvertFrameRate retimes cues made for a video at one frame rate to the
+// same video sped up or slowed down to another, such as 23.976 fps film
+// released at 25 fps for PAL
+func (d *Document) ConvertFrameRate(from, to float64) error {
+	if from <= 0 || to <= 0 {
+		return ErrFrameRate
+	}
+
+	ratio := from / to
+	for i := range d.Cues {
+		d.Cues[i].Start = time.Duration(float64(d.Cues[i].Start) * ratio)
+		d.Cues[i].End = time.Duration(float64(d.Cues[i].End) * ratio)
+	}
+	return nil
+}
+
+// parseClock reads [[H:]M:]S[.,fraction] timestamps. The fraction may have
+// any number of digits, so ASS centiseconds and SRT milliseconds both work.
+func parseClock(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	clock, fraction := value, ""
+	if i := strings.LastIndexAny(value, ".,"); i >= 0 {
+		clock, fraction = value[:i], value[i+1:]
+	}
+
+	parts := strings.Split(clock, ":")
+	if len(parts) > 3 || clock == "" {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var total time.Duration
+	for i, part := range parts {
+		n, ok := parseDigits(part)
+		if !ok || (i > 0 && n > 59) {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+		total = total*60 + time.Duration(n)*time.Second
+	}
+
+	if fraction != "" {
+		n, ok := parseDigits(fraction)
+		if !ok {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+		scale := time.Second
+		for range len(fraction) {
+			scale /= 10
+		}
+		total += time.Duration(n) * scale
+	}
+
+	return total, nil
+}
+
+func parseDigits(s string) (int, bool) {
+	if s == "" || len(s) > 9 {
+		return 0, false
+	}
+
+	n := 0
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+		n = n*10 + int(r-'0')
+	}
+	return n, true
+}
+
+// formatClock writes HH:MM:SS followed by sep and the fraction in the given
+// number of digits
+func formatClock(d time.Duration, sep string, digits int) string {
+	d = max(d, 0)
+
+	unit := time.Second
+	for range digits {
+		unit /= 10
+	}
+	units := int64((d + unit/2) / unit)
+	perSecond := int64(time.Second / unit)
+
+	seconds := units / perSecond
+	return fmt.Sprintf("%02d:%02d:%02d%s%0*d", seconds/3600, seconds/60%60, seconds%60, sep, digits, units%perSecond)
+}
+
+// parseArrow splits a cue timing line such as "00:01.000 --> 00:02.000 line:0"
+// into its start, end and trailing settings
+func parseArrow(line string) (time.Duration, time.Duration, string, error) {
+	left, right, ok := strings.Cut(line, "-->")
+	if !ok {
+		return 0, 0, "", fmt.Errorf("missing --> in %q", line)
+	}
+
+	start, err := parseClock(left)
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	fields := strings.Fields(right)
+	if len(fields) == 0 {
+		return 0, 0, "", fmt.Errorf("missing end time in %q", line)
+	}
+
+	end, err := parseClock(fields[0])
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	return start, end, strings.Join(fields[1:], " "), nil
+}
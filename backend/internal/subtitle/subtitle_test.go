@@ -0,0 +1,346 @@
+package subtitle
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"00:00:01,000", ms(1000), false},
+		{"01:02:03.456", time.Hour + 2*time.Minute + 3*time.Second + ms(456), false},
+		{"02:03.5", 2*time.Minute + 3*time.Second + ms(500), false},
+		{"0:00:01.25", ms(1250), false},
+		{"12", 12 * time.Second, false},
+		{" 00:00:02.000 ", ms(2000), false},
+		{"00:60:00.000", 0, true},
+		{"1:2:3:4", 0, true},
+		{"aa:00.000", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseClock(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClock(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseClock(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatClock(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		sep    string
+		digits int
+		want   string
+	}{
+		{ms(1500), ",", 3, "00:00:01,500"},
+		{time.Hour + ms(61005), ".", 3, "01:01:01.005"},
+		{ms(1234), ".", 2, "00:00:01.23"},
+		{ms(1995), ".", 2, "00:00:02.00"},
+		{-time.Second, ".", 3, "00:00:00.000"},
+	}
+
+	for _, tt := range tests {
+		if got := formatClock(tt.d, tt.sep, tt.digits); got != tt.want {
+			t.Errorf("formatClock(%s, %q, %d) = %s, want %s", tt.d, tt.sep, tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		format Format
+		want   []Cue
+	}{
+		{
+			name: "srt",
+			text: "1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\n<i>world</i>\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\n{\\an8}Top line\r\n",
+			want: []Cue{
+				{Start: ms(1000), End: ms(2500), Text: "Hello\n<i>world</i>"},
+				{Start: ms(3000), End: ms(4000), Text: "Top line", Top: true},
+			},
+		},
+		{
+			name: "vtt",
+			text: "\ufeffWEBVTT\n\nNOTE a comment\n\nintro\n00:01.000 --> 00:02.000 line:0\n<b>Bold</b> &amp; plain\n\n00:00:03.000 --> 00:00:04.000\n<v Alice>Spoken\n",
+			want: []Cue{
+				{Start: ms(1000), End: ms(2000), Text: "<b>Bold</b> & plain", Top: true},
+				{Start: ms(3000), End: ms(4000), Text: "Spoken"},
+			},
+		},
+		{
+			name: "ass",
+			text: "[Script Info]\nScriptType: v4.00+\n\n[V4+ Styles]\nFormat: Name, Fontname, Fontsize, Bold, Italic, Underline, Alignment\nStyle: Default,Arial,20,0,0,0,2\nStyle: Sign,Arial,20,0,1,0,8\n\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.00,0:00:02.50,Default,,0,0,0,,First\\Nline, with comma\nDialogue: 0,0:00:03.00,0:00:04.00,Sign,,0,0,0,,Sign text\n",
+			want: []Cue{
+				{Start: ms(1000), End: ms(2500), Text: "First\nline, with comma"},
+				{Start: ms(3000), End: ms(4000), Text: "<i>Sign text</i>", Top: true},
+			},
+		},
+		{
+			name: "ttml",
+			text: `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling" xml:lang="fr">
+  <body><div>
+    <p begin="00:00:01.000" end="00:00:02.500">Bonjour<br/><span tts:fontStyle="italic">monde</span></p>
+    <p begin="3s" dur="1s">Deux</p>
+  </div></body>
+</tt>`,
+			want: []Cue{
+				{Start: ms(1000), End: ms(2500), Text: "Bonjour\n<i>monde</i>"},
+				{Start: ms(3000), End: ms(4000), Text: "Deux"},
+			},
+		},
+		{
+			name:   "explicit format sorts cues",
+			text:   "2\n00:00:05,000 --> 00:00:06,000\nLater\n\n1\n00:00:01,000 --> 00:00:02,000\nEarlier\n",
+			format: FormatSRT,
+			want: []Cue{
+				{Start: ms(1000), End: ms(2000), Text: "Earlier"},
+				{Start: ms(5000), End: ms(6000), Text: "Later"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(tt.text, tt.format)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(doc.Cues, tt.want) {
+				t.Errorf("Parse cues =\n%+v\nwant\n%+v", doc.Cues, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want error
+	}{
+		{"unknown", "just some text", ErrUnknownFormat},
+		{"vtt without cues", "WEBVTT\n\nNOTE nothing here\n", ErrNoCues},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.text, ""); !errors.Is(err, tt.want) {
+				t.Errorf("Parse error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	source := &Document{Cues: []Cue{
+		{Start: ms(1000), End: ms(2500), Text: "Plain & 1 < 2"},
+		{Start: ms(3000), End: ms(4200), Text: "<i>Italic</i> then <b>bold</b>\nSecond line"},
+		{Start: ms(3600500), End: ms(3602000), Text: "An hour in", Top: true},
+	}}
+
+	for _, format := range []Format{FormatSRT, FormatVTT, FormatASS, FormatTTML} {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := source.Encode(format)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			if detected, err := Detect(string(data)); err != nil || detected != format {
+				t.Fatalf("Detect = %q, %v, want %q", detected, err, format)
+			}
+
+			doc, err := Parse(string(data), format)
+			if err != nil {
+				t.Fatalf("Parse of encoded %s: %v\n%s", format, err, data)
+			}
+			if !reflect.DeepEqual(doc.Cues, source.Cues) {
+				t.Errorf("round trip through %s =\n%+v\nwant\n%+v\n%s", format, doc.Cues, source.Cues, data)
+			}
+		})
+	}
+}
+
+func TestShift(t *testing.T) {
+	cues := func() []Cue {
+		return []Cue{
+			{Start: ms(500), End: ms(1500), Text: "a"},
+			{Start: ms(2000), End: ms(3000), Text: "b"},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   []Cue
+	}{
+		{"later", time.Second, []Cue{
+			{Start: ms(1500), End: ms(2500), Text: "a"},
+			{Start: ms(3000), End: ms(4000), Text: "b"},
+		}},
+		{"earlier straddles zero", -ms(1000), []Cue{
+			{Start: 0, End: ms(500), Text: "a"},
+			{Start: ms(1000), End: ms(2000), Text: "b"},
+		}},
+		{"earlier drops cues", -ms(1500), []Cue{
+			{Start: ms(500), End: ms(1500), Text: "b"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Cues: cues()}
+			doc.Shift(tt.offset)
+			if !reflect.DeepEqual(doc.Cues, tt.want) {
+				t.Errorf("Shift(%s) = %+v, want %+v", tt.offset, doc.Cues, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertFrameRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to float64
+		start    time.Duration
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"same rate", 25, 25, ms(10000), ms(10000), false},
+		{"pal speed-up", 24, 25, ms(25000), ms(24000), false},
+		{"slow down", 25, 24, ms(24000), ms(25000), false},
+		{"zero rate", 0, 25, ms(1000), ms(1000), true},
+		{"negative rate", 25, -1, ms(1000), ms(1000), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Cues: []Cue{{Start: tt.start, End: tt.start + time.Second}}}
+			err := doc.ConvertFrameRate(tt.from, tt.to)
+			if tt.wantErr {
+				if !errors.Is(err, ErrFrameRate) {
+					t.Fatalf("ConvertFrameRate error = %v, want ErrFrameRate", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConvertFrameRate: %v", err)
+			}
+			if doc.Cues[0].Start != tt.want {
+				t.Errorf("start = %s, want %s", doc.Cues[0].Start, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cues     []Cue
+		want     []IssueKind
+		blocking bool
+	}{
+		{"clean", []Cue{{Start: 0, End: ms(2000), Text: "ok"}}, nil, false},
+		{"reversed timing", []Cue{{Start: ms(2000), End: ms(1000), Text: "x"}}, []IssueKind{IssueTiming}, true},
+		{"empty text", []Cue{{Start: 0, End: ms(2000), Text: "<i> </i>"}}, []IssueKind{IssueEmpty}, true},
+		{"flash", []Cue{{Start: 0, End: ms(100), Text: "x"}}, []IssueKind{IssueDuration}, false},
+		{"lingering", []Cue{{Start: 0, End: 30 * time.Second, Text: "x"}}, []IssueKind{IssueDuration}, false},
+		{"mojibake", []Cue{{Start: 0, End: ms(2000), Text: "cafÃ©"}}, []IssueKind{IssueEncoding}, false},
+		{"overlap", []Cue{
+			{Start: 0, End: ms(2000), Text: "a"},
+			{Start: ms(1500), End: ms(3000), Text: "b"},
+		}, []IssueKind{IssueOverlap}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := (&Document{Cues: tt.cues}).Validate()
+
+			var kinds []IssueKind
+			for _, issue := range issues {
+				kinds = append(kinds, issue.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.want) {
+				t.Errorf("Validate kinds = %v, want %v (%+v)", kinds, tt.want, issues)
+			}
+			if Blocking(issues) != tt.blocking {
+				t.Errorf("Blocking = %v, want %v", !tt.blocking, tt.blocking)
+			}
+		})
+	}
+}
+
+func TestResolveOverlaps(t *testing.T) {
+	doc := &Document{Cues: []Cue{
+		{Start: ms(3000), End: ms(5000), Text: "c"},
+		{Start: 0, End: ms(2000), Text: "a"},
+		{Start: 0, End: ms(2500), Text: "b"},
+		{Start: ms(4000), End: ms(6000), Text: "d"},
+	}}
+	doc.ResolveOverlaps()
+
+	want := []Cue{
+		{Start: 0, End: ms(2500), Text: "a\nb"},
+		{Start: ms(3000), End: ms(4000), Text: "c"},
+		{Start: ms(4000), End: ms(6000), Text: "d"},
+	}
+	if !reflect.DeepEqual(doc.Cues, want) {
+		t.Errorf("ResolveOverlaps = %+v, want %+v", doc.Cues, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	utf16le := []byte{0xFF, 0xFE}
+	utf16noBOM := []byte{}
+	for _, r := range "WEBVTT\n" {
+		utf16le = append(utf16le, byte(r), 0)
+		utf16noBOM = append(utf16noBOM, byte(r), 0)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		charset string
+		want    string
+		wantErr error
+	}{
+		{"utf-8 bom", []byte("\xEF\xBB\xBFcafé"), "", "café", nil},
+		{"plain utf-8", []byte("café"), "", "café", nil},
+		{"utf-16le bom", utf16le, "", "WEBVTT\n", nil},
+		{"utf-16le without bom", utf16noBOM, "", "WEBVTT\n", nil},
+		{"windows-1252 fallback", []byte("caf\xE9"), "", "café", nil},
+		{"named charset", []byte("\xE4\xE0"), "windows-1251", "да", nil},
+		{"unknown charset", []byte("x"), "klingon", "", ErrCharset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.data, tt.charset)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
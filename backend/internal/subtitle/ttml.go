@@ -0,0 +1,369 @@
+// backend/internal/subtitle/ttml.go
+package subtitle
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ttmlNamespace        = "http://www.w3.org/ns/ttml"
+	ttmlStylingNamespace = "http://www.w3.org/ns/ttml#styling"
+)
+
+// ttmlTiming holds the tt element parameters time expressions depend on
+type ttmlTiming struct {
+	frameRate float64
+	tickRate  float64
+}
+
+// ttmlElement is an open body, div, p or span with its resolved begin time
+// and the styles it passes to its children
+type ttmlElement struct {
+	name  string
+	begin time.Duration
+	style assStyle
+}
+
+// parseTTML reads TTML and DFXP. Time containment is resolved through
+// nested body, div, p and span elements; styles and regions referenced by ID
+// contribute italics, bold, underline and top placement.
+func parseTTML(text string) (*Document, error) {
+	doc := &Document{}
+	timing := ttmlTiming{frameRate: 30, tickRate: 1}
+	styles := make(map[string]assStyle)
+	regions := make(map[string]bool)
+
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	decoder.Strict = false
+	// Declared encodings were already dealt with by Decode
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var stack []ttmlElement
+	var cue *Cue
+	var cueText strings.Builder
+	var cueStyle assStyle
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ttml: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			attrs := ttmlAttributes(t)
+			parent := ttmlElement{}
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+
+			switch t.Name.Local {
+			case "tt":
+				doc.Language = attrs["lang"]
+				timing = ttmlTimingOf(attrs)
+				stack = append(stack, ttmlElement{name: "tt"})
+				continue
+			case "style":
+				if id := attrs["id"]; id != "" {
+					styles[id] = ttmlStyle(attrs, styles[attrs["style"]])
+				}
+			case "region":
+				if id := attrs["id"]; id != "" {
+					regions[id] = ttmlTopRegion(attrs)
+				}
+			case "br":
+				if cue != nil {
+					cueText.WriteString("\n")
+				}
+			}
+
+			if t.Name.Local != "body" && t.Name.Local != "div" && t.Name.Local != "p" && t.Name.Local != "span" {
+				continue
+			}
+
+			element := ttmlElement{name: t.Name.Local, begin: parent.begin, style: parent.style}
+			for _, id := range strings.Fields(attrs["style"]) {
+				element.style = mergeStyle(element.style, styles[id])
+			}
+			element.style = ttmlStyle(attrs, element.style)
+
+			begin, err := timing.parse(attrs["begin"])
+			if err != nil {
+				return nil, err
+			}
+			element.begin += begin
+
+			switch t.Name.Local {
+			case "p":
+				cue = &Cue{Start: element.begin, Top: regions[attrs["region"]] || element.style.top}
+				end, err := timing.parse(attrs["end"])
+				if err != nil {
+					return nil, err
+				}
+				if attrs["end"] != "" {
+					cue.End = parent.begin + end
+				} else {
+					dur, err := timing.parse(attrs["dur"])
+					if err != nil {
+						return nil, err
+					}
+					cue.End = cue.Start + dur
+				}
+				cueText.Reset()
+				cueStyle = assStyle{}
+				writeStyleChange(&cueText, &cueStyle, element.style)
+			case "span":
+				if cue != nil {
+					writeStyleChange(&cueText, &cueStyle, element.style)
+				}
+			}
+			stack = append(stack, element)
+
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].name != t.Name.Local {
+				continue
+			}
+			stack = stack[:len(stack)-1]
+
+			switch t.Name.Local {
+			case "span":
+				if cue != nil && len(stack) > 0 {
+					writeStyleChange(&cueText, &cueStyle, stack[len(stack)-1].style)
+				}
+			case "p":
+				if cue != nil {
+					writeStyleChange(&cueText, &cueStyle, assStyle{})
+					// The tags were written balanced, so only the lines need tidying
+					cue.Text = cleanLines(cueText.String())
+					if cue.Text != "" && cue.End > cue.Start {
+						doc.Cues = append(doc.Cues, *cue)
+					}
+					cue = nil
+				}
+			}
+
+		case xml.CharData:
+			if cue != nil {
+				// Default whitespace handling collapses runs, including newlines
+				text := strings.Join(strings.Fields(string(t)), " ")
+				if text != "" && len(t) > 0 && isSpace(t[0]) {
+					text = " " + text
+				}
+				if text != "" && isSpace(t[len(t)-1]) {
+					text += " "
+				}
+				cueText.WriteString(text)
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
+
+// ttmlAttributes indexes attributes by local name, so tts:, ttp: and xml:
+// prefixes need not be resolved
+func ttmlAttributes(element xml.StartElement) map[string]string {
+	attrs := make(map[string]string, len(element.Attr))
+	for _, attr := range element.Attr {
+		attrs[attr.Name.Local] = strings.TrimSpace(attr.Value)
+	}
+	return attrs
+}
+
+func ttmlTimingOf(attrs map[string]string) ttmlTiming {
+	timing := ttmlTiming{frameRate: 30, tickRate: 1}
+
+	if rate, err := strconv.ParseFloat(attrs["frameRate"], 64); err == nil && rate > 0 {
+		timing.frameRate = rate
+		timing.tickRate = rate
+	}
+	if multiplier := strings.Fields(attrs["frameRateMultiplier"]); len(multiplier) == 2 {
+		numerator, err1 := strconv.ParseFloat(multiplier[0], 64)
+		denominator, err2 := strconv.ParseFloat(multiplier[1], 64)
+		if err1 == nil && err2 == nil && numerator > 0 && denominator > 0 {
+			timing.frameRate *= numerator / denominator
+		}
+	}
+	if rate, err := strconv.ParseFloat(attrs["tickRate"], 64); err == nil && rate > 0 {
+		timing.tickRate = rate
+	}
+
+	return timing
+}
+
+// parse reads a clock time (HH:MM:SS.fff or HH:MM:SS:FF) or an offset time
+// such as 1.5s, 200ms, 30f or 10000t
+func (t ttmlTiming) parse(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if strings.Contains(value, ":") {
+		parts := strings.Split(value, ":")
+		if len(parts) == 4 {
+			clock, err := parseClock(strings.Join(parts[:3], ":"))
+			if err != nil {
+				return 0, fmt.Errorf("ttml: %v", err)
+			}
+			frames, err := strconv.ParseFloat(parts[3], 64)
+			if err != nil {
+				return 0, fmt.Errorf("ttml: invalid time %q", value)
+			}
+			return clock + seconds(frames/t.frameRate), nil
+		}
+
+		clock, err := parseClock(value)
+		if err != nil {
+			return 0, fmt.Errorf("ttml: %v", err)
+		}
+		return clock, nil
+	}
+
+	units := []struct {
+		suffix string
+		scale  float64
+	}{
+		{"ms", 0.001},
+		{"h", 3600},
+		{"m", 60},
+		{"s", 1},
+		{"f", 1 / t.frameRate},
+		{"t", 1 / t.tickRate},
+	}
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("ttml: invalid time %q", value)
+			}
+			return seconds(n * unit.scale), nil
+		}
+	}
+
+	return 0, fmt.Errorf("ttml: invalid time %q", value)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func ttmlStyle(attrs map[string]string, style assStyle) assStyle {
+	if value, ok := attrs["fontStyle"]; ok {
+		style.italic = value == "italic" || value == "oblique"
+	}
+	if value, ok := attrs["fontWeight"]; ok {
+		style.bold = value == "bold"
+	}
+	if value, ok := attrs["textDecoration"]; ok {
+		style.underline = strings.Contains(value, "underline") && !strings.Contains(value, "noUnderline")
+	}
+	if _, ok := attrs["displayAlign"]; ok {
+		style.top = ttmlTopRegion(attrs)
+	}
+	return style
+}
+
+func mergeStyle(base, over assStyle) assStyle {
+	return assStyle{
+		top:       base.top || over.top,
+		italic:    base.italic || over.italic,
+		bold:      base.bold || over.bold,
+		underline: base.underline || over.underline,
+	}
+}
+
+// ttmlTopRegion reports whether a region shows text in the top half of the
+// frame, from its displayAlign and the vertical part of its origin
+func ttmlTopRegion(attrs map[string]string) bool {
+	var y float64
+	hasY := false
+	if origin := strings.Fields(attrs["origin"]); len(origin) == 2 {
+		if percent, ok := strings.CutSuffix(origin[1], "%"); ok {
+			value, err := strconv.ParseFloat(percent, 64)
+			y, hasY = value, err == nil
+		}
+	}
+
+	switch attrs["displayAlign"] {
+	case "before":
+		return !hasY || y < 50
+	case "":
+		return hasY && y < 40
+	}
+	return false
+}
+
+func encodeTTML(d *Document) ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, "<tt xmlns=\"%s\" xmlns:tts=\"%s\"", ttmlNamespace, ttmlStylingNamespace)
+	if d.Language != "" {
+		b.WriteString(` xml:lang="`)
+		if err := xml.EscapeText(&b, []byte(d.Language)); err != nil {
+			return nil, err
+		}
+		b.WriteString(`"`)
+	}
+	b.WriteString(">\n")
+	b.WriteString("  <head>\n    <layout>\n")
+	b.WriteString("      <region xml:id=\"bottom\" tts:origin=\"10% 10%\" tts:extent=\"80% 80%\" tts:displayAlign=\"after\" tts:textAlign=\"center\"/>\n")
+	b.WriteString("      <region xml:id=\"top\" tts:origin=\"10% 10%\" tts:extent=\"80% 80%\" tts:displayAlign=\"before\" tts:textAlign=\"center\"/>\n")
+	b.WriteString("    </layout>\n  </head>\n  <body>\n    <div>\n")
+
+	for _, cue := range d.Cues {
+		region := "bottom"
+		if cue.Top {
+			region = "top"
+		}
+		fmt.Fprintf(&b, "      <p begin=\"%s\" end=\"%s\" region=\"%s\">", formatClock(cue.Start, ".", 3), formatClock(cue.End, ".", 3), region)
+
+		for i, line := range strings.Split(cleanLines(cue.Text), "\n") {
+			if i > 0 {
+				b.WriteString("<br/>")
+			}
+			for _, span := range splitMarkup(line) {
+				var attrs string
+				if span.italic {
+					attrs += ` tts:fontStyle="italic"`
+				}
+				if span.bold {
+					attrs += ` tts:fontWeight="bold"`
+				}
+				if span.underline {
+					attrs += ` tts:textDecoration="underline"`
+				}
+
+				if attrs != "" {
+					b.WriteString("<span" + attrs + ">")
+				}
+				if err := xml.EscapeText(&b, []byte(span.text)); err != nil {
+					return nil, err
+				}
+				if attrs != "" {
+					b.WriteString("</span>")
+				}
+			}
+		}
+
+		b.WriteString("</p>\n")
+	}
+
+	b.WriteString("    </div>\n  </body>\n</tt>\n")
+	return b.Bytes(), nil
+}
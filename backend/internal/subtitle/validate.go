@@ -0,0 +1,115 @@
+// backend/internal/subtitle/validate.go
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type IssueKind string
+
+const (
+	IssueTiming   IssueKind = "timing"   // Ends before it starts
+	IssueOverlap  IssueKind = "overlap"  // Starts before the previous cue ends
+	IssueEmpty    IssueKind = "empty"    // Has no text
+	IssueDuration IssueKind = "duration" // On screen too briefly to read, or for too long
+	IssueEncoding IssueKind = "encoding" // Text that was decoded with the wrong character set
+)
+
+const (
+	minCueDuration = 300 * time.Millisecond
+	maxCueDuration = 20 * time.Second
+)
+
+// Issue is a problem found in one cue, numbered from 1 as in SRT files
+type Issue struct {
+	Cue     int       `json:"cue"`
+	Kind    IssueKind `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// UTF-8 read as windows-1252 turns é into Ã© and ’ into â€™
+var mojibake = regexp.MustCompile(`[ÂÃ][\x{80}-\x{BF}]|â€[\x{80}-\x{BF}™œ“”˜]`)
+
+// Validate reports timing, overlap and encoding problems. Issues of kind
+// timing and empty make a cue unusable; the rest are warnings.
+func (d *Document) Validate() []Issue {
+	var issues []Issue
+
+	for i, cue := range d.Cues {
+		add := func(kind IssueKind, format string, args ...interface{}) {
+			issues = append(issues, Issue{Cue: i + 1, Kind: kind, Message: fmt.Sprintf(format, args...)})
+		}
+
+		switch duration := cue.End - cue.Start; {
+		case duration <= 0:
+			add(IssueTiming, "ends at %s, not after its start at %s", formatClock(cue.End, ".", 3), formatClock(cue.Start, ".", 3))
+		case duration < minCueDuration:
+			add(IssueDuration, "is only on screen for %s", duration)
+		case duration > maxCueDuration:
+			add(IssueDuration, "stays on screen for %s", duration.Round(time.Second))
+		}
+
+		text := stripMarkup(cue.Text)
+		if strings.TrimSpace(text) == "" {
+			add(IssueEmpty, "has no text")
+		}
+		if strings.ContainsRune(text, utf8.RuneError) || mojibake.MatchString(text) {
+			add(IssueEncoding, "has garbled characters; check the file's character encoding")
+		}
+
+		if i > 0 && cue.Start < d.Cues[i-1].End && d.Cues[i-1].End > d.Cues[i-1].Start {
+			add(IssueOverlap, "starts at %s, before cue %d ends at %s", formatClock(cue.Start, ".", 3), i, formatClock(d.Cues[i-1].End, ".", 3))
+		}
+	}
+
+	return issues
+}
+
+// Blocking reports whether any issue makes a cue unusable
+func Blocking(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Kind == IssueTiming || issue.Kind == IssueEmpty {
+			return true
+		}
+	}
+	return false
+}
+
+// Clean drops cues without text or with no duration
+func (d *Document) Clean() {
+	cues := d.Cues[:0]
+	for _, cue := range d.Cues {
+		cue.Text = cleanLines(cue.Text)
+		if cue.End > cue.Start && strings.TrimSpace(stripMarkup(cue.Text)) != "" {
+			cues = append(cues, cue)
+		}
+	}
+	d.Cues = cues
+}
+
+// ResolveOverlaps ends each cue where the next one starts. Cues starting at
+// the same moment are merged, as they were meant to be shown together.
+func (d *Document) ResolveOverlaps() {
+	d.Sort()
+
+	var cues []Cue
+	for _, cue := range d.Cues {
+		if n := len(cues); n > 0 {
+			previous := &cues[n-1]
+			if cue.Start == previous.Start {
+				previous.Text += "\n" + cue.Text
+				previous.End = max(previous.End, cue.End)
+				continue
+			}
+			if cue.Start < previous.End {
+				previous.End = cue.Start
+			}
+		}
+		cues = append(cues, cue)
+	}
+	d.Cues = cues
+}
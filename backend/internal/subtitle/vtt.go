@@ -0,0 +1,87 @@
+// backend/internal/subtitle/vtt.go
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseVTT reads WebVTT. Comments, style and region blocks are skipped, cue
+// identifiers are dropped and of the cue settings only a line near the top
+// of the frame is kept.
+func parseVTT(text string) (*Document, error) {
+	doc := &Document{}
+
+	for i, block := range splitBlocks(text) {
+		first := block.lines[0]
+		if i == 0 && strings.HasPrefix(first, "WEBVTT") {
+			// The header block may carry metadata lines but never a cue
+			continue
+		}
+		if first == "NOTE" || strings.HasPrefix(first, "NOTE ") || first == "STYLE" || first == "REGION" {
+			continue
+		}
+
+		timing := 0
+		if !strings.Contains(first, "-->") {
+			timing = 1
+		}
+		if timing >= len(block.lines) || !strings.Contains(block.lines[timing], "-->") {
+			return nil, fmt.Errorf("vtt line %d: expected a cue timing line", block.start+timing)
+		}
+
+		start, end, settings, err := parseArrow(block.lines[timing])
+		if err != nil {
+			return nil, fmt.Errorf("vtt line %d: %v", block.start+timing, err)
+		}
+
+		doc.Cues = append(doc.Cues, Cue{
+			Start: start,
+			End:   end,
+			Text:  normalizeMarkup(strings.Join(block.lines[timing+1:], "\n"), unescapeEntities),
+			Top:   vttTop(settings),
+		})
+	}
+
+	return doc, nil
+}
+
+// vttTop reports whether cue settings place a cue in the top half of the frame
+func vttTop(settings string) bool {
+	for _, setting := range strings.Fields(settings) {
+		name, value, ok := strings.Cut(setting, ":")
+		if !ok || name != "line" {
+			continue
+		}
+
+		value, _, _ = strings.Cut(value, ",")
+		if percent, ok := strings.CutSuffix(value, "%"); ok {
+			n, err := strconv.ParseFloat(percent, 64)
+			return err == nil && n < 50
+		}
+
+		// Positive line numbers count down from the top, negative up from the bottom
+		n, err := strconv.Atoi(value)
+		return err == nil && n >= 0 && n < 5
+	}
+	return false
+}
+
+func encodeVTT(d *Document) []byte {
+	var b bytes.Buffer
+	b.WriteString("WEBVTT\n")
+
+	for _, cue := range d.Cues {
+		fmt.Fprintf(&b, "\n%s --> %s", formatClock(cue.Start, ".", 3), formatClock(cue.End, ".", 3))
+		if cue.Top {
+			b.WriteString(" line:0")
+		}
+		b.WriteByte('\n')
+		b.WriteString(escapeMarkup(cleanLines(cue.Text)))
+		b.WriteByte('\n')
+	}
+
+	return b.Bytes()
+}
@@ -60,8 +60,8 @@ func main() {
 		log.Fatal("Failed to seed watch history:", err)
 	}
 
-	if err := seedVideoMetrics(db, userIDs, contentIDs); err != nil {
-		log.Fatal("Failed to seed video metrics:", err)
+	if err := seedPlaybackSessions(db, userIDs, contentIDs); err != nil {
+		log.Fatal("Failed to seed playback sessions:", err)
 	}
 
 	fmt.Println("✅ Database seeding completed successfully!")
@@ -75,8 +75,8 @@ func main() {
 func clearDatabase(db *mongo.Database) error {
 	collections := []string{
 		"users", "content", "subscription_plans", "subscriptions",
-		"payments", "invoices", "login_attempts", "video_metrics",
-		"subscription_usage",
+		"payments", "invoices", "login_attempts", "playback_sessions",
+		"playback_events", "playback_rollups", "subscription_usage",
 	}
 
 	for _, collection := range collections {
@@ -829,41 +829,85 @@ func seedWatchHistory(db *mongo.Database, userIDs []primitive.ObjectID, contentI
 	return nil
 }
 
-func seedVideoMetrics(db *mongo.Database, userIDs []primitive.ObjectID, contentIDs []primitive.ObjectID) error {
-	metrics := []interface{}{
-		primitive.M{
-			"content_id":     contentIDs[0].Hex(),
-			"user_id":        userIDs[1].Hex(),
-			"quality":        "1080p",
-			"watch_duration": 6300, // seconds
-			"total_duration": 8340,
-			"buffer_events":  2,
-			"bitrate_kbps":   6000,
-			"cdn_node":       "us-east-1",
-			"client_ip":      "192.168.1.100",
-			"user_agent":     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-			"timestamp":      time.Now().AddDate(0, 0, -2),
-		},
-		primitive.M{
-			"content_id":     contentIDs[1].Hex(),
-			"user_id":        userIDs[1].Hex(),
-			"quality":        "720p",
-			"watch_duration": 8520,
-			"total_duration": 8520,
-			"buffer_events":  0,
-			"bitrate_kbps":   3000,
-			"cdn_node":       "us-east-1",
-			"client_ip":      "192.168.1.100",
-			"user_agent":     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
-			"timestamp":      time.Now().AddDate(0, 0, -5),
-		},
+func seedPlaybackSessions(db *mongo.Database, userIDs []primitive.ObjectID, contentIDs []primitive.ObjectID) error {
+	samples := []struct {
+		contentID primitive.ObjectID
+		quality   models.VideoQuality
+		bitrate   int
+		played    float64
+		duration  float64
+		rebuffers int
+		daysAgo   int
+	}{
+		{contentIDs[0], models.Quality1080p, 6000, 6300, 8340, 2, 2},
+		{contentIDs[1], models.Quality720p, 3000, 8520, 8520, 0, 5},
 	}
 
-	_, err := db.Collection("video_metrics").InsertMany(context.Background(), metrics)
-	if err != nil {
-		return fmt.Errorf("failed to insert video metrics: %v", err)
+	var sessions, events []interface{}
+	for _, sample := range samples {
+		startedAt := time.Now().AddDate(0, 0, -sample.daysAgo)
+		endedAt := startedAt.Add(time.Duration(sample.played) * time.Second)
+
+		session := models.PlaybackSession{
+			ID:              primitive.NewObjectID(),
+			UserID:          userIDs[1],
+			ContentID:       sample.contentID,
+			DeviceID:        "seed-web-browser",
+			DeviceType:      "web",
+			Quality:         sample.quality,
+			BitrateKbps:     sample.bitrate,
+			StartupTimeMs:   850,
+			PlayedSeconds:   sample.played,
+			RebufferSeconds: float64(sample.rebuffers) * 1.5,
+			RebufferCount:   sample.rebuffers,
+			Position:        sample.played,
+			Duration:        sample.duration,
+			Completed:       sample.played >= sample.duration*0.9,
+			StartedAt:       startedAt,
+			LastEventAt:     endedAt,
+			EndedAt:         &endedAt,
+		}
+		sessions = append(sessions, session)
+
+		event := func(eventType models.PlaybackEventType, at time.Time) models.PlaybackEvent {
+			return models.PlaybackEvent{
+				ID:          primitive.NewObjectID(),
+				SessionID:   session.ID,
+				UserID:      session.UserID,
+				ContentID:   session.ContentID,
+				DeviceType:  session.DeviceType,
+				Type:        eventType,
+				Quality:     session.Quality,
+				BitrateKbps: session.BitrateKbps,
+				Timestamp:   at,
+			}
+		}
+
+		start := event(models.PlaybackEventStart, startedAt)
+		start.DurationMs = session.StartupTimeMs
+		events = append(events, start)
+
+		for i := 0; i < sample.rebuffers; i++ {
+			rebuffer := event(models.PlaybackEventRebuffer, startedAt.Add(time.Duration(i+1)*time.Hour))
+			rebuffer.DurationMs = 1500
+			events = append(events, rebuffer)
+		}
+
+		stop := event(models.PlaybackEventStop, endedAt)
+		stop.PlayedSeconds = session.PlayedSeconds
+		stop.Position = session.Position
+		stop.Completed = session.Completed
+		events = append(events, stop)
+	}
+
+	if _, err := db.Collection("playback_sessions").InsertMany(context.Background(), sessions); err != nil {
+		return fmt.Errorf("failed to insert playback sessions: %v", err)
+	}
+
+	if _, err := db.Collection("playback_events").InsertMany(context.Background(), events); err != nil {
+		return fmt.Errorf("failed to insert playback events: %v", err)
 	}
 
-	fmt.Println("📊 Created video metrics")
+	fmt.Println("📊 Created playback sessions")
 	return nil
 }